@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kalmhq/kalm/api/log"
+	"go.uber.org/zap"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is a record of a mutating api call
+type Event struct {
+	Time time.Time `json:"time"`
+
+	// who made the call
+	Subject           string   `json:"subject"`
+	Groups            []string `json:"groups,omitempty"`
+	Impersonation     string   `json:"impersonation,omitempty"`
+	ImpersonationType string   `json:"impersonationType,omitempty"`
+	AccessToken       string   `json:"accessToken,omitempty"`
+	ClientIP          string   `json:"clientIP,omitempty"`
+
+	// what was changed
+	Verb      string `json:"verb"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Route     string `json:"route"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	DryRun    bool   `json:"dryRun,omitempty"`
+
+	// sha256 of the request body, empty if there is no body
+	RequestBodyHash string `json:"requestBodyHash,omitempty"`
+
+	Outcome    string `json:"outcome"`
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error,omitempty"`
+}
+
+// User is the one the call is made as, the impersonated user if the subject is impersonating
+func (e *Event) User() string {
+	if e.Impersonation != "" {
+		return e.Impersonation
+	}
+
+	return e.Subject
+}
+
+// Sink is where events are written to
+type Sink interface {
+	Write(event *Event) error
+	Close() error
+}
+
+// Query filters events, zero values match all events
+type Query struct {
+	// matches both the subject and the impersonated user
+	User      string
+	Namespace string
+	Since     time.Time
+	Until     time.Time
+
+	// the most recent events are returned if there are more than the limit
+	Limit int
+}
+
+const DefaultQueryLimit = 100
+
+func (q *Query) Match(event *Event) bool {
+	if q.User != "" && q.User != event.Subject && q.User != event.Impersonation {
+		return false
+	}
+
+	if q.Namespace != "" && q.Namespace != event.Namespace {
+		return false
+	}
+
+	if !q.Since.IsZero() && event.Time.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && event.Time.After(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Querier is a sink that events can be read back from
+type Querier interface {
+	Query(query *Query) ([]*Event, error)
+}
+
+// limitEvents sorts events from the most recent and keeps at most limit of them
+func limitEvents(events []*Event, limit int) []*Event {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events
+}
+
+// Auditor writes events to all sinks, and queries events from the first sink that can be queried
+type Auditor struct {
+	sinks   []Sink
+	querier Querier
+}
+
+func NewAuditor(sinks ...Sink) *Auditor {
+	auditor := &Auditor{sinks: sinks}
+
+	for _, sink := range sinks {
+		if querier, ok := sink.(Querier); ok {
+			auditor.querier = querier
+			break
+		}
+	}
+
+	// keep recent events in memory to be queried if no persistent sink is configured
+	if auditor.querier == nil {
+		memorySink := NewMemorySink(DefaultMemorySinkSize)
+		auditor.sinks = append(auditor.sinks, memorySink)
+		auditor.querier = memorySink
+	}
+
+	return auditor
+}
+
+// Record writes the event to all sinks. A failing sink doesn't fail the api call, errors are logged.
+func (a *Auditor) Record(event *Event) {
+	for _, sink := range a.sinks {
+		if err := sink.Write(event); err != nil {
+			log.Error("write audit event error", zap.Error(err))
+		}
+	}
+}
+
+func (a *Auditor) Query(query *Query) ([]*Event, error) {
+	return a.querier.Query(query)
+}
+
+func (a *Auditor) Close() error {
+	var lastErr error
+
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
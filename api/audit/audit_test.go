@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvent(i int, subject, namespace string) *Event {
+	return &Event{
+		Time:      time.Date(2020, 12, 1, 0, i, 0, 0, time.UTC),
+		Subject:   subject,
+		Verb:      "update",
+		Method:    http.MethodPut,
+		Resource:  "components",
+		Namespace: namespace,
+		Name:      fmt.Sprintf("web-%d", i),
+		Outcome:   OutcomeSuccess,
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	event := newTestEvent(10, "admin@kalm.dev", "shop")
+	event.Impersonation = "dev@kalm.dev"
+
+	assert.True(t, (&Query{}).Match(event))
+	assert.True(t, (&Query{User: "admin@kalm.dev"}).Match(event))
+	assert.True(t, (&Query{User: "dev@kalm.dev", Namespace: "shop"}).Match(event))
+	assert.False(t, (&Query{User: "other@kalm.dev"}).Match(event))
+	assert.False(t, (&Query{Namespace: "blog"}).Match(event))
+
+	assert.True(t, (&Query{Since: event.Time, Until: event.Time}).Match(event))
+	assert.False(t, (&Query{Since: event.Time.Add(time.Second)}).Match(event))
+	assert.False(t, (&Query{Until: event.Time.Add(-time.Second)}).Match(event))
+
+	assert.Equal(t, "dev@kalm.dev", event.User())
+}
+
+func TestMemorySink(t *testing.T) {
+	sink := NewMemorySink(3)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, sink.Write(newTestEvent(i, "admin@kalm.dev", "shop")))
+	}
+
+	// only the most recent ones are kept, returned from the most recent
+	events, err := sink.Query(&Query{})
+	assert.Nil(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "web-4", events[0].Name)
+	assert.Equal(t, "web-2", events[2].Name)
+
+	events, err = sink.Query(&Query{Limit: 1})
+	assert.Nil(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "web-4", events[0].Name)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kalm-audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit", "audit.log")
+	line, _ := json.Marshal(newTestEvent(0, "admin@kalm.dev", "shop"))
+
+	// about 3 events a file
+	sink, err := NewFileSink(path, int64(len(line)*3+10), 2)
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		subject := "admin@kalm.dev"
+		if i%2 == 1 {
+			subject = "dev@kalm.dev"
+		}
+
+		assert.Nil(t, sink.Write(newTestEvent(i, subject, "shop")))
+	}
+
+	_, err = os.Stat(path + ".2")
+	assert.Nil(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// the oldest events are rotated out
+	events, err := sink.Query(&Query{})
+	assert.Nil(t, err)
+	require.True(t, len(events) > 5 && len(events) < 10)
+	assert.Equal(t, "web-9", events[0].Name)
+	assert.Equal(t, fmt.Sprintf("web-%d", 10-len(events)), events[len(events)-1].Name)
+
+	events, err = sink.Query(&Query{
+		User:  "dev@kalm.dev",
+		Since: time.Date(2020, 12, 1, 0, 3, 0, 0, time.UTC),
+		Until: time.Date(2020, 12, 1, 0, 7, 0, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "web-7", events[0].Name)
+	assert.Equal(t, "web-3", events[2].Name)
+
+	assert.Nil(t, sink.Close())
+
+	// events are appended after restarts
+	sink, err = NewFileSink(path, 1024*1024, 2)
+	require.Nil(t, err)
+	assert.Nil(t, sink.Write(newTestEvent(10, "admin@kalm.dev", "blog")))
+
+	events, err = sink.Query(&Query{Limit: 2})
+	assert.Nil(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "web-10", events[0].Name)
+	assert.Equal(t, "web-9", events[1].Name)
+	assert.Nil(t, sink.Close())
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	assert.Nil(t, sink.Write(newTestEvent(0, "admin@kalm.dev", "shop")))
+
+	var event Event
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "admin@kalm.dev", event.Subject)
+	assert.Equal(t, "components", event.Resource)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mut sync.Mutex
+	var received []Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&event))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		mut.Lock()
+		received = append(received, event)
+		mut.Unlock()
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, sink.Write(newTestEvent(i, "admin@kalm.dev", "shop")))
+	}
+
+	// close waits for queued events
+	assert.Nil(t, sink.Close())
+	require.Len(t, received, 3)
+	assert.Equal(t, "web-0", received[0].Name)
+}
+
+func TestAuditor(t *testing.T) {
+	var buf bytes.Buffer
+
+	// events are kept in memory if there is no sink to query
+	auditor := NewAuditor(NewWriterSink(&buf))
+	auditor.Record(newTestEvent(0, "admin@kalm.dev", "shop"))
+	auditor.Record(newTestEvent(1, "admin@kalm.dev", "blog"))
+
+	events, err := auditor.Query(&Query{Namespace: "blog"})
+	assert.Nil(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "web-1", events[0].Name)
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	assert.Nil(t, auditor.Close())
+}
@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	DefaultFileSinkMaxSize    = 100 * 1024 * 1024
+	DefaultFileSinkMaxBackups = 5
+)
+
+// FileSink writes events as json lines into a local file.
+// The file is rotated when it exceeds the max size, rotated files are named with suffix .1, .2 and so on, .1 is the most recent.
+type FileSink struct {
+	mut sync.Mutex
+
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultFileSinkMaxSize
+	}
+
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+
+	sink := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+func (s *FileSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return s.open()
+	}
+
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return err
+}
+
+// Query reads the current file and all rotated files
+func (s *FileSink) Query(query *Query) ([]*Event, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var res []*Event
+
+	paths := []string{s.path}
+	for i := 1; i <= s.maxBackups; i++ {
+		paths = append(paths, s.backupPath(i))
+	}
+
+	for _, path := range paths {
+		events, err := readEventsFile(path, query)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, events...)
+	}
+
+	return limitEvents(res, query.Limit), nil
+}
+
+func readEventsFile(path string, query *Query) ([]*Event, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var res []*Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var event Event
+
+		// skip broken lines, e.g. the last line written when the disk was full
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+
+		if query.Match(&event) {
+			res = append(res, &event)
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+func (s *FileSink) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,45 @@
+package audit
+
+import "sync"
+
+const DefaultMemorySinkSize = 1000
+
+// MemorySink keeps the most recent events in memory, they are lost when the api server restarts
+type MemorySink struct {
+	mut    sync.RWMutex
+	events []*Event
+	next   int
+}
+
+func NewMemorySink(size int) *MemorySink {
+	return &MemorySink{events: make([]*Event, size)}
+}
+
+func (s *MemorySink) Write(event *Event) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.events[s.next] = event
+	s.next = (s.next + 1) % len(s.events)
+
+	return nil
+}
+
+func (s *MemorySink) Query(query *Query) ([]*Event, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	var res []*Event
+
+	for _, event := range s.events {
+		if event != nil && query.Match(event) {
+			res = append(res, event)
+		}
+	}
+
+	return limitEvents(res, query.Limit), nil
+}
+
+func (s *MemorySink) Close() error {
+	return nil
+}
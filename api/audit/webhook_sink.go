@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kalmhq/kalm/api/log"
+	"go.uber.org/zap"
+)
+
+const webhookSinkQueueSize = 1000
+
+// WebhookSink posts each event as json to the url.
+// Events are sent in background, so a slow webhook doesn't slow down api calls. Events are dropped if the queue is full.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+
+	queue chan *Event
+	wg    sync.WaitGroup
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	sink := &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *Event, webhookSinkQueueSize),
+	}
+
+	sink.wg.Add(1)
+	go sink.run()
+
+	return sink
+}
+
+func (s *WebhookSink) run() {
+	defer s.wg.Done()
+
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			log.Error("send audit event to webhook error", zap.String("url", s.url), zap.Error(err))
+		}
+	}
+}
+
+func (s *WebhookSink) send(event *Event) error {
+	body, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *WebhookSink) Write(event *Event) error {
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue is full, event dropped")
+	}
+}
+
+// Close waits until queued events are sent
+func (s *WebhookSink) Close() error {
+	close(s.queue)
+	s.wg.Wait()
+	return nil
+}
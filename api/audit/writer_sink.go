@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes events as json lines into the writer, e.g. stdout to be collected with container logs
+type WriterSink struct {
+	mut     sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{encoder: json.NewEncoder(w)}
+}
+
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Write(event *Event) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.encoder.Encode(event)
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
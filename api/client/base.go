@@ -29,6 +29,9 @@ type ClientInfo struct {
 	Groups            []string     `json:"groups"`
 	Impersonation     string       `json:"impersonation"`
 	ImpersonationType string       `json:"impersonationType"`
+
+	// set if the client is authenticated by an access token
+	AccessTokenName string `json:"-"`
 }
 
 type ClientManager interface {
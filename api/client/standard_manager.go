@@ -198,11 +198,12 @@ func (m *StandardClientManager) GetClientInfoFromToken(tokenString string) (*Cli
 	}
 
 	clientInfo := &ClientInfo{
-		Cfg:           m.ClusterConfig,
-		Name:          accessToken.Name,
-		Email:         accessToken.Name,
-		EmailVerified: false,
-		Groups:        []string{},
+		Cfg:             m.ClusterConfig,
+		Name:            accessToken.Name,
+		Email:           accessToken.Name,
+		EmailVerified:   false,
+		Groups:          []string{},
+		AccessTokenName: accessToken.Name,
 	}
 
 	return clientInfo, nil
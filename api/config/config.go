@@ -22,6 +22,17 @@ type Config struct {
 	CorsAllowedOrigins            cli.StringSlice
 
 	EnableAdminServerDebugRoutes bool
+
+	AuditLogFilePath   string
+	AuditLogMaxSizeMB  int
+	AuditLogMaxBackups int
+	AuditLogStdout     bool
+	AuditWebhookURL    string
+
+	MetricStoragePath string
+	MetricRollups     string
+
+	MetricsBindAddress string
 }
 
 type BaseDomainConfig struct {
@@ -73,3 +73,24 @@ func CustomHTTPErrorHandler(err error, c echo.Context) {
 		c.JSON(code, &ErrorRes{Status: metav1.StatusFailure, Message: message})
 	}
 }
+
+// StatusCode returns the status code CustomHTTPErrorHandler responds for the error
+func StatusCode(err error) int {
+	if errWithCode, ok := err.(ErrorWithCode); ok {
+		return errWithCode.StatusCode()
+	}
+
+	if statusError, ok := err.(*errors.StatusError); ok && statusError.Status().Code > 0 {
+		return int(statusError.ErrStatus.Code)
+	}
+
+	if _, ok := err.(v1alpha1.KalmValidateErrorList); ok {
+		return http.StatusBadRequest
+	}
+
+	if httpError, ok := err.(*echo.HTTPError); ok {
+		return httpError.Code
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kalmhq/kalm/api/errors"
+	"github.com/kalmhq/kalm/api/resources"
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (h *ApiHandler) InstallAlertRuleHandlers(e *echo.Group) {
+	e.GET("/applications/:applicationName/alertrules", h.handleListAlertRules)
+	e.GET("/applications/:applicationName/alertrules/:name", h.handleGetAlertRule)
+	e.POST("/applications/:applicationName/alertrules", h.handleCreateAlertRule)
+	e.PUT("/applications/:applicationName/alertrules/:name", h.handleUpdateAlertRule)
+	e.DELETE("/applications/:applicationName/alertrules/:name", h.handleDeleteAlertRule)
+	e.POST("/applications/:applicationName/alertrules/:name/silences", h.handleCreateAlertSilence)
+	e.DELETE("/applications/:applicationName/alertrules/:name/silences/:id", h.handleDeleteAlertSilence)
+	e.GET("/applications/:applicationName/alerts/history", h.handleListAlertHistory)
+}
+
+type AlertSilenceRequest struct {
+	// Target of the rule, all targets are silenced if it's blank
+	Target string `json:"target"`
+
+	DurationSeconds int64  `json:"durationSeconds"`
+	Comment         string `json:"comment"`
+}
+
+func (h *ApiHandler) handleListAlertRules(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "alertRules/*")
+
+	var ruleList v1alpha1.AlertRuleList
+
+	if err := h.resourceManager.List(&ruleList, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	rst := []*resources.AlertRule{}
+
+	for i := range ruleList.Items {
+		rst = append(rst, resources.BuildAlertRuleFromResource(&ruleList.Items[i]))
+	}
+
+	return c.JSON(200, rst)
+}
+
+func (h *ApiHandler) handleGetAlertRule(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "alertRules/"+c.Param("name"))
+
+	var rule v1alpha1.AlertRule
+
+	if err := h.resourceManager.Get(namespace, c.Param("name"), &rule); err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.BuildAlertRuleFromResource(&rule))
+}
+
+func (h *ApiHandler) handleCreateAlertRule(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanEdit(getCurrentUser(c), namespace, "alertRules/*")
+
+	ruleReq, err := getAlertRuleFromContext(c)
+
+	if err != nil {
+		return err
+	}
+
+	rule := &v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      ruleReq.Name,
+		},
+		Spec: *ruleReq.AlertRuleSpec,
+	}
+
+	if err := h.resourceManager.Create(rule); err != nil {
+		return err
+	}
+
+	return c.JSON(201, resources.BuildAlertRuleFromResource(rule))
+}
+
+// handleUpdateAlertRule keeps the silences, they are managed by the silence apis
+func (h *ApiHandler) handleUpdateAlertRule(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanEdit(getCurrentUser(c), namespace, "alertRules/"+c.Param("name"))
+
+	ruleReq, err := getAlertRuleFromContext(c)
+
+	if err != nil {
+		return err
+	}
+
+	var rule v1alpha1.AlertRule
+
+	if err := h.resourceManager.Get(namespace, c.Param("name"), &rule); err != nil {
+		return err
+	}
+
+	silences := rule.Spec.Silences
+	rule.Spec = *ruleReq.AlertRuleSpec
+	rule.Spec.Silences = silences
+
+	if err := h.resourceManager.Update(&rule); err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.BuildAlertRuleFromResource(&rule))
+}
+
+func (h *ApiHandler) handleDeleteAlertRule(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanEdit(getCurrentUser(c), namespace, "alertRules/"+c.Param("name"))
+
+	rule := &v1alpha1.AlertRule{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: c.Param("name")}}
+
+	if err := h.resourceManager.Delete(rule); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *ApiHandler) handleCreateAlertSilence(c echo.Context) error {
+	currentUser := getCurrentUser(c)
+	namespace := c.Param("applicationName")
+	h.MustCanEdit(currentUser, namespace, "alertRules/"+c.Param("name"))
+
+	var silenceReq AlertSilenceRequest
+
+	if err := c.Bind(&silenceReq); err != nil {
+		return err
+	}
+
+	if silenceReq.DurationSeconds <= 0 {
+		return errors.NewBadRequest("durationSeconds must be a positive integer")
+	}
+
+	var rule v1alpha1.AlertRule
+
+	if err := h.resourceManager.Get(namespace, c.Param("name"), &rule); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	silence := v1alpha1.AlertSilence{
+		ID:           uuid.New().String(),
+		Target:       silenceReq.Target,
+		EndTimestamp: now.Unix() + silenceReq.DurationSeconds,
+		Comment:      silenceReq.Comment,
+		CreatedBy:    currentUser.Name,
+	}
+
+	copied := rule.DeepCopy()
+	copied.Spec.Silences = []v1alpha1.AlertSilence{silence}
+
+	// expired silences are cleaned up on the way
+	for _, s := range rule.Spec.Silences {
+		if s.EndTimestamp > now.Unix() {
+			copied.Spec.Silences = append(copied.Spec.Silences, s)
+		}
+	}
+
+	if err := h.resourceManager.Patch(copied, client.MergeFrom(&rule)); err != nil {
+		return err
+	}
+
+	return c.JSON(201, silence)
+}
+
+func (h *ApiHandler) handleDeleteAlertSilence(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanEdit(getCurrentUser(c), namespace, "alertRules/"+c.Param("name"))
+
+	var rule v1alpha1.AlertRule
+
+	if err := h.resourceManager.Get(namespace, c.Param("name"), &rule); err != nil {
+		return err
+	}
+
+	copied := rule.DeepCopy()
+	copied.Spec.Silences = nil
+
+	for _, s := range rule.Spec.Silences {
+		if s.ID != c.Param("id") {
+			copied.Spec.Silences = append(copied.Spec.Silences, s)
+		}
+	}
+
+	if len(copied.Spec.Silences) == len(rule.Spec.Silences) {
+		return errors.NewNotFound("silence " + c.Param("id") + " is not found")
+	}
+
+	if err := h.resourceManager.Patch(copied, client.MergeFrom(&rule)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *ApiHandler) handleListAlertHistory(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "alertRules/*")
+
+	query := resources.AlertHistoryQuery{
+		Rule:   c.QueryParam("rule"),
+		Target: c.QueryParam("target"),
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+
+		if err != nil {
+			return errors.NewBadRequest("since must be a RFC3339 time")
+		}
+
+		query.Since = t.Unix()
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		var err error
+
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return errors.NewBadRequest("limit must be a positive integer")
+		}
+	}
+
+	var ruleList v1alpha1.AlertRuleList
+
+	if err := h.resourceManager.List(&ruleList, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.QueryAlertHistory(ruleList.Items, query))
+}
+
+func getAlertRuleFromContext(c echo.Context) (*resources.AlertRule, error) {
+	var rule resources.AlertRule
+
+	if err := c.Bind(&rule); err != nil {
+		return nil, err
+	}
+
+	if rule.AlertRuleSpec == nil {
+		return nil, errors.NewBadRequest("must provide alert rule spec")
+	}
+
+	return &rule, nil
+}
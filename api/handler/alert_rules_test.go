@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kalmhq/kalm/api/resources"
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/suite"
+)
+
+type AlertRulesHandlerTestSuite struct {
+	WithControllerTestSuite
+}
+
+func (suite *AlertRulesHandlerTestSuite) SetupSuite() {
+	suite.WithControllerTestSuite.SetupSuite()
+	suite.ensureNamespaceExist("test-alert-rules")
+}
+
+func (suite *AlertRulesHandlerTestSuite) TestAlertRulesHandler() {
+	rule := resources.AlertRule{
+		Name: "cpu",
+		AlertRuleSpec: &v1alpha1.AlertRuleSpec{
+			Type:      v1alpha1.AlertRuleTypeCPU,
+			Threshold: 500,
+			Channels: []v1alpha1.AlertChannel{
+				{Type: v1alpha1.AlertChannelTypeWebhook, URL: "http://kalm.test"},
+			},
+		},
+	}
+
+	// create a rule
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodPost,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules",
+		Body:   rule,
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.EqualValues(201, rec.Code)
+		},
+	})
+
+	// list rules
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetViewerRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodGet,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules",
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var rules []*resources.AlertRule
+			rec.BodyAsJSON(&rules)
+			suite.Len(rules, 1)
+			suite.EqualValues("cpu", rules[0].Name)
+			suite.EqualValues(500, rules[0].Threshold)
+		},
+	})
+
+	// silence the rule
+	var silence v1alpha1.AlertSilence
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodPost,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules/cpu/silences",
+		Body:   AlertSilenceRequest{Target: "web", DurationSeconds: 3600, Comment: "maintenance"},
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.EqualValues(201, rec.Code)
+			rec.BodyAsJSON(&silence)
+			suite.NotEmpty(silence.ID)
+		},
+	})
+
+	// silences are kept on update
+	rule.Threshold = 800
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodPut,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules/cpu",
+		Body:   rule,
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.AlertRule
+			rec.BodyAsJSON(&res)
+			suite.EqualValues(200, rec.Code)
+			suite.EqualValues(800, res.Threshold)
+			suite.Len(res.Silences, 1)
+		},
+	})
+
+	// delete the silence
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodDelete,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules/cpu/silences/" + silence.ID,
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.EqualValues(200, rec.Code)
+		},
+	})
+
+	// history
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetViewerRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodGet,
+		Path:   "/v1alpha1/applications/test-alert-rules/alerts/history?rule=cpu&limit=10",
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var records []resources.AlertHistoryRecord
+			rec.BodyAsJSON(&records)
+			suite.EqualValues(200, rec.Code)
+			suite.Len(records, 0)
+		},
+	})
+
+	// delete the rule
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace("test-alert-rules"),
+		},
+		Method: http.MethodDelete,
+		Path:   "/v1alpha1/applications/test-alert-rules/alertrules/cpu",
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.EqualValues(200, rec.Code)
+		},
+	})
+}
+
+func TestAlertRulesHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(AlertRulesHandlerTestSuite))
+}
@@ -1,15 +1,21 @@
 package handler
 
 import (
+	"io/ioutil"
 	"net/http"
 
+	client2 "github.com/kalmhq/kalm/api/client"
 	"github.com/kalmhq/kalm/api/errors"
 	"github.com/kalmhq/kalm/api/resources"
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/kalmhq/kalm/controller/controllers"
 	"github.com/labstack/echo/v4"
 	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
 // installer
@@ -17,8 +23,11 @@ import (
 func (h *ApiHandler) InstallApplicationsHandlers(e *echo.Group) {
 	e.GET("/applications", h.handleGetApplications)
 	e.POST("/applications", h.handleCreateApplication)
+	e.POST("/applications/import", h.handleImportApplication)
 	e.GET("/applications/:name", h.handleGetApplicationDetails, h.setApplicationIntoContext)
+	e.PUT("/applications/:name", h.handleUpdateApplication, h.setApplicationIntoContext)
 	e.DELETE("/applications/:name", h.handleDeleteApplication, h.setApplicationIntoContext)
+	e.GET("/applications/:name/export", h.handleExportApplication, h.setApplicationIntoContext)
 }
 
 // middlewares
@@ -82,12 +91,16 @@ func (h *ApiHandler) handleCreateApplication(c echo.Context) error {
 	currentUser := getCurrentUser(c)
 	h.MustCanEdit(currentUser, "*", "applications/*")
 
-	ns, err := bindKalmNamespaceFromRequestBody(c)
+	ns, quota, err := bindKalmNamespaceFromRequestBody(c)
 
 	if err != nil {
 		return err
 	}
 
+	if err := h.setApplicationQuota(currentUser, ns, quota); err != nil {
+		return err
+	}
+
 	if err := h.resourceManager.CreateNamespace(ns); err != nil {
 		return err
 	}
@@ -101,6 +114,41 @@ func (h *ApiHandler) handleCreateApplication(c echo.Context) error {
 	return c.JSON(http.StatusCreated, res)
 }
 
+func (h *ApiHandler) handleUpdateApplication(c echo.Context) error {
+	namespace := h.getApplicationFromContext(c)
+	currentUser := getCurrentUser(c)
+	h.MustCanEdit(currentUser, namespace.Name, "applications/"+namespace.Name)
+
+	var app resources.Application
+
+	if err := c.Bind(&app); err != nil {
+		return err
+	}
+
+	if app.ComponentRevisionHistoryLimit != nil && *app.ComponentRevisionHistoryLimit < 1 {
+		return errors.NewBadRequest("componentRevisionHistoryLimit should be positive")
+	}
+
+	copied := namespace.DeepCopy()
+	resources.SetComponentRevisionHistoryLimit(copied, app.ComponentRevisionHistoryLimit)
+
+	if err := h.setApplicationQuota(currentUser, copied, app.Quota); err != nil {
+		return err
+	}
+
+	if err := h.resourceManager.Patch(copied, client.MergeFrom(namespace)); err != nil {
+		return err
+	}
+
+	res, err := h.resourceManager.BuildApplicationDetails(copied)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, res)
+}
+
 func (h *ApiHandler) handleDeleteApplication(c echo.Context) error {
 	currentUser := getCurrentUser(c)
 	h.MustCanEdit(currentUser, "*", "applications/*")
@@ -114,13 +162,117 @@ func (h *ApiHandler) handleDeleteApplication(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// format=yaml returns the bundle in yaml, default to json
+func (h *ApiHandler) handleExportApplication(c echo.Context) error {
+	namespace := h.getApplicationFromContext(c)
+	currentUser := getCurrentUser(c)
+	h.MustCanView(currentUser, namespace.Name, "applications/"+namespace.Name)
+
+	bundle, err := h.resourceManager.ExportApplicationBundle(namespace)
+
+	if err != nil {
+		return err
+	}
+
+	switch c.QueryParam("format") {
+	case "", "json":
+		return c.JSON(200, bundle)
+	case "yaml":
+		bts, err := yaml.Marshal(bundle)
+
+		if err != nil {
+			return err
+		}
+
+		return c.Blob(200, "application/x-yaml", bts)
+	default:
+		return errors.NewBadRequest("format should be json or yaml")
+	}
+}
+
+// The body is a bundle in json or yaml. Query params:
+//   name:     import as the application of this name, default to the one in the bundle
+//   dryRun:   only return the changes
+//   conflict: fail(default), skip or overwrite existing objects which are different from the bundle
+func (h *ApiHandler) handleImportApplication(c echo.Context) error {
+	currentUser := getCurrentUser(c)
+
+	body, err := ioutil.ReadAll(c.Request().Body)
+
+	if err != nil {
+		return err
+	}
+
+	var bundle resources.ApplicationBundle
+
+	if err := yaml.Unmarshal(body, &bundle); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
+	options := resources.ApplicationImportOptions{
+		Name:     c.QueryParam("name"),
+		Conflict: resources.ApplicationImportConflictStrategy(c.QueryParam("conflict")),
+	}
+
+	if options.DryRun, err = getDryRunParam(c); err != nil {
+		return err
+	}
+
+	switch options.Conflict {
+	case "", resources.ApplicationImportConflictFail, resources.ApplicationImportConflictSkip, resources.ApplicationImportConflictOverwrite:
+	default:
+		return errors.NewBadRequest("conflict should be one of fail, skip and overwrite")
+	}
+
+	// the bundle is renamed and validated before the permissions are checked
+	options.Authorize = func(bundle *resources.ApplicationBundle) error {
+		name := bundle.Application.Name
+
+		if _, err := h.resourceManager.GetNamespace(name); err != nil {
+			if !apiErrors.IsNotFound(err) {
+				return err
+			}
+
+			h.MustCanEdit(currentUser, "*", "applications/*")
+		} else {
+			h.MustCanEdit(currentUser, name, "applications/"+name)
+		}
+
+		for i := range bundle.HttpRoutes {
+			route := &resources.HttpRoute{Name: bundle.HttpRoutes[i].Name, HttpRouteSpec: &bundle.HttpRoutes[i].Spec}
+
+			if !h.clientManager.CanOperateHttpRoute(currentUser, "edit", route) {
+				return resources.InsufficientPermissionsError
+			}
+		}
+
+		return nil
+	}
+
+	res, err := h.resourceManager.ImportApplicationBundle(&bundle, options)
+
+	if err != nil {
+		return err
+	}
+
+	if res.HasConflicts() {
+		return c.JSON(http.StatusConflict, res)
+	}
+
+	return c.JSON(200, res)
+}
+
 // helper
 
-func bindKalmNamespaceFromRequestBody(c echo.Context) (*coreV1.Namespace, error) {
+func bindKalmNamespaceFromRequestBody(c echo.Context) (*coreV1.Namespace, *v1alpha1.ApplicationQuota, error) {
 	var ns resources.Application
 
 	if err := c.Bind(&ns); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if ns.ComponentRevisionHistoryLimit != nil && *ns.ComponentRevisionHistoryLimit < 1 {
+		return nil, nil, errors.NewBadRequest("componentRevisionHistoryLimit should be positive")
 	}
 
 	coreV1Namespace := coreV1.Namespace{
@@ -132,5 +284,32 @@ func bindKalmNamespaceFromRequestBody(c echo.Context) (*coreV1.Namespace, error)
 		},
 	}
 
-	return &coreV1Namespace, nil
+	resources.SetComponentRevisionHistoryLimit(&coreV1Namespace, ns.ComponentRevisionHistoryLimit)
+
+	return &coreV1Namespace, ns.Quota, nil
+}
+
+// setApplicationQuota only cluster editors can change quotas, otherwise users could raise the quota of their own applications
+func (h *ApiHandler) setApplicationQuota(currentUser *client2.ClientInfo, namespace *coreV1.Namespace, quota *v1alpha1.ApplicationQuota) error {
+	if quota == nil {
+		return nil
+	}
+
+	current, err := v1alpha1.GetApplicationQuota(namespace)
+
+	if err != nil {
+		return err
+	}
+
+	if current == nil && quota.IsEmpty() || current != nil && equality.Semantic.DeepEqual(current, quota) {
+		return nil
+	}
+
+	h.MustCanEditCluster(currentUser)
+
+	if errList := quota.Validate(); len(errList) > 0 {
+		return errors.NewBadRequest("invalid quota, " + errList.Error())
+	}
+
+	return v1alpha1.SetApplicationQuota(namespace, quota)
 }
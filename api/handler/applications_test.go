@@ -149,6 +149,141 @@ func (suite *ApplicationsHandlerTestSuite) TestDeleteApplication() {
 	})
 }
 
+func (suite *ApplicationsHandlerTestSuite) TestApplicationQuota() {
+	name := "test-quota"
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterEditorRole(),
+		},
+		Method: http.MethodPost,
+		Path:   "/v1alpha1/applications",
+		Body:   fmt.Sprintf(`{"name": "%s", "quota": {"cpu": "2", "replicas": 4}}`, name),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.ApplicationDetails
+			rec.BodyAsJSON(&res)
+
+			suite.Equal(http.StatusCreated, rec.Code)
+			suite.Equal("2", res.Quota.CPU.String())
+			suite.Equal(int32(4), *res.Quota.Replicas)
+			suite.NotNil(res.QuotaUsage)
+			suite.Equal(int32(0), res.QuotaUsage.Replicas)
+		},
+	})
+
+	// editors of the application can't change its quota
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(name),
+		},
+		Namespace: name,
+		Method:    http.MethodPut,
+		Path:      "/v1alpha1/applications/" + name,
+		Body:      fmt.Sprintf(`{"name": "%s", "quota": {"cpu": "20"}}`, name),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+	})
+
+	// but they can keep it unchanged
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(name),
+		},
+		Namespace: name,
+		Method:    http.MethodPut,
+		Path:      "/v1alpha1/applications/" + name,
+		Body:      fmt.Sprintf(`{"name": "%s", "componentRevisionHistoryLimit": 5, "quota": {"cpu": "2", "replicas": 4}}`, name),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(200, rec.Code)
+		},
+	})
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterEditorRole(),
+		},
+		Method: http.MethodPut,
+		Path:   "/v1alpha1/applications/" + name,
+		Body:   fmt.Sprintf(`{"name": "%s", "quota": {"cpu": "-1"}}`, name),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(400, rec.Code)
+		},
+	})
+
+	// an empty quota removes it
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterEditorRole(),
+		},
+		Method: http.MethodPut,
+		Path:   "/v1alpha1/applications/" + name,
+		Body:   fmt.Sprintf(`{"name": "%s", "quota": {}}`, name),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.ApplicationDetails
+			rec.BodyAsJSON(&res)
+
+			suite.Equal(200, rec.Code)
+			suite.Nil(res.Quota)
+			suite.Nil(res.QuotaUsage)
+		},
+	})
+}
+
+func (suite *ApplicationsHandlerTestSuite) TestExportAndImportApplication() {
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterEditorRole(),
+		},
+		Method: http.MethodPost,
+		Path:   "/v1alpha1/applications",
+		Body:   `{"name": "export-test"}`,
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(201, rec.Code)
+		},
+	})
+
+	var bundle resources.ApplicationBundle
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetViewerRoleOfNamespace("export-test"),
+		},
+		Namespace: "export-test",
+		Method:    http.MethodGet,
+		Path:      "/v1alpha1/applications/export-test/export",
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec, "view", "export-test")
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			rec.BodyAsJSON(&bundle)
+			suite.Equal(200, rec.Code)
+			suite.Equal(resources.ApplicationBundleKind, bundle.Kind)
+			suite.Equal("export-test", bundle.Application.Name)
+		},
+	})
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterEditorRole(),
+		},
+		Method: http.MethodPost,
+		Path:   "/v1alpha1/applications/import?name=export-test-copy&dryRun=true",
+		Body:   bundle,
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec, "edit")
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.ApplicationImportResult
+			rec.BodyAsJSON(&res)
+			suite.Equal(200, rec.Code)
+			suite.True(res.DryRun)
+			suite.Equal("export-test-copy", res.Application)
+			suite.Equal(resources.ApplicationImportActionCreate, res.Changes[0].Action)
+		},
+	})
+}
+
 func TestApplicationsHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(ApplicationsHandlerTestSuite))
 }
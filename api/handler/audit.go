@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kalmhq/kalm/api/audit"
+	"github.com/kalmhq/kalm/api/errors"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *ApiHandler) handleListAuditEvents(c echo.Context) error {
+	h.MustCanManageCluster(getCurrentUser(c))
+
+	query := &audit.Query{
+		User:      c.QueryParam("user"),
+		Namespace: c.QueryParam("namespace"),
+	}
+
+	var err error
+
+	if since := c.QueryParam("since"); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return errors.NewBadRequest("since must be a RFC3339 time")
+		}
+	}
+
+	if until := c.QueryParam("until"); until != "" {
+		if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return errors.NewBadRequest("until must be a RFC3339 time")
+		}
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return errors.NewBadRequest("limit must be a positive integer")
+		}
+	}
+
+	events, err := h.Auditor.Query(query)
+
+	if err != nil {
+		return err
+	}
+
+	if events == nil {
+		events = []*audit.Event{}
+	}
+
+	return c.JSON(200, events)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kalmhq/kalm/api/audit"
+	client2 "github.com/kalmhq/kalm/api/client"
+	"github.com/kalmhq/kalm/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type AuditHandlerTestSuite struct {
+	WithControllerTestSuite
+}
+
+func (suite *AuditHandlerTestSuite) SetupSuite() {
+	suite.WithControllerTestSuite.SetupSuite()
+
+	suite.Nil(suite.Create(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-audit-node",
+		},
+	}))
+}
+
+// setupApiServerWithAuditor shares the auditor, so events recorded by one request can be queried by later ones
+func (suite *AuditHandlerTestSuite) setupApiServerWithAuditor(auditor *audit.Auditor) *echo.Echo {
+	e := server.NewEchoInstance()
+	policies := []string{
+		client2.BuildClusterRolePolicies(),
+		GrantUserRoles("owner@kalm.dev", GetClusterOwnerRole()),
+		GrantUserRoles("viewer@kalm.dev", GetClusterViewerRole()),
+	}
+	clientManager := client2.NewFakeClientManager(suite.cfg, strings.Join(policies, ""))
+	apiHandler := NewApiHandler(clientManager)
+	apiHandler.Auditor = auditor
+	apiHandler.InstallMainRoutes(e)
+	apiHandler.InstallWebhookRoutes(e)
+	return e
+}
+
+func (suite *AuditHandlerTestSuite) request(s *echo.Echo, method, path, user string) *ResponseRecorder {
+	return BaseRequest(s, method, path, nil, map[string]string{
+		echo.HeaderAuthorization: "Bearer " + client2.ToFakeToken(user),
+	})
+}
+
+func (suite *AuditHandlerTestSuite) TestAuditMutatingCalls() {
+	auditor := audit.NewAuditor()
+	s := suite.setupApiServerWithAuditor(auditor)
+
+	rec := suite.request(s, http.MethodPost, "/v1alpha1/nodes/test-audit-node/cordon", "owner@kalm.dev")
+	suite.EqualValues(200, rec.Code)
+
+	rec = suite.request(s, http.MethodPost, "/v1alpha1/nodes/test-audit-node/uncordon", "viewer@kalm.dev")
+	suite.IsUnauthorizedError(rec)
+
+	// read requests are not recorded
+	rec = suite.request(s, http.MethodGet, "/v1alpha1/nodes", "owner@kalm.dev")
+	suite.EqualValues(200, rec.Code)
+
+	// only cluster owners can read audit events
+	rec = suite.request(s, http.MethodGet, "/v1alpha1/audit", "viewer@kalm.dev")
+	suite.IsUnauthorizedError(rec)
+
+	var events []audit.Event
+	rec = suite.request(s, http.MethodGet, "/v1alpha1/audit", "owner@kalm.dev")
+	suite.EqualValues(200, rec.Code)
+	rec.BodyAsJSON(&events)
+	suite.Len(events, 2)
+
+	// the most recent first
+	suite.Equal("viewer@kalm.dev", events[0].Subject)
+	suite.Equal(audit.OutcomeFailure, events[0].Outcome)
+	suite.Equal(401, events[0].StatusCode)
+
+	suite.Equal("owner@kalm.dev", events[1].Subject)
+	suite.Equal("create", events[1].Verb)
+	suite.Equal("nodes", events[1].Resource)
+	suite.Equal("test-audit-node", events[1].Name)
+	suite.Equal(audit.OutcomeSuccess, events[1].Outcome)
+	suite.Equal(200, events[1].StatusCode)
+
+	rec = suite.request(s, http.MethodGet, "/v1alpha1/audit?user=owner@kalm.dev&limit=10", "owner@kalm.dev")
+	suite.EqualValues(200, rec.Code)
+	rec.BodyAsJSON(&events)
+	suite.Len(events, 1)
+
+	rec = suite.request(s, http.MethodGet, "/v1alpha1/audit?since=yesterday", "owner@kalm.dev")
+	suite.EqualValues(400, rec.Code)
+}
+
+func TestAuditHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditHandlerTestSuite))
+}
+
+func TestGetAuditResource(t *testing.T) {
+	cases := map[string]string{
+		"/v1alpha1/applications":                                        "applications",
+		"/v1alpha1/applications/:name":                                  "applications",
+		"/v1alpha1/applications/import":                                 "applications",
+		"/v1alpha1/applications/:applicationName/components":            "components",
+		"/v1alpha1/applications/:applicationName/components/:name":      "components",
+		"/v1alpha1/applications/:applicationName/components/:name/jobs": "jobs",
+		"/v1alpha1/nodes/:name/cordon":                                  "nodes",
+		"/v1alpha1/sso":                                                 "sso",
+		"/webhook/components":                                           "components",
+	}
+
+	for route, resource := range cases {
+		if got := getAuditResource(route); got != resource {
+			t.Errorf("resource of %s, expected %s, got %s", route, resource, got)
+		}
+	}
+}
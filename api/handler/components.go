@@ -2,11 +2,13 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	client2 "github.com/kalmhq/kalm/api/client"
 	"github.com/kalmhq/kalm/api/resources"
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,6 +21,8 @@ func (h *ApiHandler) InstallComponentsHandlers(e *echo.Group) {
 	e.DELETE("/applications/:applicationName/components/:name", h.handleDeleteComponent)
 	e.POST("/applications/:applicationName/components", h.handleCreateComponent)
 	e.POST("/applications/:applicationName/components/:name/jobs", h.handleTriggerJob)
+	e.GET("/applications/:applicationName/components/:name/revisions", h.handleListComponentRevisions)
+	e.POST("/applications/:applicationName/components/:name/revisions/:revision/rollback", h.handleRollbackComponent)
 }
 
 func (h *ApiHandler) handleListComponents(c echo.Context) error {
@@ -83,16 +87,25 @@ func (h *ApiHandler) handleCreateComponent(c echo.Context) error {
 	}
 
 	crdComponent := getCrdComponent(component)
+	resources.SetComponentChangeCause(crdComponent, h.getComponentChangeCause(currentUser, v1alpha1.ComponentChangeViaAPI))
 
 	// permission, check if component try to re-use disk from other ns
 	if err := h.checkPermissionOnVolume(currentUser, crdComponent.Spec.Volumes); err != nil {
 		return err
 	}
 
+	if dryRun, err := getDryRunParam(c); err != nil {
+		return err
+	} else if dryRun {
+		return h.dryRunComponent(c, component, crdComponent, true)
+	}
+
 	if err := h.resourceManager.Create(crdComponent); err != nil {
 		return err
 	}
 
+	h.recordComponentRevision(crdComponent)
+
 	if err := h.resourceManager.UpdateProtectedEndpointForComponent(crdComponent, component.ProtectedEndpointSpec); err != nil {
 		return err
 	}
@@ -128,11 +141,20 @@ func (h *ApiHandler) handleUpdateComponent(c echo.Context) error {
 	h.MustCanEdit(currentUser, c.Param("applicationName"), "components/"+component.Name)
 
 	crdComponent := getCrdComponent(component)
+	resources.SetComponentChangeCause(crdComponent, h.getComponentChangeCause(currentUser, v1alpha1.ComponentChangeViaAPI))
 
-	if err := h.resourceManager.Apply(crdComponent); err != nil {
+	if dryRun, err := getDryRunParam(c); err != nil {
 		return err
+	} else if dryRun {
+		return h.dryRunComponent(c, component, crdComponent, false)
 	}
 
+	if err := h.resourceManager.ApplyComponent(crdComponent); err != nil {
+		return err
+	}
+
+	h.recordComponentRevision(crdComponent)
+
 	if err := h.resourceManager.UpdateProtectedEndpointForComponent(crdComponent, component.ProtectedEndpointSpec); err != nil {
 		return err
 	}
@@ -154,6 +176,34 @@ func (h *ApiHandler) handleUpdateComponent(c echo.Context) error {
 	return c.JSON(200, res)
 }
 
+// getDryRunParam parses the dryRun query param, false if it's not set
+func getDryRunParam(c echo.Context) (bool, error) {
+	v := c.QueryParam("dryRun")
+
+	if v == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(v)
+
+	if err != nil {
+		return false, errors.NewBadRequest("dryRun should be a boolean")
+	}
+
+	return dryRun, nil
+}
+
+// dryRunComponent responds the objects that would be created, updated or deleted by the change of the component, nothing is persisted
+func (h *ApiHandler) dryRunComponent(c echo.Context, component *resources.Component, crdComponent *v1alpha1.Component, isCreate bool) error {
+	res, err := h.resourceManager.DryRunComponent(crdComponent, component.ProtectedEndpointSpec, component.Plugins, isCreate)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, res)
+}
+
 func (h *ApiHandler) handleGetComponent(c echo.Context) error {
 	currentUser := getCurrentUser(c)
 	h.MustCanView(currentUser, c.Param("applicationName"), "components/"+c.Param("name"))
@@ -186,8 +236,81 @@ func (h *ApiHandler) handleDeleteComponent(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *ApiHandler) handleListComponentRevisions(c echo.Context) error {
+	currentUser := getCurrentUser(c)
+	h.MustCanView(currentUser, c.Param("applicationName"), "components/"+c.Param("name"))
+
+	revisions, err := h.resourceManager.GetComponentRevisions(c.Param("applicationName"), c.Param("name"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, revisions)
+}
+
+func (h *ApiHandler) handleRollbackComponent(c echo.Context) error {
+	currentUser := getCurrentUser(c)
+	h.MustCanEdit(currentUser, c.Param("applicationName"), "components/"+c.Param("name"))
+
+	revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+
+	if err != nil || revision < 1 {
+		return errors.NewBadRequest("invalid revision: " + c.Param("revision"))
+	}
+
+	component, err := h.resourceManager.RollbackComponent(
+		c.Param("applicationName"),
+		c.Param("name"),
+		revision,
+		h.getComponentChangeCause(currentUser, v1alpha1.ComponentChangeViaRollback),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	h.recordComponentRevision(component)
+
+	res, err := h.componentResponse(component)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, res)
+}
+
 // helper
 
+// recordComponentRevision records the written spec right away, the request doesn't fail as the spec is written already
+func (h *ApiHandler) recordComponentRevision(component *v1alpha1.Component) {
+	if err := h.resourceManager.RecordComponentRevision(component); err != nil {
+		h.logger.Error("fail to record component revision", zap.String("name", component.Name), zap.Error(err))
+	}
+}
+
+// getComponentChangeCause tells who is changing a component. For requests made with an access token,
+// the creator of the token is the one to blame.
+func (h *ApiHandler) getComponentChangeCause(c *client2.ClientInfo, via v1alpha1.ComponentChangeVia) resources.ComponentChangeCause {
+	cause := resources.ComponentChangeCause{
+		ChangedVia: via,
+		ChangedBy:  c.Email,
+	}
+
+	if cause.ChangedBy == "" {
+		cause.ChangedBy = c.Name
+	}
+
+	var accessToken v1alpha1.AccessToken
+	if err := h.resourceManager.Get("", c.Name, &accessToken); err == nil {
+		cause.ChangedBy = accessToken.Spec.Creator
+		cause.AccessToken = accessToken.Name
+	}
+
+	return cause
+}
+
 func (h *ApiHandler) checkPermissionOnVolume(c *client2.ClientInfo, vols []v1alpha1.Volume) error {
 	for _, vol := range vols {
 		if vol.Type != v1alpha1.VolumeTypePersistentVolumeClaim {
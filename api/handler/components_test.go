@@ -338,3 +338,110 @@ func (suite *ComponentTestSuite) TestCreateComponentWithResourceRequirements() {
 		},
 	})
 }
+
+func (suite *ComponentTestSuite) TestSecretEnvReferencesAreNotMasked() {
+	secretEnv := v1alpha1.EnvVar{
+		Name:  "DB_PASSWORD",
+		Type:  v1alpha1.EnvVarTypeSecret,
+		Value: "db/password",
+	}
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(suite.namespace),
+		},
+		Namespace: suite.namespace,
+		Method:    http.MethodPost,
+		Path:      fmt.Sprintf("/v1alpha1/applications/%s/components", suite.namespace),
+		Body: resources.Component{
+			Name: "secret-envs",
+			ComponentSpec: &v1alpha1.ComponentSpec{
+				Image: "foo",
+				Env:   []v1alpha1.EnvVar{secretEnv},
+			},
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.Component
+			rec.BodyAsJSON(&res)
+			suite.Equal(201, rec.Code)
+			// the value is a <secret>/<key> reference, it's not the secret itself
+			suite.Equal("db/password", res.Env[0].Value)
+		},
+	})
+
+	secretEnv.Value = "db/new-password"
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(suite.namespace),
+		},
+		Namespace: suite.namespace,
+		Method:    http.MethodPut,
+		Path:      fmt.Sprintf("/v1alpha1/applications/%s/components/%s", suite.namespace, "secret-envs"),
+		Body: resources.Component{
+			Name: "secret-envs",
+			ComponentSpec: &v1alpha1.ComponentSpec{
+				Image: "foo2",
+				Env:   []v1alpha1.EnvVar{secretEnv},
+			},
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(200, rec.Code)
+
+			component, err := suite.getComponent(suite.namespace, "secret-envs")
+			suite.Nil(err)
+			suite.Equal("foo2", component.Spec.Image)
+			suite.Equal("db/new-password", component.Spec.Env[0].Value)
+		},
+	})
+}
+
+func (suite *ComponentTestSuite) TestDryRunComponent() {
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(suite.namespace),
+		},
+		Namespace: suite.namespace,
+		Method:    http.MethodPost,
+		Path:      fmt.Sprintf("/v1alpha1/applications/%s/components?dryRun=true", suite.namespace),
+		Body: resources.Component{
+			Name: "dry-run",
+			ComponentSpec: &v1alpha1.ComponentSpec{
+				Image: "foo",
+			},
+		},
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			var res resources.ComponentDryRunResult
+			rec.BodyAsJSON(&res)
+			suite.Equal(200, rec.Code)
+			suite.True(res.DryRun)
+
+			var created bool
+			for _, change := range res.Changes {
+				if change.Kind == "Component" && change.Name == "dry-run" {
+					created = change.Action == "create"
+				}
+			}
+			suite.True(created)
+		},
+	})
+
+	// nothing is created
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetEditorRoleOfNamespace(suite.namespace),
+		},
+		Namespace: suite.namespace,
+		Method:    http.MethodGet,
+		Path:      fmt.Sprintf("/v1alpha1/applications/%s/components/%s", suite.namespace, "dry-run"),
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(404, rec.Code)
+		},
+	})
+}
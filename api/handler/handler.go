@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/kalmhq/kalm/api/audit"
 	"github.com/kalmhq/kalm/api/client"
 	"github.com/kalmhq/kalm/api/log"
 	"github.com/kalmhq/kalm/api/resources"
@@ -15,11 +16,12 @@ type ApiHandler struct {
 	clientManager   client.ClientManager
 	logger          *zap.Logger
 	KalmMode        v1alpha1.KalmMode
+	Auditor         *audit.Auditor
 }
 
 func (h *ApiHandler) InstallWebhookRoutes(e *echo.Echo) {
 	e.GET("/ping", handlePing)
-	e.POST("/webhook/components", h.handleDeployWebhookCall)
+	e.POST("/webhook/components", h.handleDeployWebhookCall, h.AuditMiddleware)
 }
 
 func (h *ApiHandler) InstallMainRoutes(e *echo.Echo) {
@@ -38,14 +40,14 @@ func (h *ApiHandler) InstallMainRoutes(e *echo.Echo) {
 	e.GET("/login/status", h.handleLoginStatus)
 
 	// original resources routes
-	gV1 := e.Group("/v1", h.GetUserMiddleware, h.RequireUserMiddleware)
+	gV1 := e.Group("/v1", h.GetUserMiddleware, h.RequireUserMiddleware, h.AuditMiddleware)
 	gV1.GET("/persistentvolumes", h.handleGetPVs)
 
 	gv1Alpha1 := e.Group("/v1alpha1")
 	gv1Alpha1.GET("/logs", h.logWebsocketHandler)
 	gv1Alpha1.GET("/exec", h.execWebsocketHandler)
 
-	var gv1Alpha1WithAuth = gv1Alpha1.Group("", h.GetUserMiddleware, h.RequireUserMiddleware)
+	var gv1Alpha1WithAuth = gv1Alpha1.Group("", h.GetUserMiddleware, h.RequireUserMiddleware, h.AuditMiddleware)
 
 	// initialize the cluster
 	gv1Alpha1WithAuth.POST("/initialize", h.handleInitializeCluster)
@@ -84,6 +86,8 @@ func (h *ApiHandler) InstallMainRoutes(e *echo.Echo) {
 	h.InstallHttpCertIssuerHandlers(gv1Alpha1WithAuth)
 	h.InstallHttpsCertsHandlers(gv1Alpha1WithAuth)
 
+	h.InstallMetricsHandlers(gv1Alpha1WithAuth)
+
 	gv1Alpha1WithAuth.GET("/storageclasses", h.handleListStorageClasses)
 
 	gv1Alpha1WithAuth.GET("/volumes", h.handleListVolumes)
@@ -102,8 +106,11 @@ func (h *ApiHandler) InstallMainRoutes(e *echo.Echo) {
 	h.InstallSSOHandlers(gv1Alpha1WithAuth)
 	h.InstallProtectedEndpointHandlers(gv1Alpha1WithAuth)
 	h.InstallACMEServerHandlers(gv1Alpha1WithAuth)
+	h.InstallAlertRuleHandlers(gv1Alpha1WithAuth)
+	h.InstallLogArchiveHandlers(gv1Alpha1WithAuth)
 
 	gv1Alpha1WithAuth.GET("/settings", h.handleListSettings)
+	gv1Alpha1WithAuth.GET("/audit", h.handleListAuditEvents)
 }
 
 func NewApiHandler(clientManager client.ClientManager) *ApiHandler {
@@ -114,5 +121,6 @@ func NewApiHandler(clientManager client.ClientManager) *ApiHandler {
 		logger:          log.DefaultLogger(),
 		resourceManager: resources.NewResourceManager(clientManager.GetDefaultClusterConfig(), log.DefaultLogger()),
 		KalmMode:        kalmMode,
+		Auditor:         audit.NewAuditor(),
 	}
 }
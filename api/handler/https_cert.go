@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/kalmhq/kalm/api/errors"
 	"github.com/kalmhq/kalm/api/resources"
@@ -11,6 +12,7 @@ import (
 
 func (h *ApiHandler) InstallHttpsCertsHandlers(e *echo.Group) {
 	e.GET("/httpscerts", h.handleListHttpsCerts)
+	e.GET("/httpscerts/at-risk", h.handleListHttpsCertsAtRisk)
 	e.GET("/httpscerts/:name", h.handleGetHttpsCert)
 	e.POST("/httpscerts", h.handleCreateHttpsCert)
 	e.POST("/httpscerts/upload", h.handleUploadHttpsCert)
@@ -34,6 +36,29 @@ func (h *ApiHandler) handleListHttpsCerts(c echo.Context) error {
 	return c.JSON(200, httpsCerts)
 }
 
+// certs expiring, expired or failed to renew, withinDays=n includes certs expire in n days
+func (h *ApiHandler) handleListHttpsCertsAtRisk(c echo.Context) error {
+	h.MustCanViewCluster(getCurrentUser(c))
+
+	var withinDays int
+	if v := c.QueryParam("withinDays"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return errors.NewBadRequest("withinDays should be a non-negative integer")
+		}
+
+		withinDays = days
+	}
+
+	httpsCerts, err := h.resourceManager.GetHttpsCertsAtRisk(withinDays)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, httpsCerts)
+}
+
 func (h *ApiHandler) handleGetHttpsCert(c echo.Context) error {
 	// TODO: certs are required to support http route
 	// h.MustCanManageCluster(getCurrentUser(c))
@@ -45,6 +45,14 @@ func (h *ApiHandler) handleCreateHttpsCertIssuer(c echo.Context) (err error) {
 		resource.Spec.CAForTest = httpsCertIssuer.CAForTest
 	}
 
+	if httpsCertIssuer.ACMEDNSProvider != nil {
+		resource.Spec.ACMEDNSProvider = httpsCertIssuer.ACMEDNSProvider
+	}
+
+	if httpsCertIssuer.CA != nil {
+		resource.Spec.CA = httpsCertIssuer.CA
+	}
+
 	if httpsCertIssuer.ACMECloudFlare != nil {
 
 		acmeSecretName := resources.GenerateSecretNameForACME(httpsCertIssuer)
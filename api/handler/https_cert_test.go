@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kalmhq/kalm/api/resources"
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
@@ -347,3 +348,51 @@ func (suite *HttpsCertTestSuite) TestDeleteHttpsCert() {
 		},
 	})
 }
+
+func (suite *HttpsCertTestSuite) TestListHttpsCertsAtRisk() {
+	httpsCert := v1alpha1.HttpsCert{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: "foobar-cert",
+		},
+		Spec: v1alpha1.HttpsCertSpec{
+			HttpsCertIssuer: "foobar-issuer",
+			Domains:         []string{"example.com"},
+		},
+	}
+	suite.Nil(suite.Create(&httpsCert))
+
+	httpsCert.Status = v1alpha1.HttpsCertStatus{
+		Conditions: []v1alpha1.HttpsCertCondition{
+			{
+				Type:    v1alpha1.HttpsCertConditionReady,
+				Status:  coreV1.ConditionFalse,
+				Reason:  v1alpha1.HttpsCertReasonExpiringSoon,
+				Message: "cert expires in 3 days",
+			},
+		},
+		ExpireTimestamp: time.Now().Add(3*24*time.Hour + time.Hour).Unix(),
+	}
+	suite.Nil(suite.client.Status().Update(suite.ctx, &httpsCert))
+
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetClusterViewerRole(),
+		},
+		Method: http.MethodGet,
+		Path:   "/v1alpha1/httpscerts/at-risk",
+		TestWithoutRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.Equal(200, rec.Code)
+
+			var res []resources.HttpsCertAtRisk
+			rec.BodyAsJSON(&res)
+
+			suite.Len(res, 1)
+			suite.Equal("foobar-cert", res[0].Name)
+			suite.Equal(v1alpha1.HttpsCertReasonExpiringSoon, res[0].Risk)
+			suite.Equal(3, res[0].DaysToExpiry)
+		},
+	})
+}
@@ -0,0 +1,399 @@
+package handler
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/api/errors"
+	"github.com/kalmhq/kalm/api/log"
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// max entries of a loki query, it's the default max_entries_limit_per_query of loki
+const lokiQueryPageSize = 5000
+
+// logs are archived from an hour ago by default
+const defaultLogArchiveWindow = time.Hour
+
+func (h *ApiHandler) InstallLogArchiveHandlers(e *echo.Group) {
+	e.GET("/applications/:applicationName/logs/archive", h.handleDownloadLogArchive)
+}
+
+// handleDownloadLogArchive streams a tar.gz of logs of a component, a job run or the whole application.
+// Logs of existing pods are read from the kubelet, logs of deleted pods are read from loki if a LogSystem is installed.
+// Failures after the response is started are written into errors.txt of the archive.
+func (h *ApiHandler) handleDownloadLogArchive(c echo.Context) error {
+	currentUser := getCurrentUser(c)
+	namespace := c.Param("applicationName")
+	component := c.QueryParam("component")
+	jobName := c.QueryParam("job")
+
+	podLabels := map[string]string{}
+	obj := "pods/*"
+	archiveName := namespace
+
+	if component != "" {
+		podLabels[v1alpha1.KalmLabelComponentKey] = component
+		obj = "components/" + component
+		archiveName += "-" + component
+	}
+
+	// permissions are checked before the job is read, logs of a job run of a component
+	// can be downloaded with the permission of the component if the component is specified
+	h.MustCanView(currentUser, namespace, obj)
+
+	if jobName != "" {
+		var job batchV1.Job
+
+		if err := h.resourceManager.Get(namespace, jobName, &job); err != nil {
+			return err
+		}
+
+		if component != "" && job.Labels[v1alpha1.KalmLabelComponentKey] != component {
+			return errors.NewBadRequest(fmt.Sprintf("job %s doesn't belong to component %s", jobName, component))
+		}
+
+		// pods of the job run are labeled by the job controller
+		delete(podLabels, v1alpha1.KalmLabelComponentKey)
+		podLabels["job-name"] = jobName
+		archiveName += "-" + jobName
+	}
+
+	until := time.Now()
+
+	if c.QueryParam("until") != "" {
+		t, err := time.Parse(time.RFC3339, c.QueryParam("until"))
+
+		if err != nil {
+			return errors.NewBadRequest("until must be a RFC3339 time")
+		}
+
+		until = t
+	}
+
+	since := until.Add(-defaultLogArchiveWindow)
+
+	if c.QueryParam("since") != "" {
+		t, err := time.Parse(time.RFC3339, c.QueryParam("since"))
+
+		if err != nil {
+			return errors.NewBadRequest("since must be a RFC3339 time")
+		}
+
+		since = t
+	}
+
+	if !since.Before(until) {
+		return errors.NewBadRequest("since must be before until")
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(currentUser.Cfg)
+
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metaV1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(podLabels).String(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/gzip")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-logs-%s.tar.gz"`, archiveName, until.UTC().Format("20060102150405")))
+	res.WriteHeader(http.StatusOK)
+
+	archive := newLogArchiveWriter(res)
+
+	defer func() {
+		if err := archive.Close(); err != nil {
+			log.Error("close log archive error", zap.Error(err))
+		}
+	}()
+
+	livePods := make(map[string]bool)
+
+	for i := range pods.Items {
+		livePods[pods.Items[i].Name] = true
+		archivePodLogs(ctx, k8sClient, archive, &pods.Items[i], since, until, c.QueryParam("previous") == "true")
+	}
+
+	lokiAddress, err := findLokiAddress(h.resourceManager)
+
+	if err != nil {
+		archive.addError("loki", err)
+		return nil
+	}
+
+	if lokiAddress != "" {
+		archiveLokiLogs(ctx, http.DefaultClient, archive, lokiAddress, buildLokiLogQuery(namespace, podLabels, "", ""), since, until, livePods)
+	}
+
+	return nil
+}
+
+func archivePodLogs(ctx context.Context, k8sClient kubernetes.Interface, archive *logArchiveWriter, pod *coreV1.Pod, since, until time.Time, previous bool) {
+	restartCounts := make(map[string]int32)
+
+	var containers []coreV1.Container
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		restartCounts[status.Name] = status.RestartCount
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		restartCounts[status.Name] = status.RestartCount
+	}
+
+	for _, container := range containers {
+		names := []string{container.Name + ".log"}
+
+		if previous && restartCounts[container.Name] > 0 {
+			names = append(names, container.Name+".previous.log")
+		}
+
+		for _, name := range names {
+			name = path.Join("pods", pod.Name, name)
+
+			stream, err := k8sClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &coreV1.PodLogOptions{
+				Container:  container.Name,
+				Timestamps: true,
+				SinceTime:  &metaV1.Time{Time: since},
+				Previous:   strings.HasSuffix(name, ".previous.log"),
+			}).Stream(ctx)
+
+			if err != nil {
+				archive.addError(name, err)
+				continue
+			}
+
+			err = archive.addLogStream(name, stream, until)
+			stream.Close()
+
+			if err != nil {
+				archive.addError(name, err)
+			}
+		}
+	}
+}
+
+// archiveLokiLogs pages through loki from since to until, lines of live pods are skipped as they are read from kubelet.
+func archiveLokiLogs(ctx context.Context, httpClient *http.Client, archive *logArchiveWriter, address, query string, since, until time.Time, livePods map[string]bool) {
+	files := make(map[string]*os.File)
+
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	var names []string
+	start := since
+
+	for {
+		entries, err := queryLokiLogs(ctx, httpClient, address, query, start, until, lokiQueryPageSize, lokiDirectionForward)
+
+		if err != nil {
+			archive.addError("loki", err)
+			break
+		}
+
+		for _, entry := range entries {
+			if livePods[entry.Pod] {
+				continue
+			}
+
+			name := path.Join("loki", entry.Pod, entry.Container+".log")
+			file, ok := files[name]
+
+			if !ok {
+				if file, err = ioutil.TempFile("", "kalm-log-archive-"); err != nil {
+					archive.addError(name, err)
+					return
+				}
+
+				files[name] = file
+				names = append(names, name)
+			}
+
+			if _, err := fmt.Fprintf(file, "%s %s\n", entry.Timestamp.UTC().Format(time.RFC3339Nano), entry.Line); err != nil {
+				archive.addError(name, err)
+				return
+			}
+		}
+
+		if len(entries) < lokiQueryPageSize {
+			break
+		}
+
+		// lines at the same nanosecond of the page boundary may be missed
+		start = entries[len(entries)-1].Timestamp.Add(time.Nanosecond)
+	}
+
+	for _, name := range names {
+		if err := archive.addFile(name, files[name]); err != nil {
+			archive.addError(name, err)
+		}
+	}
+}
+
+// logArchiveWriter writes a tar.gz of log files to the response as they are ready.
+// A tar header requires the size of the file, so a log is spooled to a temp file instead of memory before it's written.
+type logArchiveWriter struct {
+	w      io.Writer
+	gz     *gzip.Writer
+	tw     *tar.Writer
+	errors []string
+}
+
+func newLogArchiveWriter(w io.Writer) *logArchiveWriter {
+	gz := gzip.NewWriter(w)
+
+	return &logArchiveWriter{
+		w:  w,
+		gz: gz,
+		tw: tar.NewWriter(gz),
+	}
+}
+
+func (a *logArchiveWriter) addError(name string, err error) {
+	log.Debug("log archive error", zap.String("name", name), zap.Error(err))
+	a.errors = append(a.errors, fmt.Sprintf("%s: %s", name, err.Error()))
+}
+
+// addLogStream archives lines of a stream with timestamps until the first line after until
+func (a *logArchiveWriter) addLogStream(name string, stream io.Reader, until time.Time) error {
+	file, err := ioutil.TempFile("", "kalm-log-archive-")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	reader := bufio.NewReader(stream)
+	writer := bufio.NewWriter(file)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if line != "" {
+			if t, _ := splitLogTimestamp(line); !t.IsZero() && t.After(until) {
+				break
+			}
+
+			if _, err := writer.WriteString(line); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	return a.addFile(name, file)
+}
+
+func (a *logArchiveWriter) addFile(name string, file *os.File) error {
+	info, err := file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	err = a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(a.tw, file); err != nil {
+		return err
+	}
+
+	a.flush()
+
+	return nil
+}
+
+func (a *logArchiveWriter) flush() {
+	_ = a.tw.Flush()
+	_ = a.gz.Flush()
+
+	if flusher, ok := a.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (a *logArchiveWriter) Close() error {
+	if len(a.errors) > 0 {
+		content := strings.Join(a.errors, "\n") + "\n"
+
+		err := a.tw.WriteHeader(&tar.Header{
+			Name:    "errors.txt",
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(a.tw, content); err != nil {
+			return err
+		}
+	}
+
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+
+	return a.gz.Close()
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LogArchiveTestSuite struct {
+	WithControllerTestSuite
+
+	namespace string
+}
+
+func TestLogArchiveTestSuite(t *testing.T) {
+	suite.Run(t, new(LogArchiveTestSuite))
+}
+
+func (suite *LogArchiveTestSuite) SetupSuite() {
+	suite.WithControllerTestSuite.SetupSuite()
+	suite.namespace = "kalm-test-log-archive"
+	suite.ensureNamespaceExist(suite.namespace)
+}
+
+func (suite *LogArchiveTestSuite) TeardownSuite() {
+	suite.ensureNamespaceDeleted(suite.namespace)
+}
+
+// the job is not read before permissions are checked, existence of jobs is not leaked
+func (suite *LogArchiveTestSuite) TestDownloadJobLogsWithoutPermission() {
+	suite.DoTestRequest(&TestRequestContext{
+		Roles: []string{
+			GetViewerRoleOfNamespace("other"),
+		},
+		Namespace: suite.namespace,
+		Method:    http.MethodGet,
+		Path:      fmt.Sprintf("/v1alpha1/applications/%s/logs/archive?job=not-exist", suite.namespace),
+		TestWithRoles: func(rec *ResponseRecorder) {
+			suite.IsUnauthorizedError(rec)
+		},
+	})
+}
+
+func readLogArchive(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+
+		if err != nil {
+			break
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		assert.Nil(t, err)
+		files[header.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestLogArchiveWriter(t *testing.T) {
+	var buf bytes.Buffer
+	archive := newLogArchiveWriter(&buf)
+
+	stream := strings.NewReader(strings.Join([]string{
+		"2020-10-01T00:00:00Z job started",
+		"2020-10-01T00:30:00Z job failed",
+		"2020-10-01T02:00:00Z job restarted",
+	}, "\n") + "\n")
+
+	assert.Nil(t, archive.addLogStream("pods/job-1/job.log", stream, time.Date(2020, 10, 1, 1, 0, 0, 0, time.UTC)))
+	archive.addError("pods/job-1/job.previous.log", fmt.Errorf("previous terminated container not found"))
+	assert.Nil(t, archive.Close())
+
+	files := readLogArchive(t, buf.Bytes())
+	assert.Equal(t, "2020-10-01T00:00:00Z job started\n2020-10-01T00:30:00Z job failed\n", files["pods/job-1/job.log"])
+	assert.Equal(t, "pods/job-1/job.previous.log: previous terminated container not found\n", files["errors.txt"])
+}
+
+func TestArchiveLokiLogs(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		assert.Equal(t, "forward", req.URL.Query().Get("direction"))
+
+		start, _ := strconv.ParseInt(req.URL.Query().Get("start"), 10, 64)
+		values := [][2]string{}
+
+		// a full page of the deleted pod, then the rest
+		if start == 0 {
+			for i := 0; i < lokiQueryPageSize; i++ {
+				values = append(values, [2]string{strconv.Itoa(i + 1), "line"})
+			}
+		} else {
+			assert.Equal(t, int64(lokiQueryPageSize+1), start)
+			values = append(values, [2]string{strconv.Itoa(lokiQueryPageSize + 1), "last line"})
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"result": []interface{}{
+					map[string]interface{}{"stream": map[string]string{"pod": "job-1", "container": "job"}, "values": values},
+					map[string]interface{}{"stream": map[string]string{"pod": "job-2", "container": "job"}, "values": [][2]string{{"1", "live"}}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	archive := newLogArchiveWriter(&buf)
+	archiveLokiLogs(context.Background(), server.Client(), archive, server.URL, `{namespace="shop"}`, time.Unix(0, 0), time.Unix(10, 0), map[string]bool{"job-2": true})
+	assert.Nil(t, archive.Close())
+
+	assert.Equal(t, 2, requests)
+
+	files := readLogArchive(t, buf.Bytes())
+	assert.Len(t, files, 1)
+
+	lines := strings.Split(strings.TrimSpace(files["loki/job-1/job.log"]), "\n")
+	assert.Len(t, lines, lokiQueryPageSize+1)
+	assert.Equal(t, "1970-01-01T00:00:00.000005001Z last line", lines[len(lines)-1])
+}
@@ -0,0 +1,728 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kalmhq/kalm/api/log"
+	"github.com/kalmhq/kalm/api/resources"
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"go.uber.org/zap"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WSLogSubscriptionRequest subscribes logs of all pods of a component, or of the whole application if component is blank.
+// Pods created after the subscription are picked up when follow is true.
+type WSLogSubscriptionRequest struct {
+	WSRequest `json:",inline"`
+
+	// chosen by the client, all responses of the subscription carry it
+	ID string `json:"id"`
+
+	Namespace string `json:"namespace"`
+	Component string `json:"component"`
+
+	// all containers are subscribed if it's blank
+	Container string `json:"container"`
+
+	TailLines  int64 `json:"tailLines"`
+	Timestamps bool  `json:"timestamps"`
+	Follow     bool  `json:"follow"`
+	Previous   bool  `json:"previous"`
+
+	// RFC3339 time window of the lines
+	Since string `json:"since"`
+	Until string `json:"until"`
+
+	// only lines matching the regexp are sent
+	Regexp string `json:"regexp"`
+
+	// only lines at or above the level are sent, one of debug, info, warn, error and fatal
+	Level string `json:"level"`
+
+	// backfill history lines from loki before streaming, only works when a LogSystem is installed
+	History      bool `json:"history"`
+	HistoryLimit int  `json:"historyLimit"`
+}
+
+type WSLogResponse struct {
+	Type      WSResponseType `json:"type"`
+	ID        string         `json:"id"`
+	Namespace string         `json:"namespace"`
+	PodName   string         `json:"podName"`
+	Container string         `json:"container"`
+	Data      string         `json:"data"`
+
+	// the line is from the log system instead of a live stream
+	History bool `json:"history,omitempty"`
+}
+
+const defaultLogHistoryLimit = 1000
+
+// lines without timestamps are backfilled from an hour ago by default
+const defaultLogHistoryWindow = time.Hour
+
+var logLevels = []string{"debug", "info", "warn", "error", "fatal"}
+
+var (
+	structuredLogLevelRegexp = regexp.MustCompile(`(?i)"?(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`)
+	plainLogLevelRegexp      = regexp.MustCompile(`\b(DEBUG|INFO|WARN|WARNING|ERROR|FATAL|PANIC|CRITICAL)\b`)
+	glogLevelRegexp          = regexp.MustCompile(`^([DIWEF])\d{4} `)
+)
+
+// logLevelIndex returns the index in logLevels, -1 if the level is unknown
+func logLevelIndex(level string) int {
+	switch strings.ToLower(level) {
+	case "d", "debug", "trace":
+		return 0
+	case "i", "info", "notice":
+		return 1
+	case "w", "warn", "warning":
+		return 2
+	case "e", "error", "err":
+		return 3
+	case "f", "fatal", "panic", "critical", "crit":
+		return 4
+	default:
+		return -1
+	}
+}
+
+// detectLogLevel recognizes levels of structured (level=error, "level":"error"), plain (ERROR ...) and glog (E0102 ...) lines
+func detectLogLevel(line string) int {
+	if m := structuredLogLevelRegexp.FindStringSubmatch(line); m != nil {
+		if level := logLevelIndex(m[1]); level >= 0 {
+			return level
+		}
+	}
+
+	if m := glogLevelRegexp.FindStringSubmatch(line); m != nil {
+		return logLevelIndex(m[1])
+	}
+
+	if m := plainLogLevelRegexp.FindStringSubmatch(line); m != nil {
+		return logLevelIndex(m[1])
+	}
+
+	return -1
+}
+
+type logLineFilter struct {
+	regexp *regexp.Regexp
+	level  int
+	since  time.Time
+	until  time.Time
+}
+
+func newLogLineFilter(m *WSLogSubscriptionRequest) (*logLineFilter, error) {
+	filter := &logLineFilter{level: -1}
+
+	if m.Regexp != "" {
+		re, err := regexp.Compile(m.Regexp)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp: %s", err.Error())
+		}
+
+		filter.regexp = re
+	}
+
+	if m.Level != "" {
+		if filter.level = logLevelIndex(m.Level); filter.level < 0 {
+			return nil, fmt.Errorf("level must be one of %s", strings.Join(logLevels, ", "))
+		}
+	}
+
+	var err error
+
+	if m.Since != "" {
+		if filter.since, err = time.Parse(time.RFC3339, m.Since); err != nil {
+			return nil, fmt.Errorf("since must be a RFC3339 time")
+		}
+	}
+
+	if m.Until != "" {
+		if filter.until, err = time.Parse(time.RFC3339, m.Until); err != nil {
+			return nil, fmt.Errorf("until must be a RFC3339 time")
+		}
+	}
+
+	return filter, nil
+}
+
+// isAfterWindow tells if lines at t and later will never match the filter
+func (f *logLineFilter) isAfterWindow(t time.Time) bool {
+	return !f.until.IsZero() && !t.IsZero() && t.After(f.until)
+}
+
+func (f *logLineFilter) match(t time.Time, line string) bool {
+	if !t.IsZero() {
+		if !f.since.IsZero() && t.Before(f.since) {
+			return false
+		}
+
+		if f.isAfterWindow(t) {
+			return false
+		}
+	}
+
+	if f.regexp != nil && !f.regexp.MatchString(line) {
+		return false
+	}
+
+	if f.level >= 0 && detectLogLevel(line) < f.level {
+		return false
+	}
+
+	return true
+}
+
+// splitLogTimestamp splits the timestamp prefix added by the kubelet when timestamps is requested
+func splitLogTimestamp(line string) (time.Time, string) {
+	idx := strings.IndexByte(line, ' ')
+
+	if idx < 0 {
+		return time.Time{}, line
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, line[:idx])
+
+	if err != nil {
+		return time.Time{}, line
+	}
+
+	return t, line[idx+1:]
+}
+
+type containerLogState struct {
+	active       bool
+	restartCount int32
+
+	// timestamp of the last line, lines are resumed from it after the container is restarted
+	lastTimestamp time.Time
+}
+
+type logSubscription struct {
+	conn      *WSConn
+	req       *WSLogSubscriptionRequest
+	filter    *logLineFilter
+	k8sClient kubernetes.Interface
+	ctx       context.Context
+	stop      context.CancelFunc
+
+	// live streams only include lines after it if history is backfilled
+	startTime time.Time
+
+	lock       sync.Mutex
+	containers map[string]*containerLogState
+	wg         sync.WaitGroup
+}
+
+func (s *logSubscription) selector() string {
+	if s.req.Component == "" {
+		return labels.Everything().String()
+	}
+
+	return labels.SelectorFromSet(labels.Set{v1alpha1.KalmLabelComponentKey: s.req.Component}).String()
+}
+
+func (s *logSubscription) send(res *WSLogResponse) error {
+	res.ID = s.req.ID
+	res.Namespace = s.req.Namespace
+	return s.conn.WriteJSON(res)
+}
+
+func (s *logSubscription) run() {
+	var endMessage string
+
+	defer func() {
+		_ = s.send(&WSLogResponse{Type: WSResponseTypeLogSubscriptionEnd, Data: endMessage})
+	}()
+
+	if s.req.History {
+		if err := s.backfill(); err != nil {
+			log.Error("backfill logs error", zap.Error(err))
+			endMessage = err.Error()
+			return
+		}
+	}
+
+	// live streams are stopped at the end of the window
+	if s.req.Follow && !s.filter.until.IsZero() {
+		var cancel context.CancelFunc
+		s.ctx, cancel = context.WithDeadline(s.ctx, s.filter.until)
+		defer cancel()
+	}
+
+	if err := s.listAndWatchPods(); err != nil && s.ctx.Err() == nil {
+		endMessage = err.Error()
+	}
+
+	s.wg.Wait()
+}
+
+// listAndWatchPods starts streams of existing pods, then new pods and restarted containers until the subscription is stopped
+func (s *logSubscription) listAndWatchPods() error {
+	for {
+		pods, err := s.k8sClient.CoreV1().Pods(s.req.Namespace).List(s.ctx, metaV1.ListOptions{LabelSelector: s.selector()})
+
+		if err != nil {
+			return err
+		}
+
+		for i := range pods.Items {
+			s.ensureContainerStreams(&pods.Items[i])
+		}
+
+		if !s.req.Follow || s.req.Previous {
+			return nil
+		}
+
+		watcher, err := s.k8sClient.CoreV1().Pods(s.req.Namespace).Watch(s.ctx, metaV1.ListOptions{
+			LabelSelector:   s.selector(),
+			ResourceVersion: pods.ResourceVersion,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for event := range watcher.ResultChan() {
+			if event.Type != watch.Added && event.Type != watch.Modified {
+				continue
+			}
+
+			if pod, ok := event.Object.(*coreV1.Pod); ok {
+				s.ensureContainerStreams(pod)
+			}
+		}
+
+		watcher.Stop()
+
+		// the watch is also closed by the api server from time to time, list and watch again
+		if s.ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *logSubscription) ensureContainerStreams(pod *coreV1.Pod) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if s.req.Container != "" && status.Name != s.req.Container {
+			continue
+		}
+
+		if s.req.Previous {
+			if status.LastTerminationState.Terminated == nil {
+				continue
+			}
+		} else if status.State.Running == nil && status.State.Terminated == nil {
+			continue
+		}
+
+		key := pod.Name + "/" + status.Name
+
+		s.lock.Lock()
+		state, seen := s.containers[key]
+
+		if seen && (state.active || s.req.Previous || status.State.Running == nil || state.restartCount == status.RestartCount) {
+			s.lock.Unlock()
+			continue
+		}
+
+		if !seen {
+			state = &containerLogState{}
+			s.containers[key] = state
+		}
+
+		state.active = true
+		state.restartCount = status.RestartCount
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go func(podName, container string, resumed bool) {
+			defer s.wg.Done()
+			s.streamContainer(podName, container, state, resumed)
+		}(pod.Name, status.Name, seen)
+	}
+}
+
+func (s *logSubscription) streamContainer(podName, container string, state *containerLogState, resumed bool) {
+	defer func() {
+		s.lock.Lock()
+		state.active = false
+		s.lock.Unlock()
+
+		// It doesn't matter if the conn is closed, ignore the error
+		_ = s.send(&WSLogResponse{
+			Type:      WSResponseTypeLogStreamDisconnected,
+			PodName:   podName,
+			Container: container,
+		})
+	}()
+
+	opts := &coreV1.PodLogOptions{
+		Container:  container,
+		Timestamps: true,
+		Follow:     s.req.Follow && !s.req.Previous,
+		Previous:   s.req.Previous,
+	}
+
+	s.lock.Lock()
+	resumeAfter := state.lastTimestamp
+	s.lock.Unlock()
+
+	switch {
+	case resumed && !resumeAfter.IsZero():
+		opts.SinceTime = &metaV1.Time{Time: resumeAfter}
+	case s.req.History:
+		opts.SinceTime = &metaV1.Time{Time: s.startTime}
+	case !s.filter.since.IsZero():
+		opts.SinceTime = &metaV1.Time{Time: s.filter.since}
+	}
+
+	if !resumed && !s.req.History && s.req.TailLines > 0 {
+		opts.TailLines = &s.req.TailLines
+	}
+
+	stream, err := s.k8sClient.CoreV1().Pods(s.req.Namespace).GetLogs(podName, opts).Stream(s.ctx)
+
+	if err != nil {
+		log.Error("stream error", zap.Error(err))
+		return
+	}
+
+	defer stream.Close()
+
+	s.copyLogLines(stream, podName, container, state, resumeAfter)
+}
+
+func (s *logSubscription) copyLogLines(stream io.Reader, podName, container string, state *containerLogState, resumeAfter time.Time) {
+	reader := bufio.NewReader(stream)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if line != "" {
+			t, content := splitLogTimestamp(strings.TrimSuffix(line, "\n"))
+
+			if s.filter.isAfterWindow(t) {
+				return
+			}
+
+			// lines since the resumed time are read again
+			if !resumeAfter.IsZero() && !t.IsZero() && !t.After(resumeAfter) {
+				continue
+			}
+
+			if !t.IsZero() {
+				s.lock.Lock()
+				state.lastTimestamp = t
+				s.lock.Unlock()
+			}
+
+			if s.filter.match(t, content) {
+				if s.req.Timestamps {
+					content = line
+				} else {
+					content = content + "\n"
+				}
+
+				err := s.send(&WSLogResponse{
+					Type:      WSResponseTypeLogStreamUpdate,
+					PodName:   podName,
+					Container: container,
+					Data:      content,
+				})
+
+				if err != nil {
+					if !isNormalWebsocketCloseError(err) {
+						log.Error("write message error", zap.Error(err))
+					}
+					return
+				}
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF && s.ctx.Err() == nil {
+				log.Error("read error", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// backfill sends history lines before the live streams from loki
+func (s *logSubscription) backfill() error {
+	address, err := findLokiAddress(s.conn.resourceManager)
+
+	if err != nil {
+		return err
+	}
+
+	if address == "" {
+		return fmt.Errorf("log system is not installed")
+	}
+
+	start := s.filter.since
+
+	if start.IsZero() {
+		start = s.startTime.Add(-defaultLogHistoryWindow)
+	}
+
+	end := s.startTime
+
+	if !s.filter.until.IsZero() && s.filter.until.Before(end) {
+		end = s.filter.until
+	}
+
+	limit := s.req.HistoryLimit
+
+	if limit <= 0 {
+		limit = defaultLogHistoryLimit
+	}
+
+	var podLabels map[string]string
+
+	if s.req.Component != "" {
+		podLabels = map[string]string{v1alpha1.KalmLabelComponentKey: s.req.Component}
+	}
+
+	query := buildLokiLogQuery(s.req.Namespace, podLabels, s.req.Container, s.req.Regexp)
+
+	entries, err := queryLokiLogs(s.ctx, http.DefaultClient, address, query, start, end, limit, lokiDirectionBackward)
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !s.filter.match(entry.Timestamp, entry.Line) {
+			continue
+		}
+
+		data := entry.Line + "\n"
+
+		if s.req.Timestamps {
+			data = entry.Timestamp.UTC().Format(time.RFC3339Nano) + " " + data
+		}
+
+		err := s.send(&WSLogResponse{
+			Type:      WSResponseTypeLogStreamUpdate,
+			PodName:   entry.Pod,
+			Container: entry.Container,
+			Data:      data,
+			History:   true,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findLokiAddress returns a blank address if no LogSystem is installed
+func findLokiAddress(resourceManager *resources.ResourceManager) (string, error) {
+	var logSystemList v1alpha1.LogSystemList
+
+	if err := resourceManager.List(&logSystemList); err != nil {
+		return "", err
+	}
+
+	for _, logSystem := range logSystemList.Items {
+		if logSystem.Spec.Stack == v1alpha1.LogSystemStackPLGMonolithic {
+			return fmt.Sprintf("http://%s-loki.%s:3100", logSystem.Name, logSystem.Namespace), nil
+		}
+	}
+
+	return "", nil
+}
+
+// buildLokiLogQuery selects streams by the labels added by promtail, pod labels are kept with "-" replaced by "_"
+func buildLokiLogQuery(namespace string, podLabels map[string]string, container, re string) string {
+	selectors := []string{fmt.Sprintf("namespace=%s", strconv.Quote(namespace))}
+
+	keys := make([]string, 0, len(podLabels))
+
+	for key := range podLabels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", strings.ReplaceAll(key, "-", "_"), strconv.Quote(podLabels[key])))
+	}
+
+	if container != "" {
+		selectors = append(selectors, fmt.Sprintf("container=%s", strconv.Quote(container)))
+	}
+
+	query := "{" + strings.Join(selectors, ",") + "}"
+
+	if re != "" {
+		query += " |~ " + strconv.Quote(re)
+	}
+
+	return query
+}
+
+type lokiLogEntry struct {
+	Timestamp time.Time
+	Pod       string
+	Container string
+	Line      string
+}
+
+const (
+	lokiDirectionForward  = "forward"
+	lokiDirectionBackward = "backward"
+)
+
+// queryLokiLogs returns at most limit lines in the range, the oldest first.
+// The earliest lines are selected in the forward direction, the latest ones in the backward direction.
+func queryLokiLogs(ctx context.Context, httpClient *http.Client, address, query string, start, end time.Time, limit int, direction string) ([]lokiLogEntry, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address+"/loki/api/v1/query_range?"+params.Encode(), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := httpClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("loki query failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data struct {
+			Result []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	var entries []lokiLogEntry
+
+	for _, stream := range result.Data.Result {
+		for _, value := range stream.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, lokiLogEntry{
+				Timestamp: time.Unix(0, ns),
+				Pod:       stream.Stream["pod"],
+				Container: stream.Stream["container"],
+				Line:      strings.TrimSuffix(value[1], "\n"),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	return entries, nil
+}
+
+func handleLogSubscriptionRequest(conn *WSConn, m *WSLogSubscriptionRequest, subscriptions map[string]*logSubscription, lock *sync.Mutex) {
+	lock.Lock()
+	if existing, ok := subscriptions[m.ID]; ok {
+		existing.stop()
+		delete(subscriptions, m.ID)
+	}
+	lock.Unlock()
+
+	if m.Type == WSRequestTypeUnsubscribeLogs {
+		return
+	}
+
+	filter, err := newLogLineFilter(m)
+
+	if err != nil {
+		_ = conn.WriteJSON(&WSLogResponse{
+			Type:      WSResponseTypeLogSubscriptionEnd,
+			ID:        m.ID,
+			Namespace: m.Namespace,
+			Data:      err.Error(),
+		})
+		return
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(conn.clientInfo.Cfg)
+
+	if err != nil {
+		log.Error("create kubernetes client error", zap.Error(err))
+		return
+	}
+
+	ctx, stop := context.WithCancel(conn.ctx)
+
+	subscription := &logSubscription{
+		conn:       conn,
+		req:        m,
+		filter:     filter,
+		k8sClient:  k8sClient,
+		ctx:        ctx,
+		stop:       stop,
+		startTime:  time.Now(),
+		containers: make(map[string]*containerLogState),
+	}
+
+	lock.Lock()
+	subscriptions[m.ID] = subscription
+	lock.Unlock()
+
+	go func() {
+		subscription.run()
+		stop()
+
+		lock.Lock()
+		// the id may be subscribed again in the meantime
+		if subscriptions[m.ID] == subscription {
+			delete(subscriptions, m.ID)
+		}
+		lock.Unlock()
+	}()
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLogLevel(t *testing.T) {
+	assert.Equal(t, 3, detectLogLevel(`time="2020-10-01T00:00:00Z" level=error msg="connection refused"`))
+	assert.Equal(t, 2, detectLogLevel(`{"level":"warning","msg":"slow query"}`))
+	assert.Equal(t, 1, detectLogLevel(`2020/10/01 00:00:00 INFO server started`))
+	assert.Equal(t, 4, detectLogLevel(`F1001 00:00:00.000000       1 main.go:10] unable to start`))
+	assert.Equal(t, -1, detectLogLevel(`GET /healthz 200`))
+}
+
+func TestLogLineFilter(t *testing.T) {
+	filter, err := newLogLineFilter(&WSLogSubscriptionRequest{
+		Regexp: "order",
+		Level:  "warn",
+		Since:  "2020-10-01T00:00:00Z",
+		Until:  "2020-10-01T01:00:00Z",
+	})
+	assert.Nil(t, err)
+
+	inWindow := time.Date(2020, 10, 1, 0, 30, 0, 0, time.UTC)
+	assert.True(t, filter.match(inWindow, "level=error msg=\"order failed\""))
+	assert.False(t, filter.match(inWindow, "level=info msg=\"order created\""))
+	assert.False(t, filter.match(inWindow, "level=error msg=\"payment failed\""))
+	assert.False(t, filter.match(inWindow.Add(-time.Hour), "level=error msg=\"order failed\""))
+	assert.False(t, filter.match(inWindow.Add(time.Hour), "level=error msg=\"order failed\""))
+	assert.True(t, filter.isAfterWindow(inWindow.Add(time.Hour)))
+
+	_, err = newLogLineFilter(&WSLogSubscriptionRequest{Level: "verbose"})
+	assert.NotNil(t, err)
+
+	_, err = newLogLineFilter(&WSLogSubscriptionRequest{Regexp: "("})
+	assert.NotNil(t, err)
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	ts, content := splitLogTimestamp("2020-10-01T00:00:00.123456789Z hello world")
+	assert.Equal(t, time.Date(2020, 10, 1, 0, 0, 0, 123456789, time.UTC), ts)
+	assert.Equal(t, "hello world", content)
+
+	ts, content = splitLogTimestamp("hello world")
+	assert.True(t, ts.IsZero())
+	assert.Equal(t, "hello world", content)
+}
+
+func TestQueryLokiLogs(t *testing.T) {
+	query := buildLokiLogQuery("shop", map[string]string{v1alpha1.KalmLabelComponentKey: "web"}, "", `order \d+`)
+	assert.Equal(t, `{namespace="shop",kalm_component="web"} |~ "order \\d+"`, query)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/loki/api/v1/query_range", req.URL.Path)
+		assert.Equal(t, query, req.URL.Query().Get("query"))
+		assert.Equal(t, "backward", req.URL.Query().Get("direction"))
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"resultType": "streams",
+				"result": []interface{}{
+					map[string]interface{}{
+						"stream": map[string]string{"pod": "web-1", "container": "web"},
+						"values": [][2]string{{"3000000000", "order 3\n"}, {"1000000000", "order 1\n"}},
+					},
+					map[string]interface{}{
+						"stream": map[string]string{"pod": "web-2", "container": "web"},
+						"values": [][2]string{{"2000000000", "order 2\n"}},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	entries, err := queryLokiLogs(context.Background(), server.Client(), server.URL, query, time.Unix(0, 0), time.Unix(10, 0), 10, lokiDirectionBackward)
+	assert.Nil(t, err)
+
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "order 1", entries[0].Line)
+		assert.Equal(t, "web-2", entries[1].Pod)
+		assert.Equal(t, time.Unix(3, 0), entries[2].Timestamp)
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/kalmhq/kalm/api/errors"
+	"github.com/kalmhq/kalm/api/resources"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultMetricRange = time.Hour
+
+func (h *ApiHandler) InstallMetricsHandlers(e *echo.Group) {
+	e.GET("/metrics/nodes", h.handleGetNodesMetricRange)
+	e.GET("/metrics/nodes/:name", h.handleGetNodeMetricRange)
+	e.GET("/metrics/applications/:applicationName", h.handleGetApplicationMetricRange)
+	e.GET("/metrics/applications/:applicationName/components/:name", h.handleGetComponentMetricRange)
+	e.GET("/metrics/applications/:applicationName/pods/:name", h.handleGetPodMetricRange)
+}
+
+// start and end are RFC3339 times, default to the last hour
+func getMetricRangeQuery(c echo.Context) (resources.MetricRangeQuery, error) {
+	query := resources.MetricRangeQuery{End: time.Now()}
+
+	var err error
+
+	if end := c.QueryParam("end"); end != "" {
+		if query.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return query, errors.NewBadRequest("end must be a RFC3339 time")
+		}
+	}
+
+	query.Start = query.End.Add(-defaultMetricRange)
+
+	if start := c.QueryParam("start"); start != "" {
+		if query.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return query, errors.NewBadRequest("start must be a RFC3339 time")
+		}
+	}
+
+	if !query.Start.Before(query.End) {
+		return query, errors.NewBadRequest("start must be before end")
+	}
+
+	return query, nil
+}
+
+func (h *ApiHandler) handleGetNodesMetricRange(c echo.Context) error {
+	h.MustCanViewCluster(getCurrentUser(c))
+
+	query, err := getMetricRangeQuery(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.GetNodesMetricRange(query))
+}
+
+func (h *ApiHandler) handleGetNodeMetricRange(c echo.Context) error {
+	h.MustCanViewCluster(getCurrentUser(c))
+
+	query, err := getMetricRangeQuery(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.GetNodeMetricRange(c.Param("name"), query))
+}
+
+func (h *ApiHandler) handleGetApplicationMetricRange(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "applications/"+namespace)
+
+	query, err := getMetricRangeQuery(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.GetApplicationMetricRange(namespace, query))
+}
+
+func (h *ApiHandler) handleGetComponentMetricRange(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "components/"+c.Param("name"))
+
+	query, err := getMetricRangeQuery(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.GetComponentMetricRange(namespace, c.Param("name"), query))
+}
+
+func (h *ApiHandler) handleGetPodMetricRange(c echo.Context) error {
+	namespace := c.Param("applicationName")
+	h.MustCanView(getCurrentUser(c), namespace, "pods/"+c.Param("name"))
+
+	query, err := getMetricRangeQuery(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, resources.GetPodMetricRange(namespace, c.Param("name"), query))
+}
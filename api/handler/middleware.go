@@ -1,13 +1,26 @@
 package handler
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/api/audit"
 	"github.com/kalmhq/kalm/api/client"
+	apiErrors "github.com/kalmhq/kalm/api/errors"
 	"github.com/labstack/echo/v4"
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
 const (
 	CURRENT_USER_KEY = "k8sClientConfig"
+	AUDIT_TARGET_KEY = "auditTarget"
 )
 
 func (h *ApiHandler) RequireUserMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -39,3 +52,171 @@ func (h *ApiHandler) GetUserMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 func getCurrentUser(c echo.Context) *client.ClientInfo {
 	return c.Get(CURRENT_USER_KEY).(*client.ClientInfo)
 }
+
+var auditVerbs = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "patch",
+	http.MethodDelete: "delete",
+}
+
+// AuditMiddleware records mutating requests to the auditor.
+// The current user is read after the request is handled, so it works with GetUserMiddleware or handlers setting the user themselves.
+func (h *ApiHandler) AuditMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		req := c.Request()
+		verb, isMutating := auditVerbs[req.Method]
+
+		if !isMutating {
+			return next(c)
+		}
+
+		bodyHash, err := hashRequestBody(req)
+
+		if err != nil {
+			return err
+		}
+
+		event := &audit.Event{
+			Time:            time.Now(),
+			ClientIP:        c.RealIP(),
+			Verb:            verb,
+			Method:          req.Method,
+			Path:            req.URL.Path,
+			Route:           c.Path(),
+			Resource:        getAuditResource(c.Path()),
+			Namespace:       getAuditNamespace(c),
+			Name:            c.Param("name"),
+			RequestBodyHash: bodyHash,
+		}
+
+		event.DryRun, _ = strconv.ParseBool(c.QueryParam("dryRun"))
+
+		// permission checks panic, see PermissionPanicRecoverMiddleware
+		defer func() {
+			r := recover()
+
+			handledErr := err
+			if r != nil {
+				if e, ok := r.(error); ok {
+					handledErr = e
+				} else {
+					handledErr = fmt.Errorf("%v", r)
+				}
+			}
+
+			fillAuditEventResult(c, event, handledErr)
+			h.Auditor.Record(event)
+
+			if r != nil {
+				panic(r)
+			}
+		}()
+
+		return next(c)
+	}
+}
+
+type auditTarget struct {
+	namespace string
+	name      string
+}
+
+// setAuditTarget is used by handlers whose target resource is not in the route params, e.g. the deploy webhook
+func setAuditTarget(c echo.Context, namespace, name string) {
+	c.Set(AUDIT_TARGET_KEY, &auditTarget{namespace: namespace, name: name})
+}
+
+func fillAuditEventResult(c echo.Context, event *audit.Event, err error) {
+	if target, ok := c.Get(AUDIT_TARGET_KEY).(*auditTarget); ok {
+		event.Namespace = target.namespace
+		event.Name = target.name
+	}
+
+	if currentUser, ok := c.Get(CURRENT_USER_KEY).(*client.ClientInfo); ok && currentUser != nil {
+		event.Subject = currentUser.Email
+		event.Groups = currentUser.Groups
+		event.Impersonation = currentUser.Impersonation
+		event.ImpersonationType = currentUser.ImpersonationType
+		event.AccessToken = currentUser.AccessTokenName
+	}
+
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.StatusCode = apiErrors.StatusCode(err)
+		event.Error = err.Error()
+		return
+	}
+
+	event.StatusCode = c.Response().Status
+
+	if event.StatusCode >= http.StatusBadRequest {
+		event.Outcome = audit.OutcomeFailure
+	} else {
+		event.Outcome = audit.OutcomeSuccess
+	}
+}
+
+// hashRequestBody returns the sha256 of the body, the body is kept to be read by handlers
+func hashRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		return "", err
+	}
+
+	_ = req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if len(body) == 0 {
+		return "", nil
+	}
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getAuditResource returns the resource of the route, which is the last static part in plural,
+// e.g. components for /v1alpha1/applications/:applicationName/components and nodes for /v1alpha1/nodes/:name/cordon.
+// Actions like cordon or import fall back to the resource before them.
+func getAuditResource(route string) string {
+	var statics []string
+
+	for _, part := range strings.Split(strings.Trim(route, "/"), "/") {
+		if part == "" || strings.HasPrefix(part, ":") || part == "v1" || part == "v1alpha1" {
+			continue
+		}
+
+		statics = append(statics, part)
+	}
+
+	for i := len(statics) - 1; i >= 0; i-- {
+		if i == 0 || strings.HasSuffix(statics[i], "s") {
+			return statics[i]
+		}
+	}
+
+	return ""
+}
+
+func getAuditNamespace(c echo.Context) string {
+	if ns := c.Param("applicationName"); ns != "" {
+		return ns
+	}
+
+	if ns := c.Param("namespace"); ns != "" {
+		return ns
+	}
+
+	// the application itself
+	if strings.HasSuffix(c.Path(), "/applications/:name") {
+		return c.Param("name")
+	}
+
+	return ""
+}
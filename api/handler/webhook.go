@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/kalmhq/kalm/api/auth"
+	"github.com/kalmhq/kalm/api/metrics"
 	"github.com/kalmhq/kalm/api/resources"
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/kalmhq/kalm/controller/controllers"
@@ -21,9 +22,22 @@ type DeployWebhookCallParams struct {
 	Namespace     string `json:"application"`
 	ComponentName string `json:"componentName"`
 	ImageTag      string `json:"imageTag"`
+
+	// Optional, deliver the new image progressively with this strategy (canary or blueGreen)
+	// instead of replacing it in place. Steps of the component delivery strategy are used if there is one,
+	// otherwise the default steps of the strategy.
+	Strategy v1alpha1.DeliveryStrategyType `json:"strategy,omitempty"`
 }
 
 func (h *ApiHandler) handleDeployWebhookCall(c echo.Context) error {
+	// the label is the name of the AccessToken, i.e. the hash of the token, never the token itself
+	accessTokenName, result := "unknown", "error"
+
+	// deferred to count failed permission checks, which panic
+	defer func() {
+		metrics.WebhookDeployCalls.WithLabelValues(accessTokenName, result).Inc()
+	}()
+
 	var callParams DeployWebhookCallParams
 
 	if err := c.Bind(&callParams); err != nil {
@@ -48,12 +62,25 @@ func (h *ApiHandler) handleDeployWebhookCall(c echo.Context) error {
 		return fmt.Errorf("componentName can't be blank")
 	}
 
+	if callParams.Strategy != "" &&
+		callParams.Strategy != v1alpha1.DeliveryStrategyCanary &&
+		callParams.Strategy != v1alpha1.DeliveryStrategyBlueGreen {
+		return fmt.Errorf("unknown strategy: %s", callParams.Strategy)
+	}
+
 	clientInfo, err := h.clientManager.GetClientInfoFromToken(callParams.DeployKey)
 
 	if err != nil {
 		return err
 	}
 
+	if clientInfo.AccessTokenName != "" {
+		accessTokenName = clientInfo.AccessTokenName
+	}
+
+	c.Set(CURRENT_USER_KEY, clientInfo)
+	setAuditTarget(c, callParams.Namespace, callParams.ComponentName)
+
 	builder := resources.NewResourceManager(clientInfo.Cfg, h.logger)
 
 	if builder == nil {
@@ -79,6 +106,20 @@ func (h *ApiHandler) handleDeployWebhookCall(c echo.Context) error {
 		copiedComp.Annotations = make(map[string]string)
 	}
 
+	// the strategy only applies to the delivery of this image, the stored delivery strategy is kept.
+	// Components without a delivery strategy are delivered with the default steps of the strategy.
+	if callParams.Strategy != "" {
+		if copiedComp.Spec.WorkloadType != v1alpha1.WorkloadTypeServer && copiedComp.Spec.WorkloadType != "" {
+			return fmt.Errorf("strategy is only supported by workload %s", v1alpha1.WorkloadTypeServer)
+		}
+
+		copiedComp.Annotations[v1alpha1.KalmAnnoDeliveryStrategy] = string(callParams.Strategy)
+	} else {
+		delete(copiedComp.Annotations, v1alpha1.KalmAnnoDeliveryStrategy)
+	}
+
+	resources.SetComponentChangeCause(copiedComp, h.getComponentChangeCause(clientInfo, v1alpha1.ComponentChangeViaWebhook))
+
 	updateTs := int(time.Now().Unix())
 	copiedComp.Annotations[controllers.AnnoLastUpdatedByWebhook] = strconv.Itoa(updateTs)
 
@@ -89,6 +130,8 @@ func (h *ApiHandler) handleDeployWebhookCall(c echo.Context) error {
 
 	h.logger.Info("updating component", zap.String("name", copiedComp.Name), zap.Int("time", updateTs))
 
+	h.recordComponentRevision(copiedComp)
+
 	var accessToken v1alpha1.AccessToken
 	if err := h.resourceManager.Get("", clientInfo.Name, &accessToken); err == nil {
 		copiedKey := accessToken.DeepCopy()
@@ -102,6 +145,8 @@ func (h *ApiHandler) handleDeployWebhookCall(c echo.Context) error {
 		h.logger.Error("fail to get access token", zap.Error(err))
 	}
 
+	result = "success"
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"status": "Success",
 	})
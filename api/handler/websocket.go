@@ -34,6 +34,12 @@ type WSConn struct {
 
 	podResourceRequest chan *WSPodResourceRequest
 	writeLock          *sync.Mutex
+
+	// only available on log connections
+	logSubscriptionRequest chan *WSLogSubscriptionRequest
+
+	// used to find the log system, requests are authorized before it's used
+	resourceManager *resources.ResourceManager
 }
 
 func (conn *WSConn) WriteJSON(v interface{}) error {
@@ -53,6 +59,10 @@ const (
 	WSRequestTypeSubscribePodLog   WSRequestType = "subscribePodLog"
 	WSRequestTypeUnsubscribePodLog WSRequestType = "unsubscribePodLog"
 
+	// logs of all pods of a component or an application
+	WSRequestTypeSubscribeLogs   WSRequestType = "subscribeLogs"
+	WSRequestTypeUnsubscribeLogs WSRequestType = "unsubscribeLogs"
+
 	// exec
 	WSRequestTypeExecStartSession WSRequestType = "execStartSession"
 	WSRequestTypeExecEndSession   WSRequestType = "execEndSession"
@@ -100,6 +110,7 @@ const (
 	// log
 	WSResponseTypeLogStreamUpdate       WSResponseType = "logStreamUpdate"
 	WSResponseTypeLogStreamDisconnected WSResponseType = "logStreamDisconnected"
+	WSResponseTypeLogSubscriptionEnd    WSResponseType = "logSubscriptionEnd"
 
 	// exec
 	WSResponseTypeExecStdout       WSResponseType = "execStreamUpdate"
@@ -268,6 +279,41 @@ func wsReadLoop(conn *WSConn, clientManager client.ClientManager) (err error) {
 			//res.Message = "Request Success"
 
 			// no need to return any value
+			continue
+		case WSRequestTypeSubscribeLogs, WSRequestTypeUnsubscribeLogs:
+			if conn.logSubscriptionRequest == nil {
+				res.Message = "Unknown Message Type"
+				break
+			}
+
+			if conn.clientInfo == nil {
+				res.Message = "Unauthorized, Please verify yourself first."
+				break
+			}
+
+			var m WSLogSubscriptionRequest
+			err = json.Unmarshal(message, &m)
+
+			if err != nil {
+				log.Error("parse message error", zap.Error(err))
+				continue
+			}
+
+			if m.Type == WSRequestTypeSubscribeLogs {
+				obj := "pods/*"
+
+				if m.Component != "" {
+					obj = "components/" + m.Component
+				}
+
+				if !conn.clientManager.CanView(conn.clientInfo, m.Namespace, obj) {
+					res.Message = resources.NoObjectViewerRoleError(m.Namespace, obj).Error()
+					break
+				}
+			}
+
+			conn.logSubscriptionRequest <- &m
+
 			continue
 		case WSRequestTypeAuthStatus:
 			res.Type = WSResponseTypeAuthStatus
@@ -294,17 +340,27 @@ func wsReadLoop(conn *WSConn, clientManager client.ClientManager) (err error) {
 
 func handleLogRequests(conn *WSConn) {
 	podRegistrations := make(map[string]context.CancelFunc)
+	subscriptions := make(map[string]*logSubscription)
+	subscriptionsLock := &sync.Mutex{}
 
 	defer func() {
 		for _, cancelFunc := range podRegistrations {
 			cancelFunc()
 		}
+
+		subscriptionsLock.Lock()
+		for _, subscription := range subscriptions {
+			subscription.stop()
+		}
+		subscriptionsLock.Unlock()
 	}()
 
 	for {
 		select {
 		case <-conn.ctx.Done():
 			return
+		case m := <-conn.logSubscriptionRequest:
+			handleLogSubscriptionRequest(conn, m, subscriptions, subscriptionsLock)
 		case m := <-conn.podResourceRequest:
 			key := fmt.Sprintf("%s___%s", m.Namespace, m.PodName)
 
@@ -570,6 +626,7 @@ func (h *ApiHandler) prepareWSConnection(c echo.Context) (*WSConn, error) {
 		podResourceRequest: make(chan *WSPodResourceRequest),
 		writeLock:          &sync.Mutex{},
 		clientManager:      h.clientManager,
+		resourceManager:    h.resourceManager,
 	}
 
 	clientInfo, err := h.clientManager.GetClientInfoFromContext(c)
@@ -593,6 +650,8 @@ func (h *ApiHandler) logWebsocketHandler(c echo.Context) error {
 		_ = conn.Close()
 	}()
 
+	conn.logSubscriptionRequest = make(chan *WSLogSubscriptionRequest)
+
 	go handleLogRequests(conn)
 	_ = wsReadLoop(conn, h.clientManager)
 
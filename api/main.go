@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/kalmhq/kalm/api/audit"
 	"github.com/kalmhq/kalm/api/log"
+	"github.com/kalmhq/kalm/api/metrics"
 	"golang.org/x/net/http2"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -22,6 +25,7 @@ import (
 	"github.com/kalmhq/kalm/api/server"
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
@@ -90,6 +94,61 @@ func main() {
 				Destination: &runningConfig.EnableAdminServerDebugRoutes,
 				EnvVars:     []string{"ENABLE_DEBUG_APIS"},
 			},
+			&cli.StringFlag{
+				Name:        "audit-log-file",
+				Usage:       "Write audit events of mutating api calls into this file. Events are only kept in memory if no file is set.",
+				Destination: &runningConfig.AuditLogFilePath,
+				EnvVars:     []string{"AUDIT_LOG_FILE"},
+			},
+			&cli.IntFlag{
+				Name:        "audit-log-max-size-mb",
+				Usage:       "The max size in megabytes of the audit log file before it gets rotated.",
+				Value:       100,
+				Destination: &runningConfig.AuditLogMaxSizeMB,
+				EnvVars:     []string{"AUDIT_LOG_MAX_SIZE_MB"},
+			},
+			&cli.IntFlag{
+				Name:        "audit-log-max-backups",
+				Usage:       "The max number of rotated audit log files to keep.",
+				Value:       5,
+				Destination: &runningConfig.AuditLogMaxBackups,
+				EnvVars:     []string{"AUDIT_LOG_MAX_BACKUPS"},
+			},
+			&cli.BoolFlag{
+				Name:        "audit-log-stdout",
+				Value:       false,
+				Usage:       "write audit events to stdout",
+				Destination: &runningConfig.AuditLogStdout,
+				EnvVars:     []string{"AUDIT_LOG_STDOUT"},
+			},
+			&cli.StringFlag{
+				Name:        "audit-webhook-url",
+				Usage:       "Post each audit event as json to this url.",
+				Destination: &runningConfig.AuditWebhookURL,
+				EnvVars:     []string{"AUDIT_WEBHOOK_URL"},
+			},
+			&cli.StringFlag{
+				Name:        "metric-storage-path",
+				Usage:       "Path of the metric database. Put it on a persistent volume to keep metrics across restarts.",
+				Value:       resources.DefaultMetricStoragePath,
+				Destination: &runningConfig.MetricStoragePath,
+				EnvVars:     []string{"METRIC_STORAGE_PATH"},
+			},
+			&cli.StringFlag{
+				Name: "metric-rollups",
+				Usage: "Resolutions and retentions of metrics, in format of resolution:retention separated by comma. " +
+					"The first one is the scrape interval, the others are downsampled from the previous one.",
+				Value:       "5s:15m,1m:24h,1h:30d",
+				Destination: &runningConfig.MetricRollups,
+				EnvVars:     []string{"METRIC_ROLLUPS"},
+			},
+			&cli.StringFlag{
+				Name:        "metrics-bind-address",
+				Usage:       "The address the prometheus metrics endpoint binds to. It's served apart from the api, so it's not exposed with the dashboard. Set to 0 to disable.",
+				Value:       ":8080",
+				Destination: &runningConfig.MetricsBindAddress,
+				EnvVars:     []string{"METRICS_BIND_ADDRESS"},
+			},
 			&cli.BoolFlag{
 				Name:        "verbose",
 				Value:       false,
@@ -140,7 +199,35 @@ func initClusterK8sClientConfiguration(config *config.Config) (cfg *rest.Config,
 	return
 }
 
-func startMainServer(runningConfig *config.Config, k8sClientConfig *rest.Config) {
+func newAuditor(runningConfig *config.Config) (*audit.Auditor, error) {
+	var sinks []audit.Sink
+
+	if runningConfig.AuditLogFilePath != "" {
+		fileSink, err := audit.NewFileSink(
+			runningConfig.AuditLogFilePath,
+			int64(runningConfig.AuditLogMaxSizeMB)*1024*1024,
+			runningConfig.AuditLogMaxBackups,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, fileSink)
+	}
+
+	if runningConfig.AuditLogStdout {
+		sinks = append(sinks, audit.NewStdoutSink())
+	}
+
+	if runningConfig.AuditWebhookURL != "" {
+		sinks = append(sinks, audit.NewWebhookSink(runningConfig.AuditWebhookURL))
+	}
+
+	return audit.NewAuditor(sinks...), nil
+}
+
+func startMainServer(runningConfig *config.Config, k8sClientConfig *rest.Config, auditor *audit.Auditor) {
 	e := server.NewEchoInstance()
 
 	// in production docker build, all things are in a single docker
@@ -165,6 +252,7 @@ func startMainServer(runningConfig *config.Config, k8sClientConfig *rest.Config)
 	}
 
 	apiHandler := handler.NewApiHandler(clientManager)
+	apiHandler.Auditor = auditor
 
 	apiHandler.InstallMainRoutes(e)
 	apiHandler.InstallWebhookRoutes(e)
@@ -188,8 +276,32 @@ func startMainServer(runningConfig *config.Config, k8sClientConfig *rest.Config)
 	}
 }
 
-func startMetricServer(cfg *rest.Config) {
-	_ = resources.StartMetricScraper(context.Background(), cfg)
+func startMetricServer(runningConfig *config.Config, cfg *rest.Config) {
+	rollups, err := resources.ParseMetricRollups(runningConfig.MetricRollups)
+
+	if err != nil {
+		log.Error("invalid metric rollups, skip running metric server", zap.Error(err))
+		return
+	}
+
+	_ = resources.StartMetricScraper(context.Background(), cfg, resources.MetricScraperOptions{
+		StoragePath: runningConfig.MetricStoragePath,
+		Rollups:     rollups,
+	})
+}
+
+func startPrometheusMetricsServer(runningConfig *config.Config) {
+	if runningConfig.MetricsBindAddress == "" || runningConfig.MetricsBindAddress == "0" {
+		log.Info("metrics bind address is not set, skip serving prometheus metrics")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	if err := http.ListenAndServe(runningConfig.MetricsBindAddress, mux); err != nil {
+		log.Error("fail to serve prometheus metrics", zap.Error(err))
+	}
 }
 
 func run(runningConfig *config.Config) {
@@ -203,22 +315,33 @@ func run(runningConfig *config.Config) {
 		panic(err)
 	}
 
+	// shared by both servers, so events from the localhost server can be queried as well
+	auditor, err := newAuditor(runningConfig)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer auditor.Close()
+
 	go func() {
 		if runningConfig.IsInCluster() {
-			startMetricServer(k8sClientConfig)
+			startMetricServer(runningConfig, k8sClientConfig)
 		} else {
 			log.Info("not running in cluster, skip running metric server")
 		}
 	}()
 
+	go startPrometheusMetricsServer(runningConfig)
+
 	// run localhost server with privilege
 	clonedConfig := runningConfig.DeepCopy()
 	clonedConfig.PrivilegedLocalhostAccess = true
 	clonedConfig.BindAddress = "127.0.0.1"
 	clonedConfig.Port = 3010
-	go startMainServer(clonedConfig, k8sClientConfig)
+	go startMainServer(clonedConfig, k8sClientConfig, auditor)
 
 	// real server serve
 	runningConfig.PrivilegedLocalhostAccess = false
-	startMainServer(runningConfig, k8sClientConfig)
+	startMainServer(runningConfig, k8sClientConfig, auditor)
 }
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry of kalm api server metrics, served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	HttpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kalm_api_http_request_duration_seconds",
+			Help:    "Duration of http requests per route.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "code"},
+	)
+
+	WebsocketClients = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kalm_api_websocket_clients",
+			Help: "Number of connected websocket clients watching resources.",
+		},
+	)
+
+	WebhookDeployCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kalm_api_webhook_deploy_calls_total",
+			Help: "Total number of deploy webhook calls per access token and result.",
+		},
+		[]string{"access_token", "result"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		HttpRequestDuration,
+		WebsocketClients,
+		WebhookDeployCalls,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the duration of requests by route pattern instead of path,
+// so resource names in paths don't blow up the cardinality.
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		// handle the error here to record the status code written by the error handler
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unknown"
+		}
+
+		HttpRequestDuration.
+			WithLabelValues(c.Request().Method, route, strconv.Itoa(c.Response().Status)).
+			Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
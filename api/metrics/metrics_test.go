@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware)
+	e.GET("/v1alpha1/applications/:name", func(c echo.Context) error {
+		if c.Param("name") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "not found")
+		}
+
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/v1alpha1/applications/foo", "/v1alpha1/applications/bar", "/v1alpha1/applications/missing"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// paths are recorded as the route pattern
+	assert.Equal(t, 2, testutil.CollectAndCount(HttpRequestDuration))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `kalm_api_http_request_duration_seconds_count{code="200",method="GET",route="/v1alpha1/applications/:name"} 2`)
+	assert.Contains(t, rec.Body.String(), `kalm_api_http_request_duration_seconds_count{code="404",method="GET",route="/v1alpha1/applications/:name"} 1`)
+}
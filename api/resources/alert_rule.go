@@ -0,0 +1,80 @@
+package resources
+
+import (
+	"sort"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+)
+
+type AlertRule struct {
+	Name                    string `json:"name"`
+	*v1alpha1.AlertRuleSpec `json:",inline"`
+	Status                  v1alpha1.AlertRuleStatus `json:"status"`
+}
+
+func BuildAlertRuleFromResource(rule *v1alpha1.AlertRule) *AlertRule {
+	// only used to compute restarts of the next evaluation
+	status := *rule.Status.DeepCopy()
+	status.LastValues = nil
+
+	return &AlertRule{
+		Name:          rule.Name,
+		AlertRuleSpec: &rule.Spec,
+		Status:        status,
+	}
+}
+
+// AlertHistoryRecord is a history record with the rule it belongs to
+type AlertHistoryRecord struct {
+	Rule                        string                 `json:"rule"`
+	Type                        v1alpha1.AlertRuleType `json:"type"`
+	v1alpha1.AlertHistoryRecord `json:",inline"`
+}
+
+type AlertHistoryQuery struct {
+	Rule   string
+	Target string
+
+	// unix timestamp, records before it are not included
+	Since int64
+
+	// the latest ones are returned, 0 means no limit
+	Limit int
+}
+
+// QueryAlertHistory merges history of the rules, the latest records first
+func QueryAlertHistory(rules []v1alpha1.AlertRule, query AlertHistoryQuery) []AlertHistoryRecord {
+	rst := []AlertHistoryRecord{}
+
+	for _, rule := range rules {
+		if query.Rule != "" && rule.Name != query.Rule {
+			continue
+		}
+
+		for _, record := range rule.Status.History {
+			if query.Target != "" && record.Target != query.Target {
+				continue
+			}
+
+			if record.Timestamp < query.Since {
+				continue
+			}
+
+			rst = append(rst, AlertHistoryRecord{
+				Rule:               rule.Name,
+				Type:               rule.Spec.Type,
+				AlertHistoryRecord: record,
+			})
+		}
+	}
+
+	sort.SliceStable(rst, func(i, j int) bool {
+		return rst[i].Timestamp > rst[j].Timestamp
+	})
+
+	if query.Limit > 0 && len(rst) > query.Limit {
+		rst = rst[:query.Limit]
+	}
+
+	return rst
+}
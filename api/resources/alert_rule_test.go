@@ -0,0 +1,49 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQueryAlertHistory(t *testing.T) {
+	rules := []v1alpha1.AlertRule{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu"},
+			Spec:       v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeCPU},
+			Status: v1alpha1.AlertRuleStatus{
+				History: []v1alpha1.AlertHistoryRecord{
+					{Target: "web", State: v1alpha1.AlertStateFiring, Timestamp: 100},
+					{Target: "web", State: v1alpha1.AlertStateResolved, Timestamp: 300},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "memory"},
+			Spec:       v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeMemory},
+			Status: v1alpha1.AlertRuleStatus{
+				History: []v1alpha1.AlertHistoryRecord{
+					{Target: "api", State: v1alpha1.AlertStateFiring, Timestamp: 200},
+				},
+			},
+		},
+	}
+
+	records := QueryAlertHistory(rules, AlertHistoryQuery{})
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, int64(300), records[0].Timestamp)
+		assert.Equal(t, "memory", records[1].Rule)
+		assert.Equal(t, v1alpha1.AlertRuleTypeMemory, records[1].Type)
+	}
+
+	assert.Len(t, QueryAlertHistory(rules, AlertHistoryQuery{Rule: "cpu"}), 2)
+	assert.Len(t, QueryAlertHistory(rules, AlertHistoryQuery{Target: "api"}), 1)
+	assert.Len(t, QueryAlertHistory(rules, AlertHistoryQuery{Since: 150}), 2)
+
+	records = QueryAlertHistory(rules, AlertHistoryQuery{Limit: 1})
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, int64(300), records[0].Timestamp)
+	}
+}
@@ -2,6 +2,7 @@ package resources
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
@@ -93,6 +94,9 @@ type ApplicationDetails struct {
 	IstioMetricHistories *IstioMetricHistories `json:"istioMetricHistories"`
 	Roles                []string              `json:"roles"`
 	Status               string                `json:"status"` // Active or Terminating
+
+	// Only counted for applications with quota
+	QuotaUsage *v1alpha1.ApplicationQuotaUsage `json:"quotaUsage,omitempty"`
 }
 
 type CreateOrUpdateApplicationRequest struct {
@@ -101,6 +105,24 @@ type CreateOrUpdateApplicationRequest struct {
 
 type Application struct {
 	Name string `json:"name"`
+
+	// How many revisions are kept for each component, default to 10
+	ComponentRevisionHistoryLimit *int `json:"componentRevisionHistoryLimit,omitempty"`
+
+	// Resources all components of the application can take, keep unchanged if absent, an empty quota removes it
+	Quota *v1alpha1.ApplicationQuota `json:"quota,omitempty"`
+}
+
+func SetComponentRevisionHistoryLimit(namespace *coreV1.Namespace, limit *int) {
+	if limit == nil {
+		return
+	}
+
+	if namespace.Labels == nil {
+		namespace.Labels = make(map[string]string)
+	}
+
+	namespace.Labels[v1alpha1.KalmLabelComponentRevisionHistoryLimit] = strconv.Itoa(*limit)
 }
 
 func (resourceManager *ResourceManager) GetNamespace(name string) (*coreV1.Namespace, error) {
@@ -168,10 +190,29 @@ func (resourceManager *ResourceManager) BuildApplicationDetails(namespace *coreV
 		}
 	}
 
+	revisionHistoryLimit := v1alpha1.GetComponentRevisionHistoryLimit(namespace.Labels)
+
+	quota, err := v1alpha1.GetApplicationQuota(namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var quotaUsage *v1alpha1.ApplicationQuotaUsage
+
+	if quota != nil {
+		if quotaUsage, err = resourceManager.GetApplicationQuotaUsage(nsName); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ApplicationDetails{
 		Application: &Application{
-			Name: nsName,
+			Name:                          nsName,
+			ComponentRevisionHistoryLimit: &revisionHistoryLimit,
+			Quota:                         quota,
 		},
+		QuotaUsage: quotaUsage,
 		Metrics: MetricHistories{
 			CPU:    applicationMetric.CPU,
 			Memory: applicationMetric.Memory,
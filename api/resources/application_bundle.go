@@ -0,0 +1,831 @@
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/controllers"
+	"github.com/kalmhq/kalm/controller/lib/files"
+	"gomodules.xyz/jsonpatch/v2"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	ApplicationBundleKind    = "ApplicationBundle"
+	ApplicationBundleVersion = "v1alpha1"
+)
+
+// ApplicationBundle is a self-contained definition of an application.
+// It is exported from one cluster and can be imported into another one, or kept in git.
+type ApplicationBundle struct {
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+
+	Application             Application                    `json:"application"`
+	Components              []BundleComponent              `json:"components,omitempty"`
+	ComponentPluginBindings []BundleComponentPluginBinding `json:"componentPluginBindings,omitempty"`
+	HttpRoutes              []BundleHttpRoute              `json:"httpRoutes,omitempty"`
+	ProtectedEndpoints      []BundleProtectedEndpoint      `json:"protectedEndpoints,omitempty"`
+	ConfigMaps              []BundleConfigMap              `json:"configMaps,omitempty"`
+
+	// Values of secrets are redacted when exporting.
+	// Redacted values are kept as they are in the target cluster when importing.
+	Secrets []BundleSecret `json:"secrets,omitempty"`
+
+	// Registries used by images of components, credentials are not exported.
+	DockerRegistries []BundleDockerRegistry `json:"dockerRegistries,omitempty"`
+}
+
+type BundleComponent struct {
+	Name string                 `json:"name"`
+	Spec v1alpha1.ComponentSpec `json:"spec"`
+}
+
+type BundleComponentPluginBinding struct {
+	Name string                              `json:"name"`
+	Spec v1alpha1.ComponentPluginBindingSpec `json:"spec"`
+}
+
+type BundleHttpRoute struct {
+	Name string                 `json:"name"`
+	Spec v1alpha1.HttpRouteSpec `json:"spec"`
+}
+
+type BundleProtectedEndpoint struct {
+	Name string                         `json:"name"`
+	Spec v1alpha1.ProtectedEndpointSpec `json:"spec"`
+}
+
+type BundleConfigMap struct {
+	Name string            `json:"name"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type BundleSecret struct {
+	Name string            `json:"name"`
+	Type coreV1.SecretType `json:"type,omitempty"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type BundleDockerRegistry struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+}
+
+func (bundle *ApplicationBundle) Validate() error {
+	if bundle.Kind != ApplicationBundleKind {
+		return fmt.Errorf("kind of bundle should be %s, got %q", ApplicationBundleKind, bundle.Kind)
+	}
+
+	if bundle.Version != ApplicationBundleVersion {
+		return fmt.Errorf("unsupported bundle version %q", bundle.Version)
+	}
+
+	if bundle.Application.Name == "" {
+		return fmt.Errorf("application name is required")
+	}
+
+	if bundle.Application.ComponentRevisionHistoryLimit != nil && *bundle.Application.ComponentRevisionHistoryLimit < 1 {
+		return fmt.Errorf("componentRevisionHistoryLimit should be positive")
+	}
+
+	return nil
+}
+
+// RenameApplication moves the bundle to the application of the new name,
+// destinations of routes pointing to the old namespace are changed accordingly.
+func (bundle *ApplicationBundle) RenameApplication(name string) {
+	oldName := bundle.Application.Name
+
+	if oldName == name {
+		return
+	}
+
+	bundle.Application.Name = name
+
+	for i := range bundle.HttpRoutes {
+		spec := &bundle.HttpRoutes[i].Spec
+
+		for j := range spec.Destinations {
+			spec.Destinations[j].Host = replaceNamespaceOfSvcHost(spec.Destinations[j].Host, oldName, name)
+		}
+
+		if spec.Mirror != nil {
+			spec.Mirror.Destination.Host = replaceNamespaceOfSvcHost(spec.Mirror.Destination.Host, oldName, name)
+		}
+	}
+}
+
+// host is in format of <svc>.<namespace>[.svc.cluster.local][:port]
+func replaceNamespaceOfSvcHost(host, oldNamespace, newNamespace string) string {
+	parts := strings.Split(host, ".")
+
+	if len(parts) < 2 {
+		return host
+	}
+
+	if parts[1] == oldNamespace {
+		parts[1] = newNamespace
+	} else if strings.HasPrefix(parts[1], oldNamespace+":") {
+		parts[1] = newNamespace + strings.TrimPrefix(parts[1], oldNamespace)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// Routes are cluster scoped, a route belongs to the application of its namespace label,
+// or the application of its destinations if it's not labeled.
+func isHttpRouteOfNamespace(route *v1alpha1.HttpRoute, namespace string) bool {
+	if label, ok := route.Labels[v1alpha1.KalmLabelNamespaceKey]; ok {
+		return label == namespace
+	}
+
+	for _, destination := range route.Spec.Destinations {
+		if _, ns := getComponentAndNSNameFromSvcName(destination.Host); strings.Split(ns, ":")[0] == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getImageRegistryHost returns the registry host of an image, images without a host are from docker hub
+func getImageRegistryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+
+	if len(parts) < 2 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return "docker.io"
+	}
+
+	return normalizeRegistryHost(parts[0])
+}
+
+// normalizeRegistryHost turns both hosts of registries and images into the same form, e.g. https://gcr.io/ -> gcr.io
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.SplitN(host, "/", 2)[0]
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+
+	return host
+}
+
+type bundleReferences struct {
+	configMaps map[string]bool
+	secrets    map[string]bool
+	imageHosts map[string]bool
+}
+
+func (refs *bundleReferences) addContainer(image string, envs []v1alpha1.EnvVar, envFrom []v1alpha1.EnvFromSource) {
+	refs.imageHosts[getImageRegistryHost(image)] = true
+
+	for _, env := range envs {
+		if env.Type != v1alpha1.EnvVarTypeSecret && env.Type != v1alpha1.EnvVarTypeConfigMap {
+			continue
+		}
+
+		name, _, err := env.GetObjectKeyReference()
+
+		if err != nil {
+			continue
+		}
+
+		refs.add(env.Type, name)
+	}
+
+	for _, source := range envFrom {
+		refs.add(source.Type, source.Name)
+	}
+}
+
+func (refs *bundleReferences) add(envType v1alpha1.EnvVarType, name string) {
+	switch envType {
+	case v1alpha1.EnvVarTypeSecret:
+		refs.secrets[name] = true
+	case v1alpha1.EnvVarTypeConfigMap:
+		refs.configMaps[name] = true
+	}
+}
+
+func (refs *bundleReferences) addComponent(spec *v1alpha1.ComponentSpec) {
+	refs.addContainer(spec.Image, spec.Env, spec.EnvFrom)
+
+	for _, container := range spec.Sidecars {
+		refs.addContainer(container.Image, container.Env, container.EnvFrom)
+	}
+
+	for _, container := range spec.InitContainers {
+		refs.addContainer(container.Image, container.Env, container.EnvFrom)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (resourceManager *ResourceManager) ExportApplicationBundle(namespace *coreV1.Namespace) (*ApplicationBundle, error) {
+	nsName := namespace.Name
+	revisionHistoryLimit := v1alpha1.GetComponentRevisionHistoryLimit(namespace.Labels)
+
+	bundle := &ApplicationBundle{
+		Kind:    ApplicationBundleKind,
+		Version: ApplicationBundleVersion,
+		Application: Application{
+			Name:                          nsName,
+			ComponentRevisionHistoryLimit: &revisionHistoryLimit,
+		},
+	}
+
+	// files of components are mounted from the files config map, it's not referenced by envs
+	refs := &bundleReferences{
+		configMaps: map[string]bool{controllers.NSScopeSharedConfigMapName: true, files.KALM_CONFIG_MAP_NAME: true},
+		secrets:    map[string]bool{},
+		imageHosts: map[string]bool{},
+	}
+
+	var components v1alpha1.ComponentList
+	if err := resourceManager.List(&components, client.InNamespace(nsName)); err != nil {
+		return nil, err
+	}
+
+	for i := range components.Items {
+		component := &components.Items[i]
+		bundle.Components = append(bundle.Components, BundleComponent{Name: component.Name, Spec: component.Spec})
+		refs.addComponent(&component.Spec)
+	}
+
+	var bindings v1alpha1.ComponentPluginBindingList
+	if err := resourceManager.List(&bindings, client.InNamespace(nsName)); err != nil {
+		return nil, err
+	}
+
+	for _, binding := range bindings.Items {
+		bundle.ComponentPluginBindings = append(bundle.ComponentPluginBindings, BundleComponentPluginBinding{Name: binding.Name, Spec: binding.Spec})
+	}
+
+	var routes v1alpha1.HttpRouteList
+	if err := resourceManager.List(&routes); err != nil {
+		return nil, err
+	}
+
+	for _, route := range routes.Items {
+		if isHttpRouteOfNamespace(&route, nsName) {
+			bundle.HttpRoutes = append(bundle.HttpRoutes, BundleHttpRoute{Name: route.Name, Spec: route.Spec})
+		}
+	}
+
+	var endpoints v1alpha1.ProtectedEndpointList
+	if err := resourceManager.List(&endpoints, client.InNamespace(nsName)); err != nil {
+		return nil, err
+	}
+
+	for _, endpoint := range endpoints.Items {
+		bundle.ProtectedEndpoints = append(bundle.ProtectedEndpoints, BundleProtectedEndpoint{Name: endpoint.Name, Spec: endpoint.Spec})
+	}
+
+	for _, name := range sortedKeys(refs.configMaps) {
+		var configMap coreV1.ConfigMap
+
+		if err := resourceManager.Get(nsName, name, &configMap); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		bundle.ConfigMaps = append(bundle.ConfigMaps, BundleConfigMap{Name: name, Data: configMap.Data})
+	}
+
+	for _, name := range sortedKeys(refs.secrets) {
+		var secret coreV1.Secret
+
+		if err := resourceManager.Get(nsName, name, &secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		bundle.Secrets = append(bundle.Secrets, BuildRedactedBundleSecret(&secret))
+	}
+
+	var registries v1alpha1.DockerRegistryList
+	if err := resourceManager.List(&registries); err != nil {
+		return nil, err
+	}
+
+	for _, registry := range registries.Items {
+		if refs.imageHosts[normalizeRegistryHost(registry.Spec.Host)] {
+			bundle.DockerRegistries = append(bundle.DockerRegistries, BundleDockerRegistry{Name: registry.Name, Host: registry.Spec.Host})
+		}
+	}
+
+	return bundle, nil
+}
+
+// MaskedSecretValue replaces values of secrets in bundles.
+// A masked value in imported bundles means the value is not changed.
+const MaskedSecretValue = "******"
+
+func BuildRedactedBundleSecret(secret *coreV1.Secret) BundleSecret {
+	res := BundleSecret{
+		Name: secret.Name,
+		Type: secret.Type,
+	}
+
+	if len(secret.Data) > 0 {
+		res.Data = make(map[string]string, len(secret.Data))
+
+		for key := range secret.Data {
+			res.Data[key] = MaskedSecretValue
+		}
+	}
+
+	return res
+}
+
+type ApplicationImportConflictStrategy string
+
+const (
+	// nothing is imported if any existing object is different from the bundle
+	ApplicationImportConflictFail ApplicationImportConflictStrategy = "fail"
+	// existing objects are left as they are
+	ApplicationImportConflictSkip ApplicationImportConflictStrategy = "skip"
+	// existing objects are updated to the ones in the bundle
+	ApplicationImportConflictOverwrite ApplicationImportConflictStrategy = "overwrite"
+)
+
+type ApplicationImportAction string
+
+const (
+	ApplicationImportActionCreate    ApplicationImportAction = "create"
+	ApplicationImportActionUpdate    ApplicationImportAction = "update"
+	ApplicationImportActionUnchanged ApplicationImportAction = "unchanged"
+	ApplicationImportActionSkip      ApplicationImportAction = "skip"
+	ApplicationImportActionConflict  ApplicationImportAction = "conflict"
+)
+
+type ApplicationImportOptions struct {
+	// Import the bundle as the application of this name, default to the name in the bundle
+	Name     string
+	DryRun   bool
+	Conflict ApplicationImportConflictStrategy
+
+	// Called with the renamed and validated bundle before any change is planned, e.g. to check permissions
+	Authorize func(bundle *ApplicationBundle) error
+}
+
+type ApplicationImportChange struct {
+	Kind   string                  `json:"kind"`
+	Name   string                  `json:"name"`
+	Action ApplicationImportAction `json:"action"`
+	Reason string                  `json:"reason,omitempty"`
+
+	// json patch from the existing object to the one in the bundle
+	Diff []jsonpatch.Operation `json:"diff,omitempty"`
+}
+
+type ApplicationImportResult struct {
+	DryRun      bool                      `json:"dryRun"`
+	Application string                    `json:"application"`
+	Changes     []ApplicationImportChange `json:"changes"`
+	Warnings    []string                  `json:"warnings,omitempty"`
+}
+
+func (result *ApplicationImportResult) HasConflicts() bool {
+	for _, change := range result.Changes {
+		if change.Action == ApplicationImportActionConflict {
+			return true
+		}
+	}
+
+	return false
+}
+
+type applicationImporter struct {
+	*ResourceManager
+	namespace string
+	conflict  ApplicationImportConflictStrategy
+	result    *ApplicationImportResult
+
+	// called in order to create or update objects once the whole bundle is planned
+	applies []func() error
+}
+
+// ImportApplicationBundle plans the changes of all objects in the bundle first.
+// Objects are only created or updated if it's not a dry run and there is no conflict.
+func (resourceManager *ResourceManager) ImportApplicationBundle(bundle *ApplicationBundle, options ApplicationImportOptions) (*ApplicationImportResult, error) {
+	if options.Name != "" {
+		bundle.RenameApplication(options.Name)
+	}
+
+	if err := bundle.Validate(); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	if options.Authorize != nil {
+		if err := options.Authorize(bundle); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Conflict == "" {
+		options.Conflict = ApplicationImportConflictFail
+	}
+
+	importer := &applicationImporter{
+		ResourceManager: resourceManager,
+		namespace:       bundle.Application.Name,
+		conflict:        options.Conflict,
+		result: &ApplicationImportResult{
+			DryRun:      options.DryRun,
+			Application: bundle.Application.Name,
+			Changes:     []ApplicationImportChange{},
+		},
+	}
+
+	if err := importer.planBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	if options.DryRun || importer.result.HasConflicts() {
+		return importer.result, nil
+	}
+
+	for _, apply := range importer.applies {
+		if err := apply(); err != nil {
+			return nil, err
+		}
+	}
+
+	return importer.result, nil
+}
+
+// the order matters, referenced configMaps and secrets are checked by the component webhook
+func (importer *applicationImporter) planBundle(bundle *ApplicationBundle) error {
+	if err := importer.planApplication(bundle.Application); err != nil {
+		return err
+	}
+
+	for i := range bundle.ConfigMaps {
+		if err := importer.planConfigMap(bundle.ConfigMaps[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range bundle.Secrets {
+		if err := importer.planSecret(bundle.Secrets[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range bundle.Components {
+		if err := importer.planComponent(bundle.Components[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range bundle.ComponentPluginBindings {
+		if err := importer.planComponentPluginBinding(bundle.ComponentPluginBindings[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range bundle.ProtectedEndpoints {
+		if err := importer.planProtectedEndpoint(bundle.ProtectedEndpoints[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range bundle.HttpRoutes {
+		if err := importer.planHttpRoute(bundle.HttpRoutes[i]); err != nil {
+			return err
+		}
+	}
+
+	return importer.checkDockerRegistries(bundle.DockerRegistries)
+}
+
+func (importer *applicationImporter) get(namespace, name string, obj runtime.Object) (exist bool, err error) {
+	if err := importer.Get(namespace, name, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (importer *applicationImporter) createOrUpdate(obj runtime.Object, mutate func()) func() error {
+	return func() error {
+		_, err := controllerutil.CreateOrUpdate(importer.ctx, importer.Client, obj, func() error {
+			mutate()
+			return nil
+		})
+
+		return err
+	}
+}
+
+func (importer *applicationImporter) warn(format string, args ...interface{}) {
+	importer.result.Warnings = append(importer.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// plan records the change of an object. current is nil if the object doesn't exist.
+// A non-empty reason marks the existing object as different from the bundle even if the contents are the same.
+func (importer *applicationImporter) plan(kind, name string, current, desired interface{}, reason string, apply func() error) error {
+	change := ApplicationImportChange{
+		Kind:   kind,
+		Name:   name,
+		Reason: reason,
+	}
+
+	if current == nil {
+		change.Action = ApplicationImportActionCreate
+	} else {
+		diff, err := DiffObjects(current, desired)
+
+		if err != nil {
+			return err
+		}
+
+		change.Diff = diff
+
+		switch {
+		case len(diff) == 0 && reason == "":
+			change.Action = ApplicationImportActionUnchanged
+		case importer.conflict == ApplicationImportConflictOverwrite:
+			change.Action = ApplicationImportActionUpdate
+		case importer.conflict == ApplicationImportConflictSkip:
+			change.Action = ApplicationImportActionSkip
+		default:
+			change.Action = ApplicationImportActionConflict
+		}
+	}
+
+	if change.Action == ApplicationImportActionCreate || change.Action == ApplicationImportActionUpdate {
+		importer.applies = append(importer.applies, apply)
+	}
+
+	importer.result.Changes = append(importer.result.Changes, change)
+
+	return nil
+}
+
+func (importer *applicationImporter) planApplication(app Application) error {
+	var namespace coreV1.Namespace
+	exist, err := importer.get("", importer.namespace, &namespace)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+	var reason string
+
+	if exist {
+		limit := v1alpha1.GetComponentRevisionHistoryLimit(namespace.Labels)
+		current = Application{Name: namespace.Name, ComponentRevisionHistoryLimit: &limit}
+
+		if namespace.Labels[controllers.KalmEnableLabelName] != controllers.KalmEnableLabelValue {
+			reason = "namespace is not managed by kalm"
+		}
+	}
+
+	namespace.Name = importer.namespace
+
+	return importer.plan("Application", importer.namespace, current, app, reason, importer.createOrUpdate(&namespace, func() {
+		if namespace.Labels == nil {
+			namespace.Labels = make(map[string]string)
+		}
+
+		namespace.Labels[controllers.KalmEnableLabelName] = controllers.KalmEnableLabelValue
+		SetComponentRevisionHistoryLimit(&namespace, app.ComponentRevisionHistoryLimit)
+	}))
+}
+
+func (importer *applicationImporter) planConfigMap(configMap BundleConfigMap) error {
+	var fetched coreV1.ConfigMap
+	exist, err := importer.get(importer.namespace, configMap.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+
+	if exist {
+		current = BundleConfigMap{Name: fetched.Name, Data: fetched.Data}
+	}
+
+	obj := &coreV1.ConfigMap{ObjectMeta: metaV1.ObjectMeta{Namespace: importer.namespace, Name: configMap.Name}}
+
+	return importer.plan("ConfigMap", configMap.Name, current, configMap, "", importer.createOrUpdate(obj, func() {
+		obj.Data = configMap.Data
+	}))
+}
+
+func (importer *applicationImporter) planSecret(secret BundleSecret) error {
+	var fetched coreV1.Secret
+	exist, err := importer.get(importer.namespace, secret.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+
+	if exist {
+		current = BuildRedactedBundleSecret(&fetched)
+	}
+
+	// redacted values are kept as they are, they can't be set if the key doesn't exist yet
+	data := make(map[string][]byte, len(secret.Data))
+	desired := BundleSecret{Name: secret.Name, Type: secret.Type, Data: make(map[string]string, len(secret.Data))}
+
+	for key, value := range secret.Data {
+		if value == MaskedSecretValue {
+			if _, ok := fetched.Data[key]; !ok {
+				importer.warn("value of key %s in secret %s is redacted, please set it after importing", key, secret.Name)
+				continue
+			}
+
+			data[key] = fetched.Data[key]
+		} else {
+			data[key] = []byte(value)
+		}
+
+		desired.Data[key] = MaskedSecretValue
+	}
+
+	var reason string
+
+	// values are compared here, as they are redacted in the diff
+	if exist && desired.Type == fetched.Type && len(data) == len(fetched.Data) {
+		for key, value := range data {
+			if string(fetched.Data[key]) != string(value) {
+				reason = "secret values are different"
+				break
+			}
+		}
+	}
+
+	obj := &coreV1.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: importer.namespace, Name: secret.Name}}
+
+	return importer.plan("Secret", secret.Name, current, desired, reason, importer.createOrUpdate(obj, func() {
+		// type of a secret can't be changed
+		if obj.Type == "" {
+			obj.Type = secret.Type
+		}
+
+		obj.Data = data
+	}))
+}
+
+func (importer *applicationImporter) planComponent(component BundleComponent) error {
+	var fetched v1alpha1.Component
+	exist, err := importer.get(importer.namespace, component.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+
+	if exist {
+		current = BundleComponent{Name: fetched.Name, Spec: fetched.Spec}
+	}
+
+	obj := &v1alpha1.Component{ObjectMeta: metaV1.ObjectMeta{Namespace: importer.namespace, Name: component.Name}}
+
+	return importer.plan("Component", component.Name, current, component, "", importer.createOrUpdate(obj, func() {
+		obj.Spec = component.Spec
+	}))
+}
+
+func (importer *applicationImporter) planComponentPluginBinding(binding BundleComponentPluginBinding) error {
+	var fetched v1alpha1.ComponentPluginBinding
+	exist, err := importer.get(importer.namespace, binding.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+
+	if exist {
+		current = BundleComponentPluginBinding{Name: fetched.Name, Spec: fetched.Spec}
+	}
+
+	obj := &v1alpha1.ComponentPluginBinding{ObjectMeta: metaV1.ObjectMeta{Namespace: importer.namespace, Name: binding.Name}}
+
+	return importer.plan("ComponentPluginBinding", binding.Name, current, binding, "", importer.createOrUpdate(obj, func() {
+		if binding.Spec.ComponentName != "" {
+			if obj.Labels == nil {
+				obj.Labels = make(map[string]string)
+			}
+
+			obj.Labels["kalm-component"] = binding.Spec.ComponentName
+		}
+
+		obj.Spec = binding.Spec
+	}))
+}
+
+func (importer *applicationImporter) planProtectedEndpoint(endpoint BundleProtectedEndpoint) error {
+	var fetched v1alpha1.ProtectedEndpoint
+	exist, err := importer.get(importer.namespace, endpoint.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+
+	if exist {
+		current = BundleProtectedEndpoint{Name: fetched.Name, Spec: fetched.Spec}
+	}
+
+	obj := &v1alpha1.ProtectedEndpoint{ObjectMeta: metaV1.ObjectMeta{Namespace: importer.namespace, Name: endpoint.Name}}
+
+	return importer.plan("ProtectedEndpoint", endpoint.Name, current, endpoint, "", importer.createOrUpdate(obj, func() {
+		obj.Spec = endpoint.Spec
+	}))
+}
+
+func (importer *applicationImporter) planHttpRoute(route BundleHttpRoute) error {
+	var fetched v1alpha1.HttpRoute
+	exist, err := importer.get("", route.Name, &fetched)
+
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+	var reason string
+
+	if exist {
+		current = BundleHttpRoute{Name: fetched.Name, Spec: fetched.Spec}
+
+		// routes are cluster scoped, don't take over the ones of other applications silently
+		if !isHttpRouteOfNamespace(&fetched, importer.namespace) {
+			reason = "route belongs to another application"
+		}
+	}
+
+	obj := &v1alpha1.HttpRoute{ObjectMeta: metaV1.ObjectMeta{Name: route.Name}}
+
+	return importer.plan("HttpRoute", route.Name, current, route, reason, importer.createOrUpdate(obj, func() {
+		obj.Spec = route.Spec
+		setHttpRouteNamespace(obj, importer.namespace)
+	}))
+}
+
+// registries are not imported as there are no credentials in bundles, warn if they are missing
+func (importer *applicationImporter) checkDockerRegistries(registries []BundleDockerRegistry) error {
+	if len(registries) == 0 {
+		return nil
+	}
+
+	var fetched v1alpha1.DockerRegistryList
+	if err := importer.List(&fetched); err != nil {
+		return err
+	}
+
+	hosts := make(map[string]bool, len(fetched.Items))
+
+	for _, registry := range fetched.Items {
+		hosts[normalizeRegistryHost(registry.Spec.Host)] = true
+	}
+
+	for _, registry := range registries {
+		if !hosts[normalizeRegistryHost(registry.Host)] {
+			importer.warn("docker registry %s is not found, please add it before the images can be pulled", registry.Host)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/controllers"
+	"github.com/kalmhq/kalm/controller/lib/files"
+	"github.com/stretchr/testify/assert"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeResourceManager(objs ...runtime.Object) *ResourceManager {
+	return &ResourceManager{
+		ctx:    context.Background(),
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+	}
+}
+
+func bundleTestObjects() []runtime.Object {
+	return []runtime.Object{
+		&coreV1.Namespace{ObjectMeta: metaV1.ObjectMeta{
+			Name:   "shop",
+			Labels: map[string]string{controllers.KalmEnableLabelName: controllers.KalmEnableLabelValue},
+		}},
+		&v1alpha1.Component{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "web"},
+			Spec: v1alpha1.ComponentSpec{
+				Image: "gcr.io/shop/web:v1",
+				Env: []v1alpha1.EnvVar{
+					{Name: "DB_PASSWORD", Type: v1alpha1.EnvVarTypeSecret, Value: "db/password"},
+				},
+				EnvFrom: []v1alpha1.EnvFromSource{
+					{Type: v1alpha1.EnvVarTypeConfigMap, Name: "web-config"},
+				},
+			},
+		},
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "db"},
+			Type:       coreV1.SecretTypeOpaque,
+			Data:       map[string][]byte{"password": []byte("s3cret")},
+		},
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "not-referenced"},
+			Data:       map[string][]byte{"key": []byte("value")},
+		},
+		&coreV1.ConfigMap{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "web-config"},
+			Data:       map[string]string{"MODE": "production"},
+		},
+		&coreV1.ConfigMap{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: files.KALM_CONFIG_MAP_NAME},
+			Data:       map[string]string{"nginx.conf": "server {}"},
+		},
+		&v1alpha1.HttpRoute{
+			ObjectMeta: metaV1.ObjectMeta{Name: "shop-web"},
+			Spec: v1alpha1.HttpRouteSpec{
+				Hosts:        []string{"shop.example.com"},
+				Destinations: []v1alpha1.HttpRouteDestination{{Host: "web.shop.svc.cluster.local:80", Weight: 1}},
+			},
+		},
+		&v1alpha1.HttpRoute{
+			ObjectMeta: metaV1.ObjectMeta{Name: "shop-www", Labels: map[string]string{v1alpha1.KalmLabelNamespaceKey: "shop"}},
+			Spec: v1alpha1.HttpRouteSpec{
+				Hosts:    []string{"www.shop.example.com"},
+				Redirect: &v1alpha1.HttpRouteRedirect{Host: "shop.example.com"},
+			},
+		},
+		&v1alpha1.HttpRoute{
+			ObjectMeta: metaV1.ObjectMeta{Name: "other"},
+			Spec: v1alpha1.HttpRouteSpec{
+				Destinations: []v1alpha1.HttpRouteDestination{{Host: "web.other.svc.cluster.local:80", Weight: 1}},
+			},
+		},
+		&v1alpha1.DockerRegistry{
+			ObjectMeta: metaV1.ObjectMeta{Name: "gcr"},
+			Spec:       v1alpha1.DockerRegistrySpec{Host: "https://gcr.io"},
+		},
+	}
+}
+
+func TestExportApplicationBundle(t *testing.T) {
+	manager := newFakeResourceManager(bundleTestObjects()...)
+	ns, err := manager.GetNamespace("shop")
+	assert.Nil(t, err)
+
+	bundle, err := manager.ExportApplicationBundle(ns)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ApplicationBundleKind, bundle.Kind)
+	assert.Equal(t, "shop", bundle.Application.Name)
+	assert.Len(t, bundle.Components, 1)
+
+	// the redirect route has no destinations, it's exported by its label
+	if assert.Len(t, bundle.HttpRoutes, 2) {
+		assert.Equal(t, "shop-web", bundle.HttpRoutes[0].Name)
+		assert.Equal(t, "shop-www", bundle.HttpRoutes[1].Name)
+	}
+
+	assert.Equal(t, []BundleConfigMap{
+		{Name: files.KALM_CONFIG_MAP_NAME, Data: map[string]string{"nginx.conf": "server {}"}},
+		{Name: "web-config", Data: map[string]string{"MODE": "production"}},
+	}, bundle.ConfigMaps)
+
+	assert.Len(t, bundle.Secrets, 1)
+	assert.Equal(t, "db", bundle.Secrets[0].Name)
+	assert.Equal(t, MaskedSecretValue, bundle.Secrets[0].Data["password"])
+
+	assert.Equal(t, []BundleDockerRegistry{{Name: "gcr", Host: "https://gcr.io"}}, bundle.DockerRegistries)
+}
+
+func TestImportApplicationBundle(t *testing.T) {
+	manager := newFakeResourceManager(bundleTestObjects()...)
+	ns, _ := manager.GetNamespace("shop")
+	bundle, err := manager.ExportApplicationBundle(ns)
+	assert.Nil(t, err)
+
+	// importing the exported bundle changes nothing
+	res, err := manager.ImportApplicationBundle(bundle, ApplicationImportOptions{})
+	assert.Nil(t, err)
+	assert.False(t, res.HasConflicts())
+
+	for _, change := range res.Changes {
+		assert.Equal(t, ApplicationImportActionUnchanged, change.Action, "%s %s", change.Kind, change.Name)
+	}
+
+	// dry run as a new application
+	bundle, _ = manager.ExportApplicationBundle(ns)
+	res, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{Name: "shop-staging", DryRun: true})
+	assert.Nil(t, err)
+	assert.True(t, res.DryRun)
+	assert.Equal(t, "shop-staging", res.Application)
+	assert.Len(t, res.Warnings, 1)
+
+	// the route is in the bundle with the same name, but it points to the old namespace now
+	routeChange := res.Changes[len(res.Changes)-1]
+	assert.Equal(t, "HttpRoute", routeChange.Kind)
+	assert.Equal(t, ApplicationImportActionConflict, routeChange.Action)
+	assert.Equal(t, "route belongs to another application", routeChange.Reason)
+
+	_, err = manager.GetNamespace("shop-staging")
+	assert.True(t, err != nil)
+
+	// nothing is applied because of the conflict
+	res, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{Name: "shop-staging"})
+	assert.Nil(t, err)
+	assert.True(t, res.HasConflicts())
+
+	_, err = manager.GetNamespace("shop-staging")
+	assert.True(t, err != nil)
+
+	// skip the route
+	res, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{Name: "shop-staging", Conflict: ApplicationImportConflictSkip})
+	assert.Nil(t, err)
+	assert.False(t, res.HasConflicts())
+
+	var component v1alpha1.Component
+	assert.Nil(t, manager.Get("shop-staging", "web", &component))
+	assert.Equal(t, "gcr.io/shop/web:v1", component.Spec.Image)
+
+	var route v1alpha1.HttpRoute
+	assert.Nil(t, manager.Get("", "shop-web", &route))
+	assert.Equal(t, "web.shop.svc.cluster.local:80", route.Spec.Destinations[0].Host)
+
+	// redacted secret values can't be created
+	var secret coreV1.Secret
+	assert.Nil(t, manager.Get("shop-staging", "db", &secret))
+	assert.Len(t, secret.Data, 0)
+
+	// overwrite an existing component of the original application
+	bundle.Components[0].Spec.Image = "gcr.io/shop/web:v2"
+	res, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{Name: "shop", Conflict: ApplicationImportConflictOverwrite})
+	assert.Nil(t, err)
+
+	for _, change := range res.Changes {
+		if change.Kind == "Component" {
+			assert.Equal(t, ApplicationImportActionUpdate, change.Action)
+			assert.Len(t, change.Diff, 1)
+			assert.Equal(t, "/spec/image", change.Diff[0].Path)
+		}
+	}
+
+	assert.Nil(t, manager.Get("shop", "web", &component))
+	assert.Equal(t, "gcr.io/shop/web:v2", component.Spec.Image)
+}
+
+func TestImportApplicationBundleRenameAndValidate(t *testing.T) {
+	manager := newFakeResourceManager(bundleTestObjects()...)
+	ns, _ := manager.GetNamespace("shop")
+	bundle, err := manager.ExportApplicationBundle(ns)
+	assert.Nil(t, err)
+
+	// the bundle is renamed before it's authorized
+	var authorized string
+	_, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{
+		Name:   "shop-staging",
+		DryRun: true,
+		Authorize: func(bundle *ApplicationBundle) error {
+			authorized = bundle.Application.Name
+			return InsufficientPermissionsError
+		},
+	})
+	assert.Equal(t, InsufficientPermissionsError, err)
+	assert.Equal(t, "shop-staging", authorized)
+
+	// invalid bundles are rejected before they're authorized
+	bundle.Kind = "Unknown"
+	_, err = manager.ImportApplicationBundle(bundle, ApplicationImportOptions{
+		Authorize: func(bundle *ApplicationBundle) error {
+			t.Fatal("invalid bundle should not be authorized")
+			return nil
+		},
+	})
+	assert.True(t, errors.IsBadRequest(err))
+}
+
+func TestIsHttpRouteOfNamespace(t *testing.T) {
+	route := &v1alpha1.HttpRoute{
+		Spec: v1alpha1.HttpRouteSpec{
+			Destinations: []v1alpha1.HttpRouteDestination{{Host: "web.shop.svc.cluster.local:80", Weight: 1}},
+		},
+	}
+
+	assert.True(t, isHttpRouteOfNamespace(route, "shop"))
+	assert.False(t, isHttpRouteOfNamespace(route, "blog"))
+
+	// the label takes precedence over destinations
+	route.Labels = map[string]string{v1alpha1.KalmLabelNamespaceKey: "blog"}
+	assert.True(t, isHttpRouteOfNamespace(route, "blog"))
+	assert.False(t, isHttpRouteOfNamespace(route, "shop"))
+
+	route.Spec.Destinations = nil
+	assert.True(t, isHttpRouteOfNamespace(route, "blog"))
+}
+
+func TestReplaceNamespaceOfSvcHost(t *testing.T) {
+	assert.Equal(t, "web.b.svc.cluster.local", replaceNamespaceOfSvcHost("web.a.svc.cluster.local", "a", "b"))
+	assert.Equal(t, "web.b:8080", replaceNamespaceOfSvcHost("web.a:8080", "a", "b"))
+	assert.Equal(t, "web.ab.svc.cluster.local", replaceNamespaceOfSvcHost("web.ab.svc.cluster.local", "a", "b"))
+	assert.Equal(t, "example.com", replaceNamespaceOfSvcHost("example.com", "a", "b"))
+}
+
+func TestGetImageRegistryHost(t *testing.T) {
+	assert.Equal(t, "docker.io", getImageRegistryHost("nginx"))
+	assert.Equal(t, "docker.io", getImageRegistryHost("kalmhq/kalm:latest"))
+	assert.Equal(t, "gcr.io", getImageRegistryHost("gcr.io/project/image"))
+	assert.Equal(t, "localhost:5000", getImageRegistryHost("localhost:5000/image"))
+	assert.Equal(t, normalizeRegistryHost("https://index.docker.io/v1/"), getImageRegistryHost("nginx"))
+}
@@ -0,0 +1,38 @@
+package resources
+
+import (
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetApplicationQuotaUsage counts the usage the same way as the component webhook does when enforcing the quota
+func (resourceManager *ResourceManager) GetApplicationQuotaUsage(namespace string) (*v1alpha1.ApplicationQuotaUsage, error) {
+	var componentList v1alpha1.ComponentList
+
+	if err := resourceManager.List(&componentList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	usage := &v1alpha1.ApplicationQuotaUsage{}
+
+	for i := range componentList.Items {
+		usage.Add(componentList.Items[i].GetQuotaUsage())
+	}
+
+	var routeList v1alpha1.HttpRouteList
+
+	if err := resourceManager.List(&routeList); err != nil {
+		return nil, err
+	}
+
+	for i := range routeList.Items {
+		for _, app := range v1alpha1.GetApplicationsOfHttpRoute(&routeList.Items[i]) {
+			if app == namespace {
+				usage.Routes++
+			}
+		}
+	}
+
+	return usage, nil
+}
+
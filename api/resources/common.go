@@ -8,6 +8,7 @@ import (
 
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"go.uber.org/zap"
+	istioScheme "istio.io/client-go/pkg/clientset/versioned/scheme"
 	appV1 "k8s.io/api/apps/v1"
 	batchV1 "k8s.io/api/batch/v1"
 	coreV1 "k8s.io/api/core/v1"
@@ -25,6 +26,9 @@ import (
 
 func init() {
 	_ = v1alpha1.AddToScheme(scheme.Scheme)
+
+	// DestinationRules are rendered in dry runs of components
+	_ = istioScheme.AddToScheme(scheme.Scheme)
 }
 
 type ResourceChannels struct {
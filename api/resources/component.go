@@ -97,6 +97,8 @@ type ComponentDetails struct {
 
 	Plugins []runtime.RawExtension `json:"plugins,omitempty"`
 
+	Status v1alpha1.ComponentStatus `json:"status"`
+
 	Metrics              MetricHistories       `json:"metrics"`
 	IstioMetricHistories *IstioMetricHistories `json:"istioMetricHistories"`
 	Services             []ServiceStatus       `json:"services"`
@@ -195,6 +197,7 @@ func (resourceManager *ResourceManager) BuildComponentDetails(
 
 		ComponentSpec: component.Spec,
 		Plugins:       plugins,
+		Status:        component.Status,
 
 		Services: servicesStatus,
 		Metrics: MetricHistories{
@@ -0,0 +1,158 @@
+package resources
+
+import (
+	"encoding/json"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/controllers"
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ComponentDryRunChange is an object that would be created, updated or deleted by the change of a component.
+// Object is set for creation and deletion, Diff is set for update.
+type ComponentDryRunChange struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Namespace  string                   `json:"namespace,omitempty"`
+	Name       string                   `json:"name"`
+	Action     controllers.DryRunAction `json:"action"`
+	Object     map[string]interface{}   `json:"object,omitempty"`
+	Diff       []jsonpatch.Operation    `json:"diff,omitempty"`
+}
+
+type ComponentDryRunResult struct {
+	DryRun  bool                    `json:"dryRun"`
+	Changes []ComponentDryRunChange `json:"changes"`
+}
+
+// DryRunComponent creates or updates the component, its protected endpoint and plugin bindings, and runs the reconciliation of it,
+// without persisting anything. Admission webhooks are called in dry run mode.
+func (resourceManager *ResourceManager) DryRunComponent(
+	component *v1alpha1.Component,
+	protectedEndpointSpec *v1alpha1.ProtectedEndpointSpec,
+	plugins []runtime.RawExtension,
+	isCreate bool,
+) (*ComponentDryRunResult, error) {
+	dryRunClient := controllers.NewDryRunClient(resourceManager.Client, scheme.Scheme)
+
+	dryRunManager := &ResourceManager{
+		ctx:    resourceManager.ctx,
+		Cfg:    resourceManager.Cfg,
+		Client: dryRunClient,
+		Logger: resourceManager.Logger,
+	}
+
+	component = component.DeepCopy()
+
+	if isCreate {
+		if err := dryRunManager.Create(component); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := dryRunManager.ApplyComponent(component); err != nil {
+			return nil, err
+		}
+	}
+
+	if protectedEndpointSpec != nil {
+		protectedEndpointSpec = protectedEndpointSpec.DeepCopy()
+	}
+
+	if err := dryRunManager.UpdateProtectedEndpointForComponent(component, protectedEndpointSpec); err != nil {
+		return nil, err
+	}
+
+	if err := dryRunManager.UpdateComponentPluginBindingsForObject(component.Namespace, component.Name, plugins); err != nil {
+		return nil, err
+	}
+
+	if err := controllers.DryRunComponentReconcile(dryRunClient, component.Namespace, component.Name); err != nil {
+		return nil, err
+	}
+
+	changes, err := buildComponentDryRunChanges(dryRunClient.Changes())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ComponentDryRunResult{DryRun: true, Changes: changes}, nil
+}
+
+func buildComponentDryRunChanges(dryRunChanges []controllers.DryRunChange) ([]ComponentDryRunChange, error) {
+	changes := make([]ComponentDryRunChange, 0, len(dryRunChanges))
+
+	for _, dryRunChange := range dryRunChanges {
+		change := ComponentDryRunChange{
+			APIVersion: dryRunChange.GroupVersionKind.GroupVersion().String(),
+			Kind:       dryRunChange.GroupVersionKind.Kind,
+			Namespace:  dryRunChange.Namespace,
+			Name:       dryRunChange.Name,
+			Action:     dryRunChange.Action(),
+		}
+
+		current, err := toDryRunObject(dryRunChange.Current)
+
+		if err != nil {
+			return nil, err
+		}
+
+		desired, err := toDryRunObject(dryRunChange.Desired)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch change.Action {
+		case controllers.DryRunActionCreate:
+			change.Object = desired
+		case controllers.DryRunActionDelete:
+			change.Object = current
+		default:
+			if change.Diff, err = DiffObjects(current, desired); err != nil {
+				return nil, err
+			}
+
+			// e.g. the spec of a workload is rendered the same as before
+			if len(change.Diff) == 0 {
+				continue
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// toDryRunObject converts the object to a map without fields maintained by the api server,
+// which are changed by every write and only add noise to the diff.
+func toDryRunObject(obj runtime.Object) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+
+	bts, err := json.Marshal(obj)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]interface{}
+
+	if err := json.Unmarshal(bts, &res); err != nil {
+		return nil, err
+	}
+
+	delete(res, "status")
+
+	if metadata, ok := res["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"managedFields", "resourceVersion", "generation", "creationTimestamp", "uid", "selfLink"} {
+			delete(metadata, field)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/controllers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDryRunComponent(t *testing.T) {
+	resourceManager := newFakeResourceManager(
+		&coreV1.Namespace{ObjectMeta: metaV1.ObjectMeta{
+			Name:   "shop",
+			Labels: map[string]string{controllers.KalmEnableLabelName: controllers.KalmEnableLabelValue},
+		}},
+		&v1alpha1.Component{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "web", UID: "web-uid"},
+			Spec: v1alpha1.ComponentSpec{
+				Image: "nginx:1.18",
+			},
+		},
+	)
+
+	component, err := resourceManager.GetComponent("shop", "web")
+	require.Nil(t, err)
+
+	component.Spec.Image = "nginx:1.19"
+	component.Spec.Ports = []v1alpha1.Port{{ContainerPort: 80, Protocol: v1alpha1.PortProtocolHTTP}}
+
+	res, err := resourceManager.DryRunComponent(component, nil, nil, false)
+	require.Nil(t, err)
+	assert.True(t, res.DryRun)
+
+	changes := make(map[string]ComponentDryRunChange)
+	for _, change := range res.Changes {
+		changes[change.Kind+"/"+change.Name] = change
+	}
+
+	update := changes["Component/web"]
+	assert.Equal(t, controllers.DryRunActionUpdate, update.Action)
+	assert.Equal(t, "core.kalm.dev/v1alpha1", update.APIVersion)
+
+	var imageChanged bool
+	for _, op := range update.Diff {
+		assert.NotContains(t, op.Path, "resourceVersion")
+
+		if op.Path == "/spec/image" {
+			imageChanged = op.Value == "nginx:1.19"
+		}
+	}
+	assert.True(t, imageChanged)
+
+	deployment := changes["Deployment/web"]
+	assert.Equal(t, controllers.DryRunActionCreate, deployment.Action)
+	assert.Equal(t, "apps/v1", deployment.APIVersion)
+	assert.NotNil(t, deployment.Object["spec"])
+
+	assert.Equal(t, controllers.DryRunActionCreate, changes["Service/web"].Action)
+
+	// nothing is persisted
+	component, err = resourceManager.GetComponent("shop", "web")
+	require.Nil(t, err)
+	assert.Equal(t, "nginx:1.18", component.Spec.Image)
+
+	var dp appsV1.Deployment
+	assert.True(t, errors.IsNotFound(resourceManager.Get("shop", "web", &dp)))
+}
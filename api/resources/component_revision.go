@@ -0,0 +1,159 @@
+package resources
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/controllers"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ComponentRevision struct {
+	Name                            string `json:"name"`
+	Namespace                       string `json:"namespace"`
+	*v1alpha1.ComponentRevisionSpec `json:",inline"`
+}
+
+// ComponentChangeCause describes who changes a component and how, it's recorded in the revision of the change.
+type ComponentChangeCause struct {
+	ChangedBy          string
+	ChangedVia         v1alpha1.ComponentChangeVia
+	AccessToken        string
+	RollbackToRevision int64
+}
+
+func SetComponentChangeCause(component *v1alpha1.Component, cause ComponentChangeCause) {
+	if component.Annotations == nil {
+		component.Annotations = make(map[string]string)
+	}
+
+	annotations := component.Annotations
+
+	setOrDelete := func(key, value string) {
+		if value == "" {
+			delete(annotations, key)
+		} else {
+			annotations[key] = value
+		}
+	}
+
+	setOrDelete(v1alpha1.KalmAnnoChangedBy, cause.ChangedBy)
+	setOrDelete(v1alpha1.KalmAnnoChangedVia, string(cause.ChangedVia))
+	setOrDelete(v1alpha1.KalmAnnoChangedWithAccessToken, cause.AccessToken)
+
+	if cause.RollbackToRevision > 0 {
+		annotations[v1alpha1.KalmAnnoChangedByRollbackRevision] = strconv.FormatInt(cause.RollbackToRevision, 10)
+	} else {
+		delete(annotations, v1alpha1.KalmAnnoChangedByRollbackRevision)
+	}
+}
+
+func BuildComponentRevisionFromResource(revision *v1alpha1.ComponentRevision) *ComponentRevision {
+	return &ComponentRevision{
+		Name:                  revision.Name,
+		Namespace:             revision.Namespace,
+		ComponentRevisionSpec: revision.Spec.DeepCopy(),
+	}
+}
+
+// GetComponentRevisions returns revisions of a component, the latest first
+func (resourceManager *ResourceManager) GetComponentRevisions(namespace, componentName string) ([]*ComponentRevision, error) {
+	var revisionList v1alpha1.ComponentRevisionList
+
+	if err := resourceManager.List(&revisionList,
+		client.InNamespace(namespace),
+		client.MatchingLabels{v1alpha1.KalmLabelComponentKey: componentName},
+	); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(revisionList.Items, func(i, j int) bool {
+		return revisionList.Items[i].Spec.Revision > revisionList.Items[j].Spec.Revision
+	})
+
+	res := make([]*ComponentRevision, len(revisionList.Items))
+
+	for i := range revisionList.Items {
+		res[i] = BuildComponentRevisionFromResource(&revisionList.Items[i])
+	}
+
+	return res, nil
+}
+
+func (resourceManager *ResourceManager) GetComponentRevision(namespace, componentName string, revision int64) (*v1alpha1.ComponentRevision, error) {
+	var componentRevision v1alpha1.ComponentRevision
+
+	if err := resourceManager.Get(namespace, v1alpha1.GetComponentRevisionName(componentName, revision), &componentRevision); err != nil {
+		return nil, err
+	}
+
+	return &componentRevision, nil
+}
+
+// ApplyComponent works like Apply, but also keeps the change cause annotations of the component.
+func (resourceManager *ResourceManager) ApplyComponent(component *v1alpha1.Component) error {
+	fetched, err := resourceManager.GetComponent(component.Namespace, component.Name)
+
+	if err != nil {
+		return err
+	}
+
+	copied := fetched.DeepCopy()
+	copied.Spec = component.Spec
+
+	for _, key := range v1alpha1.KalmComponentChangeAnnotations {
+		if value, exist := component.Annotations[key]; exist {
+			if copied.Annotations == nil {
+				copied.Annotations = make(map[string]string)
+			}
+
+			copied.Annotations[key] = value
+		} else {
+			delete(copied.Annotations, key)
+		}
+	}
+
+	if err := resourceManager.Patch(copied, client.MergeFrom(fetched)); err != nil {
+		return err
+	}
+
+	copied.DeepCopyInto(component)
+
+	return nil
+}
+
+// RecordComponentRevision records the spec just written as a new revision, so each change made through the api
+// gets its own revision. The controller still records specs it reconciles, if this fails.
+func (resourceManager *ResourceManager) RecordComponentRevision(component *v1alpha1.Component) error {
+	_, _, err := controllers.RecordComponentRevision(resourceManager.ctx, resourceManager.Client, resourceManager.Client, component)
+	return err
+}
+
+// RollbackComponent re-applies the spec of the revision to the component.
+// The rollback itself is recorded as a new revision.
+func (resourceManager *ResourceManager) RollbackComponent(namespace, componentName string, revision int64, cause ComponentChangeCause) (*v1alpha1.Component, error) {
+	componentRevision, err := resourceManager.GetComponentRevision(namespace, componentName, revision)
+
+	if err != nil {
+		return nil, err
+	}
+
+	component, err := resourceManager.GetComponent(namespace, componentName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cause.ChangedVia = v1alpha1.ComponentChangeViaRollback
+	cause.RollbackToRevision = revision
+
+	component.Spec = *componentRevision.Spec.ComponentSpec.DeepCopy()
+	SetComponentChangeCause(component, cause)
+
+	if err := resourceManager.ApplyComponent(component); err != nil {
+		return nil, err
+	}
+
+	return component, nil
+}
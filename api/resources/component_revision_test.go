@@ -0,0 +1,43 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordComponentRevision(t *testing.T) {
+	component := &v1alpha1.Component{
+		ObjectMeta: metaV1.ObjectMeta{Namespace: "shop", Name: "web", UID: "web-uid"},
+		Spec:       v1alpha1.ComponentSpec{Image: "gcr.io/shop/web:v1"},
+	}
+
+	manager := newFakeResourceManager(component.DeepCopy())
+
+	// quick successive changes are recorded one by one, before the controller reconciles any of them
+	for _, image := range []string{"gcr.io/shop/web:v2", "gcr.io/shop/web:v3", "gcr.io/shop/web:v1"} {
+		component.Spec.Image = image
+		SetComponentChangeCause(component, ComponentChangeCause{ChangedBy: "admin", ChangedVia: v1alpha1.ComponentChangeViaAPI})
+		assert.Nil(t, manager.RecordComponentRevision(component))
+	}
+
+	// the same spec is not recorded twice
+	assert.Nil(t, manager.RecordComponentRevision(component))
+
+	revisions, err := manager.GetComponentRevisions("shop", "web")
+	assert.Nil(t, err)
+
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, int64(3), revisions[0].Revision)
+		assert.Equal(t, "gcr.io/shop/web:v1", revisions[0].ComponentSpec.Image)
+		assert.Equal(t, "gcr.io/shop/web:v2", revisions[2].ComponentSpec.Image)
+		assert.Equal(t, "admin", revisions[2].ChangedBy)
+		assert.Equal(t, v1alpha1.ComponentChangeViaAPI, revisions[2].ChangedVia)
+	}
+
+	revision, err := manager.GetComponentRevision("shop", "web", 1)
+	assert.Nil(t, err)
+	assert.True(t, metaV1.IsControlledBy(revision, component))
+}
@@ -0,0 +1,24 @@
+package resources
+
+import (
+	"encoding/json"
+
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+// DiffObjects returns the json patch operations to turn current into desired, empty if they are the same
+func DiffObjects(current, desired interface{}) ([]jsonpatch.Operation, error) {
+	currentBytes, err := json.Marshal(current)
+
+	if err != nil {
+		return nil, err
+	}
+
+	desiredBytes, err := json.Marshal(desired)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonpatch.CreatePatch(currentBytes, desiredBytes)
+}
@@ -4,8 +4,11 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/kalmhq/kalm/controller/controllers"
@@ -72,7 +75,8 @@ func BuildHttpsCertResponse(httpsCert *v1alpha1.HttpsCert) *HttpsCertResp {
 		Reason: reason,
 	}
 
-	if readyCond != nil && readyCond.Status == coreV1.ConditionTrue {
+	// certs expiring or failed to renew are still in use
+	if v1alpha1.IsHttpsCertUsable(*httpsCert) || (readyCond != nil && readyCond.Reason == v1alpha1.HttpsCertReasonExpired) {
 		isSignedByTrustedCA := httpsCert.Status.IsSignedByPublicTrustedCA
 		expireTimestamp := httpsCert.Status.ExpireTimestamp
 
@@ -91,6 +95,74 @@ func BuildHttpsCertResponse(httpsCert *v1alpha1.HttpsCert) *HttpsCertResp {
 	return &resp
 }
 
+type HttpsCertAtRisk struct {
+	*HttpsCertResp `json:",inline"`
+	// one of ExpiringSoon, Expired, RenewalFailed
+	Risk         string `json:"risk"`
+	DaysToExpiry int    `json:"daysToExpiry"`
+}
+
+// GetHttpsCertsAtRisk returns certs which are expiring, expired or failed to renew, sorted by expiration.
+// Besides the expiry window of each cert, withinDays can be used to include certs expiring in the days.
+func (resourceManager *ResourceManager) GetHttpsCertsAtRisk(withinDays int) ([]HttpsCertAtRisk, error) {
+	var fetched v1alpha1.HttpsCertList
+
+	if err := resourceManager.List(&fetched); err != nil {
+		return nil, err
+	}
+
+	rst := make([]HttpsCertAtRisk, 0)
+	for i := range fetched.Items {
+		item := fetched.Items[i]
+
+		risk := getHttpsCertRisk(item, withinDays)
+		if risk == "" {
+			continue
+		}
+
+		rst = append(rst, HttpsCertAtRisk{
+			HttpsCertResp: BuildHttpsCertResponse(&item),
+			Risk:          risk,
+			DaysToExpiry:  int(math.Floor(time.Until(time.Unix(item.Status.ExpireTimestamp, 0)).Hours() / 24)),
+		})
+	}
+
+	sort.Slice(rst, func(i, j int) bool {
+		return rst[i].ExpireTimestamp < rst[j].ExpireTimestamp
+	})
+
+	return rst, nil
+}
+
+func getHttpsCertRisk(httpsCert v1alpha1.HttpsCert, withinDays int) string {
+	// certs never issued have no expiration
+	if httpsCert.Status.ExpireTimestamp <= 0 {
+		return ""
+	}
+
+	for _, cond := range httpsCert.Status.Conditions {
+		if cond.Type != v1alpha1.HttpsCertConditionReady {
+			continue
+		}
+
+		switch cond.Reason {
+		case v1alpha1.HttpsCertReasonExpiringSoon, v1alpha1.HttpsCertReasonExpired, v1alpha1.HttpsCertReasonRenewalFailed:
+			return cond.Reason
+		}
+	}
+
+	expireAt := time.Unix(httpsCert.Status.ExpireTimestamp, 0)
+	if time.Now().After(expireAt) {
+		return v1alpha1.HttpsCertReasonExpired
+	}
+
+	if withinDays > 0 && time.Until(expireAt) < time.Duration(withinDays)*24*time.Hour {
+		return v1alpha1.HttpsCertReasonExpiringSoon
+	}
+
+	return ""
+}
+
 func (resourceManager *ResourceManager) GetHttpsCert(name string) (*v1alpha1.HttpsCert, error) {
 	var fetched v1alpha1.HttpsCert
 
@@ -10,10 +10,12 @@ import (
 )
 
 type HttpsCertIssuer struct {
-	Name           string                    `json:"name"`
-	CAForTest      *v1alpha1.CAForTestIssuer `json:"caForTest,omitempty"`
-	ACMECloudFlare *AccountAndSecret         `json:"acmeCloudFlare,omitempty"`
-	HTTP01         *v1alpha1.HTTP01Issuer    `json:"http01,omitempty"`
+	Name            string                          `json:"name"`
+	CAForTest       *v1alpha1.CAForTestIssuer       `json:"caForTest,omitempty"`
+	ACMECloudFlare  *AccountAndSecret               `json:"acmeCloudFlare,omitempty"`
+	HTTP01          *v1alpha1.HTTP01Issuer          `json:"http01,omitempty"`
+	ACMEDNSProvider *v1alpha1.ACMEDNSProviderIssuer `json:"acmeDNSProvider,omitempty"`
+	CA              *v1alpha1.CAIssuer              `json:"ca,omitempty"`
 }
 
 type AccountAndSecret struct {
@@ -48,6 +50,14 @@ func (resourceManager *ResourceManager) GetHttpsCertIssuerList() ([]HttpsCertIss
 			issuer.HTTP01 = ele.Spec.HTTP01
 		}
 
+		if ele.Spec.ACMEDNSProvider != nil {
+			issuer.ACMEDNSProvider = ele.Spec.ACMEDNSProvider
+		}
+
+		if ele.Spec.CA != nil {
+			issuer.CA = ele.Spec.CA
+		}
+
 		rst = append(rst, issuer)
 	}
 
@@ -68,12 +78,16 @@ func (resourceManager *ResourceManager) UpdateHttpsCertIssuer(hcIssuer HttpsCert
 
 	if (res.Spec.CAForTest == nil) != (hcIssuer.CAForTest == nil) ||
 		(res.Spec.ACMECloudFlare == nil) != (hcIssuer.ACMECloudFlare == nil) ||
-		(res.Spec.HTTP01 == nil) != (hcIssuer.HTTP01 == nil) {
+		(res.Spec.HTTP01 == nil) != (hcIssuer.HTTP01 == nil) ||
+		(res.Spec.ACMEDNSProvider == nil) != (hcIssuer.ACMEDNSProvider == nil) ||
+		(res.Spec.CA == nil) != (hcIssuer.CA == nil) {
 		return HttpsCertIssuer{}, fmt.Errorf("can not change type of HttpsCertIssuer")
 	}
 
 	res.Spec.CAForTest = hcIssuer.CAForTest
 	res.Spec.HTTP01 = hcIssuer.HTTP01
+	res.Spec.ACMEDNSProvider = hcIssuer.ACMEDNSProvider
+	res.Spec.CA = hcIssuer.CA
 
 	if hcIssuer.ACMECloudFlare != nil {
 
@@ -2,8 +2,43 @@ package resources
 
 import (
 	"testing"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	coreV1 "k8s.io/api/core/v1"
 )
 
 func TestCleanToResName(t *testing.T) {
 
 }
+
+func TestGetHttpsCertRisk(t *testing.T) {
+	cert := v1alpha1.HttpsCert{
+		Status: v1alpha1.HttpsCertStatus{
+			Conditions: []v1alpha1.HttpsCertCondition{
+				{
+					Type:   v1alpha1.HttpsCertConditionReady,
+					Status: coreV1.ConditionTrue,
+				},
+			},
+			ExpireTimestamp: time.Now().Add(20 * 24 * time.Hour).Unix(),
+		},
+	}
+
+	assert.Equal(t, "", getHttpsCertRisk(cert, 0))
+	assert.Equal(t, "", getHttpsCertRisk(cert, 10))
+	assert.Equal(t, v1alpha1.HttpsCertReasonExpiringSoon, getHttpsCertRisk(cert, 30))
+
+	cert.Status.Conditions[0].Status = coreV1.ConditionFalse
+	cert.Status.Conditions[0].Reason = v1alpha1.HttpsCertReasonRenewalFailed
+	assert.Equal(t, v1alpha1.HttpsCertReasonRenewalFailed, getHttpsCertRisk(cert, 0))
+
+	cert.Status.Conditions[0].Reason = ""
+	cert.Status.ExpireTimestamp = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, v1alpha1.HttpsCertReasonExpired, getHttpsCertRisk(cert, 0))
+
+	// not issued yet
+	cert.Status.ExpireTimestamp = 0
+	assert.Equal(t, "", getHttpsCertRisk(cert, 30))
+}
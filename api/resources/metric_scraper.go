@@ -23,7 +23,23 @@ var metricDb *sql.DB
 var metricResolution = 5 * time.Second
 var metricDuration = 15 * time.Minute
 
-func StartMetricScraper(ctx context.Context, cfg *rest.Config) error {
+func StartMetricScraper(ctx context.Context, cfg *rest.Config, options MetricScraperOptions) error {
+	if options.StoragePath == "" {
+		options.StoragePath = DefaultMetricStoragePath
+	}
+
+	if options.Rollups != nil {
+		if err := ValidateMetricRollups(options.Rollups); err != nil {
+			log.Error("Invalid metric rollups", zap.Error(err))
+			return err
+		}
+
+		metricRollups = options.Rollups
+	}
+
+	metricResolution = metricRollups[0].Resolution
+	metricDuration = metricRollups[0].Retention
+
 	metricClient, err := mclientv1beta1.NewForConfig(cfg)
 	if err != nil {
 		log.Error("Init metric client error", zap.Error(err))
@@ -35,7 +51,7 @@ func StartMetricScraper(ctx context.Context, cfg *rest.Config) error {
 		return err
 	}
 
-	metricDb, err = sql.Open("sqlite3", "/tmp/metric_scraper.db")
+	metricDb, err = sql.Open("sqlite3", options.StoragePath)
 	if err != nil {
 		log.Error("Unable to open Sqlite database", zap.Error(err))
 		return err
@@ -49,7 +65,13 @@ func StartMetricScraper(ctx context.Context, cfg *rest.Config) error {
 		return err
 	}
 
-	log.Info("Metric scraper started")
+	err = CreateRollupTables(metricDb)
+	if err != nil {
+		log.Error("Unable to initialize database rollup tables", zap.Error(err))
+		return err
+	}
+
+	log.Info("Metric scraper started", zap.String("path", options.StoragePath))
 
 	// Start the machine. Scrape every metricResolution
 	ticker := time.NewTicker(metricResolution)
@@ -96,6 +118,13 @@ func update(client *mclientv1beta1.MetricsV1beta1Client, restClient *kubernetes.
 		return err
 	}
 
+	// Downsample before raw rows are deleted
+	err = RollupDatabase(db, time.Now())
+	if err != nil {
+		log.Error("Error rolling up database", zap.Error(err))
+		return err
+	}
+
 	// Delete rows outside of the metricDuration time
 	err = CullDatabase(db, metricDuration)
 	if err != nil {
@@ -103,6 +132,12 @@ func update(client *mclientv1beta1.MetricsV1beta1Client, restClient *kubernetes.
 		return err
 	}
 
+	err = CullRollups(db)
+	if err != nil {
+		log.Error("Error culling database rollups", zap.Error(err))
+		return err
+	}
+
 	log.Debug(fmt.Sprintf("Database updated: %d nodes, %d pods", len(nodeMetrics.Items), len(podMetrics.Items)))
 	return nil
 }
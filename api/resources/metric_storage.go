@@ -0,0 +1,338 @@
+package resources
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/api/log"
+	"go.uber.org/zap"
+)
+
+const DefaultMetricStoragePath = "/tmp/metric_scraper.db"
+
+// MetricRollup keeps metrics at the resolution for the retention.
+// The first rollup is the raw scraped metrics, each of the others is downsampled from the previous one.
+type MetricRollup struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+var DefaultMetricRollups = []MetricRollup{
+	{Resolution: 5 * time.Second, Retention: 15 * time.Minute},
+	{Resolution: time.Minute, Retention: 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 30 * 24 * time.Hour},
+}
+
+var metricRollups = DefaultMetricRollups
+
+type MetricScraperOptions struct {
+	// Path of the sqlite database, put it on a persistent volume to keep metrics across restarts
+	StoragePath string
+
+	// Default to DefaultMetricRollups
+	Rollups []MetricRollup
+}
+
+// ParseMetricRollups parses rollups in format of resolution:retention separated by comma, e.g. 5s:15m,1m:24h,1h:30d
+func ParseMetricRollups(value string) ([]MetricRollup, error) {
+	var rollups []MetricRollup
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid metric rollup %s, should be in format of resolution:retention", item)
+		}
+
+		resolution, err := parseDurationWithDays(parts[0])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid resolution of metric rollup %s: %s", item, err)
+		}
+
+		retention, err := parseDurationWithDays(parts[1])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention of metric rollup %s: %s", item, err)
+		}
+
+		rollups = append(rollups, MetricRollup{Resolution: resolution, Retention: retention})
+	}
+
+	if err := ValidateMetricRollups(rollups); err != nil {
+		return nil, err
+	}
+
+	return rollups, nil
+}
+
+// parseDurationWithDays also accepts days, e.g. 30d
+func parseDurationWithDays(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func ValidateMetricRollups(rollups []MetricRollup) error {
+	if len(rollups) == 0 {
+		return fmt.Errorf("at least one metric rollup is required")
+	}
+
+	for i, rollup := range rollups {
+		if rollup.Resolution < time.Second || rollup.Resolution%time.Second != 0 {
+			return fmt.Errorf("resolution of metric rollup %d should be whole seconds", i)
+		}
+
+		if rollup.Retention < rollup.Resolution {
+			return fmt.Errorf("retention of metric rollup %d should not be less than its resolution", i)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		prev := rollups[i-1]
+
+		if rollup.Resolution <= prev.Resolution || rollup.Resolution%prev.Resolution != 0 {
+			return fmt.Errorf("resolution of metric rollup %d should be a multiple of the previous one", i)
+		}
+
+		// a whole bucket of the previous rollup is required to downsample
+		if prev.Retention < rollup.Resolution {
+			return fmt.Errorf("retention of metric rollup %d should not be less than the resolution of the next one", i-1)
+		}
+	}
+
+	return nil
+}
+
+type metricTableKind struct {
+	name    string
+	columns string
+}
+
+var (
+	nodeMetricTable = metricTableKind{
+		name:    "nodes",
+		columns: "uid, name",
+	}
+	podMetricTable = metricTableKind{
+		name:    "pods",
+		columns: "uid, name, namespace, container, component",
+	}
+)
+
+// tableOf returns the table of the rollup, the first rollup uses the original table
+func (k metricTableKind) tableOf(rollup int) string {
+	if rollup == 0 {
+		return k.name
+	}
+
+	return fmt.Sprintf("%s_%ds", k.name, int64(metricRollups[rollup].Resolution.Seconds()))
+}
+
+/*
+	CreateRollupTables creates tables for downsampled metrics and the state of rollups
+*/
+func CreateRollupTables(db *sql.DB) error {
+	sqlStmt := `
+	create table if not exists metric_rollups (name text primary key, rolled_until integer);
+	create index if not exists nodes_time on nodes (time);
+	create index if not exists pods_time on pods (time);
+	`
+
+	for i := 1; i < len(metricRollups); i++ {
+		nodes := nodeMetricTable.tableOf(i)
+		pods := podMetricTable.tableOf(i)
+
+		sqlStmt += fmt.Sprintf(`
+	create table if not exists %s (uid text, name text, cpu text, memory text, storage text, time datetime);
+	create table if not exists %s (uid text, name text, namespace text, container text, component text, cpu text, memory text, storage text, time datetime);
+	create index if not exists %s_time on %s (time);
+	create index if not exists %s_time on %s (time);
+	`, nodes, pods, nodes, nodes, pods, pods)
+	}
+
+	_, err := db.Exec(sqlStmt)
+
+	return err
+}
+
+/*
+	RollupDatabase downsamples completed buckets of each rollup from the previous one
+*/
+func RollupDatabase(db *sql.DB, now time.Time) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(metricRollups); i++ {
+		for _, kind := range []metricTableKind{nodeMetricTable, podMetricTable} {
+			if err := rollupTable(tx, kind, i, now); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func rollupTable(tx *sql.Tx, kind metricTableKind, rollup int, now time.Time) error {
+	source := kind.tableOf(rollup - 1)
+	target := kind.tableOf(rollup)
+	resolution := int64(metricRollups[rollup].Resolution.Seconds())
+
+	var rolledUntil sql.NullInt64
+
+	err := tx.QueryRow("select rolled_until from metric_rollups where name = ?", target).Scan(&rolledUntil)
+
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// the first rollup starts from the oldest metrics
+	if !rolledUntil.Valid {
+		if err := tx.QueryRow(fmt.Sprintf("select min(cast(strftime('%%s', time) as integer)) from %s", source)).Scan(&rolledUntil); err != nil {
+			return err
+		}
+
+		if !rolledUntil.Valid {
+			return nil
+		}
+
+		rolledUntil.Int64 = rolledUntil.Int64 / resolution * resolution
+	}
+
+	// the current bucket is not completed yet
+	end := now.Unix() / resolution * resolution
+
+	if end <= rolledUntil.Int64 {
+		return nil
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+	insert into %s (%s, cpu, memory, storage, time)
+	select %s, avg(cpu), avg(memory), avg(storage), datetime(cast(strftime('%%s', time) as integer) / %d * %d, 'unixepoch') as bucket
+	from %s
+	where time >= datetime(?, 'unixepoch') and time < datetime(?, 'unixepoch')
+	group by %s, bucket;`,
+		target, kind.columns, kind.columns, resolution, resolution, source, kind.columns,
+	), rolledUntil.Int64, end)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("insert or replace into metric_rollups (name, rolled_until) values (?, ?)", target, end)
+
+	return err
+}
+
+/*
+	CullRollups deletes rows of each downsampled rollup out of its retention, raw metrics are culled by CullDatabase
+*/
+func CullRollups(db *sql.DB) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(metricRollups); i++ {
+		rollup := metricRollups[i]
+		windowStr := fmt.Sprintf("-%.0f seconds", rollup.Retention.Seconds())
+
+		for _, kind := range []metricTableKind{nodeMetricTable, podMetricTable} {
+			res, err := tx.Exec(fmt.Sprintf("delete from %s where time <= datetime('now', ?);", kind.tableOf(i)), windowStr)
+
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+
+			affected, _ := res.RowsAffected()
+			log.Debug("Cleaning up metrics", zap.String("table", kind.tableOf(i)), zap.Int64("rows", affected))
+		}
+	}
+
+	return tx.Commit()
+}
+
+type MetricRangeQuery struct {
+	Start time.Time
+	End   time.Time
+}
+
+type MetricRange struct {
+	// Resolution of the metric points in seconds
+	Resolution      int64 `json:"resolution"`
+	MetricHistories `json:",inline"`
+}
+
+// rollupOfRange picks the finest rollup still keeping metrics of the start time
+func rollupOfRange(start, now time.Time) int {
+	for i, rollup := range metricRollups {
+		if now.Sub(start) <= rollup.Retention {
+			return i
+		}
+	}
+
+	return len(metricRollups) - 1
+}
+
+func getMetricRange(kind metricTableKind, query MetricRangeQuery, where string, args ...interface{}) MetricRange {
+	rollup := rollupOfRange(query.Start, time.Now())
+
+	sqlStmt := fmt.Sprintf(
+		"select time, sum(cpu) as cpu, sum(memory) as memory from %s where %s time >= ? and time <= ? group by time order by time asc;",
+		kind.tableOf(rollup), where,
+	)
+
+	layout := "2006-01-02 15:04:05"
+	args = append(args, query.Start.UTC().Format(layout), query.End.UTC().Format(layout))
+
+	return MetricRange{
+		Resolution:      int64(metricRollups[rollup].Resolution.Seconds()),
+		MetricHistories: getMetricHistories(sqlStmt, args...),
+	}
+}
+
+func GetNodesMetricRange(query MetricRangeQuery) MetricRange {
+	return getMetricRange(nodeMetricTable, query, "")
+}
+
+func GetNodeMetricRange(name string, query MetricRangeQuery) MetricRange {
+	return getMetricRange(nodeMetricTable, query, "name = ? and", name)
+}
+
+func GetApplicationMetricRange(namespace string, query MetricRangeQuery) MetricRange {
+	return getMetricRange(podMetricTable, query, "namespace = ? and", namespace)
+}
+
+func GetComponentMetricRange(namespace, componentName string, query MetricRangeQuery) MetricRange {
+	return getMetricRange(podMetricTable, query, "namespace = ? and component = ? and", namespace, componentName)
+}
+
+func GetPodMetricRange(namespace, podName string, query MetricRangeQuery) MetricRange {
+	return getMetricRange(podMetricTable, query, "namespace = ? and name = ? and", namespace, podName)
+}
@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetricRollups(t *testing.T) {
+	rollups, err := ParseMetricRollups("5s:15m, 1m:24h,1h:30d")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultMetricRollups, rollups)
+
+	for _, invalid := range []string{"", "5s", "5s:15m,7s:1h", "5s:15m,1m:1h,2h:30d", "500ms:1m", "5s:1s", "1m:1h,5s:15m"} {
+		_, err := ParseMetricRollups(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func setupTestMetricDb(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "kalm-metrics")
+	require.Nil(t, err)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "metrics.db"))
+	require.Nil(t, err)
+
+	require.Nil(t, CreateDatabase(db))
+	require.Nil(t, CreateRollupTables(db))
+	metricDb = db
+
+	return func() {
+		metricDb = nil
+		_ = db.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func insertTestPodMetric(t *testing.T, name, namespace string, cpu int, at time.Time) {
+	_, err := metricDb.Exec(
+		"insert into pods(uid, name, namespace, container, component, cpu, memory, storage, time) values(?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		name, name, namespace, "main", "web", cpu, cpu*1000, 0, at.UTC().Format("2006-01-02 15:04:05"),
+	)
+	require.Nil(t, err)
+}
+
+func TestRollupDatabase(t *testing.T) {
+	defer setupTestMetricDb(t)()
+
+	now := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+
+	// 3 minutes of metrics of two pods, 5 seconds apart
+	for i := 0; i < 36; i++ {
+		at := now.Add(time.Duration(i*5) * time.Second)
+		insertTestPodMetric(t, "web-1", "shop", 100+i%12*10, at)
+		insertTestPodMetric(t, "web-2", "shop", 200, at)
+	}
+
+	// the last minute is not completed yet
+	require.Nil(t, RollupDatabase(metricDb, now.Add(2*time.Minute+30*time.Second)))
+
+	var count int
+	require.Nil(t, metricDb.QueryRow("select count(*) from pods_60s").Scan(&count))
+	assert.Equal(t, 4, count)
+
+	// rolled up buckets are not rolled up again
+	require.Nil(t, RollupDatabase(metricDb, now.Add(3*time.Minute)))
+	require.Nil(t, metricDb.QueryRow("select count(*) from pods_60s").Scan(&count))
+	assert.Equal(t, 6, count)
+
+	// web-1 averages 155 in each minute, web-2 is 200
+	metrics := GetApplicationMetricRange("shop", MetricRangeQuery{Start: now, End: now.Add(time.Hour)})
+	assert.Equal(t, int64(60), metrics.Resolution)
+	require.Len(t, metrics.CPU, 3)
+	assert.Equal(t, now, metrics.CPU[0].Timestamp)
+	assert.Equal(t, 355.0, metrics.CPU[0].Value)
+	assert.Equal(t, 355000.0, metrics.Memory[0].Value)
+
+	// the hourly rollup waits for a completed hour
+	require.Nil(t, metricDb.QueryRow("select count(*) from pods_3600s").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	require.Nil(t, RollupDatabase(metricDb, now.Add(time.Hour)))
+	require.Nil(t, metricDb.QueryRow("select count(*) from pods_3600s").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestGetMetricRange(t *testing.T) {
+	defer setupTestMetricDb(t)()
+
+	now := time.Now()
+	insertTestPodMetric(t, "web-1", "shop", 100, now.Add(-time.Minute))
+	insertTestPodMetric(t, "web-1", "blog", 100, now.Add(-time.Minute))
+	insertTestPodMetric(t, "web-1", "shop", 200, now.Add(-20*time.Minute))
+
+	metrics := GetApplicationMetricRange("shop", MetricRangeQuery{Start: now.Add(-10 * time.Minute), End: now})
+	assert.Equal(t, int64(5), metrics.Resolution)
+	require.Len(t, metrics.CPU, 1)
+	assert.Equal(t, 100.0, metrics.CPU[0].Value)
+
+	// out of the retention of raw metrics, the rollups are used
+	metrics = GetApplicationMetricRange("shop", MetricRangeQuery{Start: now.Add(-time.Hour), End: now})
+	assert.Equal(t, int64(60), metrics.Resolution)
+
+	metrics = GetComponentMetricRange("shop", "web", MetricRangeQuery{Start: now.Add(-7 * 24 * time.Hour), End: now})
+	assert.Equal(t, int64(3600), metrics.Resolution)
+}
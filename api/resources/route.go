@@ -45,6 +45,9 @@ type HttpRoute struct {
 	*v1alpha1.HttpRouteSpec `json:",inline"`
 	DestinationsStatus      []v1alpha1.HttpRouteDestinationStatus `json:"destinationsStatus,omitempty"`
 	Name                    string                                `json:"name"`
+
+	// application the route belongs to, routes without destinations are exported with it
+	Namespace string `json:"namespace,omitempty"`
 }
 
 func (resourceManager *ResourceManager) GetHttpRoute(namespace, name string) (*HttpRoute, error) {
@@ -77,7 +80,22 @@ func BuildHttpRouteFromResource(route *v1alpha1.HttpRoute) *HttpRoute {
 		HttpRouteSpec:      &route.Spec,
 		DestinationsStatus: route.Status.DestinationsStatus,
 		Name:               route.Name,
+		Namespace:          route.Labels[v1alpha1.KalmLabelNamespaceKey],
+	}
+}
+
+// setHttpRouteNamespace labels the route with the application it belongs to
+func setHttpRouteNamespace(route *v1alpha1.HttpRoute, namespace string) {
+	if namespace == "" {
+		delete(route.Labels, v1alpha1.KalmLabelNamespaceKey)
+		return
 	}
+
+	if route.Labels == nil {
+		route.Labels = make(map[string]string)
+	}
+
+	route.Labels[v1alpha1.KalmLabelNamespaceKey] = namespace
 }
 
 func (resourceManager *ResourceManager) CreateHttpRoute(routeSpec *HttpRoute) (*HttpRoute, error) {
@@ -88,6 +106,8 @@ func (resourceManager *ResourceManager) CreateHttpRoute(routeSpec *HttpRoute) (*
 		Spec: *routeSpec.HttpRouteSpec,
 	}
 
+	setHttpRouteNamespace(route, routeSpec.Namespace)
+
 	if err := resourceManager.Create(route); err != nil {
 		return nil, err
 	}
@@ -103,6 +123,7 @@ func (resourceManager *ResourceManager) UpdateHttpRoute(routeSpec *HttpRoute) (*
 	}
 
 	route.Spec = *routeSpec.HttpRouteSpec
+	setHttpRouteNamespace(route, routeSpec.Namespace)
 
 	if err := resourceManager.Update(route); err != nil {
 		return nil, err
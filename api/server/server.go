@@ -9,6 +9,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/kalmhq/kalm/api/errors"
 	"github.com/kalmhq/kalm/api/log"
+	"github.com/kalmhq/kalm/api/metrics"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap/zapcore"
@@ -42,6 +43,7 @@ func NewEchoInstance() *echo.Echo {
 
 	e.Use(middleware.Gzip())
 	e.Use(middleware.Logger())
+	e.Use(metrics.Middleware)
 	e.Pre(debugHeaderMiddleware)
 	e.Pre(middleware.RemoveTrailingSlash())
 
@@ -6,6 +6,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/kalmhq/kalm/api/client"
 	"github.com/kalmhq/kalm/api/log"
+	"github.com/kalmhq/kalm/api/metrics"
 	"github.com/kalmhq/kalm/api/resources"
 	"go.uber.org/zap"
 )
@@ -54,10 +55,12 @@ func (h *ClientPool) run() {
 		select {
 		case c := <-h.register:
 			h.clients[c] = true
+			metrics.WebsocketClients.Inc()
 		case c := <-h.unregister:
 			if _, ok := h.clients[c]; ok {
 				delete(h.clients, c)
 				c.send = nil
+				metrics.WebsocketClients.Dec()
 			}
 		}
 	}
@@ -0,0 +1,277 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DefaultAlertRuleIntervalSeconds = 60
+
+	// only the latest records are kept in the status
+	AlertRuleHistoryLimit = 100
+)
+
+// +kubebuilder:validation:Enum=cpu;memory;restarts;crashLoopBackOff;certExpiry;http5xxRatio
+type AlertRuleType string
+
+const (
+	// cpu used by pods of the component, in millicores
+	AlertRuleTypeCPU AlertRuleType = "cpu"
+
+	// memory used by pods of the component, in MiB
+	AlertRuleTypeMemory AlertRuleType = "memory"
+
+	// increase of container restarts of the component since the last evaluation
+	AlertRuleTypeRestarts AlertRuleType = "restarts"
+
+	// number of containers of the component waiting in CrashLoopBackOff
+	AlertRuleTypeCrashLoopBackOff AlertRuleType = "crashLoopBackOff"
+
+	// days until the https cert expires, fires when it's LESS than the threshold
+	AlertRuleTypeCertExpiry AlertRuleType = "certExpiry"
+
+	// percentage of 5xx responses of the http route since the last evaluation
+	AlertRuleTypeHttp5xxRatio AlertRuleType = "http5xxRatio"
+)
+
+// +kubebuilder:validation:Enum=webhook;slack;smtp
+type AlertChannelType string
+
+const (
+	// post the AlertNotification as json
+	AlertChannelTypeWebhook AlertChannelType = "webhook"
+
+	// post a slack compatible payload, i.e. {"text": "..."}
+	AlertChannelTypeSlack AlertChannelType = "slack"
+
+	AlertChannelTypeSMTP AlertChannelType = "smtp"
+)
+
+type AlertState string
+
+const (
+	// the condition is met, but not for long enough yet
+	AlertStatePending AlertState = "pending"
+
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// AlertRuleSpec defines the desired state of AlertRule
+type AlertRuleSpec struct {
+	Type AlertRuleType `json:"type"`
+
+	// Name of the component for cpu, memory, restarts and crashLoopBackOff rules,
+	// the https cert for certExpiry rules, or the http route for http5xxRatio rules.
+	// Each component, cert or route is evaluated as a separate alert if it's blank.
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	// The alert fires when the value is greater than the threshold, or less than it for certExpiry rules.
+	// See AlertRuleType for the unit of each type.
+	// +kubebuilder:validation:Minimum=0
+	Threshold int64 `json:"threshold"`
+
+	// How long the condition must hold before the alert fires
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ForSeconds int `json:"forSeconds,omitempty"`
+
+	// How often the rule is evaluated, default to 60
+	// +kubebuilder:validation:Minimum=10
+	// +optional
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+
+	// Notified when an alert fires or is resolved
+	// +optional
+	Channels []AlertChannel `json:"channels,omitempty"`
+
+	// +optional
+	Silences []AlertSilence `json:"silences,omitempty"`
+}
+
+type AlertChannel struct {
+	Type AlertChannelType `json:"type"`
+
+	// Url of webhook and slack channels
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// +optional
+	SMTP *AlertSMTPConfig `json:"smtp,omitempty"`
+}
+
+type AlertSMTPConfig struct {
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+
+	// +kubebuilder:validation:MinLength=1
+	From string `json:"from"`
+
+	// +kubebuilder:validation:MinItems=1
+	To []string `json:"to"`
+
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Secret in the namespace of the rule, the password is in the "password" key
+	// +optional
+	PasswordSecretName string `json:"passwordSecretName,omitempty"`
+}
+
+// AlertSilence stops notifications of alerts until the end time, alerts are still evaluated and recorded in history.
+type AlertSilence struct {
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+
+	// Target to silence, all targets of the rule are silenced if it's blank
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	EndTimestamp int64 `json:"endTimestamp"`
+
+	// +optional
+	Comment string `json:"comment,omitempty"`
+
+	// +optional
+	CreatedBy string `json:"createdBy,omitempty"`
+}
+
+// Alert is an active alert of a target
+type Alert struct {
+	Target string     `json:"target"`
+	State  AlertState `json:"state"`
+	Value  int64      `json:"value"`
+
+	// When the condition is met
+	SinceTimestamp int64 `json:"sinceTimestamp"`
+
+	// +optional
+	FiringTimestamp int64 `json:"firingTimestamp,omitempty"`
+}
+
+type AlertHistoryRecord struct {
+	Target string `json:"target"`
+
+	// firing or resolved
+	State     AlertState `json:"state"`
+	Value     int64      `json:"value"`
+	Timestamp int64      `json:"timestamp"`
+
+	// Notifications were not sent because of a silence
+	// +optional
+	Silenced bool `json:"silenced,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Indexes of the channels the notification is not delivered to yet, failed deliveries are retried
+	// +optional
+	UndeliveredChannels []int `json:"undeliveredChannels,omitempty"`
+}
+
+// AlertRuleStatus defines the observed state of AlertRule
+type AlertRuleStatus struct {
+	// Pending and firing alerts
+	// +optional
+	Alerts []Alert `json:"alerts,omitempty"`
+
+	// Latest firing and resolved records, oldest first
+	// +optional
+	History []AlertHistoryRecord `json:"history,omitempty"`
+
+	// +optional
+	LastEvaluationTimestamp int64 `json:"lastEvaluationTimestamp,omitempty"`
+
+	// Error of the last evaluation
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Values of the last evaluation by target, only used by rules comparing to the previous value, e.g. restarts
+	// +optional
+	LastValues map[string]int64 `json:"lastValues,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.target"
+// +kubebuilder:printcolumn:name="Threshold",type="integer",JSONPath=".spec.threshold"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// AlertRule is the Schema for the alertrules API
+type AlertRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AlertRuleSpec   `json:"spec,omitempty"`
+	Status AlertRuleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AlertRuleList contains a list of AlertRule
+type AlertRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertRule `json:"items"`
+}
+
+func (r *AlertRule) GetInterval() time.Duration {
+	if r.Spec.IntervalSeconds <= 0 {
+		return DefaultAlertRuleIntervalSeconds * time.Second
+	}
+
+	return time.Duration(r.Spec.IntervalSeconds) * time.Second
+}
+
+// GetActiveSilence returns the silence of the target at the time, nil if it's not silenced
+func (r *AlertRule) GetActiveSilence(target string, now time.Time) *AlertSilence {
+	for i := range r.Spec.Silences {
+		silence := &r.Spec.Silences[i]
+
+		if silence.Target != "" && silence.Target != target {
+			continue
+		}
+
+		if now.Unix() < silence.EndTimestamp {
+			return silence
+		}
+	}
+
+	return nil
+}
+
+// IsConditionMet compares the value to the threshold of the rule
+func (r *AlertRule) IsConditionMet(value int64) bool {
+	if r.Spec.Type == AlertRuleTypeCertExpiry {
+		return value < r.Spec.Threshold
+	}
+
+	return value > r.Spec.Threshold
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertRule{}, &AlertRuleList{})
+}
@@ -0,0 +1,179 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var alertrulelog = logf.Log.WithName("alertrule-resource")
+
+func (r *AlertRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kalm-dev-v1alpha1-alertrule,mutating=false,failurePolicy=fail,groups=core.kalm.dev,resources=alertrules,versions=v1alpha1,name=valertrule.kb.io
+
+var _ webhook.Validator = &AlertRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AlertRule) ValidateCreate() error {
+	alertrulelog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AlertRule) ValidateUpdate(old runtime.Object) error {
+	alertrulelog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AlertRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *AlertRule) validate() error {
+	var rst KalmValidateErrorList
+
+	switch r.Spec.Type {
+	case AlertRuleTypeCPU, AlertRuleTypeMemory, AlertRuleTypeRestarts, AlertRuleTypeCrashLoopBackOff,
+		AlertRuleTypeCertExpiry, AlertRuleTypeHttp5xxRatio:
+	default:
+		rst = append(rst, KalmValidateError{
+			Err:  "unknown type",
+			Path: "spec.type",
+		})
+	}
+
+	if r.Spec.Threshold < 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should not be negative",
+			Path: "spec.threshold",
+		})
+	}
+
+	if r.Spec.Type == AlertRuleTypeHttp5xxRatio && r.Spec.Threshold > 100 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be a percentage, not greater than 100",
+			Path: "spec.threshold",
+		})
+	}
+
+	if r.Spec.ForSeconds < 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should not be negative",
+			Path: "spec.forSeconds",
+		})
+	}
+
+	if r.Spec.IntervalSeconds != 0 && r.Spec.IntervalSeconds < 10 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be at least 10",
+			Path: "spec.intervalSeconds",
+		})
+	}
+
+	for i, channel := range r.Spec.Channels {
+		rst = append(rst, validateAlertChannel(channel, fmt.Sprintf("spec.channels[%d]", i))...)
+	}
+
+	ids := make(map[string]bool)
+
+	for i, silence := range r.Spec.Silences {
+		if silence.ID == "" || ids[silence.ID] {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be unique and not blank",
+				Path: fmt.Sprintf("spec.silences[%d].id", i),
+			})
+		}
+
+		ids[silence.ID] = true
+	}
+
+	if len(rst) == 0 {
+		return nil
+	}
+
+	return rst
+}
+
+func validateAlertChannel(channel AlertChannel, path string) KalmValidateErrorList {
+	var rst KalmValidateErrorList
+
+	switch channel.Type {
+	case AlertChannelTypeWebhook, AlertChannelTypeSlack:
+		if u, err := url.Parse(channel.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be an http(s) url",
+				Path: path + ".url",
+			})
+		}
+	case AlertChannelTypeSMTP:
+		if channel.SMTP == nil {
+			rst = append(rst, KalmValidateError{
+				Err:  "is required by smtp channels",
+				Path: path + ".smtp",
+			})
+
+			break
+		}
+
+		if channel.SMTP.Host == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "should not be blank",
+				Path: path + ".smtp.host",
+			})
+		}
+
+		if channel.SMTP.Port <= 0 || channel.SMTP.Port > 65535 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be a valid port",
+				Path: path + ".smtp.port",
+			})
+		}
+
+		if channel.SMTP.From == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "should not be blank",
+				Path: path + ".smtp.from",
+			})
+		}
+
+		if len(channel.SMTP.To) == 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should have at least one recipient",
+				Path: path + ".smtp.to",
+			})
+		}
+	default:
+		rst = append(rst, KalmValidateError{
+			Err:  "unknown channel type",
+			Path: path + ".type",
+		})
+	}
+
+	return rst
+}
@@ -0,0 +1,61 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertRuleValidate(t *testing.T) {
+	rule := AlertRule{
+		Spec: AlertRuleSpec{
+			Type:      AlertRuleTypeCPU,
+			Threshold: 500,
+			Channels: []AlertChannel{
+				{Type: AlertChannelTypeSlack, URL: "https://hooks.slack.com/services/foo"},
+				{Type: AlertChannelTypeSMTP, SMTP: &AlertSMTPConfig{Host: "smtp.kalm.dev", Port: 587, From: "alert@kalm.dev", To: []string{"ops@kalm.dev"}}},
+			},
+		},
+	}
+
+	assert.Nil(t, rule.validate())
+
+	rule.Spec.IntervalSeconds = 5
+	rule.Spec.Channels = append(rule.Spec.Channels,
+		AlertChannel{Type: AlertChannelTypeWebhook, URL: "ftp://kalm.dev"},
+		AlertChannel{Type: AlertChannelTypeSMTP},
+	)
+	rule.Spec.Silences = []AlertSilence{{ID: "a"}, {ID: "a"}}
+
+	errList := rule.validate().(KalmValidateErrorList)
+	assert.Len(t, errList, 4)
+	assert.Equal(t, "spec.intervalSeconds", errList[0].Path)
+	assert.Equal(t, "spec.channels[2].url", errList[1].Path)
+	assert.Equal(t, "spec.channels[3].smtp", errList[2].Path)
+	assert.Equal(t, "spec.silences[1].id", errList[3].Path)
+
+	rule = AlertRule{Spec: AlertRuleSpec{Type: AlertRuleTypeHttp5xxRatio, Threshold: 101}}
+	assert.NotNil(t, rule.validate())
+}
+
+func TestAlertRuleConditionAndSilence(t *testing.T) {
+	rule := AlertRule{Spec: AlertRuleSpec{Type: AlertRuleTypeCPU, Threshold: 500}}
+	assert.True(t, rule.IsConditionMet(501))
+	assert.False(t, rule.IsConditionMet(500))
+
+	rule.Spec.Type = AlertRuleTypeCertExpiry
+	rule.Spec.Threshold = 14
+	assert.True(t, rule.IsConditionMet(13))
+	assert.False(t, rule.IsConditionMet(14))
+
+	now := time.Now()
+	rule.Spec.Silences = []AlertSilence{
+		{ID: "web", Target: "web", EndTimestamp: now.Add(time.Hour).Unix()},
+		{ID: "expired", EndTimestamp: now.Add(-time.Hour).Unix()},
+	}
+
+	assert.Equal(t, "web", rule.GetActiveSilence("web", now).ID)
+	assert.Nil(t, rule.GetActiveSilence("api", now))
+	assert.Nil(t, rule.GetActiveSilence("web", now.Add(2*time.Hour)))
+}
@@ -0,0 +1,302 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// Annotation on namespace, the quota of the application in json
+	KalmAnnoApplicationQuota = "kalm-application-quota"
+
+	// Name of the ResourceQuota and LimitRange rendered from the application quota
+	ApplicationQuotaObjectName = "kalm-application-quota"
+)
+
+const (
+	QuotaResourceCPU      = "cpu"
+	QuotaResourceMemory   = "memory"
+	QuotaResourceStorage  = "storage"
+	QuotaResourceReplicas = "replicas"
+	QuotaResourceRoutes   = "routes"
+)
+
+// ApplicationQuota limits the resources all components of an application (namespace) can take.
+// Nil fields are unlimited.
+type ApplicationQuota struct {
+	// Sum of cpu limits of components, including istio sidecars, times their replicas
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Sum of memory limits of components, including istio sidecars, times their replicas
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// Sum of sizes of persistent volumes
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	// Sum of replicas of components, max replicas are counted for autoscaled components
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Number of http routes having destinations in the application
+	Routes *int32 `json:"routes,omitempty"`
+}
+
+// ApplicationQuotaUsage is counted from component specs, so it's known before pods are created
+type ApplicationQuotaUsage struct {
+	CPU      resource.Quantity `json:"cpu"`
+	Memory   resource.Quantity `json:"memory"`
+	Storage  resource.Quantity `json:"storage"`
+	Replicas int32             `json:"replicas"`
+	Routes   int32             `json:"routes"`
+}
+
+// GetApplicationQuota reads the quota from the annotations of a namespace, nil means no quota
+func GetApplicationQuota(namespace *v1.Namespace) (*ApplicationQuota, error) {
+	raw, exist := namespace.Annotations[KalmAnnoApplicationQuota]
+
+	if !exist || raw == "" {
+		return nil, nil
+	}
+
+	var quota ApplicationQuota
+
+	if err := json.Unmarshal([]byte(raw), &quota); err != nil {
+		return nil, fmt.Errorf("invalid application quota of %s: %s", namespace.Name, err)
+	}
+
+	return &quota, nil
+}
+
+// SetApplicationQuota saves the quota into the annotations of a namespace, nil or empty quota removes it
+func SetApplicationQuota(namespace *v1.Namespace, quota *ApplicationQuota) error {
+	if quota == nil || quota.IsEmpty() {
+		delete(namespace.Annotations, KalmAnnoApplicationQuota)
+		return nil
+	}
+
+	bts, err := json.Marshal(quota)
+
+	if err != nil {
+		return err
+	}
+
+	if namespace.Annotations == nil {
+		namespace.Annotations = make(map[string]string)
+	}
+
+	namespace.Annotations[KalmAnnoApplicationQuota] = string(bts)
+
+	return nil
+}
+
+func (q *ApplicationQuota) IsEmpty() bool {
+	return q.CPU == nil && q.Memory == nil && q.Storage == nil && q.Replicas == nil && q.Routes == nil
+}
+
+func (q *ApplicationQuota) Validate() (rst KalmValidateErrorList) {
+	quantities := map[string]*resource.Quantity{
+		QuotaResourceCPU:     q.CPU,
+		QuotaResourceMemory:  q.Memory,
+		QuotaResourceStorage: q.Storage,
+	}
+
+	for name, quantity := range quantities {
+		if quantity != nil {
+			rst = append(rst, toKalmValidateErrors(ValidateNonnegativeQuantity(*quantity, field.NewPath(name)))...)
+		}
+	}
+
+	counts := map[string]*int32{
+		QuotaResourceReplicas: q.Replicas,
+		QuotaResourceRoutes:   q.Routes,
+	}
+
+	for name, count := range counts {
+		if count != nil && *count < 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  isNegativeErrorMsg,
+				Path: name,
+			})
+		}
+	}
+
+	return rst
+}
+
+// Exceeded returns names of resources whose usage is over the quota
+func (q *ApplicationQuota) Exceeded(usage *ApplicationQuotaUsage) []string {
+	var rst []string
+
+	if q.CPU != nil && usage.CPU.Cmp(*q.CPU) > 0 {
+		rst = append(rst, QuotaResourceCPU)
+	}
+
+	if q.Memory != nil && usage.Memory.Cmp(*q.Memory) > 0 {
+		rst = append(rst, QuotaResourceMemory)
+	}
+
+	if q.Storage != nil && usage.Storage.Cmp(*q.Storage) > 0 {
+		rst = append(rst, QuotaResourceStorage)
+	}
+
+	if q.Replicas != nil && usage.Replicas > *q.Replicas {
+		rst = append(rst, QuotaResourceReplicas)
+	}
+
+	if q.Routes != nil && usage.Routes > *q.Routes {
+		rst = append(rst, QuotaResourceRoutes)
+	}
+
+	return rst
+}
+
+// ExceededMoreThan returns names of resources which are over the quota and more used than in the old usage.
+// Changes reducing usage are always allowed, even if the application is still over the quota.
+func (q *ApplicationQuota) ExceededMoreThan(usage, oldUsage *ApplicationQuotaUsage) []string {
+	var rst []string
+
+	for _, name := range q.Exceeded(usage) {
+		if usage.isMoreUsedThan(oldUsage, name) {
+			rst = append(rst, name)
+		}
+	}
+
+	return rst
+}
+
+func (u *ApplicationQuotaUsage) isMoreUsedThan(other *ApplicationQuotaUsage, name string) bool {
+	switch name {
+	case QuotaResourceCPU:
+		return u.CPU.Cmp(other.CPU) > 0
+	case QuotaResourceMemory:
+		return u.Memory.Cmp(other.Memory) > 0
+	case QuotaResourceStorage:
+		return u.Storage.Cmp(other.Storage) > 0
+	case QuotaResourceReplicas:
+		return u.Replicas > other.Replicas
+	case QuotaResourceRoutes:
+		return u.Routes > other.Routes
+	}
+
+	return false
+}
+
+func (u *ApplicationQuotaUsage) Add(other *ApplicationQuotaUsage) {
+	u.CPU.Add(other.CPU)
+	u.Memory.Add(other.Memory)
+	u.Storage.Add(other.Storage)
+	u.Replicas += other.Replicas
+	u.Routes += other.Routes
+}
+
+func (u *ApplicationQuotaUsage) Sub(other *ApplicationQuotaUsage) {
+	u.CPU.Sub(other.CPU)
+	u.Memory.Sub(other.Memory)
+	u.Storage.Sub(other.Storage)
+	u.Replicas -= other.Replicas
+	u.Routes -= other.Routes
+}
+
+// GetQuotaReplicas is the number of replicas counted in the quota.
+// Daemonsets and cronjobs are counted as one, as their number of pods are not decided by the component.
+func (r *Component) GetQuotaReplicas() int32 {
+	switch r.Spec.WorkloadType {
+	case WorkloadTypeDaemonSet, WorkloadTypeCronjob:
+		return 1
+	}
+
+	if r.Spec.Replicas != nil && *r.Spec.Replicas == 0 {
+		return 0
+	}
+
+	if r.Spec.Autoscaling != nil {
+		return r.Spec.Autoscaling.MaxReplicas
+	}
+
+	if r.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *r.Spec.Replicas
+}
+
+// GetQuotaUsage counts the resources of the component in the application quota
+func (r *Component) GetQuotaUsage() *ApplicationQuotaUsage {
+	replicas := r.GetQuotaReplicas()
+	usage := &ApplicationQuotaUsage{Replicas: replicas}
+
+	for _, requirements := range []*v1.ResourceRequirements{r.Spec.ResourceRequirements, r.Spec.IstioResourceRequirements} {
+		cpu := getResourceLimitOrRequest(requirements, v1.ResourceCPU)
+		memory := getResourceLimitOrRequest(requirements, v1.ResourceMemory)
+
+		for i := int32(0); i < replicas; i++ {
+			usage.CPU.Add(cpu)
+			usage.Memory.Add(memory)
+		}
+	}
+
+	for _, vol := range r.Spec.Volumes {
+		switch vol.Type {
+		case VolumeTypePersistentVolumeClaim:
+			usage.Storage.Add(vol.Size)
+		case VolumeTypePersistentVolumeClaimTemplate:
+			// each replica of statefulset has its own volume
+			for i := int32(0); i < replicas; i++ {
+				usage.Storage.Add(vol.Size)
+			}
+		}
+	}
+
+	return usage
+}
+
+func getResourceLimitOrRequest(requirements *v1.ResourceRequirements, name v1.ResourceName) resource.Quantity {
+	if requirements == nil {
+		return resource.Quantity{}
+	}
+
+	if limit, exist := requirements.Limits[name]; exist {
+		return limit
+	}
+
+	return requirements.Requests[name]
+}
+
+// GetApplicationsOfHttpRoute returns namespaces of the route destinations, the route is counted in their quota
+func GetApplicationsOfHttpRoute(route *HttpRoute) []string {
+	var rst []string
+	seen := make(map[string]bool)
+
+	for _, dest := range route.Spec.Destinations {
+		// the host is in format of name.namespace.svc.cluster.local:port
+		parts := strings.Split(strings.Split(dest.Host, ":")[0], ".")
+
+		if len(parts) < 2 || parts[1] == "" || seen[parts[1]] {
+			continue
+		}
+
+		seen[parts[1]] = true
+		rst = append(rst, parts[1])
+	}
+
+	return rst
+}
@@ -0,0 +1,179 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func newQuotaTestComponent(name string, replicas int32) *Component {
+	component := &Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "shop",
+			Name:      name,
+		},
+		Spec: ComponentSpec{
+			Image:    "foo:bar",
+			Replicas: int32Ptr(replicas),
+			ResourceRequirements: &v1.ResourceRequirements{
+				Limits: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("500m"),
+					v1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			},
+		},
+	}
+
+	component.Default()
+
+	return component
+}
+
+func TestApplicationQuotaAnnotation(t *testing.T) {
+	ns := &v1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "shop"}}
+
+	quota, err := GetApplicationQuota(ns)
+	assert.Nil(t, err)
+	assert.Nil(t, quota)
+
+	assert.Nil(t, SetApplicationQuota(ns, &ApplicationQuota{CPU: quantityPtr("2"), Replicas: int32Ptr(5)}))
+	quota, err = GetApplicationQuota(ns)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", quota.CPU.String())
+	assert.Equal(t, int32(5), *quota.Replicas)
+	assert.Nil(t, quota.Memory)
+
+	// empty quota removes it
+	assert.Nil(t, SetApplicationQuota(ns, &ApplicationQuota{}))
+	_, exist := ns.Annotations[KalmAnnoApplicationQuota]
+	assert.False(t, exist)
+
+	ns.Annotations[KalmAnnoApplicationQuota] = "{"
+	_, err = GetApplicationQuota(ns)
+	assert.NotNil(t, err)
+
+	assert.Len(t, (&ApplicationQuota{CPU: quantityPtr("-1"), Routes: int32Ptr(-1)}).Validate(), 2)
+	assert.Len(t, (&ApplicationQuota{CPU: quantityPtr("1"), Routes: int32Ptr(0)}).Validate(), 0)
+}
+
+func TestComponentQuotaUsage(t *testing.T) {
+	component := newQuotaTestComponent("web", 2)
+	component.Spec.WorkloadType = WorkloadTypeStatefulSet
+	component.Spec.Volumes = []Volume{
+		{Type: VolumeTypePersistentVolumeClaimTemplate, Size: resource.MustParse("1Gi")},
+		{Type: VolumeTypePersistentVolumeClaim, Size: resource.MustParse("2Gi")},
+		{Type: VolumeTypeTemporaryDisk, Size: resource.MustParse("4Gi")},
+	}
+
+	// istio sidecars are counted, 100m and 128Mi by default
+	usage := component.GetQuotaUsage()
+	assert.Equal(t, int32(2), usage.Replicas)
+	assert.Equal(t, "1200m", usage.CPU.String())
+	assert.Equal(t, "768Mi", usage.Memory.String())
+	assert.Equal(t, "4Gi", usage.Storage.String())
+
+	// max replicas are counted for autoscaled components
+	component.Spec.Autoscaling = &AutoscalingSpec{MaxReplicas: 4}
+	assert.Equal(t, int32(4), component.GetQuotaReplicas())
+
+	// scaled down components are free
+	component.Spec.Replicas = int32Ptr(0)
+	assert.Equal(t, int32(0), component.GetQuotaReplicas())
+
+	component.Spec.WorkloadType = WorkloadTypeCronjob
+	assert.Equal(t, int32(1), component.GetQuotaReplicas())
+}
+
+func TestApplicationQuotaExceeded(t *testing.T) {
+	quota := &ApplicationQuota{CPU: quantityPtr("1"), Replicas: int32Ptr(3)}
+
+	usage := &ApplicationQuotaUsage{CPU: resource.MustParse("1500m"), Replicas: 3}
+	assert.Equal(t, []string{QuotaResourceCPU}, quota.Exceeded(usage))
+
+	// reducing usage is allowed even if it's still over the quota
+	oldUsage := &ApplicationQuotaUsage{CPU: resource.MustParse("2"), Replicas: 2}
+	assert.Len(t, quota.ExceededMoreThan(usage, oldUsage), 0)
+
+	oldUsage.CPU = resource.MustParse("1")
+	assert.Equal(t, []string{QuotaResourceCPU}, quota.ExceededMoreThan(usage, oldUsage))
+}
+
+func newQuotaTestWebhookClient(objs ...runtime.Object) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = AddToScheme(scheme)
+
+	ns := &v1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "shop"}}
+	_ = SetApplicationQuota(ns, &ApplicationQuota{
+		CPU:      quantityPtr("2"),
+		Replicas: int32Ptr(3),
+		Routes:   int32Ptr(1),
+	})
+
+	webhookClient = fake.NewFakeClientWithScheme(scheme, append(objs, ns)...)
+}
+
+func TestComponentWebhookApplicationQuota(t *testing.T) {
+	// 2 replicas with 600m cpu each
+	newQuotaTestWebhookClient(newQuotaTestComponent("web", 2))
+	defer func() { webhookClient = nil }()
+
+	assert.Nil(t, newQuotaTestComponent("worker", 1).ValidateCreate())
+
+	err := newQuotaTestComponent("worker", 2).ValidateCreate()
+	if assert.NotNil(t, err) {
+		errList := err.(KalmValidateErrorList)
+		assert.Len(t, errList, 2)
+		assert.Equal(t, "spec.resourceRequirements.limits.cpu", errList[0].Path)
+		assert.Equal(t, "spec.replicas", errList[1].Path)
+	}
+
+	// scale web up is rejected
+	old := newQuotaTestComponent("web", 2)
+	assert.NotNil(t, newQuotaTestComponent("web", 4).ValidateUpdate(old))
+	assert.Nil(t, newQuotaTestComponent("web", 3).ValidateUpdate(old))
+
+	// other applications are not limited
+	other := newQuotaTestComponent("worker", 10)
+	other.Namespace = "blog"
+	assert.Nil(t, other.ValidateCreate())
+}
+
+func TestHttpRouteWebhookApplicationQuota(t *testing.T) {
+	newRoute := func(name string, hosts ...string) *HttpRoute {
+		route := &HttpRoute{ObjectMeta: ctrl.ObjectMeta{Name: name}}
+
+		for _, host := range hosts {
+			route.Spec.Destinations = append(route.Spec.Destinations, HttpRouteDestination{Host: host, Weight: 1})
+		}
+
+		return route
+	}
+
+	existing := newRoute("web", "web.shop.svc.cluster.local:80")
+	assert.Equal(t, []string{"shop"}, GetApplicationsOfHttpRoute(existing))
+
+	newQuotaTestWebhookClient(existing)
+	defer func() { webhookClient = nil }()
+
+	assert.Len(t, newRoute("api", "api.shop.svc.cluster.local").validateApplicationQuota(nil), 1)
+	assert.Len(t, newRoute("blog", "blog.blog.svc.cluster.local").validateApplicationQuota(nil), 0)
+
+	// updates of routes already counted in the quota are allowed
+	assert.Len(t, newRoute("web", "web.shop.svc.cluster.local", "web-v2.shop.svc.cluster.local").validateApplicationQuota(existing), 0)
+}
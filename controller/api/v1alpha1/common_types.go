@@ -1,6 +1,9 @@
 package v1alpha1
 
 import (
+	"fmt"
+	"strings"
+
 	rbacV1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -17,6 +20,10 @@ const (
 	EnvVarTypeFieldRef EnvVarType = "fieldref"
 	EnvVarTypeBuiltin  EnvVarType = "builtin"
 
+	// value of secret and configMap env is in format of <name>/<key>
+	EnvVarTypeSecret    EnvVarType = "secret"
+	EnvVarTypeConfigMap EnvVarType = "configMap"
+
 	EnvVarBuiltinHost      string = "host"
 	EnvVarBuiltinPodName   string = "podName"
 	EnvVarBuiltinNamespace string = "namespace"
@@ -44,7 +51,7 @@ type EnvVar struct {
 
 	Value string `json:"value,omitempty"`
 
-	// +kubebuilder:validation:Enum=static;external;linked;fieldref;builtin
+	// +kubebuilder:validation:Enum=static;external;linked;fieldref;builtin;secret;configMap
 	Type EnvVarType `json:"type,omitempty"`
 
 	Prefix string `json:"prefix,omitempty"`
@@ -52,6 +59,31 @@ type EnvVar struct {
 	Suffix string `json:"suffix,omitempty"`
 }
 
+// GetObjectKeyReference returns the object name and key referenced by a secret or configMap env
+func (e EnvVar) GetObjectKeyReference() (name, key string, err error) {
+	parts := strings.Split(e.Value, "/")
+
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("wrong %s env value %s, should be in format of <name>/<key>", e.Type, e.Value)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// EnvFromSource exposes all keys of a Secret or ConfigMap as environment variables.
+type EnvFromSource struct {
+	// +kubebuilder:validation:Enum=secret;configMap
+	Type EnvVarType `json:"type"`
+
+	// Name of the Secret or ConfigMap in the same namespace
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// An optional identifier to prepend to each key
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
 type Port struct {
 	// +kubebuilder:validation:Maximum=65535
 	// +kubebuilder:validation:Minimum=1
@@ -18,6 +18,7 @@ package v1alpha1
 import (
 	apps1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,6 +45,140 @@ type PreInjectFile struct {
 	Readonly bool `json:"readonly,omitempty"`
 
 	Runnable bool `json:"runnable"`
+
+	// Names of the containers the file is mounted into, default to the main container.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+}
+
+// ContainerVolumeMount mounts a volume of the component into a sidecar or init container.
+type ContainerVolumeMount struct {
+	// The path of the volume in the component volumes
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Where to mount the volume in the container, default to the path of the volume
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// Container is a sidecar or init container running next to the main container of a component.
+type Container struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Same as the command of the component
+	// +optional
+	Command string `json:"command,omitempty"`
+
+	// +optional
+	Env []EnvVar `json:"env,omitempty"`
+
+	// +optional
+	EnvFrom []EnvFromSource `json:"envFrom,omitempty"`
+
+	// Ports the container listens on, only the ports of the component are exposed by the service
+	// +optional
+	Ports []Port `json:"ports,omitempty"`
+
+	// +optional
+	VolumeMounts []ContainerVolumeMount `json:"volumeMounts,omitempty"`
+
+	// +optional
+	ResourceRequirements *v1.ResourceRequirements `json:"resourceRequirements,omitempty"`
+}
+
+// AutoscalingSpec configures a HorizontalPodAutoscaler for server and statefulset workloads.
+// When it's set, the replicas of the workload are managed by the autoscaler and `replicas` is ignored.
+type AutoscalingSpec struct {
+	// Default to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Target average CPU utilization, in percentage of the requested CPU.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// Target average memory utilization, in percentage of the requested memory.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+
+	// +optional
+	CustomMetric *AutoscalingCustomMetric `json:"customMetric,omitempty"`
+}
+
+// AutoscalingCustomMetric is a per pod metric served by the custom metrics API, e.g. requests per second.
+type AutoscalingCustomMetric struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Target value of the metric averaged across all pods.
+	TargetAverageValue resource.Quantity `json:"targetAverageValue"`
+}
+
+func (s *AutoscalingSpec) GetMinReplicas() int32 {
+	if s.MinReplicas == nil {
+		return 1
+	}
+
+	return *s.MinReplicas
+}
+
+type DeliveryStrategyType string
+
+const (
+	// Canary runs the new image next to the stable one and shifts route traffic to it step by step.
+	DeliveryStrategyCanary DeliveryStrategyType = "canary"
+	// BlueGreen runs the new image next to the stable one and switches all route traffic at once.
+	DeliveryStrategyBlueGreen DeliveryStrategyType = "blueGreen"
+)
+
+// KalmAnnoDeliveryStrategy overrides the type of the component delivery strategy for the next delivery only,
+// it's set by the deploy webhook along with the new image, and removed once the delivery is started.
+// Components without a delivery strategy are delivered once with the default steps of the type.
+const KalmAnnoDeliveryStrategy = "kalm-delivery-strategy"
+
+type DeliveryStep struct {
+	// percentage of the route traffic sent to the new image during this step
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int `json:"weight"`
+
+	// how long to stay on this step before moving to the next one
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PauseSeconds int `json:"pauseSeconds,omitempty"`
+}
+
+// DeliveryStrategy describes how an image change of a server component is rolled out.
+// The controller keeps the stable image running, starts a parallel workload with the new image,
+// and moves the weights of the HttpRoutes pointing at the component between the two.
+type DeliveryStrategy struct {
+	// +kubebuilder:validation:Enum=canary;blueGreen
+	Type DeliveryStrategyType `json:"type"`
+
+	// Traffic steps of a canary delivery, default to 5%, 25%, 50%, 100%.
+	// For blueGreen, only the pause of the last step is used.
+	// +optional
+	Steps []DeliveryStep `json:"steps,omitempty"`
+
+	// If the new pods are not all ready within this duration, the delivery is rolled back.
+	// Default to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
 }
 
 // ComponentSpec defines the desired state of Component
@@ -56,6 +191,10 @@ type ComponentSpec struct {
 
 	Env []EnvVar `json:"env,omitempty"`
 
+	// Secrets and ConfigMaps whose keys are all exposed as envs
+	// +optional
+	EnvFrom []EnvFromSource `json:"envFrom,omitempty"`
+
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
 	Image string `json:"image"`
@@ -110,24 +249,158 @@ type ComponentSpec struct {
 
 	PreInjectedFiles []PreInjectFile `json:"preInjectedFiles,omitempty"`
 
+	// Containers running next to the main container in the pods
+	// +optional
+	Sidecars []Container `json:"sidecars,omitempty"`
+
+	// Containers running in order before the main container and sidecars are started
+	// +optional
+	InitContainers []Container `json:"initContainers,omitempty"`
+
 	// +optional
 	Priority int `json:"priority"`
 
 	// This is only meaningful if this component is a cronjob workload.
 	// Controller should immediately trigger a job and set its value to false if it's true.
 	ImmediateTrigger bool `json:"immediateTrigger,omitempty"`
+
+	// Horizontal autoscaling, only meaningful for server and statefulset workloads.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Progressive delivery of image changes, only meaningful for server workloads.
+	// Without it, image changes are rolled out in place.
+	// +optional
+	DeliveryStrategy *DeliveryStrategy `json:"deliveryStrategy,omitempty"`
+}
+
+type ComponentConditionType string
+
+const (
+	// Progressing means the workload is rolling out a new pod template or scaling.
+	ComponentConditionProgressing ComponentConditionType = "Progressing"
+	// Available means the workload has at least the desired number of ready pods.
+	ComponentConditionAvailable ComponentConditionType = "Available"
+	// Degraded means the rollout is stuck or the component can't run as declared.
+	ComponentConditionDegraded ComponentConditionType = "Degraded"
+)
+
+// Reasons used in component conditions
+const (
+	ComponentReasonRolloutInProgress        = "RolloutInProgress"
+	ComponentReasonRolloutComplete          = "RolloutComplete"
+	ComponentReasonMinimumReplicasAvailable = "MinimumReplicasAvailable"
+	ComponentReasonReplicasUnavailable      = "ReplicasUnavailable"
+	ComponentReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ComponentReasonWorkloadNotFound         = "WorkloadNotFound"
+	ComponentReasonCronJobScheduled         = "CronJobScheduled"
+	ComponentReasonCronJobSuspended         = "CronJobSuspended"
+	ComponentReasonAsExpected               = "AsExpected"
+)
+
+type ComponentCondition struct {
+	// Type of the condition, one of ('Progressing', 'Available', 'Degraded').
+	Type ComponentConditionType `json:"type"`
+
+	// Status of the condition, one of ('True', 'False', 'Unknown').
+	Status v1.ConditionStatus `json:"status"`
+
+	// Reason is a brief machine readable explanation for the condition's last
+	// transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the details of the last
+	// transition, complementing reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // ComponentStatus defines the observed state of Component
 type ComponentStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// The generation of the component spec that the status was computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Desired number of pods, aggregated from the managed workload.
+	// +optional
+	Replicas int32 `json:"replicas"`
+
+	// Number of pods which are ready.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// Number of pods running the latest pod template.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+
+	// The latest revision of the component spec, see ComponentRevision.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// The image of the main container that has been completely rolled out.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// +optional
+	Conditions []ComponentCondition `json:"conditions,omitempty"`
+
+	// State of the progressive delivery, only set if the component has a delivery strategy.
+	// +optional
+	Delivery *DeliveryStatus `json:"delivery,omitempty"`
+}
+
+type DeliveryPhase string
+
+const (
+	DeliveryPhaseProgressing DeliveryPhase = "Progressing"
+	DeliveryPhasePromoting   DeliveryPhase = "Promoting"
+	DeliveryPhaseSucceeded   DeliveryPhase = "Succeeded"
+	DeliveryPhaseRolledBack  DeliveryPhase = "RolledBack"
+)
+
+type DeliveryStatus struct {
+	// The image serving the traffic that is not sent to the new image.
+	StableImage string `json:"stableImage,omitempty"`
+
+	// The image being delivered.
+	// +optional
+	NewImage string `json:"newImage,omitempty"`
+
+	// +optional
+	Phase DeliveryPhase `json:"phase,omitempty"`
+
+	// The strategy type of the current delivery.
+	// +optional
+	Strategy DeliveryStrategyType `json:"strategy,omitempty"`
+
+	// Index of the current step in the delivery steps.
+	// +optional
+	Step int `json:"step"`
+
+	// Percentage of the route traffic currently sent to the new image.
+	// +optional
+	Weight int `json:"weight"`
+
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// +optional
+	StepStartedAt *metav1.Time `json:"stepStartedAt,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Tenant",type="string",JSONPath=".metadata.labels.tenant"
 // +kubebuilder:printcolumn:name="Workload",type="string",JSONPath=".spec.workloadType"
 // +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.image"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Component is the Schema for the components API
@@ -151,3 +424,77 @@ type ComponentList struct {
 func init() {
 	SchemeBuilder.Register(&Component{}, &ComponentList{})
 }
+
+var DefaultCanarySteps = []DeliveryStep{
+	{Weight: 5, PauseSeconds: 60},
+	{Weight: 25, PauseSeconds: 60},
+	{Weight: 50, PauseSeconds: 60},
+	{Weight: 100},
+}
+
+const DefaultDeliveryProgressDeadlineSeconds = 600
+
+// GetSteps returns the effective traffic steps of the strategy.
+func (s *DeliveryStrategy) GetSteps() []DeliveryStep {
+	if s.Type == DeliveryStrategyBlueGreen {
+		step := DeliveryStep{Weight: 100}
+
+		if len(s.Steps) > 0 {
+			step.PauseSeconds = s.Steps[len(s.Steps)-1].PauseSeconds
+		}
+
+		return []DeliveryStep{step}
+	}
+
+	if len(s.Steps) == 0 {
+		return DefaultCanarySteps
+	}
+
+	return s.Steps
+}
+
+func (s *DeliveryStrategy) GetProgressDeadlineSeconds() int32 {
+	if s.ProgressDeadlineSeconds == nil {
+		return DefaultDeliveryProgressDeadlineSeconds
+	}
+
+	return *s.ProgressDeadlineSeconds
+}
+
+func GetComponentCondition(status ComponentStatus, condType ComponentConditionType) *ComponentCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// SetComponentCondition adds or replaces the condition of the same type.
+// LastTransitionTime is only bumped when the status of the condition changes.
+func SetComponentCondition(status *ComponentStatus, cond ComponentCondition) {
+	existing := GetComponentCondition(*status, cond.Type)
+
+	if existing == nil {
+		if cond.LastTransitionTime.IsZero() {
+			cond.LastTransitionTime = metav1.Now()
+		}
+
+		status.Conditions = append(status.Conditions, cond)
+		return
+	}
+
+	if existing.Status != cond.Status {
+		existing.Status = cond.Status
+		existing.LastTransitionTime = metav1.Now()
+	}
+
+	existing.Reason = cond.Reason
+	existing.Message = cond.Message
+}
+
+func IsComponentConditionTrue(status ComponentStatus, condType ComponentConditionType) bool {
+	cond := GetComponentCondition(status, condType)
+	return cond != nil && cond.Status == v1.ConditionTrue
+}
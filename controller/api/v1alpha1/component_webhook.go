@@ -16,6 +16,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -23,11 +24,14 @@ import (
 
 	"github.com/robfig/cron"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	apimachineryval "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -36,6 +40,7 @@ import (
 var componentlog = logf.Log.WithName("component-webhook")
 
 func (r *Component) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -116,7 +121,10 @@ var _ webhook.Validator = &Component{}
 func (r *Component) ValidateCreate() error {
 	componentlog.Info("validate create", "ns", r.Namespace, "name", r.Name)
 
-	if errList := r.validate(); len(errList) > 0 {
+	errList := r.validate()
+	errList = append(errList, r.validateApplicationQuota(nil)...)
+
+	if len(errList) > 0 {
 		componentlog.Error(errList, "validate fail")
 		return error(errList)
 	}
@@ -129,9 +137,11 @@ func (r *Component) ValidateUpdate(old runtime.Object) error {
 	componentlog.Info("validate update", "ns", r.Namespace, "name", r.Name)
 
 	var volErrList KalmValidateErrorList
+	oldComponent, isComponent := old.(*Component)
+
 	// for sts, persistent vols should NOT be updated
 	if r.Spec.WorkloadType == WorkloadTypeStatefulSet {
-		if oldComponent, ok := old.(*Component); !ok {
+		if !isComponent {
 			componentlog.Info("oldObject is not *Component")
 		} else {
 			volMapNew := getStsTemplateVolMap(r)
@@ -149,8 +159,12 @@ func (r *Component) ValidateUpdate(old runtime.Object) error {
 		}
 	}
 
-	commonValidateErr := r.validate()
-	volErrList = append(volErrList, commonValidateErr...)
+	if isComponent {
+		volErrList = append(volErrList, r.validateChange(oldComponent)...)
+		volErrList = append(volErrList, r.validateApplicationQuota(oldComponent)...)
+	} else {
+		volErrList = append(volErrList, r.validate()...)
+	}
 
 	if len(volErrList) > 0 {
 		return error(volErrList)
@@ -218,9 +232,16 @@ func getStsTemplateVolMap(component *Component) map[string]Volume {
 }
 
 func (r *Component) validate() KalmValidateErrorList {
+	return r.validateChange(nil)
+}
+
+// validateChange validates the component changed from old, old is nil for creation.
+// Envs kept unchanged from old are not validated again, the Secrets and ConfigMaps they reference
+// may be changed afterwards, which should not block other changes of the component.
+func (r *Component) validateChange(old *Component) KalmValidateErrorList {
 	var rst KalmValidateErrorList
 
-	rst = append(rst, r.validateEnvVarList()...)
+	rst = append(rst, r.validateEnvVarList(old)...)
 	rst = append(rst, validateLabels(r.Spec.NodeSelectorLabels, ".spec.nodeSelectorLabels")...)
 	rst = append(rst, r.validateScheduleOfComponentIfIsCronJob()...)
 	rst = append(rst, r.validateProbes()...)
@@ -228,6 +249,9 @@ func (r *Component) validate() KalmValidateErrorList {
 	rst = append(rst, r.validateVolumesOfComponent()...)
 	rst = append(rst, r.validateRunnerPermission()...)
 	rst = append(rst, r.validatePreInjectedFiles()...)
+	rst = append(rst, r.validateDeliveryStrategy()...)
+	rst = append(rst, r.validateAutoscaling()...)
+	rst = append(rst, r.validateContainers(old)...)
 
 	if len(rst) == 0 {
 		return nil
@@ -237,6 +261,67 @@ func (r *Component) validate() KalmValidateErrorList {
 	return rst
 }
 
+var quotaResourcePaths = map[string]string{
+	QuotaResourceCPU:      "spec.resourceRequirements.limits.cpu",
+	QuotaResourceMemory:   "spec.resourceRequirements.limits.memory",
+	QuotaResourceStorage:  "spec.volumes",
+	QuotaResourceReplicas: "spec.replicas",
+}
+
+// validateApplicationQuota rejects changes making the application use more than its quota, old is nil for creation
+func (r *Component) validateApplicationQuota(old *Component) (rst KalmValidateErrorList) {
+	if webhookClient == nil {
+		return nil
+	}
+
+	var ns v1.Namespace
+	if err := webhookClient.Get(context.Background(), types.NamespacedName{Name: r.Namespace}, &ns); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+
+		return KalmValidateErrorList{{Err: err.Error(), Path: "metadata.namespace"}}
+	}
+
+	quota, err := GetApplicationQuota(&ns)
+	if err != nil {
+		return KalmValidateErrorList{{Err: err.Error(), Path: "metadata.namespace"}}
+	}
+
+	if quota == nil {
+		return nil
+	}
+
+	var compList ComponentList
+	if err := webhookClient.List(context.Background(), &compList, client.InNamespace(r.Namespace)); err != nil {
+		return KalmValidateErrorList{{Err: err.Error(), Path: "metadata.namespace"}}
+	}
+
+	othersUsage := &ApplicationQuotaUsage{}
+	for i := range compList.Items {
+		if compList.Items[i].Name != r.Name {
+			othersUsage.Add(compList.Items[i].GetQuotaUsage())
+		}
+	}
+
+	usage := othersUsage.DeepCopy()
+	usage.Add(r.GetQuotaUsage())
+
+	oldUsage := othersUsage
+	if old != nil {
+		oldUsage.Add(old.GetQuotaUsage())
+	}
+
+	for _, name := range quota.ExceededMoreThan(usage, oldUsage) {
+		rst = append(rst, KalmValidateError{
+			Err:  fmt.Sprintf("exceeding %s quota of application %s", name, r.Namespace),
+			Path: quotaResourcePaths[name],
+		})
+	}
+
+	return rst
+}
+
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (r *Component) ValidateDelete() error {
 	componentlog.Info("validate delete", "name", r.Name)
@@ -348,6 +433,174 @@ func (r *Component) validateVolumesOfComponent() (rst KalmValidateErrorList) {
 	return rst
 }
 
+func (r *Component) validateAutoscaling() (rst KalmValidateErrorList) {
+	autoscaling := r.Spec.Autoscaling
+	if autoscaling == nil {
+		return nil
+	}
+
+	if r.Spec.WorkloadType != WorkloadTypeServer &&
+		r.Spec.WorkloadType != WorkloadTypeStatefulSet &&
+		r.Spec.WorkloadType != "" {
+		rst = append(rst, KalmValidateError{
+			Err:  fmt.Sprintf("autoscaling is only supported by workload %s and %s", WorkloadTypeServer, WorkloadTypeStatefulSet),
+			Path: ".spec.autoscaling",
+		})
+	}
+
+	if autoscaling.MaxReplicas < 1 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: ".spec.autoscaling.maxReplicas",
+		})
+	}
+
+	if autoscaling.MinReplicas != nil && *autoscaling.MinReplicas < 1 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: ".spec.autoscaling.minReplicas",
+		})
+	} else if autoscaling.GetMinReplicas() > autoscaling.MaxReplicas {
+		rst = append(rst, KalmValidateError{
+			Err:  "should not be greater than maxReplicas",
+			Path: ".spec.autoscaling.minReplicas",
+		})
+	}
+
+	if autoscaling.TargetCPUUtilizationPercentage == nil &&
+		autoscaling.TargetMemoryUtilizationPercentage == nil &&
+		autoscaling.CustomMetric == nil {
+		rst = append(rst, KalmValidateError{
+			Err:  "at least one of targetCPUUtilizationPercentage, targetMemoryUtilizationPercentage and customMetric should be set",
+			Path: ".spec.autoscaling",
+		})
+	}
+
+	// utilization is relative to the resource request, which is defaulted to the limit
+	targets := []struct {
+		percentage *int32
+		resName    v1.ResourceName
+		path       string
+	}{
+		{autoscaling.TargetCPUUtilizationPercentage, v1.ResourceCPU, ".spec.autoscaling.targetCPUUtilizationPercentage"},
+		{autoscaling.TargetMemoryUtilizationPercentage, v1.ResourceMemory, ".spec.autoscaling.targetMemoryUtilizationPercentage"},
+	}
+
+	for _, target := range targets {
+		if target.percentage == nil {
+			continue
+		}
+
+		if *target.percentage < 1 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be positive",
+				Path: target.path,
+			})
+		}
+
+		if !r.hasResourceRequestOrLimit(target.resName) {
+			rst = append(rst, KalmValidateError{
+				Err:  fmt.Sprintf("%s request or limit should be set to autoscale on its utilization", target.resName),
+				Path: target.path,
+			})
+		}
+	}
+
+	if metric := autoscaling.CustomMetric; metric != nil {
+		if metric.Name == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "should not be blank",
+				Path: ".spec.autoscaling.customMetric.name",
+			})
+		}
+
+		if metric.TargetAverageValue.Sign() <= 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be positive",
+				Path: ".spec.autoscaling.customMetric.targetAverageValue",
+			})
+		}
+	}
+
+	return rst
+}
+
+func (r *Component) hasResourceRequestOrLimit(resName v1.ResourceName) bool {
+	resRequirement := r.Spec.ResourceRequirements
+	if resRequirement == nil {
+		return false
+	}
+
+	if _, exist := resRequirement.Requests[resName]; exist {
+		return true
+	}
+
+	_, exist := resRequirement.Limits[resName]
+
+	return exist
+}
+
+func (r *Component) validateDeliveryStrategy() (rst KalmValidateErrorList) {
+	strategy := r.Spec.DeliveryStrategy
+	if strategy == nil {
+		return nil
+	}
+
+	if r.Spec.WorkloadType != WorkloadTypeServer && r.Spec.WorkloadType != "" {
+		rst = append(rst, KalmValidateError{
+			Err:  fmt.Sprintf("delivery strategy is only supported by workload %s", WorkloadTypeServer),
+			Path: ".spec.deliveryStrategy",
+		})
+	}
+
+	if strategy.Type != DeliveryStrategyCanary && strategy.Type != DeliveryStrategyBlueGreen {
+		rst = append(rst, KalmValidateError{
+			Err:  "unknown delivery strategy type: " + string(strategy.Type),
+			Path: ".spec.deliveryStrategy.type",
+		})
+	}
+
+	prevWeight := 0
+	for i, step := range strategy.Steps {
+		if step.Weight <= 0 || step.Weight > 100 {
+			rst = append(rst, KalmValidateError{
+				Err:  "weight should be in range (0, 100]",
+				Path: fmt.Sprintf(".spec.deliveryStrategy.steps[%d].weight", i),
+			})
+		} else if step.Weight <= prevWeight {
+			rst = append(rst, KalmValidateError{
+				Err:  "weight should be greater than the weight of the previous step",
+				Path: fmt.Sprintf(".spec.deliveryStrategy.steps[%d].weight", i),
+			})
+		}
+
+		if step.PauseSeconds < 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should not be negative",
+				Path: fmt.Sprintf(".spec.deliveryStrategy.steps[%d].pauseSeconds", i),
+			})
+		}
+
+		prevWeight = step.Weight
+	}
+
+	if strategy.Type == DeliveryStrategyCanary && len(strategy.Steps) > 0 && prevWeight != 100 {
+		rst = append(rst, KalmValidateError{
+			Err:  "weight of the last step should be 100",
+			Path: fmt.Sprintf(".spec.deliveryStrategy.steps[%d].weight", len(strategy.Steps)-1),
+		})
+	}
+
+	if strategy.ProgressDeadlineSeconds != nil && *strategy.ProgressDeadlineSeconds <= 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: ".spec.deliveryStrategy.progressDeadlineSeconds",
+		})
+	}
+
+	return rst
+}
+
 func (r *Component) isStatelessWorkload() bool {
 	switch r.Spec.WorkloadType {
 	case WorkloadTypeServer, WorkloadTypeDaemonSet, WorkloadTypeCronjob:
@@ -399,17 +652,69 @@ func (r *Component) validateProbes() (rst KalmValidateErrorList) {
 	return
 }
 
-func (r *Component) validateEnvVarList() (rst KalmValidateErrorList) {
-	if len(r.Spec.Env) == 0 {
-		return nil
+func (r *Component) validateEnvVarList(old *Component) (rst KalmValidateErrorList) {
+	var oldEnvs []EnvVar
+	var oldEnvFrom []EnvFromSource
+
+	if old != nil {
+		oldEnvs, oldEnvFrom = old.Spec.Env, old.Spec.EnvFrom
+	}
+
+	return validateEnvs(r.Namespace, r.Spec.Env, r.Spec.EnvFrom, oldEnvs, oldEnvFrom, ".spec")
+}
+
+// validateEnvs checks env names and the Secrets and ConfigMaps referenced by envs, envs existing in the old ones are skipped.
+// The existence of referenced keys is only checked when running as webhook.
+func validateEnvs(namespace string, envs []EnvVar, envFrom []EnvFromSource, oldEnvs []EnvVar, oldEnvFrom []EnvFromSource, path string) (rst KalmValidateErrorList) {
+	unchangedEnvs := make(map[EnvVar]bool, len(oldEnvs))
+	for _, env := range oldEnvs {
+		unchangedEnvs[env] = true
 	}
 
-	for i, env := range r.Spec.Env {
+	unchangedEnvFrom := make(map[EnvFromSource]bool, len(oldEnvFrom))
+	for _, source := range oldEnvFrom {
+		unchangedEnvFrom[source] = true
+	}
+
+	for i, env := range envs {
+		if unchangedEnvs[env] {
+			continue
+		}
+
 		errs := apimachineryval.IsCIdentifier(env.Name)
 		for _, err := range errs {
 			rst = append(rst, KalmValidateError{
 				Err:  err,
-				Path: fmt.Sprintf(".spec.env[%d]", i),
+				Path: fmt.Sprintf("%s.env[%d]", path, i),
+			})
+		}
+
+		if env.Type != EnvVarTypeSecret && env.Type != EnvVarTypeConfigMap {
+			continue
+		}
+
+		name, key, err := env.GetObjectKeyReference()
+		if err == nil {
+			err = checkEnvReference(namespace, env.Type, name, key)
+		}
+
+		if err != nil {
+			rst = append(rst, KalmValidateError{
+				Err:  err.Error(),
+				Path: fmt.Sprintf("%s.env[%d].value", path, i),
+			})
+		}
+	}
+
+	for i, source := range envFrom {
+		if unchangedEnvFrom[source] {
+			continue
+		}
+
+		if err := checkEnvReference(namespace, source.Type, source.Name, ""); err != nil {
+			rst = append(rst, KalmValidateError{
+				Err:  err.Error(),
+				Path: fmt.Sprintf("%s.envFrom[%d].name", path, i),
 			})
 		}
 	}
@@ -417,6 +722,54 @@ func (r *Component) validateEnvVarList() (rst KalmValidateErrorList) {
 	return rst
 }
 
+// checkEnvReference makes sure the Secret or ConfigMap exists and has the key, empty key means any key.
+func checkEnvReference(namespace string, envType EnvVarType, name, key string) error {
+	if envType != EnvVarTypeSecret && envType != EnvVarTypeConfigMap {
+		return fmt.Errorf("unknown env source type: %s", envType)
+	}
+
+	if webhookClient == nil {
+		return nil
+	}
+
+	objKey := types.NamespacedName{Namespace: namespace, Name: name}
+	var keyExist bool
+
+	if envType == EnvVarTypeSecret {
+		var secret v1.Secret
+		if err := webhookClient.Get(context.Background(), objKey, &secret); err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Errorf("secret %s not exist", name)
+			}
+
+			return err
+		}
+
+		// StringData is write-only, it's merged into Data by the api server
+		_, keyExist = secret.Data[key]
+	} else {
+		var configMap v1.ConfigMap
+		if err := webhookClient.Get(context.Background(), objKey, &configMap); err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Errorf("configMap %s not exist", name)
+			}
+
+			return err
+		}
+
+		_, keyExist = configMap.Data[key]
+		if !keyExist {
+			_, keyExist = configMap.BinaryData[key]
+		}
+	}
+
+	if key != "" && !keyExist {
+		return fmt.Errorf("key %s not exist in %s %s", key, envType, name)
+	}
+
+	return nil
+}
+
 func (r *Component) validateResRequirement() (rst KalmValidateErrorList) {
 	resRequirement := r.Spec.ResourceRequirements
 	if resRequirement == nil {
@@ -458,6 +811,112 @@ func (r *Component) validatePreInjectedFiles() (rst KalmValidateErrorList) {
 	return rst
 }
 
+// names of containers added by kalm or istio
+var reservedContainerNames = map[string]bool{
+	"inject-files": true,
+	"istio-proxy":  true,
+	"istio-init":   true,
+}
+
+func (r *Component) validateContainers(old *Component) (rst KalmValidateErrorList) {
+	names := map[string]bool{r.Name: true}
+
+	// envs of a container are compared with the ones of the old container with the same name
+	oldContainers := make(map[string]Container)
+	if old != nil {
+		for _, container := range old.Spec.Sidecars {
+			oldContainers[container.Name] = container
+		}
+
+		for _, container := range old.Spec.InitContainers {
+			oldContainers[container.Name] = container
+		}
+	}
+
+	volumePaths := make(map[string]bool)
+	for _, vol := range r.Spec.Volumes {
+		volumePaths[vol.Path] = true
+	}
+
+	validate := func(containers []Container, fieldName string) {
+		for i, container := range containers {
+			path := fmt.Sprintf(".spec.%s[%d]", fieldName, i)
+
+			for _, err := range apimachineryval.IsDNS1123Label(container.Name) {
+				rst = append(rst, KalmValidateError{
+					Err:  err,
+					Path: path + ".name",
+				})
+			}
+
+			if names[container.Name] || reservedContainerNames[container.Name] {
+				rst = append(rst, KalmValidateError{
+					Err:  "container name is already used: " + container.Name,
+					Path: path + ".name",
+				})
+			}
+
+			names[container.Name] = true
+
+			if container.Image == "" {
+				rst = append(rst, KalmValidateError{
+					Err:  "should not be blank",
+					Path: path + ".image",
+				})
+			}
+
+			oldContainer := oldContainers[container.Name]
+			rst = append(rst, validateEnvs(r.Namespace, container.Env, container.EnvFrom, oldContainer.Env, oldContainer.EnvFrom, path)...)
+
+			for j, mount := range container.VolumeMounts {
+				if !volumePaths[mount.Path] {
+					rst = append(rst, KalmValidateError{
+						Err:  "no volume of the component has path: " + mount.Path,
+						Path: fmt.Sprintf("%s.volumeMounts[%d].path", path, j),
+					})
+				}
+
+				if mount.MountPath != "" && !isValidPath(mount.MountPath) {
+					rst = append(rst, KalmValidateError{
+						Err:  "invalid path:" + mount.MountPath,
+						Path: fmt.Sprintf("%s.volumeMounts[%d].mountPath", path, j),
+					})
+				}
+			}
+
+			if container.ResourceRequirements != nil {
+				for resName, quantity := range container.ResourceRequirements.Limits {
+					fldPath := field.NewPath(fmt.Sprintf("%s.resourceRequirements.limits.%s", path, resName))
+					errList := ValidateResourceQuantityValue(quantity, fldPath, resName == v1.ResourceMemory)
+					rst = append(rst, toKalmValidateErrors(errList)...)
+				}
+
+				for resName, quantity := range container.ResourceRequirements.Requests {
+					fldPath := field.NewPath(fmt.Sprintf("%s.resourceRequirements.requests.%s", path, resName))
+					errList := ValidateResourceQuantityValue(quantity, fldPath, resName == v1.ResourceMemory)
+					rst = append(rst, toKalmValidateErrors(errList)...)
+				}
+			}
+		}
+	}
+
+	validate(r.Spec.Sidecars, "sidecars")
+	validate(r.Spec.InitContainers, "initContainers")
+
+	for i, file := range r.Spec.PreInjectedFiles {
+		for j, containerName := range file.Containers {
+			if !names[containerName] {
+				rst = append(rst, KalmValidateError{
+					Err:  "no container named: " + containerName,
+					Path: fmt.Sprintf(".spec.preInjectedFiles[%d].containers[%d]", i, j),
+				})
+			}
+		}
+	}
+
+	return rst
+}
+
 func (r *Component) validateRunnerPermission() (rst KalmValidateErrorList) {
 	runnerPermission := r.Spec.RunnerPermission
 	if runnerPermission == nil {
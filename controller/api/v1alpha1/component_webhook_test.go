@@ -5,8 +5,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestComponentValidate(t *testing.T) {
@@ -218,3 +221,233 @@ func TestSTSForbiddenUpdatePersistVol(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "should not update volume of type: pvcTemplate")
 }
+
+func TestComponentDeliveryStrategy(t *testing.T) {
+	component := Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      "kalm-comp-delivery",
+		},
+		Spec: ComponentSpec{
+			Image:        "foo:bar",
+			WorkloadType: WorkloadTypeServer,
+			DeliveryStrategy: &DeliveryStrategy{
+				Type: DeliveryStrategyCanary,
+				Steps: []DeliveryStep{
+					{Weight: 10, PauseSeconds: 30},
+					{Weight: 100},
+				},
+			},
+		},
+	}
+
+	component.Default()
+	assert.Nil(t, component.validate())
+
+	component.Spec.DeliveryStrategy.Steps = []DeliveryStep{
+		{Weight: 50},
+		{Weight: 20},
+	}
+	errs := component.validate()
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, ".spec.deliveryStrategy.steps[1].weight", errs[0].Path)
+
+	component.Spec.DeliveryStrategy.Steps = nil
+	component.Spec.WorkloadType = WorkloadTypeDaemonSet
+	errs = component.validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, ".spec.deliveryStrategy", errs[0].Path)
+}
+
+func TestComponentAutoscaling(t *testing.T) {
+	minReplicas := int32(2)
+	cpuUtilization := int32(80)
+
+	component := Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      "kalm-comp-autoscaling",
+		},
+		Spec: ComponentSpec{
+			Image:        "foo:bar",
+			WorkloadType: WorkloadTypeServer,
+			ResourceRequirements: &v1.ResourceRequirements{
+				Limits: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("100m"),
+				},
+			},
+			Autoscaling: &AutoscalingSpec{
+				MinReplicas:                    &minReplicas,
+				MaxReplicas:                    4,
+				TargetCPUUtilizationPercentage: &cpuUtilization,
+			},
+		},
+	}
+
+	component.Default()
+	assert.Nil(t, component.validate())
+
+	component.Spec.Autoscaling.MaxReplicas = 1
+	errs := component.validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, ".spec.autoscaling.minReplicas", errs[0].Path)
+
+	component.Spec.Autoscaling.MaxReplicas = 4
+	component.Spec.ResourceRequirements = nil
+	errs = component.validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, ".spec.autoscaling.targetCPUUtilizationPercentage", errs[0].Path)
+
+	component.Spec.Autoscaling.TargetCPUUtilizationPercentage = nil
+	component.Spec.Autoscaling.CustomMetric = &AutoscalingCustomMetric{
+		Name:               "http_requests_per_second",
+		TargetAverageValue: resource.MustParse("100"),
+	}
+	assert.Nil(t, component.validate())
+}
+
+func TestComponentSidecarsAndInitContainers(t *testing.T) {
+	component := Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      "kalm-comp-sidecars",
+		},
+		Spec: ComponentSpec{
+			Image:        "foo:bar",
+			WorkloadType: WorkloadTypeServer,
+			Volumes: []Volume{
+				{
+					Path: "/data",
+					Type: VolumeTypeTemporaryDisk,
+					Size: resource.MustParse("1Gi"),
+				},
+			},
+			Sidecars: []Container{
+				{
+					Name:  "log-shipper",
+					Image: "fluent-bit:latest",
+					VolumeMounts: []ContainerVolumeMount{
+						{Path: "/data", MountPath: "/logs", ReadOnly: true},
+					},
+				},
+			},
+			InitContainers: []Container{
+				{
+					Name:    "migrate",
+					Image:   "foo:bar",
+					Command: "/migrate.sh",
+				},
+			},
+			PreInjectedFiles: []PreInjectFile{
+				{
+					Content:    "echo migrate",
+					MountPath:  "/migrate.sh",
+					Runnable:   true,
+					Containers: []string{"migrate"},
+				},
+			},
+		},
+	}
+
+	component.Default()
+	assert.Nil(t, component.validate())
+
+	component.Spec.InitContainers[0].Name = "log-shipper"
+	errs := component.validate()
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, ".spec.initContainers[0].name", errs[0].Path)
+	assert.Equal(t, ".spec.preInjectedFiles[0].containers[0]", errs[1].Path)
+
+	component.Spec.InitContainers[0].Name = "migrate"
+	component.Spec.Sidecars[0].VolumeMounts[0].Path = "/not-exist"
+	errs = component.validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, ".spec.sidecars[0].volumeMounts[0].path", errs[0].Path)
+}
+
+func TestComponentSecretAndConfigMapEnvs(t *testing.T) {
+	webhookClient = fake.NewFakeClientWithScheme(
+		scheme.Scheme,
+		&v1.Secret{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: "kalm-system", Name: "db"},
+			Data:       map[string][]byte{"password": []byte("pass")},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: "kalm-system", Name: "settings"},
+			Data:       map[string]string{"mode": "production"},
+		},
+	)
+	defer func() { webhookClient = nil }()
+
+	component := Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      "kalm-comp-envs",
+		},
+		Spec: ComponentSpec{
+			Image:        "foo:bar",
+			WorkloadType: WorkloadTypeServer,
+			Env: []EnvVar{
+				{Name: "DB_PASSWORD", Type: EnvVarTypeSecret, Value: "db/password"},
+				{Name: "MODE", Type: EnvVarTypeConfigMap, Value: "settings/mode"},
+			},
+			EnvFrom: []EnvFromSource{
+				{Type: EnvVarTypeConfigMap, Name: "settings"},
+			},
+		},
+	}
+
+	component.Default()
+	assert.Nil(t, component.validate())
+
+	component.Spec.Env[0].Value = "db/user"
+	component.Spec.Env[1].Value = "settings"
+	component.Spec.EnvFrom[0].Type = EnvVarTypeSecret
+	errs := component.validate()
+	assert.Equal(t, 3, len(errs))
+	assert.Equal(t, ".spec.env[0].value", errs[0].Path)
+	assert.Equal(t, ".spec.env[1].value", errs[1].Path)
+	assert.Equal(t, ".spec.envFrom[0].name", errs[2].Path)
+}
+
+func TestComponentOnlyChangedEnvsValidatedOnUpdate(t *testing.T) {
+	webhookClient = fake.NewFakeClientWithScheme(
+		scheme.Scheme,
+		&v1.Secret{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: "kalm-system", Name: "db"},
+			Data:       map[string][]byte{"password": []byte("pass")},
+		},
+	)
+	defer func() { webhookClient = nil }()
+
+	old := Component{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      "kalm-comp-envs",
+		},
+		Spec: ComponentSpec{
+			Image:        "foo:bar",
+			WorkloadType: WorkloadTypeServer,
+			Env: []EnvVar{
+				// the key is removed from the secret after the env is added
+				{Name: "DB_USER", Type: EnvVarTypeSecret, Value: "db/user"},
+			},
+			EnvFrom: []EnvFromSource{
+				{Type: EnvVarTypeConfigMap, Name: "removed-settings"},
+			},
+		},
+	}
+	old.Default()
+
+	component := old.DeepCopy()
+	component.Spec.Image = "foo:v2"
+	assert.Nil(t, component.validateChange(&old))
+
+	component.Spec.Env = append(component.Spec.Env, EnvVar{Name: "DB_HOST", Type: EnvVarTypeSecret, Value: "db/host"})
+	errs := component.validateChange(&old)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, ".spec.env[1].value", errs[0].Path)
+
+	// creation validates all envs
+	assert.Equal(t, 3, len(component.validate()))
+}
@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations set on a component by whoever changes it, they are recorded in the next revision
+// and removed from the component once recorded.
+const (
+	KalmAnnoChangedBy                 = "kalm-changed-by"
+	KalmAnnoChangedVia                = "kalm-changed-via"
+	KalmAnnoChangedWithAccessToken    = "kalm-changed-with-access-token"
+	KalmAnnoChangedByRollbackRevision = "kalm-changed-by-rollback-revision"
+
+	// Label on namespace, how many revisions are kept for each component in the namespace
+	KalmLabelComponentRevisionHistoryLimit = "kalm-component-revision-history-limit"
+)
+
+var KalmComponentChangeAnnotations = []string{
+	KalmAnnoChangedBy,
+	KalmAnnoChangedVia,
+	KalmAnnoChangedWithAccessToken,
+	KalmAnnoChangedByRollbackRevision,
+}
+
+const DefaultComponentRevisionHistoryLimit = 10
+
+type ComponentChangeVia string
+
+const (
+	ComponentChangeViaAPI      ComponentChangeVia = "api"
+	ComponentChangeViaWebhook  ComponentChangeVia = "webhook"
+	ComponentChangeViaRollback ComponentChangeVia = "rollback"
+	// the change is not made through kalm api, e.g. kubectl
+	ComponentChangeViaUnknown ComponentChangeVia = "unknown"
+)
+
+// ComponentRevisionSpec is an immutable snapshot of a component spec
+type ComponentRevisionSpec struct {
+	// +kubebuilder:validation:MinLength=1
+	ComponentName string `json:"componentName"`
+
+	// Revisions of a component are numbered from 1
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision"`
+
+	// The generation of the component when this revision is recorded
+	ComponentGeneration int64 `json:"componentGeneration"`
+
+	ComponentSpec ComponentSpec `json:"componentSpec"`
+
+	// The user who made the change, or the creator of the access token used
+	// +optional
+	ChangedBy string `json:"changedBy,omitempty"`
+
+	// +optional
+	ChangedVia ComponentChangeVia `json:"changedVia,omitempty"`
+
+	// Name of the access token used to make the change
+	// +optional
+	AccessToken string `json:"accessToken,omitempty"`
+
+	// If the change is a rollback, the revision rolled back to
+	// +optional
+	RollbackToRevision int64 `json:"rollbackToRevision,omitempty"`
+
+	ChangedAt metav1.Time `json:"changedAt"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Component",type="string",JSONPath=".spec.componentName"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".spec.revision"
+// +kubebuilder:printcolumn:name="ChangedBy",type="string",JSONPath=".spec.changedBy"
+// +kubebuilder:printcolumn:name="Via",type="string",JSONPath=".spec.changedVia"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ComponentRevision is the Schema for the componentrevisions API
+type ComponentRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ComponentRevisionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ComponentRevisionList contains a list of ComponentRevision
+type ComponentRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComponentRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComponentRevision{}, &ComponentRevisionList{})
+}
+
+// GetComponentRevisionHistoryLimit reads the history limit from the labels of a namespace
+func GetComponentRevisionHistoryLimit(namespaceLabels map[string]string) int {
+	limit, err := strconv.Atoi(namespaceLabels[KalmLabelComponentRevisionHistoryLimit])
+	if err != nil || limit < 1 {
+		return DefaultComponentRevisionHistoryLimit
+	}
+
+	return limit
+}
+
+func GetComponentRevisionName(componentName string, revision int64) string {
+	return fmt.Sprintf("%s-%d", componentName, revision)
+}
@@ -0,0 +1,72 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var componentrevisionlog = logf.Log.WithName("componentrevision-resource")
+
+func (r *ComponentRevision) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=update,path=/validate-core-kalm-dev-v1alpha1-componentrevision,mutating=false,failurePolicy=fail,groups=core.kalm.dev,resources=componentrevisions,versions=v1alpha1,name=vcomponentrevision.kb.io
+
+var _ webhook.Validator = &ComponentRevision{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ComponentRevision) ValidateCreate() error {
+	componentrevisionlog.Info("validate create", "name", r.Name)
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// Revisions are immutable, only metadata can be changed.
+func (r *ComponentRevision) ValidateUpdate(old runtime.Object) error {
+	componentrevisionlog.Info("validate update", "name", r.Name)
+
+	oldRevision, ok := old.(*ComponentRevision)
+	if !ok {
+		return fmt.Errorf("old object is not a component revision")
+	}
+
+	if !equality.Semantic.DeepEqual(r.Spec, oldRevision.Spec) {
+		return KalmValidateErrorList{
+			{
+				Err:  "component revision is immutable",
+				Path: ".spec",
+			},
+		}
+	}
+
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ComponentRevision) ValidateDelete() error {
+	componentrevisionlog.Info("validate delete", "name", r.Name)
+	return nil
+}
@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestComponentRevisionIsImmutable(t *testing.T) {
+	revision := ComponentRevision{
+		ObjectMeta: ctrl.ObjectMeta{
+			Namespace: "kalm-system",
+			Name:      GetComponentRevisionName("web", 1),
+		},
+		Spec: ComponentRevisionSpec{
+			ComponentName: "web",
+			Revision:      1,
+			ComponentSpec: ComponentSpec{
+				Image: "nginx:1.19",
+			},
+			ChangedBy:  "foo@bar.com",
+			ChangedVia: ComponentChangeViaAPI,
+		},
+	}
+
+	assert.Nil(t, revision.ValidateCreate())
+
+	labeled := revision.DeepCopy()
+	labeled.Labels = map[string]string{KalmLabelComponentKey: "web"}
+	assert.Nil(t, labeled.ValidateUpdate(&revision))
+
+	modified := revision.DeepCopy()
+	modified.Spec.ComponentSpec.Image = "nginx:1.20"
+	assert.NotNil(t, modified.ValidateUpdate(&revision))
+}
+
+func TestGetComponentRevisionHistoryLimit(t *testing.T) {
+	assert.Equal(t, DefaultComponentRevisionHistoryLimit, GetComponentRevisionHistoryLimit(nil))
+	assert.Equal(t, DefaultComponentRevisionHistoryLimit, GetComponentRevisionHistoryLimit(map[string]string{
+		KalmLabelComponentRevisionHistoryLimit: "0",
+	}))
+	assert.Equal(t, 3, GetComponentRevisionHistoryLimit(map[string]string{
+		KalmLabelComponentRevisionHistoryLimit: "3",
+	}))
+}
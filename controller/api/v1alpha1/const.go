@@ -52,8 +52,27 @@ const (
 	ENV_CLOUDFLARE_TOKEN                   = "CLOUDFLARE_TOKEN"
 	ENV_CLOUDFLARE_DOMAIN_TO_ZONEID_CONFIG = "CLOUDFLARE_DOMAIN_TO_ZONEID_CONFIG"
 
+	// which DNSManager provider is used, default to cloudflare
+	ENV_DNS_PROVIDER = "DNS_PROVIDER"
+
+	// rfc2136 dns provider
+	ENV_RFC2136_NAMESERVER     = "RFC2136_NAMESERVER"
+	ENV_RFC2136_ZONES          = "RFC2136_ZONES"
+	ENV_RFC2136_TSIG_KEY_NAME  = "RFC2136_TSIG_KEY_NAME"
+	ENV_RFC2136_TSIG_SECRET    = "RFC2136_TSIG_SECRET"
+	ENV_RFC2136_TSIG_ALGORITHM = "RFC2136_TSIG_ALGORITHM"
+	ENV_RFC2136_TTL            = "RFC2136_TTL"
+
 	ENV_EXTERNAL_DNS_SERVER_IP = "EXTERNAL_DNS_SERVER_IP"
 
+	// days before expiration when https certs are considered expiring, default to 14
+	ENV_CERT_EXPIRY_WARNING_DAYS = "CERT_EXPIRY_WARNING_DAYS"
+
+	// Set to true if ingress gateways run an envoy supporting wildcard descriptors of the local rate limit filter,
+	// which rate limits of http routes are built with. The istio 1.7 installed by kalm supports neither of them,
+	// filters of rate limits are rejected by its envoy, so rate limits are disabled by default.
+	ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED = "HTTP_ROUTE_RATE_LIMIT_ENABLED"
+
 	// auth-proxy
 	ENV_NEED_EXTRA_OAUTH_SCOPE = "NEED_EXTRA_OAUTH_SCOPE"
 )
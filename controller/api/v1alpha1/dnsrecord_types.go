@@ -25,6 +25,7 @@ const (
 	DNSTypeCNAME = "CNAME"
 	DNSTypeA     = "A"
 	DNSTypeNS    = "NS"
+	DNSTypeTXT   = "TXT"
 )
 
 // DNSRecordSpec defines the desired state of DNSRecord
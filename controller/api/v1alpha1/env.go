@@ -1,6 +1,9 @@
 package v1alpha1
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 func GetEnvPhysicalClusterID() string {
 	return os.Getenv(ENV_KALM_PHYSICAL_CLUSTER_ID)
@@ -30,6 +33,47 @@ func GetEnvCloudflareDomainToZoneIDConfig() string {
 	return os.Getenv(ENV_CLOUDFLARE_DOMAIN_TO_ZONEID_CONFIG)
 }
 
+func GetEnvDNSProvider() string {
+	return os.Getenv(ENV_DNS_PROVIDER)
+}
+
+func GetEnvRFC2136Nameserver() string {
+	return os.Getenv(ENV_RFC2136_NAMESERVER)
+}
+
+func GetEnvRFC2136Zones() string {
+	return os.Getenv(ENV_RFC2136_ZONES)
+}
+
+func GetEnvRFC2136TSIGKeyName() string {
+	return os.Getenv(ENV_RFC2136_TSIG_KEY_NAME)
+}
+
+func GetEnvRFC2136TSIGSecret() string {
+	return os.Getenv(ENV_RFC2136_TSIG_SECRET)
+}
+
+func GetEnvRFC2136TSIGAlgorithm() string {
+	return os.Getenv(ENV_RFC2136_TSIG_ALGORITHM)
+}
+
+func GetEnvRFC2136TTL() string {
+	return os.Getenv(ENV_RFC2136_TTL)
+}
+
 func GetEnvExternalDNSServerIP() string {
 	return os.Getenv(ENV_EXTERNAL_DNS_SERVER_IP)
 }
+
+func GetEnvCertExpiryWarningDays() int {
+	days, err := strconv.Atoi(os.Getenv(ENV_CERT_EXPIRY_WARNING_DAYS))
+	if err != nil || days <= 0 {
+		return DefaultCertExpiryWarningDays
+	}
+
+	return days
+}
+
+func IsHttpRouteRateLimitEnabled() bool {
+	return os.Getenv(ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED) == "true"
+}
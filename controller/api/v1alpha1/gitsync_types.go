@@ -0,0 +1,126 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// objects applied by a GitSync are labeled with its name
+	GitSyncLabelName = "kalm-gitsync"
+
+	DefaultGitSyncIntervalSeconds = 300
+)
+
+// GitSyncSpec defines the desired state of GitSync
+type GitSyncSpec struct {
+	// URL of the git repo, file:// URLs and local paths of bare repos are supported as well
+	// +kubebuilder:validation:MinLength=1
+	Repo string `json:"repo"`
+
+	// Branch or tag to sync, default to the HEAD of the repo
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Directory of the manifests in the repo, default to the root of the repo
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// How often the repo is synced, default to 300
+	// +kubebuilder:validation:Minimum=10
+	// +optional
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+
+	// Delete objects which are removed from the repo
+	// +optional
+	Prune bool `json:"prune,omitempty"`
+
+	// Stop syncing, objects applied before are left as they are
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+type GitSyncResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// GitSyncStatus defines the observed state of GitSync
+type GitSyncStatus struct {
+	Ready bool `json:"ready"`
+
+	// Error of the last sync
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	LastSyncedCommit string `json:"lastSyncedCommit,omitempty"`
+
+	// +optional
+	LastSyncTimestamp int64 `json:"lastSyncTimestamp,omitempty"`
+
+	// Objects applied from the repo in the last sync, used to find objects to prune
+	// +optional
+	Resources []GitSyncResource `json:"resources,omitempty"`
+
+	// Objects changed or deleted outside of the repo, found in the last sync before they were applied again
+	// +optional
+	Drifted []GitSyncResource `json:"drifted,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Repo",type="string",JSONPath=".spec.repo"
+// +kubebuilder:printcolumn:name="Path",type="string",JSONPath=".spec.path"
+// +kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.lastSyncedCommit"
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// GitSync is the Schema for the gitsyncs API
+type GitSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitSyncSpec   `json:"spec,omitempty"`
+	Status GitSyncStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GitSyncList contains a list of GitSync
+type GitSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GitSync `json:"items"`
+}
+
+func (s *GitSync) GetInterval() time.Duration {
+	if s.Spec.IntervalSeconds <= 0 {
+		return DefaultGitSyncIntervalSeconds * time.Second
+	}
+
+	return time.Duration(s.Spec.IntervalSeconds) * time.Second
+}
+
+func init() {
+	SchemeBuilder.Register(&GitSync{}, &GitSyncList{})
+}
@@ -0,0 +1,123 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var gitsynclog = logf.Log.WithName("gitsync-resource")
+
+func (r *GitSync) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kalm-dev-v1alpha1-gitsync,mutating=false,failurePolicy=fail,groups=core.kalm.dev,resources=gitsyncs,versions=v1alpha1,name=vgitsync.kb.io
+
+var _ webhook.Validator = &GitSync{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *GitSync) ValidateCreate() error {
+	gitsynclog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *GitSync) ValidateUpdate(old runtime.Object) error {
+	gitsynclog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *GitSync) ValidateDelete() error {
+	return nil
+}
+
+var validGitRepoSchemes = map[string]bool{
+	"file":  true,
+	"http":  true,
+	"https": true,
+	"ssh":   true,
+	"git":   true,
+}
+
+func (r *GitSync) validate() error {
+	var rst KalmValidateErrorList
+
+	if !isValidGitRepo(r.Spec.Repo) {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be an url of file, http(s), ssh or git scheme, or an absolute path",
+			Path: "spec.repo",
+		})
+	}
+
+	// git options can't be passed in as refs
+	if strings.HasPrefix(r.Spec.Ref, "-") {
+		rst = append(rst, KalmValidateError{
+			Err:  "invalid ref",
+			Path: "spec.ref",
+		})
+	}
+
+	if r.Spec.Path != "" {
+		if cleaned := path.Clean(r.Spec.Path); path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be a relative path in the repo",
+				Path: "spec.path",
+			})
+		}
+	}
+
+	if r.Spec.IntervalSeconds != 0 && r.Spec.IntervalSeconds < 10 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be at least 10",
+			Path: "spec.intervalSeconds",
+		})
+	}
+
+	if len(rst) == 0 {
+		return nil
+	}
+
+	return rst
+}
+
+func isValidGitRepo(repo string) bool {
+	if strings.HasPrefix(repo, "/") {
+		return true
+	}
+
+	u, err := url.Parse(repo)
+	if err != nil || !validGitRepoSchemes[u.Scheme] {
+		return false
+	}
+
+	if u.Scheme == "file" {
+		return u.Path != ""
+	}
+
+	return u.Host != ""
+}
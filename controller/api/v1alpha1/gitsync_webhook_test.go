@@ -0,0 +1,47 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestGitSync_Validate(t *testing.T) {
+	gitSync := GitSync{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name: "test-name",
+		},
+		Spec: GitSyncSpec{
+			Repo: "https://github.com/kalmhq/kalm.git",
+			Path: "deploy/prod",
+		},
+	}
+
+	assert.Nil(t, gitSync.validate())
+
+	for _, repo := range []string{"file:///srv/git/apps.git", "/srv/git/apps.git", "ssh://git@github.com/kalmhq/kalm.git"} {
+		gitSync.Spec.Repo = repo
+		assert.Nil(t, gitSync.validate(), repo)
+	}
+
+	for _, repo := range []string{"", "apps.git", "ftp://example.com/apps.git", "https:///apps.git"} {
+		gitSync.Spec.Repo = repo
+		assert.NotNil(t, gitSync.validate(), repo)
+	}
+
+	gitSync.Spec.Repo = "/srv/git/apps.git"
+
+	for _, p := range []string{"/etc", "..", "../other", "deploy/../../other"} {
+		gitSync.Spec.Path = p
+		assert.NotNil(t, gitSync.validate(), p)
+	}
+
+	gitSync.Spec.Path = ""
+	gitSync.Spec.Ref = "--upload-pack=touch"
+	assert.NotNil(t, gitSync.validate())
+
+	gitSync.Spec.Ref = "main"
+	gitSync.Spec.IntervalSeconds = 5
+	assert.NotNil(t, gitSync.validate())
+}
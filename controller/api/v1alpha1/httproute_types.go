@@ -16,13 +16,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"reflect"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// +kubebuilder:validation:Enum=query;header
+// +kubebuilder:validation:Enum=query;header;cookie;sourceIP;authority
 type HttpRouteConditionType string
 
-// +kubebuilder:validation:Enum=equal;withPrefix;matchRegexp
+// +kubebuilder:validation:Enum=equal;withPrefix;matchRegexp;notEqual;withoutPrefix;notMatchRegexp
 type HttpRouteConditionOperator string
 
 //type HttpRouteCertValue string
@@ -30,31 +32,65 @@ type HttpRouteConditionOperator string
 const (
 	HttpRouteConditionTypeQuery  HttpRouteConditionType = "query"
 	HttpRouteConditionTypeHeader HttpRouteConditionType = "header"
+	HttpRouteConditionTypeCookie HttpRouteConditionType = "cookie"
+
+	// value is an ip or an ipv4 cidr, only equal and notEqual operators are supported.
+	// Only external clients can be matched, the ingress gateway doesn't know the source ip of internal ones,
+	// and it must see the source ips of clients, which the istio control plane installed by the operator keeps.
+	HttpRouteConditionTypeSourceIP HttpRouteConditionType = "sourceIP"
+
+	// host of the request, no name is required
+	HttpRouteConditionTypeAuthority HttpRouteConditionType = "authority"
 
-	HRCOEqual       HttpRouteConditionOperator = "equal"
-	HRCOWithPrefix  HttpRouteConditionOperator = "withPrefix"
-	HRCOMatchRegexp HttpRouteConditionOperator = "matchRegexp"
-	//HRCONotEqual       HttpRouteConditionOperator = "notEqual"
-	//HRCOWithoutPrefix  HttpRouteConditionOperator = "withoutPrefix"
-	//HRCONotMatchRegexp HttpRouteConditionOperator = "notMatchRegexp"
+	HRCOEqual          HttpRouteConditionOperator = "equal"
+	HRCOWithPrefix     HttpRouteConditionOperator = "withPrefix"
+	HRCOMatchRegexp    HttpRouteConditionOperator = "matchRegexp"
+	HRCONotEqual       HttpRouteConditionOperator = "notEqual"
+	HRCOWithoutPrefix  HttpRouteConditionOperator = "withoutPrefix"
+	HRCONotMatchRegexp HttpRouteConditionOperator = "notMatchRegexp"
 
 	//HttpCertAuto    HttpRouteCertValue = "Auto"
 	//HttpCertDefault HttpRouteCertValue = "Default"
 )
 
+// IsNegative reports whether the operator matches requests the positive one doesn't
+func (o HttpRouteConditionOperator) IsNegative() bool {
+	return o == HRCONotEqual || o == HRCOWithoutPrefix || o == HRCONotMatchRegexp
+}
+
+// Positive returns the operator without negation
+func (o HttpRouteConditionOperator) Positive() HttpRouteConditionOperator {
+	switch o {
+	case HRCONotEqual:
+		return HRCOEqual
+	case HRCOWithoutPrefix:
+		return HRCOWithPrefix
+	case HRCONotMatchRegexp:
+		return HRCOMatchRegexp
+	}
+
+	return o
+}
+
 type HttpRouteCondition struct {
-	// +kubebuilder:validation:Enum=query;header
+	// +kubebuilder:validation:Enum=query;header;cookie;sourceIP;authority
 	Type HttpRouteConditionType `json:"type"`
 
-	// +kubebuilder:validation:MinLength=1
-	Name string `json:"name"`
+	// name of the query, header or cookie
+	Name string `json:"name,omitempty"`
 
 	Value string `json:"value"`
 
-	// +kubebuilder:validation:Enum=equal;withPrefix;matchRegexp
+	// +kubebuilder:validation:Enum=equal;withPrefix;matchRegexp;notEqual;withoutPrefix;notMatchRegexp
 	Operator HttpRouteConditionOperator `json:"operator"`
 }
 
+// HttpRouteConditionGroup matches if all of its conditions match
+type HttpRouteConditionGroup struct {
+	// +kubebuilder:validation:MinItems=1
+	Conditions []HttpRouteCondition `json:"conditions"`
+}
+
 type HttpRouteDestination struct {
 	// +kubebuilder:validation:MinLength=1
 	Host string `json:"host"`
@@ -63,11 +99,63 @@ type HttpRouteDestination struct {
 }
 
 type HttpRouteRetries struct {
+	// 0 disables retries
 	// +kubebuilder:validation:Minimum=0
 	Attempts int `json:"attempts"`
+
+	// 0 means each try can use up the whole timeout of the route
+	// +kubebuilder:validation:Minimum=0
+	PerTtyTimeoutSeconds int `json:"perTtyTimeoutSeconds,omitempty"`
+
+	// for sub-second per try timeouts, can't be used along with perTtyTimeoutSeconds
 	// +kubebuilder:validation:Minimum=1
-	PerTtyTimeoutSeconds int      `json:"perTtyTimeoutSeconds"`
-	RetryOn              []string `json:"retryOn"`
+	PerTryTimeoutMilliseconds *int `json:"perTryTimeoutMilliseconds,omitempty"`
+
+	// envoy retry conditions, such as 5xx, gateway-error, connect-failure, retriable-4xx, refused-stream
+	RetryOn []string `json:"retryOn"`
+
+	Budget *HttpRouteRetryBudget `json:"budget,omitempty"`
+}
+
+// KalmAnnoHttpRouteTimeoutsMigrated marks routes checked for the timeout and retries saved by old dashboards.
+// Old dashboards saved a 5s timeout and 3 retries as defaults, which were never applied, so they are removed once
+// from routes created before timeouts and retries were applied. Routes created since then are marked by the webhook.
+const KalmAnnoHttpRouteTimeoutsMigrated = "kalm-timeouts-migrated"
+
+// RemoveLegacyDefaultTimeouts removes the timeout and retries which are the defaults saved by old dashboards,
+// and returns the paths of the removed fields.
+func (spec *HttpRouteSpec) RemoveLegacyDefaultTimeouts() (removed []string) {
+	if spec.Timeout != nil && *spec.Timeout == 5 && spec.TimeoutMilliseconds == nil {
+		spec.Timeout = nil
+		removed = append(removed, "spec.timeout")
+	}
+
+	retries := spec.Retries
+
+	if retries != nil &&
+		retries.Attempts == 3 &&
+		retries.PerTtyTimeoutSeconds == 2 &&
+		retries.PerTryTimeoutMilliseconds == nil &&
+		retries.Budget == nil &&
+		reflect.DeepEqual(retries.RetryOn, []string{"gateway-error", "connect-failure", "refused-stream"}) {
+		spec.Retries = nil
+		removed = append(removed, "spec.retries")
+	}
+
+	return removed
+}
+
+// HttpRouteRetryBudget limits concurrent retries to destinations of the route, so retries can't make an overloaded upstream worse.
+// It's applied to the destination clusters on the ingress gateway, routes sharing a destination should use the same budget.
+type HttpRouteRetryBudget struct {
+	// max percentage of active requests to the destination that can be retries
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	BudgetPercent int `json:"budgetPercent"`
+
+	// retries are always allowed if there are fewer active retries than it, envoy defaults it to 3
+	// +kubebuilder:validation:Minimum=0
+	MinRetryConcurrency *int `json:"minRetryConcurrency,omitempty"`
 }
 
 type HttpRouteMirror struct {
@@ -97,6 +185,111 @@ type HttpRouteCORS struct {
 	MaxAgeSeconds    *int     `json:"maxAgeSeconds,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=second;minute
+type HttpRouteRateLimitUnit string
+
+const (
+	HttpRouteRateLimitUnitSecond HttpRouteRateLimitUnit = "second"
+	HttpRouteRateLimitUnitMinute HttpRouteRateLimitUnit = "minute"
+)
+
+// +kubebuilder:validation:Enum=clientIP;header;jwtClaim
+type HttpRouteRateLimitKeyType string
+
+const (
+	HttpRouteRateLimitKeyTypeClientIP HttpRouteRateLimitKeyType = "clientIP"
+	HttpRouteRateLimitKeyTypeHeader   HttpRouteRateLimitKeyType = "header"
+	HttpRouteRateLimitKeyTypeJWTClaim HttpRouteRateLimitKeyType = "jwtClaim"
+)
+
+// HttpRouteRateLimit is enforced by each ingress gateway replica separately.
+// It's only supported if ingress gateways run an envoy with the local rate limit filter and its wildcard descriptors,
+// which the istio 1.7 installed by kalm doesn't have, see ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED.
+type HttpRouteRateLimit struct {
+	// +kubebuilder:validation:Minimum=1
+	Requests int                    `json:"requests"`
+	Unit     HttpRouteRateLimitUnit `json:"unit"`
+
+	// extra requests allowed on top of requests in a short burst
+	// +kubebuilder:validation:Minimum=0
+	Burst int `json:"burst,omitempty"`
+
+	// each key has its own limit, the route is limited as a whole if it's nil
+	Key *HttpRouteRateLimitKey `json:"key,omitempty"`
+
+	// customize the 429 responses
+	Response *HttpRouteRateLimitResponse `json:"response,omitempty"`
+}
+
+type HttpRouteRateLimitKey struct {
+	Type HttpRouteRateLimitKeyType `json:"type"`
+
+	// name of the header or the jwt claim
+	Name string `json:"name,omitempty"`
+
+	// issuer of the jwt, claims are read from the payload verified by the RequestAuthentication of the issuer
+	Issuer string `json:"issuer,omitempty"`
+}
+
+type HttpRouteRateLimitResponse struct {
+	Body    string            `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type HttpRouteRedirect struct {
+	// the request scheme is kept if it's blank
+	// +kubebuilder:validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+
+	// the request host is kept if it's blank, one of host and path is required
+	Host string `json:"host,omitempty"`
+
+	// replaces the whole path, the request path is kept if it's blank
+	Path string `json:"path,omitempty"`
+
+	// 301 if it's not set
+	// +kubebuilder:validation:Enum=301;302;303;307;308
+	StatusCode int `json:"statusCode,omitempty"`
+}
+
+type HttpRouteDirectResponse struct {
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	StatusCode int `json:"statusCode"`
+
+	// envoy limits the body to 4KB
+	// +kubebuilder:validation:MaxLength=4096
+	Body string `json:"body,omitempty"`
+
+	// text/plain if it's not set
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type HttpRouteRewrite struct {
+	// replaces the matched path prefix, can't be used with stripPath
+	Prefix string `json:"prefix,omitempty"`
+
+	// replaces the host of requests sent to destinations
+	Host string `json:"host,omitempty"`
+}
+
+type HttpRouteHeaderOperations struct {
+	// overwrite the headers
+	Set map[string]string `json:"set,omitempty"`
+
+	// append values to the headers
+	Add map[string]string `json:"add,omitempty"`
+
+	Remove []string `json:"remove,omitempty"`
+}
+
+// HttpRouteHeaders manipulates headers of requests sent to destinations and responses of them.
+// Headers used by kalm sso can't be manipulated.
+type HttpRouteHeaders struct {
+	Request  *HttpRouteHeaderOperations `json:"request,omitempty"`
+	Response *HttpRouteHeaderOperations `json:"response,omitempty"`
+}
+
 // +kubebuilder:validation:Enum=GET;HEAD;POST;PUT;PATCH;DELETE;OPTIONS;TRACE;CONNECT
 type AllowMethod string
 
@@ -124,20 +317,40 @@ type HttpRouteSpec struct {
 
 	StripPath bool `json:"stripPath,omitempty"`
 
+	Rewrite *HttpRouteRewrite `json:"rewrite,omitempty"`
+	Headers *HttpRouteHeaders `json:"headers,omitempty"`
+
 	Conditions []HttpRouteCondition `json:"conditions,omitempty"`
 
-	// +kubebuilder:validation:MinItems=1
-	Destinations []HttpRouteDestination `json:"destinations"`
+	// the route matches if any of the groups matches, conditions above are required by all groups
+	ConditionGroups []HttpRouteConditionGroup `json:"conditionGroups,omitempty"`
+
+	// required unless the route responds with a redirect or a direct response
+	Destinations []HttpRouteDestination `json:"destinations,omitempty"`
+
+	// respond with a redirect instead of forwarding requests to destinations
+	Redirect *HttpRouteRedirect `json:"redirect,omitempty"`
+
+	// respond with a fixed response instead of forwarding requests to destinations
+	DirectResponse *HttpRouteDirectResponse `json:"directResponse,omitempty"`
 
 	HttpRedirectToHttps bool `json:"httpRedirectToHttps,omitempty"`
 
-	Timeout *int              `json:"timeout,omitempty"`
+	// timeout of a request in seconds, including all retries
+	Timeout *int `json:"timeout,omitempty"`
+
+	// for sub-second timeouts, can't be used along with timeout
+	// +kubebuilder:validation:Minimum=1
+	TimeoutMilliseconds *int `json:"timeoutMilliseconds,omitempty"`
+
 	Retries *HttpRouteRetries `json:"retries,omitempty"`
 
 	Mirror *HttpRouteMirror `json:"mirror,omitempty"`
 	Fault  *HttpRouteFault  `json:"fault,omitempty"`
 	Delay  *HttpRouteDelay  `json:"delay,omitempty"`
 	CORS   *HttpRouteCORS   `json:"cors,omitempty"`
+
+	RateLimit *HttpRouteRateLimit `json:"rateLimit,omitempty"`
 }
 
 type HttpRouteDestinationStatus struct {
@@ -146,10 +359,19 @@ type HttpRouteDestinationStatus struct {
 	Error           string `json:"error,omitempty"`
 }
 
+// HttpRouteEnvoyFilterStatus is the status of an envoy filter implementing features istio doesn't support.
+// Ingress gateways apply saved filters asynchronously, a direct response route responds 503 before that.
+type HttpRouteEnvoyFilterStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
 // HttpRouteStatus defines the observed state of HttpRoute
 type HttpRouteStatus struct {
 	HostCertifications map[string]string            `json:"hostCertifications,omitempty"`
 	DestinationsStatus []HttpRouteDestinationStatus `json:"destinationsStatus"`
+	EnvoyFilters       []HttpRouteEnvoyFilterStatus `json:"envoyFilters,omitempty"`
 }
 
 // +kubebuilder:object:root=true
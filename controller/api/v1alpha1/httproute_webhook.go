@@ -16,12 +16,18 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/kalmhq/kalm/controller/validation"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -43,6 +49,16 @@ var _ webhook.Defaulter = &HttpRoute{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *HttpRoute) Default() {
 	httproutelog.Info("default", "name", r.Name)
+
+	// the creation timestamp is only set after mutating webhooks when a route is created,
+	// timeout and retries of new routes are applied as they are.
+	if r.CreationTimestamp.IsZero() {
+		if r.Annotations == nil {
+			r.Annotations = make(map[string]string)
+		}
+
+		r.Annotations[KalmAnnoHttpRouteTimeoutsMigrated] = "true"
+	}
 }
 
 // +kubebuilder:webhook:verbs=create;update;delete,path=/validate-core-kalm-dev-v1alpha1-httproute,mutating=false,failurePolicy=fail,groups=core.kalm.dev,resources=httproutes,versions=v1alpha1,name=vhttproute.kb.io
@@ -57,6 +73,10 @@ func (r *HttpRoute) ValidateCreate() error {
 		return err
 	}
 
+	if errList := r.validateApplicationQuota(nil); len(errList) > 0 {
+		return errList
+	}
+
 	return nil
 }
 
@@ -64,7 +84,17 @@ func (r *HttpRoute) ValidateCreate() error {
 func (r *HttpRoute) ValidateUpdate(old runtime.Object) error {
 	httproutelog.Info("validate update", "name", r.Name)
 
-	return r.validate()
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	oldRoute, _ := old.(*HttpRoute)
+
+	if errList := r.validateApplicationQuota(oldRoute); len(errList) > 0 {
+		return errList
+	}
+
+	return nil
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -94,6 +124,8 @@ func (r *HttpRoute) validate() error {
 		}
 	}
 
+	rst = append(rst, r.validateResponseActions()...)
+
 	for i, dest := range r.Spec.Destinations {
 		if !isValidDestinationHost(dest.Host) {
 			rst = append(rst, KalmValidateError{
@@ -113,6 +145,25 @@ func (r *HttpRoute) validate() error {
 		}
 	}
 
+	if r.Spec.TimeoutMilliseconds != nil && *r.Spec.TimeoutMilliseconds <= 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: "spec.timeoutMilliseconds",
+		})
+	}
+
+	if timeout != nil && r.Spec.TimeoutMilliseconds != nil {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be used along with timeout",
+			Path: "spec.timeoutMilliseconds",
+		})
+	}
+
+	rst = append(rst, r.validateRewrite()...)
+	rst = append(rst, r.validateConditions()...)
+	rst = append(rst, r.validateRetries()...)
+	rst = append(rst, r.validateRateLimit()...)
+
 	mirror := r.Spec.Mirror
 	if mirror != nil {
 		mirrorDestinationHost := mirror.Destination.Host
@@ -131,6 +182,680 @@ func (r *HttpRoute) validate() error {
 	return rst
 }
 
+// isRedirectLoop reports whether the redirect target, which keeps the request host and scheme, matches the route again.
+// Conditions are not considered, a redirect from a path to itself depending on headers is rejected too.
+func (r *HttpRoute) isRedirectLoop() bool {
+	redirect := r.Spec.Redirect
+
+	if redirect.Host != "" || redirect.Path == "" {
+		return false
+	}
+
+	if redirect.Scheme != "" {
+		sameScheme := false
+
+		for _, scheme := range r.Spec.Schemes {
+			if string(scheme) == redirect.Scheme {
+				sameScheme = true
+				break
+			}
+		}
+
+		if !sameScheme {
+			return false
+		}
+	}
+
+	// paths of routes are matched by prefix
+	for _, path := range r.Spec.Paths {
+		if strings.HasPrefix(redirect.Path, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// token of RFC 7230
+var httpHeaderNameRegexp = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// envoy retry conditions, https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/router_filter#x-envoy-retry-on
+var validRetryOnConditions = map[string]bool{
+	"5xx":                    true,
+	"gateway-error":          true,
+	"reset":                  true,
+	"connect-failure":        true,
+	"envoy-ratelimited":      true,
+	"retriable-4xx":          true,
+	"refused-stream":         true,
+	"retriable-status-codes": true,
+	"retriable-headers":      true,
+	"cancelled":              true,
+	"deadline-exceeded":      true,
+	"internal":               true,
+	"resource-exhausted":     true,
+	"unavailable":            true,
+}
+
+func (r *HttpRoute) validateRetries() (rst KalmValidateErrorList) {
+	retries := r.Spec.Retries
+
+	if retries == nil {
+		return nil
+	}
+
+	if retries.Attempts < 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be negative",
+			Path: "spec.retries.attempts",
+		})
+	}
+
+	if retries.PerTtyTimeoutSeconds < 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be negative",
+			Path: "spec.retries.perTtyTimeoutSeconds",
+		})
+	}
+
+	if retries.PerTryTimeoutMilliseconds != nil && *retries.PerTryTimeoutMilliseconds <= 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: "spec.retries.perTryTimeoutMilliseconds",
+		})
+	}
+
+	if retries.PerTtyTimeoutSeconds > 0 && retries.PerTryTimeoutMilliseconds != nil {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be used along with perTtyTimeoutSeconds",
+			Path: "spec.retries.perTryTimeoutMilliseconds",
+		})
+	}
+
+	for i, condition := range retries.RetryOn {
+		// http status codes are also allowed
+		if _, err := strconv.Atoi(condition); err == nil {
+			continue
+		}
+
+		if !validRetryOnConditions[condition] {
+			rst = append(rst, KalmValidateError{
+				Err:  "unknown retry condition: " + condition,
+				Path: fmt.Sprintf("spec.retries.retryOn[%d]", i),
+			})
+		}
+	}
+
+	if budget := retries.Budget; budget != nil {
+		if budget.BudgetPercent < 0 || budget.BudgetPercent > 100 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be between 0 and 100",
+				Path: "spec.retries.budget.budgetPercent",
+			})
+		}
+
+		if budget.MinRetryConcurrency != nil && *budget.MinRetryConcurrency < 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "can't be negative",
+				Path: "spec.retries.budget.minRetryConcurrency",
+			})
+		}
+	}
+
+	return rst
+}
+
+// the envoy default of max_direct_response_body_size_bytes
+const maxDirectResponseBodySize = 4096
+
+func (r *HttpRoute) validateResponseActions() (rst KalmValidateErrorList) {
+	redirect := r.Spec.Redirect
+	directResponse := r.Spec.DirectResponse
+
+	if redirect == nil && directResponse == nil {
+		if len(r.Spec.Destinations) == 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should have at least one destination",
+				Path: "spec.destinations",
+			})
+		}
+
+		return rst
+	}
+
+	if redirect != nil && directResponse != nil {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be used with redirect",
+			Path: "spec.directResponse",
+		})
+	}
+
+	if len(r.Spec.Destinations) > 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be used with redirect or directResponse",
+			Path: "spec.destinations",
+		})
+	}
+
+	// requests are not forwarded, options of forwarding are never applied
+	forwardingOptions := []struct {
+		path string
+		set  bool
+	}{
+		{"spec.timeout", r.Spec.Timeout != nil},
+		{"spec.timeoutMilliseconds", r.Spec.TimeoutMilliseconds != nil},
+		{"spec.retries", r.Spec.Retries != nil},
+		{"spec.rewrite", r.Spec.Rewrite != nil},
+		{"spec.stripPath", r.Spec.StripPath},
+		{"spec.mirror", r.Spec.Mirror != nil},
+		{"spec.headers", r.Spec.Headers != nil},
+		{"spec.httpRedirectToHttps", r.Spec.HttpRedirectToHttps},
+	}
+
+	for _, option := range forwardingOptions {
+		if option.set {
+			rst = append(rst, KalmValidateError{
+				Err:  "can't be used with redirect or directResponse",
+				Path: option.path,
+			})
+		}
+	}
+
+	if redirect != nil {
+		// istio requires a redirect to have a host or a path, use httpRedirectToHttps for scheme only redirects
+		if redirect.Host == "" && redirect.Path == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "host or path is required",
+				Path: "spec.redirect",
+			})
+		}
+
+		if redirect.Scheme != "" && redirect.Scheme != "http" && redirect.Scheme != "https" {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be http or https",
+				Path: "spec.redirect.scheme",
+			})
+		}
+
+		if redirect.Host != "" && (!isValidRouteHost(stripIfHasPort(redirect.Host)) || strings.Contains(redirect.Host, "*")) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid host:" + redirect.Host,
+				Path: "spec.redirect.host",
+			})
+		}
+
+		if redirect.Path != "" && !isValidPath(redirect.Path) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid path, should start with: /",
+				Path: "spec.redirect.path",
+			})
+		} else if r.isRedirectLoop() {
+			rst = append(rst, KalmValidateError{
+				Err:  "redirect loop, the target is matched by the route itself",
+				Path: "spec.redirect.path",
+			})
+		}
+
+		switch redirect.StatusCode {
+		case 0, 301, 302, 303, 307, 308:
+		default:
+			rst = append(rst, KalmValidateError{
+				Err:  "should be one of 301, 302, 303, 307 and 308",
+				Path: "spec.redirect.statusCode",
+			})
+		}
+	}
+
+	if directResponse != nil {
+		if directResponse.StatusCode < 200 || directResponse.StatusCode > 599 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be between 200 and 599",
+				Path: "spec.directResponse.statusCode",
+			})
+		}
+
+		if len(directResponse.Body) > maxDirectResponseBodySize {
+			rst = append(rst, KalmValidateError{
+				Err:  fmt.Sprintf("can't be larger than %d bytes", maxDirectResponseBodySize),
+				Path: "spec.directResponse.body",
+			})
+		}
+	}
+
+	return rst
+}
+
+func (r *HttpRoute) validateRewrite() (rst KalmValidateErrorList) {
+	if rewrite := r.Spec.Rewrite; rewrite != nil {
+		if rewrite.Prefix != "" {
+			if r.Spec.StripPath {
+				rst = append(rst, KalmValidateError{
+					Err:  "can't be used with stripPath",
+					Path: "spec.rewrite.prefix",
+				})
+			}
+
+			if !isValidPath(rewrite.Prefix) {
+				rst = append(rst, KalmValidateError{
+					Err:  "invalid path, should start with: /",
+					Path: "spec.rewrite.prefix",
+				})
+			}
+		}
+
+		if rewrite.Host != "" && (!isValidRouteHost(stripIfHasPort(rewrite.Host)) || strings.Contains(rewrite.Host, "*")) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid host:" + rewrite.Host,
+				Path: "spec.rewrite.host",
+			})
+		}
+	}
+
+	if headers := r.Spec.Headers; headers != nil {
+		rst = append(rst, validateHeaderOperations(headers.Request, "spec.headers.request")...)
+		rst = append(rst, validateHeaderOperations(headers.Response, "spec.headers.response")...)
+	}
+
+	return rst
+}
+
+func validateHeaderOperations(operations *HttpRouteHeaderOperations, path string) (rst KalmValidateErrorList) {
+	if operations == nil {
+		return nil
+	}
+
+	invalidName := func(name, path string) {
+		rst = append(rst, KalmValidateError{
+			Err:  "invalid header name: " + name,
+			Path: path,
+		})
+	}
+
+	for name := range operations.Set {
+		if !httpHeaderNameRegexp.MatchString(name) {
+			invalidName(name, path+".set")
+		}
+	}
+
+	for name := range operations.Add {
+		if !httpHeaderNameRegexp.MatchString(name) {
+			invalidName(name, path+".add")
+		}
+	}
+
+	for i, name := range operations.Remove {
+		if !httpHeaderNameRegexp.MatchString(name) {
+			invalidName(name, fmt.Sprintf("%s.remove[%d]", path, i))
+		}
+	}
+
+	return rst
+}
+
+func (r *HttpRoute) validateConditions() (rst KalmValidateErrorList) {
+	for i, condition := range r.Spec.Conditions {
+		rst = append(rst, validateCondition(condition, fmt.Sprintf("spec.conditions[%d]", i))...)
+	}
+
+	rst = append(rst, validateConditionsConflict(r.Spec.Conditions, "spec.conditions")...)
+
+	for i, group := range r.Spec.ConditionGroups {
+		path := fmt.Sprintf("spec.conditionGroups[%d].conditions", i)
+
+		if len(group.Conditions) == 0 {
+			rst = append(rst, KalmValidateError{
+				Err:  "should have at least one condition",
+				Path: path,
+			})
+		}
+
+		for j, condition := range group.Conditions {
+			rst = append(rst, validateCondition(condition, fmt.Sprintf("%s[%d]", path, j))...)
+		}
+
+		conditions := make([]HttpRouteCondition, 0, len(r.Spec.Conditions)+len(group.Conditions))
+		conditions = append(conditions, r.Spec.Conditions...)
+		conditions = append(conditions, group.Conditions...)
+		rst = append(rst, validateConditionsConflict(conditions, path)...)
+	}
+
+	return rst
+}
+
+func validateCondition(condition HttpRouteCondition, path string) (rst KalmValidateErrorList) {
+	switch condition.Type {
+	case HttpRouteConditionTypeQuery, HttpRouteConditionTypeHeader, HttpRouteConditionTypeCookie:
+		if condition.Name == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "is required for " + string(condition.Type) + " condition",
+				Path: path + ".name",
+			})
+		} else if condition.Type != HttpRouteConditionTypeQuery && !httpHeaderNameRegexp.MatchString(condition.Name) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid " + string(condition.Type) + " name: " + condition.Name,
+				Path: path + ".name",
+			})
+		}
+	case HttpRouteConditionTypeSourceIP, HttpRouteConditionTypeAuthority:
+	default:
+		rst = append(rst, KalmValidateError{
+			Err:  "unknown condition type: " + string(condition.Type),
+			Path: path + ".type",
+		})
+	}
+
+	switch condition.Operator.Positive() {
+	case HRCOEqual, HRCOWithPrefix:
+	case HRCOMatchRegexp:
+		if _, err := regexp.Compile(condition.Value); err != nil {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid regexp: " + err.Error(),
+				Path: path + ".value",
+			})
+		}
+	default:
+		rst = append(rst, KalmValidateError{
+			Err:  "unknown operator: " + string(condition.Operator),
+			Path: path + ".operator",
+		})
+	}
+
+	// istio can only negate header matches
+	if condition.Type == HttpRouteConditionTypeQuery && condition.Operator.IsNegative() {
+		rst = append(rst, KalmValidateError{
+			Err:  "negative operators are not supported for query conditions",
+			Path: path + ".operator",
+		})
+	}
+
+	if condition.Type == HttpRouteConditionTypeSourceIP {
+		if condition.Operator.Positive() != HRCOEqual {
+			rst = append(rst, KalmValidateError{
+				Err:  "only equal and notEqual operators are supported for sourceIP conditions",
+				Path: path + ".operator",
+			})
+		}
+
+		if !isValidSourceIP(condition.Value) {
+			rst = append(rst, KalmValidateError{
+				Err:  "should be an ip or an ipv4 cidr",
+				Path: path + ".value",
+			})
+		} else if isInternalSourceIP(condition.Value) {
+			// Source ips of external clients are kept by the Local externalTrafficPolicy of the ingress gateway service,
+			// or the x-forwarded-for header trusted by numTrustedProxies, see sourceIPHeader of the http route controller.
+			rst = append(rst, KalmValidateError{
+				Err:  "internal addresses can't be matched, the ingress gateway only knows source ips of external clients",
+				Path: path + ".value",
+			})
+		}
+	}
+
+	return rst
+}
+
+// Addresses envoy treats as internal by default, the external address header is not set for them
+var internalIPNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "127.0.0.0/8", "fd00::/8", "::1/128"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		nets = append(nets, ipNet)
+	}
+
+	return nets
+}()
+
+// isInternalSourceIP reports whether the ip or the cidr overlaps any internal network
+func isInternalSourceIP(value string) bool {
+	var ipNet *net.IPNet
+
+	if ip := net.ParseIP(value); ip != nil {
+		ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+	} else {
+		_, ipNet, _ = net.ParseCIDR(value)
+	}
+
+	// two networks overlap if either of them contains the other one
+	for _, internal := range internalIPNets {
+		if internal.Contains(ipNet.IP) || ipNet.Contains(internal.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isValidSourceIP(value string) bool {
+	if !strings.Contains(value, "/") {
+		return net.ParseIP(value) != nil
+	}
+
+	ip, _, err := net.ParseCIDR(value)
+
+	return err == nil && ip.To4() != nil
+}
+
+// conditionMatchKey is the header, query or cookie a condition is translated to.
+// Conditions of a match on the same key overwrite each other, except a positive one and a negative one.
+// Conditions on different cookies are combined into one match on the cookie header.
+func conditionMatchKey(condition HttpRouteCondition) string {
+	switch condition.Type {
+	case HttpRouteConditionTypeQuery:
+		return "query:" + condition.Name
+	case HttpRouteConditionTypeCookie:
+		return "cookie:" + condition.Name
+	case HttpRouteConditionTypeSourceIP:
+		return "header:x-envoy-external-address"
+	case HttpRouteConditionTypeAuthority:
+		return "header::authority"
+	}
+
+	return "header:" + strings.ToLower(condition.Name)
+}
+
+// Positive cookie conditions are matched in any order, the regexp of the cookie header grows factorially
+const maxCookieConditions = 3
+
+func validateConditionsConflict(conditions []HttpRouteCondition, path string) (rst KalmValidateErrorList) {
+	keys := make(map[string]bool)
+	cookieConditions := 0
+
+	for _, condition := range conditions {
+		key := conditionMatchKey(condition)
+
+		if condition.Operator.IsNegative() {
+			key = "not " + key
+		}
+
+		if keys[key] {
+			rst = append(rst, KalmValidateError{
+				Err:  "conflict conditions on " + strings.TrimPrefix(strings.TrimPrefix(key, "not "), "header:"),
+				Path: path,
+			})
+		}
+
+		keys[key] = true
+
+		if condition.Type == HttpRouteConditionTypeCookie && !condition.Operator.IsNegative() {
+			cookieConditions++
+		}
+	}
+
+	// header conditions on the cookie header can't be combined with cookie conditions
+	for _, condition := range conditions {
+		if condition.Type != HttpRouteConditionTypeCookie {
+			continue
+		}
+
+		key := "header:cookie"
+
+		if condition.Operator.IsNegative() {
+			key = "not " + key
+		}
+
+		if keys[key] {
+			rst = append(rst, KalmValidateError{
+				Err:  "conflict conditions on cookie",
+				Path: path,
+			})
+
+			break
+		}
+	}
+
+	if cookieConditions > maxCookieConditions {
+		rst = append(rst, KalmValidateError{
+			Err:  fmt.Sprintf("at most %d positive cookie conditions are supported", maxCookieConditions),
+			Path: path,
+		})
+	}
+
+	return rst
+}
+
+func (r *HttpRoute) validateRateLimit() (rst KalmValidateErrorList) {
+	rateLimit := r.Spec.RateLimit
+
+	if rateLimit == nil {
+		return nil
+	}
+
+	if !IsHttpRouteRateLimitEnabled() {
+		return KalmValidateErrorList{{
+			Err:  "rate limits are not supported by the ingress gateways of the cluster",
+			Path: "spec.rateLimit",
+		}}
+	}
+
+	if rateLimit.Requests <= 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be positive",
+			Path: "spec.rateLimit.requests",
+		})
+	}
+
+	if rateLimit.Unit != HttpRouteRateLimitUnitSecond && rateLimit.Unit != HttpRouteRateLimitUnitMinute {
+		rst = append(rst, KalmValidateError{
+			Err:  "should be second or minute",
+			Path: "spec.rateLimit.unit",
+		})
+	}
+
+	if rateLimit.Burst < 0 {
+		rst = append(rst, KalmValidateError{
+			Err:  "can't be negative",
+			Path: "spec.rateLimit.burst",
+		})
+	}
+
+	if key := rateLimit.Key; key != nil {
+		switch key.Type {
+		case HttpRouteRateLimitKeyTypeClientIP:
+		case HttpRouteRateLimitKeyTypeHeader, HttpRouteRateLimitKeyTypeJWTClaim:
+			if key.Name == "" {
+				rst = append(rst, KalmValidateError{
+					Err:  "is required for " + string(key.Type) + " key",
+					Path: "spec.rateLimit.key.name",
+				})
+			}
+
+			if key.Type == HttpRouteRateLimitKeyTypeJWTClaim && key.Issuer == "" {
+				rst = append(rst, KalmValidateError{
+					Err:  "is required for jwtClaim key",
+					Path: "spec.rateLimit.key.issuer",
+				})
+			}
+		default:
+			rst = append(rst, KalmValidateError{
+				Err:  "unknown key type: " + string(key.Type),
+				Path: "spec.rateLimit.key.type",
+			})
+		}
+	}
+
+	if rateLimit.Response != nil {
+		for name := range rateLimit.Response.Headers {
+			if !httpHeaderNameRegexp.MatchString(name) {
+				rst = append(rst, KalmValidateError{
+					Err:  "invalid header name: " + name,
+					Path: "spec.rateLimit.response.headers",
+				})
+			}
+		}
+	}
+
+	return rst
+}
+
+// validateApplicationQuota rejects the route if an application it newly routes to has reached its routes quota
+func (r *HttpRoute) validateApplicationQuota(old *HttpRoute) (rst KalmValidateErrorList) {
+	if webhookClient == nil {
+		return nil
+	}
+
+	oldApplications := make(map[string]bool)
+	if old != nil {
+		for _, ns := range GetApplicationsOfHttpRoute(old) {
+			oldApplications[ns] = true
+		}
+	}
+
+	var routeList HttpRouteList
+
+	for _, ns := range GetApplicationsOfHttpRoute(r) {
+		if oldApplications[ns] {
+			continue
+		}
+
+		var namespace v1.Namespace
+		if err := webhookClient.Get(context.Background(), types.NamespacedName{Name: ns}, &namespace); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+
+			return KalmValidateErrorList{{Err: err.Error(), Path: "spec.destinations"}}
+		}
+
+		quota, err := GetApplicationQuota(&namespace)
+		if err != nil {
+			return KalmValidateErrorList{{Err: err.Error(), Path: "spec.destinations"}}
+		}
+
+		if quota == nil || quota.Routes == nil {
+			continue
+		}
+
+		if routeList.Items == nil {
+			if err := webhookClient.List(context.Background(), &routeList); err != nil {
+				return KalmValidateErrorList{{Err: err.Error(), Path: "spec.destinations"}}
+			}
+		}
+
+		var count int32
+		for i := range routeList.Items {
+			if routeList.Items[i].Name == r.Name {
+				continue
+			}
+
+			for _, app := range GetApplicationsOfHttpRoute(&routeList.Items[i]) {
+				if app == ns {
+					count++
+				}
+			}
+		}
+
+		if count+1 > *quota.Routes {
+			rst = append(rst, KalmValidateError{
+				Err:  fmt.Sprintf("exceeding %s quota of application %s", QuotaResourceRoutes, ns),
+				Path: "spec.destinations",
+			})
+		}
+	}
+
+	return rst
+}
+
 func getValidSuffixOfAppDomain(tenantName, baseAppDomain string) string {
 	validSuffix := fmt.Sprintf("%s.%s", tenantName, baseAppDomain)
 	return validSuffix
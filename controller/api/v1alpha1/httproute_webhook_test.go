@@ -1,9 +1,11 @@
 package v1alpha1
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -34,6 +36,284 @@ func TestHttpRoute_Validate(t *testing.T) {
 
 	route.Default()
 	assert.Nil(t, route.validate())
+
+	// only one of the timeouts
+	timeout, timeoutMilliseconds := 5, 500
+	route.Spec.Timeout = &timeout
+	route.Spec.TimeoutMilliseconds = &timeoutMilliseconds
+
+	errs, _ := route.validate().(KalmValidateErrorList)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "spec.timeoutMilliseconds", errs[0].Path)
+	}
+}
+
+func TestHttpRoute_validateResponseActions(t *testing.T) {
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			Redirect: &HttpRouteRedirect{Scheme: "https", Host: "new.example.com", StatusCode: 308},
+		},
+	}
+
+	assert.Nil(t, route.validateResponseActions())
+
+	route.Spec.Redirect = &HttpRouteRedirect{Scheme: "ftp", StatusCode: 200}
+	route.Spec.Destinations = []HttpRouteDestination{{Host: "web:80", Weight: 1}}
+
+	errs := route.validateResponseActions()
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "spec.destinations", errs[0].Path)
+		assert.Equal(t, "spec.redirect", errs[1].Path)
+		assert.Equal(t, "spec.redirect.scheme", errs[2].Path)
+		assert.Equal(t, "spec.redirect.statusCode", errs[3].Path)
+	}
+
+	route.Spec.Redirect = nil
+	route.Spec.Destinations = nil
+	route.Spec.DirectResponse = &HttpRouteDirectResponse{StatusCode: 503, Body: "maintenance"}
+	assert.Nil(t, route.validateResponseActions())
+
+	// options of forwarding requests can't be used
+	timeout := 1000
+	route.Spec.TimeoutMilliseconds = &timeout
+	route.Spec.StripPath = true
+	route.Spec.HttpRedirectToHttps = true
+	route.Spec.Headers = &HttpRouteHeaders{Response: &HttpRouteHeaderOperations{Remove: []string{"Server"}}}
+
+	errs = route.validateResponseActions()
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "spec.timeoutMilliseconds", errs[0].Path)
+		assert.Equal(t, "spec.stripPath", errs[1].Path)
+		assert.Equal(t, "spec.headers", errs[2].Path)
+		assert.Equal(t, "spec.httpRedirectToHttps", errs[3].Path)
+	}
+
+	route.Spec.TimeoutMilliseconds = nil
+	route.Spec.StripPath = false
+	route.Spec.HttpRedirectToHttps = false
+	route.Spec.Headers = nil
+
+	route.Spec.DirectResponse = nil
+	errs = route.validateResponseActions()
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "spec.destinations", errs[0].Path)
+	}
+}
+
+func TestHttpRoute_validateRedirectLoop(t *testing.T) {
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			Paths:    []string{"/docs", "/blog"},
+			Schemes:  []HttpRouteScheme{"http"},
+			Redirect: &HttpRouteRedirect{Path: "/docs/index.html"},
+		},
+	}
+
+	errs := route.validateResponseActions()
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "spec.redirect.path", errs[0].Path)
+	}
+
+	// the target is not matched by the route
+	route.Spec.Redirect.Path = "/help"
+	assert.Nil(t, route.validateResponseActions())
+
+	// the target is matched by another route
+	route.Spec.Redirect = &HttpRouteRedirect{Host: "docs.example.com", Path: "/docs"}
+	assert.Nil(t, route.validateResponseActions())
+
+	route.Spec.Redirect = &HttpRouteRedirect{Scheme: "https", Path: "/docs"}
+	assert.Nil(t, route.validateResponseActions())
+
+	route.Spec.Schemes = append(route.Spec.Schemes, "https")
+	assert.Len(t, route.validateResponseActions(), 1)
+}
+
+func TestHttpRoute_validateRewrite(t *testing.T) {
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			Rewrite: &HttpRouteRewrite{Prefix: "/v2", Host: "api.internal:8080"},
+			Headers: &HttpRouteHeaders{
+				Request:  &HttpRouteHeaderOperations{Set: map[string]string{"X-Version": "2"}, Remove: []string{"Cookie"}},
+				Response: &HttpRouteHeaderOperations{Add: map[string]string{"Vary": "Origin"}},
+			},
+		},
+	}
+
+	assert.Nil(t, route.validateRewrite())
+
+	route.Spec.StripPath = true
+	route.Spec.Rewrite.Host = "*.internal"
+	route.Spec.Headers.Request.Remove = []string{"bad header"}
+
+	errs := route.validateRewrite()
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, "spec.rewrite.prefix", errs[0].Path)
+		assert.Equal(t, "spec.rewrite.host", errs[1].Path)
+		assert.Equal(t, "spec.headers.request.remove[0]", errs[2].Path)
+	}
+}
+
+func TestHttpRoute_validateConditions(t *testing.T) {
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			Conditions: []HttpRouteCondition{
+				{Type: HttpRouteConditionTypeHeader, Name: "x-canary", Operator: HRCOEqual, Value: "true"},
+				{Type: HttpRouteConditionTypeHeader, Name: "X-Canary", Operator: HRCONotMatchRegexp, Value: "^v1"},
+			},
+			ConditionGroups: []HttpRouteConditionGroup{
+				{Conditions: []HttpRouteCondition{{Type: HttpRouteConditionTypeSourceIP, Operator: HRCOEqual, Value: "203.0.113.0/24"}}},
+				{Conditions: []HttpRouteCondition{
+					{Type: HttpRouteConditionTypeCookie, Name: "beta", Operator: HRCOWithoutPrefix, Value: "0"},
+					{Type: HttpRouteConditionTypeCookie, Name: "a", Operator: HRCOEqual, Value: "1"},
+					{Type: HttpRouteConditionTypeCookie, Name: "b", Operator: HRCOEqual, Value: "1"},
+				}},
+			},
+		},
+	}
+
+	assert.Nil(t, route.validateConditions())
+
+	route.Spec.Conditions = []HttpRouteCondition{
+		{Type: HttpRouteConditionTypeQuery, Name: "debug", Operator: HRCONotEqual, Value: "1"},
+		{Type: HttpRouteConditionTypeHeader, Operator: HRCOMatchRegexp, Value: "("},
+	}
+	route.Spec.ConditionGroups = []HttpRouteConditionGroup{
+		{Conditions: []HttpRouteCondition{{Type: HttpRouteConditionTypeSourceIP, Operator: HRCOWithPrefix, Value: "fd00::/8"}}},
+		{Conditions: []HttpRouteCondition{
+			{Type: HttpRouteConditionTypeCookie, Name: "a", Operator: HRCOEqual, Value: "1"},
+			{Type: HttpRouteConditionTypeCookie, Name: "a", Operator: HRCOWithPrefix, Value: "1"},
+		}},
+	}
+
+	errs := route.validateConditions()
+	if assert.Len(t, errs, 6) {
+		assert.Equal(t, "spec.conditions[0].operator", errs[0].Path)
+		assert.Equal(t, "spec.conditions[1].name", errs[1].Path)
+		assert.Equal(t, "spec.conditions[1].value", errs[2].Path)
+		assert.Equal(t, "spec.conditionGroups[0].conditions[0].operator", errs[3].Path)
+		assert.Equal(t, "spec.conditionGroups[0].conditions[0].value", errs[4].Path)
+		assert.Equal(t, "spec.conditionGroups[1].conditions", errs[5].Path)
+	}
+}
+
+func TestHttpRoute_validateSourceIPAndCookieConditions(t *testing.T) {
+	for _, value := range []string{"10.1.2.3", "192.168.0.0/24", "0.0.0.0/0", "fd12::1"} {
+		errs := validateCondition(HttpRouteCondition{Type: HttpRouteConditionTypeSourceIP, Operator: HRCOEqual, Value: value}, "condition")
+		assert.Len(t, errs, 1, value)
+	}
+
+	assert.Nil(t, validateCondition(HttpRouteCondition{Type: HttpRouteConditionTypeSourceIP, Operator: HRCONotEqual, Value: "8.8.8.8"}, "condition"))
+
+	cookie := func(name string, operator HttpRouteConditionOperator) HttpRouteCondition {
+		return HttpRouteCondition{Type: HttpRouteConditionTypeCookie, Name: name, Operator: operator, Value: "1"}
+	}
+
+	// negative cookie conditions are not limited
+	assert.Nil(t, validateConditionsConflict([]HttpRouteCondition{
+		cookie("a", HRCOEqual), cookie("b", HRCOEqual), cookie("c", HRCOEqual),
+		cookie("d", HRCONotEqual), cookie("e", HRCONotEqual), cookie("f", HRCONotEqual), cookie("g", HRCONotEqual),
+	}, "conditions"))
+
+	assert.Len(t, validateConditionsConflict([]HttpRouteCondition{
+		cookie("a", HRCOEqual), cookie("b", HRCOEqual), cookie("c", HRCOEqual), cookie("d", HRCOEqual),
+	}, "conditions"), 1)
+
+	// the cookie header can't be matched by a header condition and cookie conditions at the same time
+	assert.Len(t, validateConditionsConflict([]HttpRouteCondition{
+		cookie("a", HRCOEqual),
+		{Type: HttpRouteConditionTypeHeader, Name: "Cookie", Operator: HRCOMatchRegexp, Value: "b=1"},
+	}, "conditions"), 1)
+}
+
+func TestHttpRoute_validateRetries(t *testing.T) {
+	perTryTimeout := 500
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			Retries: &HttpRouteRetries{
+				Attempts:                  3,
+				PerTryTimeoutMilliseconds: &perTryTimeout,
+				RetryOn:                   []string{"gateway-error", "503"},
+				Budget:                    &HttpRouteRetryBudget{BudgetPercent: 20},
+			},
+		},
+	}
+
+	assert.Nil(t, route.validateRetries())
+
+	perTryTimeout = 0
+	route.Spec.Retries.RetryOn = []string{"on-failure"}
+	route.Spec.Retries.Budget.BudgetPercent = 120
+
+	errs := route.validateRetries()
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, "spec.retries.perTryTimeoutMilliseconds", errs[0].Path)
+		assert.Equal(t, "spec.retries.retryOn[0]", errs[1].Path)
+		assert.Equal(t, "spec.retries.budget.budgetPercent", errs[2].Path)
+	}
+
+	// only one of the per try timeouts
+	perTryTimeout = 500
+	route.Spec.Retries = &HttpRouteRetries{Attempts: 3, PerTtyTimeoutSeconds: 2, PerTryTimeoutMilliseconds: &perTryTimeout}
+
+	errs = route.validateRetries()
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "spec.retries.perTryTimeoutMilliseconds", errs[0].Path)
+	}
+}
+
+func TestHttpRoute_TimeoutsMigratedOnCreate(t *testing.T) {
+	timeout := 5
+	route := HttpRoute{Spec: HttpRouteSpec{Timeout: &timeout}}
+
+	route.Default()
+	assert.Equal(t, "true", route.Annotations[KalmAnnoHttpRouteTimeoutsMigrated])
+
+	// routes created before timeouts were applied are migrated by the controller
+	route = HttpRoute{ObjectMeta: ctrl.ObjectMeta{CreationTimestamp: metav1.Now()}, Spec: HttpRouteSpec{Timeout: &timeout}}
+	route.Default()
+	assert.Empty(t, route.Annotations[KalmAnnoHttpRouteTimeoutsMigrated])
+
+	assert.Equal(t, []string{"spec.timeout"}, route.Spec.RemoveLegacyDefaultTimeouts())
+	assert.Nil(t, route.Spec.Timeout)
+}
+
+func TestHttpRoute_validateRateLimit(t *testing.T) {
+	route := HttpRoute{
+		Spec: HttpRouteSpec{
+			RateLimit: &HttpRouteRateLimit{
+				Requests: 100,
+				Unit:     HttpRouteRateLimitUnitMinute,
+				Burst:    20,
+				Key:      &HttpRouteRateLimitKey{Type: HttpRouteRateLimitKeyTypeJWTClaim, Name: "sub", Issuer: "https://auth.example.com"},
+				Response: &HttpRouteRateLimitResponse{Body: "slow down", Headers: map[string]string{"Retry-After": "60"}},
+			},
+		},
+	}
+
+	// not supported by the istio installed by kalm
+	errs := route.validateRateLimit()
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "spec.rateLimit", errs[0].Path)
+	}
+
+	assert.Nil(t, os.Setenv(ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED, "true"))
+	defer os.Unsetenv(ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED)
+
+	assert.Nil(t, route.validateRateLimit())
+
+	route.Spec.RateLimit.Requests = 0
+	route.Spec.RateLimit.Unit = "hour"
+	route.Spec.RateLimit.Key = &HttpRouteRateLimitKey{Type: HttpRouteRateLimitKeyTypeHeader}
+	route.Spec.RateLimit.Response.Headers = map[string]string{"Retry After": "60"}
+
+	errs = route.validateRateLimit()
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "spec.rateLimit.requests", errs[0].Path)
+		assert.Equal(t, "spec.rateLimit.unit", errs[1].Path)
+		assert.Equal(t, "spec.rateLimit.key.name", errs[2].Path)
+		assert.Equal(t, "spec.rateLimit.response.headers", errs[3].Path)
+	}
 }
 
 func TestHttpRoute_isValidRouteHost(t *testing.T) {
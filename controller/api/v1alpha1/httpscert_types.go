@@ -16,6 +16,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -32,6 +34,12 @@ type HttpsCertSpec struct {
 
 	// +kubebuilder:validation:MinItems=1
 	Domains []string `json:"domains"`
+
+	// days before expiration when the cert is considered expiring,
+	// default to ENV CERT_EXPIRY_WARNING_DAYS or 14
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ExpiryWarningDays int `json:"expiryWarningDays,omitempty"`
 }
 
 // HttpsCertStatus defines the observed state of HttpsCert
@@ -47,6 +55,43 @@ type HttpsCertStatus struct {
 	IsSignedByPublicTrustedCA bool `json:"isSignedByTrustedCA"`
 	// +optional
 	WildcardCertDNSChallengeDomainMap map[string]string `json:"wildcardCertDNSChallengeDomainMap,omitempty"`
+	// The ACME order in progress, only for certs of ACMEDNSProvider issuers
+	// +optional
+	ACMEOrder *HttpsCertACMEOrder `json:"acmeOrder,omitempty"`
+	// The expiry warning of the current cert, ExpiringSoon or Expired. The event is emitted once it changes.
+	// +optional
+	ExpiryWarning string `json:"expiryWarning,omitempty"`
+}
+
+type HttpsCertACMEOrderPhase string
+
+const (
+	// the dns-01 challenge records are set, waiting for them to be visible
+	HttpsCertACMEOrderPhaseWaitingForRecords HttpsCertACMEOrderPhase = "WaitingForRecords"
+	// the challenges are accepted, waiting for the ACME server to validate them
+	HttpsCertACMEOrderPhaseValidating HttpsCertACMEOrderPhase = "Validating"
+)
+
+// HttpsCertACMEOrder is the progress of an ACME order, the order is moved forward by successive reconciles.
+type HttpsCertACMEOrder struct {
+	URL     string                  `json:"url"`
+	Domains []string                `json:"domains"`
+	Phase   HttpsCertACMEOrderPhase `json:"phase"`
+
+	// TXT records set for the dns-01 challenges
+	// +optional
+	Records []HttpsCertACMEChallengeRecord `json:"records,omitempty"`
+
+	// dns-01 challenges to accept once the records are visible
+	// +optional
+	ChallengeURLs []string `json:"challengeURLs,omitempty"`
+
+	StartedAt metav1.Time `json:"startedAt"`
+}
+
+type HttpsCertACMEChallengeRecord struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 type HttpsCertConditionType string
@@ -56,6 +101,15 @@ const (
 	TenantDefaultHttpsCertKey                        = "IsTenantDefaultHttpsCert"
 )
 
+// reasons of Ready=False conditions for certs which are issued but at risk
+const (
+	HttpsCertReasonExpiringSoon  = "ExpiringSoon"
+	HttpsCertReasonExpired       = "Expired"
+	HttpsCertReasonRenewalFailed = "RenewalFailed"
+)
+
+const DefaultCertExpiryWarningDays = 14
+
 type HttpsCertCondition struct {
 	// Type of the condition, currently ('Ready').
 	Type HttpsCertConditionType `json:"type"`
@@ -117,3 +171,33 @@ func IsHttpsCertReady(cert HttpsCert) bool {
 
 	return false
 }
+
+// IsHttpsCertUsable returns true if the cert is ready, or issued before and not expired yet
+func IsHttpsCertUsable(cert HttpsCert) bool {
+	if IsHttpsCertReady(cert) {
+		return true
+	}
+
+	for _, cond := range cert.Status.Conditions {
+		if cond.Type != HttpsCertConditionReady {
+			continue
+		}
+
+		if cond.Reason != HttpsCertReasonExpiringSoon && cond.Reason != HttpsCertReasonRenewalFailed {
+			return false
+		}
+
+		return cert.Status.ExpireTimestamp > time.Now().Unix()
+	}
+
+	return false
+}
+
+// GetExpiryWarningDays returns the expiry window of the cert
+func (cert HttpsCert) GetExpiryWarningDays() int {
+	if cert.Spec.ExpiryWarningDays > 0 {
+		return cert.Spec.ExpiryWarningDays
+	}
+
+	return GetEnvCertExpiryWarningDays()
+}
@@ -16,10 +16,12 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -29,6 +31,7 @@ import (
 var httpscertlog = logf.Log.WithName("httpscert-resource")
 
 func (r *HttpsCert) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -139,6 +142,20 @@ func (r *HttpsCert) validate() error {
 		case DefaultCAIssuerName:
 			//nothing
 		default:
+			if issuer, err := getHttpsCertIssuer(r.Spec.HttpsCertIssuer); err == nil {
+				if issuer.Spec.HTTP01 != nil {
+					for _, d := range r.Spec.Domains {
+						if strings.Contains(d, "*") {
+							rst = append(rst, KalmValidateError{
+								Err:  fmt.Sprintf("http01 cert should not have '*' in domain: %s", d),
+								Path: "spec.domains",
+							})
+						}
+					}
+				}
+
+				break
+			}
 
 			validIssuers := []string{
 				DefaultDNS01IssuerName,
@@ -147,7 +164,7 @@ func (r *HttpsCert) validate() error {
 			}
 
 			rst = append(rst, KalmValidateError{
-				Err: fmt.Sprintf("for auto managed cert, httpsCertIssuer should be one of: %s, but: %s",
+				Err: fmt.Sprintf("for auto managed cert, httpsCertIssuer should be one of: %s or an existing HttpsCertIssuer, but: %s",
 					validIssuers, r.Spec.HttpsCertIssuer),
 				Path: "spec.httpsCertIssuer",
 			})
@@ -160,3 +177,17 @@ func (r *HttpsCert) validate() error {
 
 	return rst
 }
+
+// getHttpsCertIssuer returns the HttpsCertIssuer created by users, only works when running as webhook
+func getHttpsCertIssuer(name string) (*HttpsCertIssuer, error) {
+	if webhookClient == nil {
+		return nil, fmt.Errorf("no client to get HttpsCertIssuer")
+	}
+
+	var issuer HttpsCertIssuer
+	if err := webhookClient.Get(context.Background(), types.NamespacedName{Name: name}, &issuer); err != nil {
+		return nil, err
+	}
+
+	return &issuer, nil
+}
@@ -29,6 +29,10 @@ type HttpsCertIssuerSpec struct {
 	HTTP01 *HTTP01Issuer `json:"http01,omitempty"`
 	// +optional
 	DNS01 *DNS01Issuer `json:"dns01,omitempty"`
+	// +optional
+	ACMEDNSProvider *ACMEDNSProviderIssuer `json:"acmeDNSProvider,omitempty"`
+	// +optional
+	CA *CAIssuer `json:"ca,omitempty"`
 }
 
 type CAForTestIssuer struct{}
@@ -59,6 +63,68 @@ type DNS01IssuerConfig struct {
 	AllowFrom []string `json:"allowfrom,omitempty"`
 }
 
+// ACMEDNSProviderIssuer issues certs from an ACME server with DNS-01 challenges,
+// TXT records of the challenges are set by the DNSManager of the controller, so wildcard certs
+// can be issued without the ACMEServer.
+type ACMEDNSProviderIssuer struct {
+	// +kubebuilder:validation:MinLength=1
+	Email string `json:"email"`
+	// ACME directory url, default to the letsencrypt api used by the controller
+	// +optional
+	Server string `json:"server,omitempty"`
+	// PEM encoded CA certs to trust when connecting to the ACME server, for private ACME servers
+	// +optional
+	ServerCABundle string `json:"serverCABundle,omitempty"`
+	// Name of the dns provider to set the TXT records, default to the provider of the controller
+	// +optional
+	DNSProvider string `json:"dnsProvider,omitempty"`
+}
+
+// CAIssuer signs certs with your own CA, e.g. an intermediate CA of the company.
+// The CA is either kept in a Secret, or a Vault PKI secrets engine.
+type CAIssuer struct {
+	// Secret in the cert-manager namespace holding tls.crt and tls.key of the CA
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// +optional
+	Vault *VaultPKIIssuer `json:"vault,omitempty"`
+	// Validity of issued certs, e.g. 2160h, default to 90 days
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+	// Private key algorithm of issued certs, default to rsa
+	// +kubebuilder:validation:Enum=rsa;ecdsa
+	// +optional
+	KeyAlgorithm CAIssuerKeyAlgorithm `json:"keyAlgorithm,omitempty"`
+	// Key size of issued certs, 2048 to 8192 for rsa (default 2048), 256 or 384 for ecdsa (default 256)
+	// +optional
+	KeySize int `json:"keySize,omitempty"`
+}
+
+type CAIssuerKeyAlgorithm string
+
+const (
+	CAIssuerKeyAlgorithmRSA   CAIssuerKeyAlgorithm = "rsa"
+	CAIssuerKeyAlgorithmECDSA CAIssuerKeyAlgorithm = "ecdsa"
+)
+
+type VaultPKIIssuer struct {
+	// Address of vault, e.g. https://vault.example.com:8200
+	// +kubebuilder:validation:MinLength=1
+	Server string `json:"server"`
+	// Path of the sign endpoint of the PKI role, e.g. pki_int/sign/example-dot-com
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+	// PEM encoded CA certs to trust when connecting to vault
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+	// Secret in the cert-manager namespace holding the vault token
+	// +kubebuilder:validation:MinLength=1
+	TokenSecretName string `json:"tokenSecretName"`
+	// Key of the token in the secret, default to token
+	// +optional
+	TokenSecretKey string `json:"tokenSecretKey,omitempty"`
+}
+
 // HttpsCertIssuerStatus defines the observed state of HttpsCertIssuer
 type HttpsCertIssuerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
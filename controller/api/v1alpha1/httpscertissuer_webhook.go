@@ -16,6 +16,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/pem"
+	"net/url"
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -69,17 +73,23 @@ func (r *HttpsCertIssuer) validate() error {
 	if r.Spec.DNS01 != nil {
 		setConfigCnt += 1
 	}
+	if r.Spec.ACMEDNSProvider != nil {
+		setConfigCnt += 1
+	}
+	if r.Spec.CA != nil {
+		setConfigCnt += 1
+	}
 
 	if setConfigCnt == 0 {
 		rst = append(rst, KalmValidateError{
-			Err:  "should provide at least 1 among: acmeCloudFlare, caForTest, http01 and dns01",
+			Err:  "should provide at least 1 among: acmeCloudFlare, caForTest, http01, dns01, acmeDNSProvider and ca",
 			Path: "spec",
 		})
 	}
 
 	if setConfigCnt > 1 {
 		rst = append(rst, KalmValidateError{
-			Err:  "should provide at most 1 among: acmeCloudFlare, caForTest, http01 and dns01",
+			Err:  "should provide at most 1 among: acmeCloudFlare, caForTest, http01, dns01, acmeDNSProvider and ca",
 			Path: "spec",
 		})
 	}
@@ -111,9 +121,121 @@ func (r *HttpsCertIssuer) validate() error {
 		}
 	}
 
+	acmeDNSProvider := r.Spec.ACMEDNSProvider
+	if acmeDNSProvider != nil {
+		if !isValidEmail(acmeDNSProvider.Email) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid email:" + acmeDNSProvider.Email,
+				Path: "spec.acmeDNSProvider.email",
+			})
+		}
+
+		if acmeDNSProvider.Server != "" {
+			if u, err := url.Parse(acmeDNSProvider.Server); err != nil || u.Scheme != "https" || u.Host == "" {
+				rst = append(rst, KalmValidateError{
+					Err:  "server should be a https url:" + acmeDNSProvider.Server,
+					Path: "spec.acmeDNSProvider.server",
+				})
+			}
+		}
+
+		if acmeDNSProvider.ServerCABundle != "" {
+			if block, _ := pem.Decode([]byte(acmeDNSProvider.ServerCABundle)); block == nil {
+				rst = append(rst, KalmValidateError{
+					Err:  "serverCABundle should be PEM encoded",
+					Path: "spec.acmeDNSProvider.serverCABundle",
+				})
+			}
+		}
+	}
+
+	if r.Spec.CA != nil {
+		rst = append(rst, validateCAIssuer(r.Spec.CA)...)
+	}
+
 	if len(rst) == 0 {
 		return nil
 	}
 
 	return rst
 }
+
+func validateCAIssuer(ca *CAIssuer) (rst KalmValidateErrorList) {
+	if (ca.SecretName == "") == (ca.Vault == nil) {
+		rst = append(rst, KalmValidateError{
+			Err:  "should provide exactly 1 among: secretName and vault",
+			Path: "spec.ca",
+		})
+	}
+
+	if ca.SecretName != "" && !isValidResourceName(ca.SecretName) {
+		rst = append(rst, KalmValidateError{
+			Err:  "invalid secret name",
+			Path: "spec.ca.secretName",
+		})
+	}
+
+	if vault := ca.Vault; vault != nil {
+		if u, err := url.Parse(vault.Server); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "server should be a http(s) url:" + vault.Server,
+				Path: "spec.ca.vault.server",
+			})
+		}
+
+		if vault.Path == "" {
+			rst = append(rst, KalmValidateError{
+				Err:  "path should not be blank",
+				Path: "spec.ca.vault.path",
+			})
+		}
+
+		if !isValidResourceName(vault.TokenSecretName) {
+			rst = append(rst, KalmValidateError{
+				Err:  "invalid secret name",
+				Path: "spec.ca.vault.tokenSecretName",
+			})
+		}
+
+		if vault.CABundle != "" {
+			if block, _ := pem.Decode([]byte(vault.CABundle)); block == nil {
+				rst = append(rst, KalmValidateError{
+					Err:  "caBundle should be PEM encoded",
+					Path: "spec.ca.vault.caBundle",
+				})
+			}
+		}
+	}
+
+	// same limit as cert-manager
+	if ca.Validity != nil && ca.Validity.Duration < time.Hour {
+		rst = append(rst, KalmValidateError{
+			Err:  "validity should be at least 1h",
+			Path: "spec.ca.validity",
+		})
+	}
+
+	switch ca.KeyAlgorithm {
+	case "", CAIssuerKeyAlgorithmRSA:
+		if ca.KeySize != 0 && (ca.KeySize < 2048 || ca.KeySize > 8192) {
+			rst = append(rst, KalmValidateError{
+				Err:  "key size of rsa should be between 2048 and 8192",
+				Path: "spec.ca.keySize",
+			})
+		}
+	case CAIssuerKeyAlgorithmECDSA:
+		if ca.KeySize != 0 && ca.KeySize != 256 && ca.KeySize != 384 {
+			rst = append(rst, KalmValidateError{
+				Err:  "key size of ecdsa should be 256 or 384",
+				Path: "spec.ca.keySize",
+			})
+		}
+	default:
+		rst = append(rst, KalmValidateError{
+			Err:  "key algorithm should be rsa or ecdsa",
+			Path: "spec.ca.keyAlgorithm",
+		})
+	}
+
+	return rst
+}
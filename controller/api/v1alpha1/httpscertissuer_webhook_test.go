@@ -1,9 +1,12 @@
 package v1alpha1
 
 import (
+	"testing"
+	"time"
+
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"testing"
 )
 
 func TestHttpsCertIssuer_Validate(t *testing.T) {
@@ -40,3 +43,84 @@ func TestHttpsCertIssuer_Validate2(t *testing.T) {
 
 	assert.Nil(t, issuer.validate())
 }
+
+func TestHttpsCertIssuer_ValidateACMEDNSProvider(t *testing.T) {
+	issuer := HttpsCertIssuer{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name: "test-name",
+		},
+		Spec: HttpsCertIssuerSpec{
+			ACMEDNSProvider: &ACMEDNSProviderIssuer{
+				Email: "admin@example.com",
+			},
+		},
+	}
+
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.ACMEDNSProvider.Server = "https://localhost:14000/dir"
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.ACMEDNSProvider.Server = "localhost:14000/dir"
+	assert.NotNil(t, issuer.validate())
+
+	issuer.Spec.ACMEDNSProvider.Server = ""
+	issuer.Spec.ACMEDNSProvider.ServerCABundle = "not a pem"
+	assert.NotNil(t, issuer.validate())
+
+	issuer.Spec.ACMEDNSProvider.ServerCABundle = ""
+	issuer.Spec.ACMEDNSProvider.Email = ""
+	assert.NotNil(t, issuer.validate())
+
+	// at most 1 config
+	issuer.Spec.ACMEDNSProvider.Email = "admin@example.com"
+	issuer.Spec.HTTP01 = &HTTP01Issuer{Email: "admin@example.com"}
+	assert.NotNil(t, issuer.validate())
+}
+
+func TestHttpsCertIssuer_ValidateCA(t *testing.T) {
+	issuer := HttpsCertIssuer{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name: "test-name",
+		},
+		Spec: HttpsCertIssuerSpec{
+			CA: &CAIssuer{
+				SecretName: "corp-intermediate-ca",
+			},
+		},
+	}
+
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.CA.Validity = &metav1.Duration{Duration: 30 * 24 * time.Hour}
+	issuer.Spec.CA.KeyAlgorithm = CAIssuerKeyAlgorithmECDSA
+	issuer.Spec.CA.KeySize = 384
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.CA.KeySize = 2048
+	assert.NotNil(t, issuer.validate())
+
+	issuer.Spec.CA.KeyAlgorithm = CAIssuerKeyAlgorithmRSA
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.CA.Validity = &metav1.Duration{Duration: time.Minute}
+	assert.NotNil(t, issuer.validate())
+	issuer.Spec.CA.Validity = nil
+
+	// secret or vault
+	issuer.Spec.CA.Vault = &VaultPKIIssuer{
+		Server:          "https://vault.example.com:8200",
+		Path:            "pki_int/sign/example-dot-com",
+		TokenSecretName: "vault-token",
+	}
+	assert.NotNil(t, issuer.validate())
+
+	issuer.Spec.CA.SecretName = ""
+	assert.Nil(t, issuer.validate())
+
+	issuer.Spec.CA.Vault.Server = "vault.example.com"
+	assert.NotNil(t, issuer.validate())
+
+	issuer.Spec.CA.Vault = nil
+	assert.NotNil(t, issuer.validate())
+}
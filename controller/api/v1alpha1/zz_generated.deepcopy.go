@@ -22,6 +22,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -40,6 +41,21 @@ func (in *ACMECloudFlareIssuer) DeepCopy() *ACMECloudFlareIssuer {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACMEDNSProviderIssuer) DeepCopyInto(out *ACMEDNSProviderIssuer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACMEDNSProviderIssuer.
+func (in *ACMEDNSProviderIssuer) DeepCopy() *ACMEDNSProviderIssuer {
+	if in == nil {
+		return nil
+	}
+	out := new(ACMEDNSProviderIssuer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ACMEServer) DeepCopyInto(out *ACMEServer) {
 	*out = *in
@@ -242,6 +258,325 @@ func (in *AccessTokenStatus) DeepCopy() *AccessTokenStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Alert) DeepCopyInto(out *Alert) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Alert.
+func (in *Alert) DeepCopy() *Alert {
+	if in == nil {
+		return nil
+	}
+	out := new(Alert)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertChannel) DeepCopyInto(out *AlertChannel) {
+	*out = *in
+	if in.SMTP != nil {
+		in, out := &in.SMTP, &out.SMTP
+		*out = new(AlertSMTPConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertChannel.
+func (in *AlertChannel) DeepCopy() *AlertChannel {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertChannel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertHistoryRecord) DeepCopyInto(out *AlertHistoryRecord) {
+	*out = *in
+	if in.UndeliveredChannels != nil {
+		in, out := &in.UndeliveredChannels, &out.UndeliveredChannels
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertHistoryRecord.
+func (in *AlertHistoryRecord) DeepCopy() *AlertHistoryRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertHistoryRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRule) DeepCopyInto(out *AlertRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRule.
+func (in *AlertRule) DeepCopy() *AlertRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AlertRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleList) DeepCopyInto(out *AlertRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AlertRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleList.
+func (in *AlertRuleList) DeepCopy() *AlertRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AlertRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleSpec) DeepCopyInto(out *AlertRuleSpec) {
+	*out = *in
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = make([]AlertChannel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Silences != nil {
+		in, out := &in.Silences, &out.Silences
+		*out = make([]AlertSilence, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleSpec.
+func (in *AlertRuleSpec) DeepCopy() *AlertRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleStatus) DeepCopyInto(out *AlertRuleStatus) {
+	*out = *in
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = make([]Alert, len(*in))
+		copy(*out, *in)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]AlertHistoryRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastValues != nil {
+		in, out := &in.LastValues, &out.LastValues
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleStatus.
+func (in *AlertRuleStatus) DeepCopy() *AlertRuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertSMTPConfig) DeepCopyInto(out *AlertSMTPConfig) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertSMTPConfig.
+func (in *AlertSMTPConfig) DeepCopy() *AlertSMTPConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertSMTPConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertSilence) DeepCopyInto(out *AlertSilence) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertSilence.
+func (in *AlertSilence) DeepCopy() *AlertSilence {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertSilence)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationQuota) DeepCopyInto(out *ApplicationQuota) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationQuota.
+func (in *ApplicationQuota) DeepCopy() *ApplicationQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationQuotaUsage) DeepCopyInto(out *ApplicationQuotaUsage) {
+	*out = *in
+	out.CPU = in.CPU.DeepCopy()
+	out.Memory = in.Memory.DeepCopy()
+	out.Storage = in.Storage.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationQuotaUsage.
+func (in *ApplicationQuotaUsage) DeepCopy() *ApplicationQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingCustomMetric) DeepCopyInto(out *AutoscalingCustomMetric) {
+	*out = *in
+	out.TargetAverageValue = in.TargetAverageValue.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingCustomMetric.
+func (in *AutoscalingCustomMetric) DeepCopy() *AutoscalingCustomMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingCustomMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingSpec) DeepCopyInto(out *AutoscalingSpec) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetMemoryUtilizationPercentage != nil {
+		in, out := &in.TargetMemoryUtilizationPercentage, &out.TargetMemoryUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CustomMetric != nil {
+		in, out := &in.CustomMetric, &out.CustomMetric
+		*out = new(AutoscalingCustomMetric)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingSpec.
+func (in *AutoscalingSpec) DeepCopy() *AutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CAForTestIssuer) DeepCopyInto(out *CAForTestIssuer) {
 	*out = *in
@@ -257,13 +592,38 @@ func (in *CAForTestIssuer) DeepCopy() *CAForTestIssuer {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CAIssuer) DeepCopyInto(out *CAIssuer) {
+	*out = *in
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = new(VaultPKIIssuer)
+		**out = **in
+	}
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CAIssuer.
+func (in *CAIssuer) DeepCopy() *CAIssuer {
+	if in == nil {
+		return nil
+	}
+	out := new(CAIssuer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Component) DeepCopyInto(out *Component) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Component.
@@ -284,6 +644,22 @@ func (in *Component) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentCondition) DeepCopyInto(out *ComponentCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentCondition.
+func (in *ComponentCondition) DeepCopy() *ComponentCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentList) DeepCopyInto(out *ComponentList) {
 	*out = *in
@@ -432,37 +808,103 @@ func (in *ComponentPluginBindingStatus) DeepCopy() *ComponentPluginBindingStatus
 	if in == nil {
 		return nil
 	}
-	out := new(ComponentPluginBindingStatus)
+	out := new(ComponentPluginBindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPluginList) DeepCopyInto(out *ComponentPluginList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ComponentPlugin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginList.
+func (in *ComponentPluginList) DeepCopy() *ComponentPluginList {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPluginList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComponentPluginList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPluginSpec) DeepCopyInto(out *ComponentPluginSpec) {
+	*out = *in
+	if in.AvailableWorkloadType != nil {
+		in, out := &in.AvailableWorkloadType, &out.AvailableWorkloadType
+		*out = make([]WorkloadType, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConfigSchema != nil {
+		in, out := &in.ConfigSchema, &out.ConfigSchema
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginSpec.
+func (in *ComponentPluginSpec) DeepCopy() *ComponentPluginSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPluginSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPluginStatus) DeepCopyInto(out *ComponentPluginStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginStatus.
+func (in *ComponentPluginStatus) DeepCopy() *ComponentPluginStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPluginStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ComponentPluginList) DeepCopyInto(out *ComponentPluginList) {
+func (in *ComponentRevision) DeepCopyInto(out *ComponentRevision) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ComponentPlugin, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginList.
-func (in *ComponentPluginList) DeepCopy() *ComponentPluginList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRevision.
+func (in *ComponentRevision) DeepCopy() *ComponentRevision {
 	if in == nil {
 		return nil
 	}
-	out := new(ComponentPluginList)
+	out := new(ComponentRevision)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ComponentPluginList) DeepCopyObject() runtime.Object {
+func (in *ComponentRevision) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -470,41 +912,50 @@ func (in *ComponentPluginList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ComponentPluginSpec) DeepCopyInto(out *ComponentPluginSpec) {
+func (in *ComponentRevisionList) DeepCopyInto(out *ComponentRevisionList) {
 	*out = *in
-	if in.AvailableWorkloadType != nil {
-		in, out := &in.AvailableWorkloadType, &out.AvailableWorkloadType
-		*out = make([]WorkloadType, len(*in))
-		copy(*out, *in)
-	}
-	if in.ConfigSchema != nil {
-		in, out := &in.ConfigSchema, &out.ConfigSchema
-		*out = new(runtime.RawExtension)
-		(*in).DeepCopyInto(*out)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ComponentRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginSpec.
-func (in *ComponentPluginSpec) DeepCopy() *ComponentPluginSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRevisionList.
+func (in *ComponentRevisionList) DeepCopy() *ComponentRevisionList {
 	if in == nil {
 		return nil
 	}
-	out := new(ComponentPluginSpec)
+	out := new(ComponentRevisionList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComponentRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ComponentPluginStatus) DeepCopyInto(out *ComponentPluginStatus) {
+func (in *ComponentRevisionSpec) DeepCopyInto(out *ComponentRevisionSpec) {
 	*out = *in
+	in.ComponentSpec.DeepCopyInto(&out.ComponentSpec)
+	in.ChangedAt.DeepCopyInto(&out.ChangedAt)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPluginStatus.
-func (in *ComponentPluginStatus) DeepCopy() *ComponentPluginStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRevisionSpec.
+func (in *ComponentRevisionSpec) DeepCopy() *ComponentRevisionSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ComponentPluginStatus)
+	out := new(ComponentRevisionSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -531,6 +982,11 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 		*out = make([]EnvVar, len(*in))
 		copy(*out, *in)
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]EnvFromSource, len(*in))
+		copy(*out, *in)
+	}
 	if in.Replicas != nil {
 		in, out := &in.Replicas, &out.Replicas
 		*out = new(int32)
@@ -593,7 +1049,33 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 	if in.PreInjectedFiles != nil {
 		in, out := &in.PreInjectedFiles, &out.PreInjectedFiles
 		*out = make([]PreInjectFile, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeliveryStrategy != nil {
+		in, out := &in.DeliveryStrategy, &out.DeliveryStrategy
+		*out = new(DeliveryStrategy)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -610,6 +1092,18 @@ func (in *ComponentSpec) DeepCopy() *ComponentSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ComponentCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliveryStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
@@ -759,6 +1253,61 @@ func (in *Config) DeepCopy() *Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Container) DeepCopyInto(out *Container) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]EnvVar, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]EnvFromSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]Port, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]ContainerVolumeMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResourceRequirements != nil {
+		in, out := &in.ResourceRequirements, &out.ResourceRequirements
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Container.
+func (in *Container) DeepCopy() *Container {
+	if in == nil {
+		return nil
+	}
+	out := new(Container)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerVolumeMount) DeepCopyInto(out *ContainerVolumeMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerVolumeMount.
+func (in *ContainerVolumeMount) DeepCopy() *ContainerVolumeMount {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerVolumeMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNS01Issuer) DeepCopyInto(out *DNS01Issuer) {
 	*out = *in
@@ -890,6 +1439,69 @@ func (in *DNSRecordStatus) DeepCopy() *DNSRecordStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryStatus) DeepCopyInto(out *DeliveryStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.StepStartedAt != nil {
+		in, out := &in.StepStartedAt, &out.StepStartedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryStatus.
+func (in *DeliveryStatus) DeepCopy() *DeliveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryStep) DeepCopyInto(out *DeliveryStep) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryStep.
+func (in *DeliveryStep) DeepCopy() *DeliveryStep {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryStrategy) DeepCopyInto(out *DeliveryStrategy) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]DeliveryStep, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryStrategy.
+func (in *DeliveryStrategy) DeepCopy() *DeliveryStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DexConnector) DeepCopyInto(out *DexConnector) {
 	*out = *in
@@ -1099,52 +1711,181 @@ func (in *DomainSpec) DeepCopy() *DomainSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainSpec)
+	out := new(DomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
+func (in *DomainStatus) DeepCopy() *DomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvFromSource) DeepCopyInto(out *EnvFromSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvFromSource.
+func (in *EnvFromSource) DeepCopy() *EnvFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
+func (in *EnvVar) DeepCopy() *EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtAuthzEndpoint) DeepCopyInto(out *ExtAuthzEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtAuthzEndpoint.
+func (in *ExtAuthzEndpoint) DeepCopy() *ExtAuthzEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtAuthzEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSync) DeepCopyInto(out *GitSync) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSync.
+func (in *GitSync) DeepCopy() *GitSync {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitSync) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSyncList) DeepCopyInto(out *GitSyncList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitSync, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSyncList.
+func (in *GitSyncList) DeepCopy() *GitSyncList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSyncList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitSyncList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+func (in *GitSyncResource) DeepCopyInto(out *GitSyncResource) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
-func (in *DomainStatus) DeepCopy() *DomainStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSyncResource.
+func (in *GitSyncResource) DeepCopy() *GitSyncResource {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainStatus)
+	out := new(GitSyncResource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+func (in *GitSyncSpec) DeepCopyInto(out *GitSyncSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
-func (in *EnvVar) DeepCopy() *EnvVar {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSyncSpec.
+func (in *GitSyncSpec) DeepCopy() *GitSyncSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(EnvVar)
+	out := new(GitSyncSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ExtAuthzEndpoint) DeepCopyInto(out *ExtAuthzEndpoint) {
+func (in *GitSyncStatus) DeepCopyInto(out *GitSyncStatus) {
 	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]GitSyncResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.Drifted != nil {
+		in, out := &in.Drifted, &out.Drifted
+		*out = make([]GitSyncResource, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtAuthzEndpoint.
-func (in *ExtAuthzEndpoint) DeepCopy() *ExtAuthzEndpoint {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSyncStatus.
+func (in *GitSyncStatus) DeepCopy() *GitSyncStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ExtAuthzEndpoint)
+	out := new(GitSyncStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1256,6 +1997,26 @@ func (in *HttpRouteCondition) DeepCopy() *HttpRouteCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteConditionGroup) DeepCopyInto(out *HttpRouteConditionGroup) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]HttpRouteCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteConditionGroup.
+func (in *HttpRouteConditionGroup) DeepCopy() *HttpRouteConditionGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteConditionGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpRouteDelay) DeepCopyInto(out *HttpRouteDelay) {
 	*out = *in
@@ -1301,6 +2062,36 @@ func (in *HttpRouteDestinationStatus) DeepCopy() *HttpRouteDestinationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteDirectResponse) DeepCopyInto(out *HttpRouteDirectResponse) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteDirectResponse.
+func (in *HttpRouteDirectResponse) DeepCopy() *HttpRouteDirectResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteDirectResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteEnvoyFilterStatus) DeepCopyInto(out *HttpRouteEnvoyFilterStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteEnvoyFilterStatus.
+func (in *HttpRouteEnvoyFilterStatus) DeepCopy() *HttpRouteEnvoyFilterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteEnvoyFilterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpRouteFault) DeepCopyInto(out *HttpRouteFault) {
 	*out = *in
@@ -1316,6 +2107,65 @@ func (in *HttpRouteFault) DeepCopy() *HttpRouteFault {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteHeaderOperations) DeepCopyInto(out *HttpRouteHeaderOperations) {
+	*out = *in
+	if in.Set != nil {
+		in, out := &in.Set, &out.Set
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Add != nil {
+		in, out := &in.Add, &out.Add
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Remove != nil {
+		in, out := &in.Remove, &out.Remove
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteHeaderOperations.
+func (in *HttpRouteHeaderOperations) DeepCopy() *HttpRouteHeaderOperations {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteHeaderOperations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteHeaders) DeepCopyInto(out *HttpRouteHeaders) {
+	*out = *in
+	if in.Request != nil {
+		in, out := &in.Request, &out.Request
+		*out = new(HttpRouteHeaderOperations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Response != nil {
+		in, out := &in.Response, &out.Response
+		*out = new(HttpRouteHeaderOperations)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteHeaders.
+func (in *HttpRouteHeaders) DeepCopy() *HttpRouteHeaders {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteHeaders)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpRouteList) DeepCopyInto(out *HttpRouteList) {
 	*out = *in
@@ -1364,14 +2214,101 @@ func (in *HttpRouteMirror) DeepCopy() *HttpRouteMirror {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRateLimit) DeepCopyInto(out *HttpRouteRateLimit) {
+	*out = *in
+	if in.Key != nil {
+		in, out := &in.Key, &out.Key
+		*out = new(HttpRouteRateLimitKey)
+		**out = **in
+	}
+	if in.Response != nil {
+		in, out := &in.Response, &out.Response
+		*out = new(HttpRouteRateLimitResponse)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRateLimit.
+func (in *HttpRouteRateLimit) DeepCopy() *HttpRouteRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRateLimitKey) DeepCopyInto(out *HttpRouteRateLimitKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRateLimitKey.
+func (in *HttpRouteRateLimitKey) DeepCopy() *HttpRouteRateLimitKey {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRateLimitKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRateLimitResponse) DeepCopyInto(out *HttpRouteRateLimitResponse) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRateLimitResponse.
+func (in *HttpRouteRateLimitResponse) DeepCopy() *HttpRouteRateLimitResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRateLimitResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRedirect) DeepCopyInto(out *HttpRouteRedirect) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRedirect.
+func (in *HttpRouteRedirect) DeepCopy() *HttpRouteRedirect {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRedirect)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpRouteRetries) DeepCopyInto(out *HttpRouteRetries) {
 	*out = *in
+	if in.PerTryTimeoutMilliseconds != nil {
+		in, out := &in.PerTryTimeoutMilliseconds, &out.PerTryTimeoutMilliseconds
+		*out = new(int)
+		**out = **in
+	}
 	if in.RetryOn != nil {
 		in, out := &in.RetryOn, &out.RetryOn
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = new(HttpRouteRetryBudget)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRetries.
@@ -1384,6 +2321,41 @@ func (in *HttpRouteRetries) DeepCopy() *HttpRouteRetries {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRetryBudget) DeepCopyInto(out *HttpRouteRetryBudget) {
+	*out = *in
+	if in.MinRetryConcurrency != nil {
+		in, out := &in.MinRetryConcurrency, &out.MinRetryConcurrency
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRetryBudget.
+func (in *HttpRouteRetryBudget) DeepCopy() *HttpRouteRetryBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRetryBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpRouteRewrite) DeepCopyInto(out *HttpRouteRewrite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteRewrite.
+func (in *HttpRouteRewrite) DeepCopy() *HttpRouteRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpRouteRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpRouteSpec) DeepCopyInto(out *HttpRouteSpec) {
 	*out = *in
@@ -1407,21 +2379,53 @@ func (in *HttpRouteSpec) DeepCopyInto(out *HttpRouteSpec) {
 		*out = make([]HttpRouteScheme, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rewrite != nil {
+		in, out := &in.Rewrite, &out.Rewrite
+		*out = new(HttpRouteRewrite)
+		**out = **in
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = new(HttpRouteHeaders)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]HttpRouteCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConditionGroups != nil {
+		in, out := &in.ConditionGroups, &out.ConditionGroups
+		*out = make([]HttpRouteConditionGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Destinations != nil {
 		in, out := &in.Destinations, &out.Destinations
 		*out = make([]HttpRouteDestination, len(*in))
 		copy(*out, *in)
 	}
+	if in.Redirect != nil {
+		in, out := &in.Redirect, &out.Redirect
+		*out = new(HttpRouteRedirect)
+		**out = **in
+	}
+	if in.DirectResponse != nil {
+		in, out := &in.DirectResponse, &out.DirectResponse
+		*out = new(HttpRouteDirectResponse)
+		**out = **in
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(int)
 		**out = **in
 	}
+	if in.TimeoutMilliseconds != nil {
+		in, out := &in.TimeoutMilliseconds, &out.TimeoutMilliseconds
+		*out = new(int)
+		**out = **in
+	}
 	if in.Retries != nil {
 		in, out := &in.Retries, &out.Retries
 		*out = new(HttpRouteRetries)
@@ -1447,6 +2451,11 @@ func (in *HttpRouteSpec) DeepCopyInto(out *HttpRouteSpec) {
 		*out = new(HttpRouteCORS)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HttpRouteRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteSpec.
@@ -1474,6 +2483,11 @@ func (in *HttpRouteStatus) DeepCopyInto(out *HttpRouteStatus) {
 		*out = make([]HttpRouteDestinationStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.EnvoyFilters != nil {
+		in, out := &in.EnvoyFilters, &out.EnvoyFilters
+		*out = make([]HttpRouteEnvoyFilterStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpRouteStatus.
@@ -1513,6 +2527,52 @@ func (in *HttpsCert) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpsCertACMEChallengeRecord) DeepCopyInto(out *HttpsCertACMEChallengeRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpsCertACMEChallengeRecord.
+func (in *HttpsCertACMEChallengeRecord) DeepCopy() *HttpsCertACMEChallengeRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpsCertACMEChallengeRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpsCertACMEOrder) DeepCopyInto(out *HttpsCertACMEOrder) {
+	*out = *in
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Records != nil {
+		in, out := &in.Records, &out.Records
+		*out = make([]HttpsCertACMEChallengeRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.ChallengeURLs != nil {
+		in, out := &in.ChallengeURLs, &out.ChallengeURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpsCertACMEOrder.
+func (in *HttpsCertACMEOrder) DeepCopy() *HttpsCertACMEOrder {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpsCertACMEOrder)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpsCertCondition) DeepCopyInto(out *HttpsCertCondition) {
 	*out = *in
@@ -1610,6 +2670,16 @@ func (in *HttpsCertIssuerSpec) DeepCopyInto(out *HttpsCertIssuerSpec) {
 		*out = new(DNS01Issuer)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ACMEDNSProvider != nil {
+		in, out := &in.ACMEDNSProvider, &out.ACMEDNSProvider
+		*out = new(ACMEDNSProviderIssuer)
+		**out = **in
+	}
+	if in.CA != nil {
+		in, out := &in.CA, &out.CA
+		*out = new(CAIssuer)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpsCertIssuerSpec.
@@ -1704,6 +2774,11 @@ func (in *HttpsCertStatus) DeepCopyInto(out *HttpsCertStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.ACMEOrder != nil {
+		in, out := &in.ACMEOrder, &out.ACMEOrder
+		*out = new(HttpsCertACMEOrder)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpsCertStatus.
@@ -1957,6 +3032,11 @@ func (in *Port) DeepCopy() *Port {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreInjectFile) DeepCopyInto(out *PreInjectFile) {
 	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreInjectFile.
@@ -2369,6 +3449,21 @@ func (in *TemporaryDexUser) DeepCopy() *TemporaryDexUser {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultPKIIssuer) DeepCopyInto(out *VaultPKIIssuer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultPKIIssuer.
+func (in *VaultPKIIssuer) DeepCopy() *VaultPKIIssuer {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultPKIIssuer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"golang.org/x/crypto/acme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	acmeDNS01ChallengeType = "dns-01"
+
+	// how long to wait for the TXT records to be visible in the DNSManager
+	acmeDNS01PropagationTimeout = 2 * time.Minute
+
+	// how often an ACME order in progress is checked
+	acmeOrderPollInterval = 5 * time.Second
+)
+
+// newACMEClient creates an ACME client for the directory, caBundle is optional PEM encoded CA certs to trust.
+func newACMEClient(accountKey crypto.Signer, directoryURL, caBundle string) (*acme.Client, error) {
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: directoryURL,
+	}
+
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, fmt.Errorf("no valid cert in CA bundle of ACME server")
+		}
+
+		client.HTTPClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	return client, nil
+}
+
+// newACMEClientForIssuer creates the ACME client of an ACMEDNSProvider issuer, keySec holds the account key.
+func newACMEClientForIssuer(issuer v1alpha1.HttpsCertIssuer, keySec corev1.Secret) (*acme.Client, error) {
+	spec := issuer.Spec.ACMEDNSProvider
+	if spec == nil {
+		return nil, fmt.Errorf("issuer %s is not an ACME DNS provider issuer", issuer.Name)
+	}
+
+	accountKey, err := parseACMEAccountKey(keySec.Data[SecretKeyOfTLSKey])
+	if err != nil {
+		return nil, err
+	}
+
+	directoryURL := spec.Server
+	if directoryURL == "" {
+		directoryURL = letsEncryptACMEIssuerServerURL
+	}
+
+	return newACMEClient(accountKey, directoryURL, spec.ServerCABundle)
+}
+
+// registerACMEAccount registers the account of the client, existing account is fine.
+func registerACMEAccount(ctx context.Context, client *acme.Client, email string) error {
+	account := &acme.Account{}
+	if email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+
+	return nil
+}
+
+func generateACMEAccountKey() (keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func parseACMEAccountKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse ACME account key PEM")
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// startDNS01Order creates an order of the domains, and sets the TXT records of its dns-01 challenges.
+// The order is moved forward by progressDNS01Order, so no call waits for DNS propagation or validation.
+func startDNS01Order(
+	ctx context.Context,
+	client *acme.Client,
+	dnsMgr DNSManager,
+	domains []string,
+) (*v1alpha1.HttpsCertACMEOrder, error) {
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &v1alpha1.HttpsCertACMEOrder{
+		URL:       order.URI,
+		Domains:   domains,
+		Phase:     v1alpha1.HttpsCertACMEOrderPhaseWaitingForRecords,
+		StartedAt: metav1.Now(),
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		var challenge *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == acmeDNS01ChallengeType {
+				challenge = c
+				break
+			}
+		}
+
+		if challenge == nil {
+			return nil, fmt.Errorf("no dns-01 challenge for domain: %s", authz.Identifier.Value)
+		}
+
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Records = append(res.Records, v1alpha1.HttpsCertACMEChallengeRecord{
+			Name:  "_acme-challenge." + authz.Identifier.Value,
+			Value: value,
+		})
+		res.ChallengeURLs = append(res.ChallengeURLs, challenge.URI)
+	}
+
+	records := groupDNS01Records(res)
+
+	for name, values := range records {
+		// remove records left by former orders
+		if err := dnsMgr.DeleteDNSRecord(v1alpha1.DNSTypeTXT, name); err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			if err := dnsMgr.CreateDNSRecord(v1alpha1.DNSTypeTXT, name, value); err != nil {
+				cleanupDNS01Order(dnsMgr, res)
+				return nil, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// progressDNS01Order moves the order one step forward, the cert and key are returned once the cert is issued.
+// Nothing is returned if the order is still waiting, the caller checks it again later.
+func progressDNS01Order(
+	ctx context.Context,
+	client *acme.Client,
+	dnsMgr DNSManager,
+	order *v1alpha1.HttpsCertACMEOrder,
+) (certPEM, keyPEM []byte, err error) {
+
+	switch order.Phase {
+	case v1alpha1.HttpsCertACMEOrderPhaseWaitingForRecords:
+		exist, err := dnsRecordsExist(dnsMgr, groupDNS01Records(order))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !exist {
+			if time.Since(order.StartedAt.Time) > acmeDNS01PropagationTimeout {
+				return nil, nil, fmt.Errorf("dns-01 challenge records not ready after %s", acmeDNS01PropagationTimeout)
+			}
+
+			return nil, nil, nil
+		}
+
+		for _, challengeURL := range order.ChallengeURLs {
+			if _, err := client.Accept(ctx, &acme.Challenge{URI: challengeURL}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		order.Phase = v1alpha1.HttpsCertACMEOrderPhaseValidating
+
+		return nil, nil, nil
+	case v1alpha1.HttpsCertACMEOrderPhaseValidating:
+		o, err := client.GetOrder(ctx, order.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch o.Status {
+		case acme.StatusPending, acme.StatusProcessing:
+			return nil, nil, nil
+		case acme.StatusReady:
+			return finalizeOrder(ctx, client, o.FinalizeURL, order.Domains)
+		default:
+			// a valid order here was finalized with a key that is lost, a new order is needed as well
+			return nil, nil, &acme.OrderError{OrderURL: o.URI, Status: o.Status}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown ACME order phase: %s", order.Phase)
+	}
+}
+
+func finalizeOrder(ctx context.Context, client *acme.Client, finalizeURL string, domains []string) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: pickCommonName(domains)},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, finalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var certBuf bytes.Buffer
+	for _, b := range der {
+		if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: b}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return certBuf.Bytes(), keyPEM, nil
+}
+
+// cleanupDNS01Order removes the challenge records of the order, it's called once the order is done or failed.
+func cleanupDNS01Order(dnsMgr DNSManager, order *v1alpha1.HttpsCertACMEOrder) {
+	for name := range groupDNS01Records(order) {
+		if err := dnsMgr.DeleteDNSRecord(v1alpha1.DNSTypeTXT, name); err != nil {
+			mLog.Error(err, "fail to clean dns-01 challenge record", "name", name)
+		}
+	}
+}
+
+// a domain and its wildcard share the same record name, so values are grouped by name
+func groupDNS01Records(order *v1alpha1.HttpsCertACMEOrder) map[string][]string {
+	records := make(map[string][]string)
+
+	for _, record := range order.Records {
+		records[record.Name] = append(records[record.Name], record.Value)
+	}
+
+	return records
+}
+
+func dnsRecordsExist(dnsMgr DNSManager, records map[string][]string) (bool, error) {
+	for name, values := range records {
+		for _, value := range values {
+			exist, err := dnsMgr.Exist(v1alpha1.DNSTypeTXT, name, value)
+			if err != nil {
+				return false, err
+			}
+
+			if !exist {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// newDNSManagerForIssuer returns the DNSManager of the issuer's provider, default to the one of the controller.
+func newDNSManagerForIssuer(issuer v1alpha1.HttpsCertIssuer) (DNSManager, error) {
+	if issuer.Spec.ACMEDNSProvider != nil && issuer.Spec.ACMEDNSProvider.DNSProvider != "" {
+		return NewDNSManager(issuer.Spec.ACMEDNSProvider.DNSProvider)
+	}
+
+	return initDNSManagerFromEnv()
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsCertForDomains(t *testing.T) {
+	cert := &x509.Certificate{DNSNames: []string{"*.kalm.test", "kalm.test"}}
+
+	assert.True(t, isCertForDomains(cert, []string{"kalm.test", "*.KALM.test"}))
+	assert.False(t, isCertForDomains(cert, []string{"kalm.test"}))
+	assert.False(t, isCertForDomains(cert, []string{"kalm.test", "foo.kalm.test"}))
+}
+
+func TestDNSRecordsExist(t *testing.T) {
+	server := startTestDNSServer(t, "kalm.test", nil)
+	mgr := NewRFC2136DNSManager(server.Addr, []string{"kalm.test"})
+
+	// a domain and its wildcard share the record name
+	order := &v1alpha1.HttpsCertACMEOrder{
+		Phase: v1alpha1.HttpsCertACMEOrderPhaseWaitingForRecords,
+		Records: []v1alpha1.HttpsCertACMEChallengeRecord{
+			{Name: "_acme-challenge.kalm.test", Value: "token-1"},
+			{Name: "_acme-challenge.kalm.test", Value: "token-2"},
+		},
+		StartedAt: metav1.Now(),
+	}
+
+	records := groupDNS01Records(order)
+	assert.Len(t, records, 1)
+
+	for _, value := range records["_acme-challenge.kalm.test"] {
+		assert.Nil(t, mgr.CreateDNSRecord(v1alpha1.DNSTypeTXT, "_acme-challenge.kalm.test", value))
+	}
+
+	exist, err := dnsRecordsExist(mgr, records)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	order.Records = append(order.Records, v1alpha1.HttpsCertACMEChallengeRecord{Name: "_acme-challenge.kalm.test", Value: "token-3"})
+
+	exist, err = dnsRecordsExist(mgr, groupDNS01Records(order))
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	// the order keeps waiting for the records, until the propagation timeout
+	certPEM, _, err := progressDNS01Order(context.Background(), nil, mgr, order)
+	assert.Nil(t, err)
+	assert.Nil(t, certPEM)
+	assert.Equal(t, v1alpha1.HttpsCertACMEOrderPhaseWaitingForRecords, order.Phase)
+
+	order.StartedAt = metav1.NewTime(time.Now().Add(-acmeDNS01PropagationTimeout - time.Second))
+	_, _, err = progressDNS01Order(context.Background(), nil, mgr, order)
+	assert.NotNil(t, err)
+
+	cleanupDNS01Order(mgr, order)
+
+	exist, err = dnsRecordsExist(mgr, map[string][]string{"_acme-challenge.kalm.test": {"token-1"}})
+	assert.Nil(t, err)
+	assert.False(t, exist)
+}
+
+// Run against Pebble (https://github.com/letsencrypt/pebble) started with `-dnsserver $PEBBLE_DNS_SERVER`.
+// PEBBLE_DIRECTORY_URL is the directory url, e.g. https://localhost:14000/dir
+// PEBBLE_CA_BUNDLE is the path of the CA cert of Pebble's https server.
+func TestACMEDNS01WithPebble(t *testing.T) {
+	directoryURL := os.Getenv("PEBBLE_DIRECTORY_URL")
+	dnsServerAddr := os.Getenv("PEBBLE_DNS_SERVER")
+	if directoryURL == "" || dnsServerAddr == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL or PEBBLE_DNS_SERVER not set")
+	}
+
+	var caBundle []byte
+	if path := os.Getenv("PEBBLE_CA_BUNDLE"); path != "" {
+		var err error
+		caBundle, err = ioutil.ReadFile(path)
+		assert.Nil(t, err)
+	}
+
+	server := startTestDNSServerOn(t, dnsServerAddr, "kalm.test", nil)
+	dnsMgr := NewRFC2136DNSManager(server.Addr, []string{"kalm.test"})
+
+	keyPEM, err := generateACMEAccountKey()
+	assert.Nil(t, err)
+
+	accountKey, err := parseACMEAccountKey(keyPEM)
+	assert.Nil(t, err)
+
+	client, err := newACMEClient(accountKey, directoryURL, string(caBundle))
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	assert.Nil(t, registerACMEAccount(ctx, client, "admin@kalm.test"))
+
+	// register again is fine
+	assert.Nil(t, registerACMEAccount(ctx, client, "admin@kalm.test"))
+
+	domains := []string{"kalm.test", "*.kalm.test"}
+	order, err := startDNS01Order(ctx, client, dnsMgr, domains)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// the order is moved forward by reconciles, each of them doesn't wait
+	var certPEM []byte
+	for certPEM == nil && time.Since(order.StartedAt.Time) < acmeCertObtainTimeout {
+		certPEM, keyPEM, err = progressDNS01Order(ctx, client, dnsMgr, order)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	cleanupDNS01Order(dnsMgr, order)
+
+	assert.NotEmpty(t, keyPEM)
+
+	cert, _, err := ParseCert(string(certPEM))
+	assert.Nil(t, err)
+	assert.True(t, isCertForDomains(cert, domains))
+
+	// challenge records are cleaned
+	records, err := dnsMgr.GetDNSRecords("_acme-challenge.kalm.test")
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+}
@@ -360,7 +360,7 @@ func (r *ACMEServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&corev1.Service{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: &ACMEDNSServiceMapper{},
 		}).
-		Complete(r)
+		Complete(instrumentReconciler("ACMEServerReconciler", r))
 }
 
 func (r *ACMEServerReconciler) registerDomainsInACMEServer(
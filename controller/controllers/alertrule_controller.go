@@ -0,0 +1,520 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// how often undelivered notifications are retried
+	alertNotificationRetryInterval = 30 * time.Second
+
+	// notifications not delivered within this duration are dropped
+	alertNotificationRetryTimeout = time.Hour
+)
+
+// AlertRuleReconciler evaluates alert rules periodically and notifies channels when alerts fire or are resolved
+type AlertRuleReconciler struct {
+	*BaseReconciler
+	ctx           context.Context
+	metricsSource AlertMetricsSource
+	notifier      AlertNotifier
+	now           func() time.Time
+}
+
+func NewAlertRuleReconciler(mgr ctrl.Manager) (*AlertRuleReconciler, error) {
+	metricsClient, err := metricsv.NewForConfig(mgr.GetConfig())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AlertRuleReconciler{
+		BaseReconciler: NewBaseReconciler(mgr, "AlertRule"),
+		ctx:            context.Background(),
+		metricsSource:  newDefaultAlertMetricsSource(mgr.GetClient(), metricsClient),
+		notifier:       newDefaultAlertNotifier(mgr.GetClient()),
+		now:            time.Now,
+	}, nil
+}
+
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=alertrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=alertrules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
+
+func (r *AlertRuleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	var rule v1alpha1.AlertRule
+
+	if err := r.Get(r.ctx, req.NamespacedName, &rule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if rule.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	now := r.now()
+	values, err := r.evaluate(&rule)
+
+	rule.Status.LastEvaluationTimestamp = now.Unix()
+
+	var notifications []AlertNotification
+
+	if err != nil {
+		// alerts are kept as they are until the rule can be evaluated again
+		rule.Status.Message = err.Error()
+		r.EmitWarningEvent(&rule, err, "fail to evaluate alert rule")
+	} else {
+		rule.Status.Message = ""
+		notifications = r.updateAlerts(&rule, values, now)
+	}
+
+	// the state is saved before notifying, a failed update never leads to notifications of a state that is lost
+	if err := r.Status().Update(r.ctx, &rule); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, notification := range notifications {
+		r.EmitNormalEvent(&rule, "Alert", notification.Message)
+	}
+
+	if r.deliverNotifications(&rule, now) {
+		if err := r.Status().Update(r.ctx, &rule); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	requeueAfter := rule.GetInterval()
+
+	if hasUndeliveredNotifications(&rule) && alertNotificationRetryInterval < requeueAfter {
+		requeueAfter = alertNotificationRetryInterval
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// evaluate returns the current value of each target of the rule
+func (r *AlertRuleReconciler) evaluate(rule *v1alpha1.AlertRule) (map[string]int64, error) {
+	var values map[string]int64
+	var err error
+
+	switch rule.Spec.Type {
+	case v1alpha1.AlertRuleTypeCPU, v1alpha1.AlertRuleTypeMemory:
+		values, err = r.evaluateResourceUsage(rule)
+	case v1alpha1.AlertRuleTypeRestarts:
+		values, err = r.evaluateRestarts(rule)
+	case v1alpha1.AlertRuleTypeCrashLoopBackOff:
+		values, err = r.evaluateCrashLoopBackOff(rule)
+	case v1alpha1.AlertRuleTypeCertExpiry:
+		values, err = r.evaluateCertExpiry()
+	case v1alpha1.AlertRuleTypeHttp5xxRatio:
+		values, err = r.evaluateHttp5xxRatio(rule)
+	default:
+		return nil, fmt.Errorf("unknown alert rule type %s", rule.Spec.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Spec.Target == "" {
+		return values, nil
+	}
+
+	if value, exist := values[rule.Spec.Target]; exist {
+		return map[string]int64{rule.Spec.Target: value}, nil
+	}
+
+	return map[string]int64{}, nil
+}
+
+func (r *AlertRuleReconciler) evaluateResourceUsage(rule *v1alpha1.AlertRule) (map[string]int64, error) {
+	usages, err := r.metricsSource.GetComponentsUsage(r.ctx, rule.Namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(usages))
+
+	for component, usage := range usages {
+		if rule.Spec.Type == v1alpha1.AlertRuleTypeCPU {
+			values[component] = usage.CPU
+		} else {
+			values[component] = usage.Memory / 1024 / 1024
+		}
+	}
+
+	return values, nil
+}
+
+func (r *AlertRuleReconciler) listComponentPods(namespace string) (map[string][]corev1.Pod, error) {
+	var pods corev1.PodList
+
+	if err := r.List(r.ctx, &pods, client.InNamespace(namespace), client.HasLabels{v1alpha1.KalmLabelComponentKey}); err != nil {
+		return nil, err
+	}
+
+	rst := make(map[string][]corev1.Pod)
+
+	for _, pod := range pods.Items {
+		component := pod.Labels[v1alpha1.KalmLabelComponentKey]
+		rst[component] = append(rst[component], pod)
+	}
+
+	return rst, nil
+}
+
+// evaluateRestarts returns the increase of restarts since the last evaluation,
+// the total restarts are saved in the status for the next evaluation.
+func (r *AlertRuleReconciler) evaluateRestarts(rule *v1alpha1.AlertRule) (map[string]int64, error) {
+	podsOfComponents, err := r.listComponentPods(rule.Namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(podsOfComponents))
+	totals := make(map[string]int64, len(podsOfComponents))
+
+	for component, pods := range podsOfComponents {
+		var total int64
+
+		for _, pod := range pods {
+			for _, status := range pod.Status.ContainerStatuses {
+				total += int64(status.RestartCount)
+			}
+		}
+
+		totals[component] = total
+
+		// restarts are lost when pods are replaced, the total may go down
+		if last, exist := rule.Status.LastValues[component]; exist && total > last {
+			values[component] = total - last
+		} else {
+			values[component] = 0
+		}
+	}
+
+	rule.Status.LastValues = totals
+
+	return values, nil
+}
+
+func (r *AlertRuleReconciler) evaluateCrashLoopBackOff(rule *v1alpha1.AlertRule) (map[string]int64, error) {
+	podsOfComponents, err := r.listComponentPods(rule.Namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(podsOfComponents))
+
+	for component, pods := range podsOfComponents {
+		var count int64
+
+		for _, pod := range pods {
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+					count++
+				}
+			}
+		}
+
+		values[component] = count
+	}
+
+	return values, nil
+}
+
+func (r *AlertRuleReconciler) evaluateCertExpiry() (map[string]int64, error) {
+	var certs v1alpha1.HttpsCertList
+
+	if err := r.List(r.ctx, &certs); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(certs.Items))
+
+	for _, cert := range certs.Items {
+		// not issued yet
+		if cert.Status.ExpireTimestamp <= 0 {
+			continue
+		}
+
+		values[cert.Name] = int64(time.Unix(cert.Status.ExpireTimestamp, 0).Sub(r.now()).Hours() / 24)
+	}
+
+	return values, nil
+}
+
+// evaluateHttp5xxRatio evaluates routes to the services of the application of the rule
+func (r *AlertRuleReconciler) evaluateHttp5xxRatio(rule *v1alpha1.AlertRule) (map[string]int64, error) {
+	var routes v1alpha1.HttpRouteList
+
+	if err := r.List(r.ctx, &routes); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64)
+
+	for _, route := range routes.Items {
+		if rule.Spec.Target != "" && route.Name != rule.Spec.Target {
+			continue
+		}
+
+		services := getServicesOfHttpRouteInNamespace(&route, rule.Namespace)
+
+		if len(services) == 0 {
+			continue
+		}
+
+		ratio, ok, err := r.metricsSource.GetHttp5xxRatio(r.ctx, services, rule.GetInterval())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			values[route.Name] = int64(ratio + 0.5)
+		}
+	}
+
+	return values, nil
+}
+
+// getServicesOfHttpRouteInNamespace returns destination services of the route in the namespace, in format of name.namespace.svc.cluster.local
+func getServicesOfHttpRouteInNamespace(route *v1alpha1.HttpRoute, namespace string) []string {
+	var rst []string
+	seen := make(map[string]bool)
+
+	for _, dest := range route.Spec.Destinations {
+		// the host is in format of name.namespace.svc.cluster.local:port
+		parts := strings.Split(strings.Split(dest.Host, ":")[0], ".")
+
+		if len(parts) < 2 || parts[0] == "" || parts[1] != namespace {
+			continue
+		}
+
+		service := fmt.Sprintf("%s.%s.svc.cluster.local", parts[0], namespace)
+
+		if seen[service] {
+			continue
+		}
+
+		seen[service] = true
+		rst = append(rst, service)
+	}
+
+	return rst
+}
+
+// updateAlerts moves alerts of the rule through pending, firing and resolved with the new values.
+// Notifications of firing and resolved alerts are returned, unless the alert is silenced.
+func (r *AlertRuleReconciler) updateAlerts(rule *v1alpha1.AlertRule, values map[string]int64, now time.Time) []AlertNotification {
+	var notifications []AlertNotification
+
+	activeAlerts := make(map[string]v1alpha1.Alert, len(rule.Status.Alerts))
+	for _, alert := range rule.Status.Alerts {
+		activeAlerts[alert.Target] = alert
+	}
+
+	targets := make([]string, 0, len(values))
+	for target := range values {
+		targets = append(targets, target)
+	}
+
+	sort.Strings(targets)
+
+	record := func(alert v1alpha1.Alert, state v1alpha1.AlertState) {
+		silence := rule.GetActiveSilence(alert.Target, now)
+		message := formatAlertMessage(rule, alert.Target, alert.Value, state)
+
+		rule.Status.History = append(rule.Status.History, v1alpha1.AlertHistoryRecord{
+			Target:    alert.Target,
+			State:     state,
+			Value:     alert.Value,
+			Timestamp: now.Unix(),
+			Silenced:  silence != nil,
+			Message:   message,
+		})
+
+		if silence != nil {
+			return
+		}
+
+		historyRecord := &rule.Status.History[len(rule.Status.History)-1]
+
+		for i := range rule.Spec.Channels {
+			historyRecord.UndeliveredChannels = append(historyRecord.UndeliveredChannels, i)
+		}
+
+		notifications = append(notifications, buildAlertNotification(rule, *historyRecord))
+	}
+
+	var alerts []v1alpha1.Alert
+
+	for _, target := range targets {
+		value := values[target]
+
+		if !rule.IsConditionMet(value) {
+			continue
+		}
+
+		alert, exist := activeAlerts[target]
+		delete(activeAlerts, target)
+
+		if !exist {
+			alert = v1alpha1.Alert{
+				Target:         target,
+				State:          v1alpha1.AlertStatePending,
+				SinceTimestamp: now.Unix(),
+			}
+		}
+
+		alert.Value = value
+
+		if alert.State == v1alpha1.AlertStatePending && now.Unix()-alert.SinceTimestamp >= int64(rule.Spec.ForSeconds) {
+			alert.State = v1alpha1.AlertStateFiring
+			alert.FiringTimestamp = now.Unix()
+			record(alert, v1alpha1.AlertStateFiring)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	// the rest are not met anymore, or the targets are gone
+	for _, alert := range rule.Status.Alerts {
+		if _, exist := activeAlerts[alert.Target]; !exist || alert.State != v1alpha1.AlertStateFiring {
+			continue
+		}
+
+		if value, exist := values[alert.Target]; exist {
+			alert.Value = value
+		}
+
+		record(alert, v1alpha1.AlertStateResolved)
+	}
+
+	rule.Status.Alerts = alerts
+
+	if len(rule.Status.History) > v1alpha1.AlertRuleHistoryLimit {
+		rule.Status.History = rule.Status.History[len(rule.Status.History)-v1alpha1.AlertRuleHistoryLimit:]
+	}
+
+	return notifications
+}
+
+func formatAlertMessage(rule *v1alpha1.AlertRule, target string, value int64, state v1alpha1.AlertState) string {
+	var subject, unit string
+
+	switch rule.Spec.Type {
+	case v1alpha1.AlertRuleTypeCPU:
+		subject, unit = "cpu usage of component "+target, "m"
+	case v1alpha1.AlertRuleTypeMemory:
+		subject, unit = "memory usage of component "+target, "Mi"
+	case v1alpha1.AlertRuleTypeRestarts:
+		subject = "restarts of component " + target
+	case v1alpha1.AlertRuleTypeCrashLoopBackOff:
+		subject = "containers in CrashLoopBackOff of component " + target
+	case v1alpha1.AlertRuleTypeCertExpiry:
+		subject, unit = "days until https cert "+target+" expires", ""
+	case v1alpha1.AlertRuleTypeHttp5xxRatio:
+		subject, unit = "5xx responses of http route "+target, "%"
+	}
+
+	comparison := "above"
+	if rule.Spec.Type == v1alpha1.AlertRuleTypeCertExpiry {
+		comparison = "below"
+	}
+
+	if state == v1alpha1.AlertStateResolved {
+		return fmt.Sprintf("[resolved] %s/%s: %s is %d%s, back within the threshold %d%s", rule.Namespace, rule.Name, subject, value, unit, rule.Spec.Threshold, unit)
+	}
+
+	return fmt.Sprintf("[firing] %s/%s: %s is %d%s, %s the threshold %d%s", rule.Namespace, rule.Name, subject, value, unit, comparison, rule.Spec.Threshold, unit)
+}
+
+func buildAlertNotification(rule *v1alpha1.AlertRule, record v1alpha1.AlertHistoryRecord) AlertNotification {
+	return AlertNotification{
+		Namespace: rule.Namespace,
+		Rule:      rule.Name,
+		Type:      rule.Spec.Type,
+		Target:    record.Target,
+		State:     record.State,
+		Value:     record.Value,
+		Threshold: rule.Spec.Threshold,
+		Timestamp: record.Timestamp,
+		Message:   record.Message,
+	}
+}
+
+// deliverNotifications sends the history records to the channels they are not delivered to yet,
+// failed channels don't stop the others. It returns whether any record is changed.
+func (r *AlertRuleReconciler) deliverNotifications(rule *v1alpha1.AlertRule, now time.Time) bool {
+	changed := false
+
+	for i := range rule.Status.History {
+		record := &rule.Status.History[i]
+
+		if len(record.UndeliveredChannels) == 0 {
+			continue
+		}
+
+		changed = true
+		notification := buildAlertNotification(rule, *record)
+
+		var undelivered []int
+
+		for _, idx := range record.UndeliveredChannels {
+			// channels may be removed after the record
+			if idx >= len(rule.Spec.Channels) {
+				continue
+			}
+
+			channel := rule.Spec.Channels[idx]
+
+			if err := r.notifier.Notify(r.ctx, rule, channel, notification); err != nil {
+				r.EmitWarningEvent(rule, err, "fail to notify %s channel: %s", channel.Type, err.Error())
+				undelivered = append(undelivered, idx)
+			}
+		}
+
+		if len(undelivered) > 0 && now.Sub(time.Unix(record.Timestamp, 0)) > alertNotificationRetryTimeout {
+			r.EmitWarningEvent(rule, fmt.Errorf("notification is not delivered in %s", alertNotificationRetryTimeout), "give up notification: %s", record.Message)
+			undelivered = nil
+		}
+
+		record.UndeliveredChannels = undelivered
+	}
+
+	return changed
+}
+
+func hasUndeliveredNotifications(rule *v1alpha1.AlertRule) bool {
+	for _, record := range rule.Status.History {
+		if len(record.UndeliveredChannels) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *AlertRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// rules are evaluated periodically, status updates don't trigger an extra evaluation
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.AlertRule{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(instrumentReconciler("AlertRuleReconciler", r))
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeAlertMetricsSource struct {
+	usages map[string]ComponentResourceUsage
+	ratio  float64
+}
+
+func (s *fakeAlertMetricsSource) GetComponentsUsage(ctx context.Context, namespace string) (map[string]ComponentResourceUsage, error) {
+	return s.usages, nil
+}
+
+func (s *fakeAlertMetricsSource) GetHttp5xxRatio(ctx context.Context, services []string, window time.Duration) (float64, bool, error) {
+	return s.ratio, true, nil
+}
+
+type recordingAlertNotifier struct {
+	notifications []AlertNotification
+}
+
+func (n *recordingAlertNotifier) Notify(ctx context.Context, rule *v1alpha1.AlertRule, channel v1alpha1.AlertChannel, notification AlertNotification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// failingAlertNotifier fails until it's fixed, the alert state saved when notified is recorded
+type failingAlertNotifier struct {
+	r        *AlertRuleReconciler
+	fixed    bool
+	attempts int
+	states   []v1alpha1.AlertState
+}
+
+func (n *failingAlertNotifier) Notify(ctx context.Context, rule *v1alpha1.AlertRule, channel v1alpha1.AlertChannel, notification AlertNotification) error {
+	n.attempts++
+
+	var saved v1alpha1.AlertRule
+	if err := n.r.Get(ctx, types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}, &saved); err == nil && len(saved.Status.Alerts) > 0 {
+		n.states = append(n.states, saved.Status.Alerts[0].State)
+	}
+
+	if !n.fixed {
+		return fmt.Errorf("channel is down")
+	}
+
+	return nil
+}
+
+func newFakeAlertRuleReconciler(source AlertMetricsSource, notifier AlertNotifier, now *time.Time, objs ...runtime.Object) *AlertRuleReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	return &AlertRuleReconciler{
+		BaseReconciler: &BaseReconciler{
+			Client:   fake.NewFakeClientWithScheme(scheme, objs...),
+			Log:      ctrl.Log.WithName("test"),
+			Scheme:   scheme,
+			Recorder: record.NewFakeRecorder(100),
+		},
+		ctx:           context.Background(),
+		metricsSource: source,
+		notifier:      notifier,
+		now:           func() time.Time { return *now },
+	}
+}
+
+func TestAlertRuleReconcileCPU(t *testing.T) {
+	rule := &v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "cpu"},
+		Spec: v1alpha1.AlertRuleSpec{
+			Type:       v1alpha1.AlertRuleTypeCPU,
+			Threshold:  500,
+			ForSeconds: 120,
+			Channels:   []v1alpha1.AlertChannel{{Type: v1alpha1.AlertChannelTypeWebhook, URL: "http://kalm.test"}},
+		},
+	}
+
+	source := &fakeAlertMetricsSource{usages: map[string]ComponentResourceUsage{
+		"web": {CPU: 800},
+		"api": {CPU: 100},
+	}}
+	notifier := &recordingAlertNotifier{}
+	now := time.Now()
+	r := newFakeAlertRuleReconciler(source, notifier, &now, rule)
+
+	reconcile := func() *v1alpha1.AlertRule {
+		result, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "cpu"}})
+		assert.Nil(t, err)
+		assert.Equal(t, time.Minute, result.RequeueAfter)
+
+		var rule v1alpha1.AlertRule
+		assert.Nil(t, r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: "cpu"}, &rule))
+		return &rule
+	}
+
+	// pending until the condition holds for 2 minutes
+	rule = reconcile()
+	assert.Len(t, rule.Status.Alerts, 1)
+	assert.Equal(t, v1alpha1.AlertStatePending, rule.Status.Alerts[0].State)
+	assert.Len(t, notifier.notifications, 0)
+
+	now = now.Add(2 * time.Minute)
+	rule = reconcile()
+	assert.Equal(t, v1alpha1.AlertStateFiring, rule.Status.Alerts[0].State)
+	assert.Len(t, rule.Status.History, 1)
+	if assert.Len(t, notifier.notifications, 1) {
+		assert.Equal(t, "web", notifier.notifications[0].Target)
+		assert.Equal(t, int64(800), notifier.notifications[0].Value)
+		assert.Equal(t, "[firing] shop/cpu: cpu usage of component web is 800m, above the threshold 500m", notifier.notifications[0].Message)
+	}
+
+	// still firing, not notified again
+	now = now.Add(time.Minute)
+	rule = reconcile()
+	assert.Len(t, notifier.notifications, 1)
+
+	// resolved notifications are not sent in silence, but still recorded
+	rule.Spec.Silences = []v1alpha1.AlertSilence{{ID: "maintenance", Target: "web", EndTimestamp: now.Add(time.Hour).Unix()}}
+	assert.Nil(t, r.Update(r.ctx, rule))
+
+	source.usages["web"] = ComponentResourceUsage{CPU: 300}
+	now = now.Add(time.Minute)
+	rule = reconcile()
+	assert.Len(t, rule.Status.Alerts, 0)
+	assert.Len(t, notifier.notifications, 1)
+	if assert.Len(t, rule.Status.History, 2) {
+		assert.Equal(t, v1alpha1.AlertStateResolved, rule.Status.History[1].State)
+		assert.True(t, rule.Status.History[1].Silenced)
+	}
+}
+
+func TestAlertRuleRetryNotifications(t *testing.T) {
+	rule := &v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "cpu"},
+		Spec: v1alpha1.AlertRuleSpec{
+			Type:      v1alpha1.AlertRuleTypeCPU,
+			Threshold: 500,
+			Channels:  []v1alpha1.AlertChannel{{Type: v1alpha1.AlertChannelTypeWebhook, URL: "http://kalm.test"}},
+		},
+	}
+
+	source := &fakeAlertMetricsSource{usages: map[string]ComponentResourceUsage{"web": {CPU: 800}}}
+	notifier := &failingAlertNotifier{}
+	now := time.Now()
+	r := newFakeAlertRuleReconciler(source, notifier, &now, rule)
+	notifier.r = r
+
+	reconcile := func() (ctrl.Result, *v1alpha1.AlertRule) {
+		result, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "cpu"}})
+		assert.Nil(t, err)
+
+		var rule v1alpha1.AlertRule
+		assert.Nil(t, r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: "cpu"}, &rule))
+		return result, &rule
+	}
+
+	// the firing state is saved before notifying, the failed delivery is retried sooner than the interval
+	result, rule := reconcile()
+	assert.Equal(t, []v1alpha1.AlertState{v1alpha1.AlertStateFiring}, notifier.states)
+	assert.Equal(t, alertNotificationRetryInterval, result.RequeueAfter)
+	if assert.Len(t, rule.Status.History, 1) {
+		assert.Equal(t, []int{0}, rule.Status.History[0].UndeliveredChannels)
+	}
+
+	notifier.fixed = true
+	now = now.Add(alertNotificationRetryInterval)
+	result, rule = reconcile()
+	assert.Equal(t, 2, notifier.attempts)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+	assert.Len(t, rule.Status.History, 1)
+	assert.Empty(t, rule.Status.History[0].UndeliveredChannels)
+
+	// delivered notifications are not sent again
+	now = now.Add(time.Minute)
+	reconcile()
+	assert.Equal(t, 2, notifier.attempts)
+
+	// undelivered notifications are dropped after the retry timeout
+	notifier.fixed = false
+	source.usages["web"] = ComponentResourceUsage{CPU: 100}
+	now = now.Add(time.Minute)
+	_, rule = reconcile()
+	assert.Equal(t, 3, notifier.attempts)
+	assert.Equal(t, []int{0}, rule.Status.History[1].UndeliveredChannels)
+
+	now = now.Add(alertNotificationRetryTimeout + time.Minute)
+	_, rule = reconcile()
+	assert.Equal(t, 4, notifier.attempts)
+	assert.Empty(t, rule.Status.History[1].UndeliveredChannels)
+}
+
+func TestSendMailTimeout(t *testing.T) {
+	// a server accepting connections without ever greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = sendMailWithContext(ctx, listener.Addr().String(), "127.0.0.1", nil, "kalm@kalm.test", []string{"admin@kalm.test"}, []byte("hi"))
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < smtpTimeout)
+}
+
+func TestAlertRuleReconcileRestartsAndCrashLoop(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "shop",
+			Name:      "web-1",
+			Labels:    map[string]string{v1alpha1.KalmLabelComponentKey: "web"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "web",
+					RestartCount: 3,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				},
+			},
+		},
+	}
+
+	restarts := &v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "restarts"},
+		Spec:       v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeRestarts, Threshold: 1},
+	}
+
+	now := time.Now()
+	r := newFakeAlertRuleReconciler(&fakeAlertMetricsSource{}, &recordingAlertNotifier{}, &now, pod, restarts)
+
+	// the first evaluation only saves the restarts
+	values, err := r.evaluate(restarts)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), values["web"])
+	assert.Equal(t, int64(3), restarts.Status.LastValues["web"])
+
+	pod.Status.ContainerStatuses[0].RestartCount = 5
+	assert.Nil(t, r.Update(r.ctx, pod))
+
+	values, err = r.evaluate(restarts)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), values["web"])
+
+	crashLoop := &v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "crash-loop"},
+		Spec:       v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeCrashLoopBackOff, Target: "web"},
+	}
+
+	values, err = r.evaluate(crashLoop)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"web": 1}, values)
+}
+
+func TestAlertRuleEvaluateCertsAndRoutes(t *testing.T) {
+	now := time.Now()
+
+	cert := &v1alpha1.HttpsCert{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+		Status:     v1alpha1.HttpsCertStatus{ExpireTimestamp: now.Add(10*24*time.Hour + time.Hour).Unix()},
+	}
+	notIssued := &v1alpha1.HttpsCert{ObjectMeta: metav1.ObjectMeta{Name: "blog"}}
+
+	route := &v1alpha1.HttpRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{Host: "web.shop.svc.cluster.local:80", Weight: 1},
+				{Host: "web.blog.svc.cluster.local:80", Weight: 1},
+			},
+		},
+	}
+	otherRoute := &v1alpha1.HttpRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "blog"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Destinations: []v1alpha1.HttpRouteDestination{{Host: "web.blog.svc.cluster.local:80", Weight: 1}},
+		},
+	}
+
+	assert.Equal(t, []string{"web.shop.svc.cluster.local"}, getServicesOfHttpRouteInNamespace(route, "shop"))
+
+	r := newFakeAlertRuleReconciler(&fakeAlertMetricsSource{ratio: 12.6}, &recordingAlertNotifier{}, &now, cert, notIssued, route, otherRoute)
+
+	values, err := r.evaluate(&v1alpha1.AlertRule{Spec: v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeCertExpiry}})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"shop": 10}, values)
+
+	values, err = r.evaluate(&v1alpha1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop"},
+		Spec:       v1alpha1.AlertRuleSpec{Type: v1alpha1.AlertRuleTypeHttp5xxRatio},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"shop": 13}, values)
+}
+
+func TestAlertMetricsSourceHttp5xxRatio(t *testing.T) {
+	var result interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Contains(t, req.URL.Query().Get("query"), `destination_service=~"web\\.shop\\.svc\\.cluster\\.local"`)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"result": result},
+		})
+	}))
+	defer server.Close()
+
+	source := newDefaultAlertMetricsSource(nil, nil)
+	source.prometheusAddress = server.URL
+
+	result = []interface{}{map[string]interface{}{"value": []interface{}{1600000000, "0.25"}}}
+	ratio, ok, err := source.GetHttp5xxRatio(context.Background(), []string{"web.shop.svc.cluster.local"}, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 25.0, ratio)
+
+	// no 5xx responses
+	result = []interface{}{}
+	ratio, ok, err = source.GetHttp5xxRatio(context.Background(), []string{"web.shop.svc.cluster.local"}, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, ratio)
+
+	// no requests
+	result = []interface{}{map[string]interface{}{"value": []interface{}{1600000000, "NaN"}}}
+	_, ok, err = source.GetHttp5xxRatio(context.Background(), []string{"web.shop.svc.cluster.local"}, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const smtpTimeout = 10 * time.Second
+
+// AlertNotification is posted to webhook channels as json
+type AlertNotification struct {
+	Namespace string                 `json:"namespace"`
+	Rule      string                 `json:"rule"`
+	Type      v1alpha1.AlertRuleType `json:"type"`
+	Target    string                 `json:"target"`
+	State     v1alpha1.AlertState    `json:"state"`
+	Value     int64                  `json:"value"`
+	Threshold int64                  `json:"threshold"`
+	Timestamp int64                  `json:"timestamp"`
+	Message   string                 `json:"message"`
+}
+
+type AlertNotifier interface {
+	Notify(ctx context.Context, rule *v1alpha1.AlertRule, channel v1alpha1.AlertChannel, notification AlertNotification) error
+}
+
+type defaultAlertNotifier struct {
+	client.Reader
+	httpClient *http.Client
+}
+
+func newDefaultAlertNotifier(reader client.Reader) *defaultAlertNotifier {
+	return &defaultAlertNotifier{
+		Reader:     reader,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *defaultAlertNotifier) Notify(ctx context.Context, rule *v1alpha1.AlertRule, channel v1alpha1.AlertChannel, notification AlertNotification) error {
+	switch channel.Type {
+	case v1alpha1.AlertChannelTypeWebhook:
+		return n.post(ctx, channel.URL, notification)
+	case v1alpha1.AlertChannelTypeSlack:
+		return n.post(ctx, channel.URL, map[string]string{"text": notification.Message})
+	case v1alpha1.AlertChannelTypeSMTP:
+		return n.sendMail(ctx, rule.Namespace, channel.SMTP, notification)
+	default:
+		return fmt.Errorf("unknown channel type %s", channel.Type)
+	}
+}
+
+func (n *defaultAlertNotifier) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+
+	return nil
+}
+
+func (n *defaultAlertNotifier) sendMail(ctx context.Context, namespace string, config *v1alpha1.AlertSMTPConfig, notification AlertNotification) error {
+	if config == nil {
+		return fmt.Errorf("smtp config is missing")
+	}
+
+	var auth smtp.Auth
+
+	if config.Username != "" {
+		var password string
+
+		if config.PasswordSecretName != "" {
+			var secret corev1.Secret
+			if err := n.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.PasswordSecretName}, &secret); err != nil {
+				return err
+			}
+
+			password = string(secret.Data["password"])
+		}
+
+		auth = smtp.PlainAuth("", config.Username, password, config.Host)
+	}
+
+	subject := fmt.Sprintf("[%s] %s/%s %s", strings.ToUpper(string(notification.State)), notification.Namespace, notification.Rule, notification.Target)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", notification.Message)
+
+	return sendMailWithContext(ctx, net.JoinHostPort(config.Host, strconv.Itoa(config.Port)), config.Host, auth, config.From, config.To, msg.Bytes())
+}
+
+// sendMailWithContext works like smtp.SendMail, but never takes longer than smtpTimeout,
+// and the connection is closed once the ctx is done.
+func sendMailWithContext(ctx context.Context, addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	dialer := net.Dialer{Timeout: smtpTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server %s doesn't support AUTH", addr)
+		}
+
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AlertMetricsSource provides metrics which are not in kubernetes objects
+type AlertMetricsSource interface {
+	// Resources used by pods of each component in the namespace
+	GetComponentsUsage(ctx context.Context, namespace string) (map[string]ComponentResourceUsage, error)
+
+	// Percentage of 5xx responses of the services during the window, ok is false if it can't be computed.
+	// Services are in format of name.namespace.svc.cluster.local
+	GetHttp5xxRatio(ctx context.Context, services []string, window time.Duration) (ratio float64, ok bool, err error)
+}
+
+type ComponentResourceUsage struct {
+	// millicores
+	CPU int64
+
+	// bytes
+	Memory int64
+}
+
+// defaultAlertMetricsSource reads pod metrics from metrics-server and istio metrics from the prometheus of istio
+type defaultAlertMetricsSource struct {
+	client.Reader
+	metricsClient     metricsv.Interface
+	prometheusAddress string
+	httpClient        *http.Client
+}
+
+func newDefaultAlertMetricsSource(reader client.Reader, metricsClient metricsv.Interface) *defaultAlertMetricsSource {
+	prometheusAddress := os.Getenv("KALM_ISTIO_PROMETHEUS_API_ADDRESS")
+
+	if prometheusAddress == "" {
+		prometheusAddress = "http://prometheus.istio-system:9090"
+	}
+
+	return &defaultAlertMetricsSource{
+		Reader:            reader,
+		metricsClient:     metricsClient,
+		prometheusAddress: prometheusAddress,
+		httpClient:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *defaultAlertMetricsSource) GetComponentsUsage(ctx context.Context, namespace string) (map[string]ComponentResourceUsage, error) {
+	var pods corev1.PodList
+	if err := s.List(ctx, &pods, client.InNamespace(namespace), client.HasLabels{v1alpha1.KalmLabelComponentKey}); err != nil {
+		return nil, err
+	}
+
+	componentOfPod := make(map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		componentOfPod[pod.Name] = pod.Labels[v1alpha1.KalmLabelComponentKey]
+	}
+
+	podMetricsList, err := s.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rst := make(map[string]ComponentResourceUsage)
+
+	for _, podMetrics := range podMetricsList.Items {
+		component, ok := componentOfPod[podMetrics.Name]
+		if !ok {
+			continue
+		}
+
+		usage := rst[component]
+
+		for _, container := range podMetrics.Containers {
+			usage.CPU += container.Usage.Cpu().MilliValue()
+			usage.Memory += container.Usage.Memory().Value()
+		}
+
+		rst[component] = usage
+	}
+
+	return rst, nil
+}
+
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			// [timestamp, "value"]
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func (s *defaultAlertMetricsSource) GetHttp5xxRatio(ctx context.Context, services []string, window time.Duration) (float64, bool, error) {
+	if len(services) == 0 {
+		return 0, false, nil
+	}
+
+	quoted := make([]string, len(services))
+	for i, service := range services {
+		// backslashes are escaped again in promql strings
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(service), `\`, `\\`)
+	}
+
+	selector := fmt.Sprintf(`reporter="destination",destination_service=~"%s"`, strings.Join(quoted, "|"))
+	rangeStr := fmt.Sprintf("%ds", int64(window.Seconds()))
+	query := fmt.Sprintf(
+		`sum(rate(istio_requests_total{%s,response_code=~"5.."}[%s])) / sum(rate(istio_requests_total{%s}[%s]))`,
+		selector, rangeStr, selector, rangeStr,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.prometheusAddress+"/api/v1/query?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer res.Body.Close()
+
+	var body prometheusQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, false, fmt.Errorf("fail to parse prometheus response: %s", err)
+	}
+
+	if body.Status != "success" {
+		return 0, false, fmt.Errorf("prometheus query failed: %s", body.Error)
+	}
+
+	// there is no result instead of 0 if there is no 5xx response
+	if len(body.Data.Result) == 0 || len(body.Data.Result[0].Value) != 2 {
+		return 0, true, nil
+	}
+
+	valueStr, _ := body.Data.Result[0].Value[1].(string)
+	value, err := strconv.ParseFloat(valueStr, 64)
+
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid prometheus value %v", body.Data.Result[0].Value[1])
+	}
+
+	// 5xx responses were seen before, but no request during the window
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, nil
+	}
+
+	return value * 100, true, nil
+}
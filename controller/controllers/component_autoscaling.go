@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	autoscalingV2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isAutoscalingEnabled tells if the replicas of the workload is owned by a HorizontalPodAutoscaler.
+// A component exceeding quota is scaled down to zero, so the autoscaler is removed until it's recovered.
+func (r *ComponentReconcilerTask) isAutoscalingEnabled() bool {
+	if r.component.Spec.Autoscaling == nil || isComponentLabeledAsExceedingQuota(r.component) {
+		return false
+	}
+
+	switch r.component.Spec.WorkloadType {
+	case v1alpha1.WorkloadTypeServer, v1alpha1.WorkloadTypeStatefulSet, "":
+		return true
+	default:
+		return false
+	}
+}
+
+// getWorkloadReplicas returns the replicas to be set on the workload.
+// With autoscaling, the current replicas set by the autoscaler is kept, as long as it's in the range of the autoscaler.
+func (r *ComponentReconcilerTask) getWorkloadReplicas(current *int32) *int32 {
+	if !r.isAutoscalingEnabled() {
+		return r.component.Spec.Replicas
+	}
+
+	replicas := clampReplicasToAutoscaling(r.component.Spec.Autoscaling, current)
+
+	return &replicas
+}
+
+func clampReplicasToAutoscaling(autoscaling *v1alpha1.AutoscalingSpec, current *int32) int32 {
+	replicas := autoscaling.GetMinReplicas()
+
+	if current != nil && *current > replicas {
+		replicas = *current
+	}
+
+	if replicas > autoscaling.MaxReplicas {
+		replicas = autoscaling.MaxReplicas
+	}
+
+	return replicas
+}
+
+// the replicas of the workload that is running now
+func (r *ComponentReconcilerTask) getCurrentWorkloadReplicas() *int32 {
+	switch r.component.Spec.WorkloadType {
+	case v1alpha1.WorkloadTypeServer, "":
+		if r.deployment != nil {
+			return r.deployment.Spec.Replicas
+		}
+	case v1alpha1.WorkloadTypeStatefulSet:
+		if r.statefulSet != nil {
+			return r.statefulSet.Spec.Replicas
+		}
+	}
+
+	return nil
+}
+
+func (r *ComponentReconcilerTask) ReconcileHorizontalPodAutoscaler() error {
+	if !IsNamespaceKalmEnabled(r.namespace) || !r.isAutoscalingEnabled() {
+		if r.hpa != nil {
+			if err := r.DeleteItem(r.hpa); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+
+			r.hpa = nil
+		}
+
+		return nil
+	}
+
+	autoscaling := r.component.Spec.Autoscaling
+
+	hpa := r.hpa
+	isNew := hpa == nil
+
+	if isNew {
+		hpa = &autoscalingV2beta2.HorizontalPodAutoscaler{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      r.component.Name,
+				Namespace: r.component.Namespace,
+				Labels:    r.GetLabels(),
+			},
+		}
+	}
+
+	scaleTargetKind := "Deployment"
+	if r.component.Spec.WorkloadType == v1alpha1.WorkloadTypeStatefulSet {
+		scaleTargetKind = "StatefulSet"
+	}
+
+	minReplicas := autoscaling.GetMinReplicas()
+
+	hpa.Spec = autoscalingV2beta2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingV2beta2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       scaleTargetKind,
+			Name:       r.component.Name,
+		},
+		MinReplicas: &minReplicas,
+		MaxReplicas: autoscaling.MaxReplicas,
+		Metrics:     buildAutoscalingMetrics(autoscaling),
+	}
+
+	if err := ctrl.SetControllerReference(r.component, hpa, r.Scheme); err != nil {
+		r.WarningEvent(err, "unable to set owner for HorizontalPodAutoscaler")
+		return err
+	}
+
+	if isNew {
+		if err := r.Create(r.ctx, hpa); err != nil {
+			r.WarningEvent(err, "unable to create HorizontalPodAutoscaler for Component")
+			return err
+		}
+
+		r.NormalEvent("HorizontalPodAutoscalerCreated", hpa.Name+" is created.")
+	} else {
+		if err := r.Update(r.ctx, hpa); err != nil {
+			r.WarningEvent(err, "unable to update HorizontalPodAutoscaler for Component")
+			return err
+		}
+	}
+
+	r.hpa = hpa
+
+	return nil
+}
+
+func buildAutoscalingMetrics(autoscaling *v1alpha1.AutoscalingSpec) []autoscalingV2beta2.MetricSpec {
+	var metrics []autoscalingV2beta2.MetricSpec
+
+	resourceTargets := []struct {
+		name       corev1.ResourceName
+		percentage *int32
+	}{
+		{corev1.ResourceCPU, autoscaling.TargetCPUUtilizationPercentage},
+		{corev1.ResourceMemory, autoscaling.TargetMemoryUtilizationPercentage},
+	}
+
+	for _, target := range resourceTargets {
+		if target.percentage == nil {
+			continue
+		}
+
+		utilization := *target.percentage
+
+		metrics = append(metrics, autoscalingV2beta2.MetricSpec{
+			Type: autoscalingV2beta2.ResourceMetricSourceType,
+			Resource: &autoscalingV2beta2.ResourceMetricSource{
+				Name: target.name,
+				Target: autoscalingV2beta2.MetricTarget{
+					Type:               autoscalingV2beta2.UtilizationMetricType,
+					AverageUtilization: &utilization,
+				},
+			},
+		})
+	}
+
+	if metric := autoscaling.CustomMetric; metric != nil {
+		averageValue := metric.TargetAverageValue.DeepCopy()
+
+		metrics = append(metrics, autoscalingV2beta2.MetricSpec{
+			Type: autoscalingV2beta2.PodsMetricSourceType,
+			Pods: &autoscalingV2beta2.PodsMetricSource{
+				Metric: autoscalingV2beta2.MetricIdentifier{
+					Name: metric.Name,
+				},
+				Target: autoscalingV2beta2.MetricTarget{
+					Type:         autoscalingV2beta2.AverageValueMetricType,
+					AverageValue: &averageValue,
+				},
+			},
+		})
+	}
+
+	return metrics
+}
+
+func (r *ComponentReconcilerTask) LoadHorizontalPodAutoscaler() error {
+	var hpa autoscalingV2beta2.HorizontalPodAutoscaler
+	if err := r.LoadItem(&hpa); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if metaV1.IsControlledBy(&hpa, r.component) {
+		r.hpa = &hpa
+	}
+
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	js "github.com/dop251/goja"
 	"github.com/kalmhq/kalm/controller/vm"
@@ -33,6 +34,7 @@ import (
 	v1alpha32 "istio.io/api/networking/v1alpha3"
 	"istio.io/client-go/pkg/apis/networking/v1alpha3"
 	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV2beta2 "k8s.io/api/autoscaling/v2beta2"
 	batchV1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/batch/v1"
 	batchV1Beta1 "k8s.io/api/batch/v1beta1"
@@ -80,11 +82,27 @@ type ComponentReconcilerTask struct {
 	daemonSet       *appsV1.DaemonSet
 	statefulSet     *appsV1.StatefulSet
 	pluginBindings  *v1alpha1.ComponentPluginBindingList
+	hpa             *autoscalingV2beta2.HorizontalPodAutoscaler
+
+	// resources of progressive delivery, see component_delivery.go
+	canaryDeployment *appsV1.Deployment
+	canaryService    *corev1.Service
+	deliveryStatus   *v1alpha1.DeliveryStatus
+
+	// the latest revision of the component spec, see component_revision.go
+	revision int64
+
+	// set if the task needs to be run again later, e.g. a delivery step is paused
+	requeueAfter time.Duration
+
+	// programs of component plugins, the shared cache is used if not set, see component_dryrun.go
+	pluginsCache *ComponentPluginsCache
 }
 
 // +kubebuilder:rbac:groups=core.kalm.dev,resources=components,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.kalm.dev,resources=components/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.kalm.dev,resources=componentplugins,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=componentrevisions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.kalm.dev,resources=componentplugins/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=extensions,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=extensions,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
@@ -96,6 +114,8 @@ type ComponentReconcilerTask struct {
 // +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.istio.io,resources=destinationrules,verbs=*
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=httproutes,verbs=get;list;watch;update;patch
 
 func (r *ComponentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	r.Log.Info("reconciling component", "req", req)
@@ -105,7 +125,9 @@ func (r *ComponentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		ctx:                 context.Background(),
 	}
 
-	return ctrl.Result{}, task.Run(req)
+	err := task.Run(req)
+
+	return ctrl.Result{RequeueAfter: task.requeueAfter}, err
 }
 
 func (r *ComponentReconcilerTask) WarningEvent(err error, msg string, args ...interface{}) {
@@ -221,7 +243,8 @@ func (r *ComponentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&appsV1.DaemonSet{}).
 		Owns(&appsV1.StatefulSet{}).
 		Owns(&corev1.Service{}).
-		Complete(r)
+		Owns(&autoscalingV2beta2.HorizontalPodAutoscaler{}).
+		Complete(instrumentReconciler("ComponentReconciler", r))
 }
 func (r *ComponentReconcilerTask) Run(req ctrl.Request) error {
 	if err := r.SetupAttributes(req); err != nil {
@@ -240,6 +263,10 @@ func (r *ComponentReconcilerTask) Run(req ctrl.Request) error {
 		return nil
 	}
 
+	if err := r.ReconcileRevision(); err != nil {
+		return err
+	}
+
 	if err := r.ReconcileService(); err != nil {
 		return err
 	}
@@ -252,7 +279,11 @@ func (r *ComponentReconcilerTask) Run(req ctrl.Request) error {
 		return err
 	}
 
-	return nil
+	if err := r.ReconcileHorizontalPodAutoscaler(); err != nil {
+		return err
+	}
+
+	return r.UpdateStatus()
 }
 
 func (r *ComponentReconcilerTask) GetLabels() map[string]string {
@@ -390,11 +421,11 @@ func (r *ComponentReconcilerTask) ReconcileService() (err error) {
 					Labels:    labels,
 				},
 				Spec: corev1.ServiceSpec{
-					Selector: labels,
+					Selector: r.getServiceSelector(labels),
 				},
 			}
 		} else {
-			r.service.Spec.Selector = labels
+			r.service.Spec.Selector = r.getServiceSelector(labels)
 		}
 
 		if r.component.Spec.WorkloadType == v1alpha1.WorkloadTypeStatefulSet {
@@ -625,24 +656,28 @@ func (r *ComponentReconcilerTask) ReconcileWorkload() (err error) {
 			if err := r.Delete(r.ctx, r.deployment); err != nil {
 				return err
 			}
+			r.deployment = nil
 		}
 		if r.cronJob != nil {
 			if err := r.Delete(r.ctx, r.cronJob); err != nil {
 				return err
 			}
+			r.cronJob = nil
 		}
 		if r.daemonSet != nil {
 			if err := r.Delete(r.ctx, r.daemonSet); err != nil {
 				return err
 			}
+			r.daemonSet = nil
 		}
 		if r.statefulSet != nil {
 			if err := r.Delete(r.ctx, r.statefulSet); err != nil {
 				return err
 			}
+			r.statefulSet = nil
 		}
 
-		return
+		return r.cleanupDelivery()
 	}
 
 	if isComponentLabeledAsExceedingQuota(r.component) &&
@@ -677,6 +712,14 @@ func (r *ComponentReconcilerTask) ReconcileWorkload() (err error) {
 			return err
 		}
 
+		if r.getComponentDeliveryStrategy() != nil {
+			return r.ReconcileDelivery(template)
+		}
+
+		if err := r.cleanupDelivery(); err != nil {
+			return err
+		}
+
 		return r.ReconcileDeployment(template)
 	case v1alpha1.WorkloadTypeCronjob:
 		if err := r.prepareVolsForSimpleWorkload(template); err != nil {
@@ -727,6 +770,12 @@ func (r *ComponentReconcilerTask) forceScaleDownExceedingQuotaComponent() (err e
 	} else {
 		originalReplica = *copy.Spec.Replicas
 	}
+
+	// the autoscaler may have scaled the workload out, remember what is running, up to the autoscaler's ceiling
+	if copy.Spec.Autoscaling != nil {
+		originalReplica = clampReplicasToAutoscaling(copy.Spec.Autoscaling, r.getCurrentWorkloadReplicas())
+	}
+
 	copy.Labels[v1alpha1.KalmLabelKeyOriginalReplicas] = fmt.Sprintf("%d", originalReplica)
 
 	zero := int32(0)
@@ -783,11 +832,7 @@ func (r *ComponentReconcilerTask) ReconcileDeployment(podTemplateSpec *corev1.Po
 	}
 
 	// TODO consider to move to plugin
-	if component.Spec.Replicas != nil {
-		deployment.Spec.Replicas = component.Spec.Replicas
-	} else {
-		deployment.Spec.Replicas = nil
-	}
+	deployment.Spec.Replicas = r.getWorkloadReplicas(deployment.Spec.Replicas)
 
 	if err := ctrl.SetControllerReference(component, deployment, r.Scheme); err != nil {
 		r.WarningEvent(err, "unable to set owner for deployment")
@@ -815,6 +860,8 @@ func (r *ComponentReconcilerTask) ReconcileDeployment(podTemplateSpec *corev1.Po
 		r.NormalEvent("DeploymentUpdated", deployment.Name+" is updated.")
 	}
 
+	r.deployment = deployment
+
 	return nil
 }
 
@@ -867,6 +914,8 @@ func (r *ComponentReconcilerTask) ReconcileDaemonSet(podTemplateSpec *corev1.Pod
 		r.NormalEvent("DaemonSetUpdated", daemonSet.Name+" is updated.")
 	}
 
+	r.daemonSet = daemonSet
+
 	return nil
 }
 
@@ -1004,6 +1053,8 @@ func (r *ComponentReconcilerTask) ReconcileCronJob(podTemplateSpec *corev1.PodTe
 		r.NormalEvent("CronJobUpdated", cj.Name+" is updated.")
 	}
 
+	r.cronJob = cj
+
 	if r.component.Spec.ImmediateTrigger {
 		return r.ReconcileImmediateJob(template)
 	}
@@ -1052,8 +1103,8 @@ func (r *ComponentReconcilerTask) ReconcileStatefulSet(
 		sts.Spec.Template = *spec
 	}
 
-	if r.component.Spec.Replicas != nil {
-		sts.Spec.Replicas = r.component.Spec.Replicas
+	if replicas := r.getWorkloadReplicas(sts.Spec.Replicas); replicas != nil {
+		sts.Spec.Replicas = replicas
 	}
 
 	if isNewSts {
@@ -1077,6 +1128,8 @@ func (r *ComponentReconcilerTask) ReconcileStatefulSet(
 		r.NormalEvent("StatefulSetUpdated", sts.Name+" is updated.")
 	}
 
+	r.statefulSet = sts
+
 	return nil
 }
 
@@ -1099,7 +1152,7 @@ func (r *ComponentReconcilerTask) GetPodTemplateWithoutVols() (template *corev1.
 
 	labels := r.GetLabels()
 	labels["app"] = component.Name
-	labels["version"] = "v1" // TODO
+	labels["version"] = componentPodVersionStable // TODO
 
 	annotations := r.GetAnnotations()
 
@@ -1156,18 +1209,7 @@ func (r *ComponentReconcilerTask) GetPodTemplateWithoutVols() (template *corev1.
 		template.Spec.TerminationGracePeriodSeconds = component.Spec.TerminationGracePeriodSeconds
 	}
 
-	if component.Spec.Command != "" {
-		if strings.HasPrefix(component.Spec.Command, "-") {
-			space := regexp.MustCompile(`\s+`)
-			parts := space.Split(component.Spec.Command, -1)
-			mainContainer.Args = parts
-		} else if strings.Contains(component.Spec.Command, " ") {
-			mainContainer.Command = []string{"sh"}
-			mainContainer.Args = []string{"-c", component.Spec.Command}
-		} else {
-			mainContainer.Command = []string{component.Spec.Command}
-		}
-	}
+	mainContainer.Command, mainContainer.Args = parseContainerCommand(component.Spec.Command)
 
 	var pullImageSecrets corev1.SecretList
 	if err := r.Client.List(
@@ -1215,8 +1257,77 @@ func (r *ComponentReconcilerTask) GetPodTemplateWithoutVols() (template *corev1.
 	}
 
 	// apply envs
-	var envs []corev1.EnvVar
-	for _, env := range component.Spec.Env {
+	mainContainer.Env, err = r.buildContainerEnvs(component.Spec.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	envFromCommonCM := corev1.EnvFromSource{
+		ConfigMapRef: &corev1.ConfigMapEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: NSScopeSharedConfigMapName,
+			},
+		},
+	}
+
+	// envFromCommonSec := corev1.EnvFromSource{
+	// 	SecretRef: &corev1.SecretEnvSource{
+	// 		LocalObjectReference: corev1.LocalObjectReference{
+	// 			Name: NSScopeSharedConfigMapName,
+	// 		},
+	// 	},
+	// }
+
+	mainContainer.EnvFrom = append(mainContainer.EnvFrom, envFromCommonCM)
+	mainContainer.EnvFrom = append(mainContainer.EnvFrom, buildContainerEnvFrom(component.Spec.EnvFrom)...)
+
+	// sidecars and init containers
+	for _, c := range component.Spec.Sidecars {
+		container, err := r.buildExtraContainer(c, envFromCommonCM)
+		if err != nil {
+			return nil, err
+		}
+
+		template.Spec.Containers = append(template.Spec.Containers, container)
+	}
+
+	for _, c := range component.Spec.InitContainers {
+		container, err := r.buildExtraContainer(c, envFromCommonCM)
+		if err != nil {
+			return nil, err
+		}
+
+		template.Spec.InitContainers = append(template.Spec.InitContainers, container)
+	}
+
+	err = r.runPlugins(ComponentPluginMethodAfterPodTemplateGeneration, component, template, template)
+	if err != nil {
+		r.WarningEvent(err, "run "+ComponentPluginMethodAfterPodTemplateGeneration+" save plugin error")
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// parseContainerCommand converts the command of a component into container command and args.
+// A command starts with "-" is treated as args, a command contains spaces is run by sh.
+func parseContainerCommand(command string) (cmd []string, args []string) {
+	if command == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(command, "-") {
+		space := regexp.MustCompile(`\s+`)
+		return nil, space.Split(command, -1)
+	} else if strings.Contains(command, " ") {
+		return []string{"sh"}, []string{"-c", command}
+	}
+
+	return []string{command}, nil
+}
+
+func (r *ComponentReconcilerTask) buildContainerEnvs(envVars []v1alpha1.EnvVar) (envs []corev1.EnvVar, err error) {
+	for _, env := range envVars {
 		var value string
 		var valueFrom *corev1.EnvVarSource
 
@@ -1235,6 +1346,11 @@ func (r *ComponentReconcilerTask) GetPodTemplateWithoutVols() (template *corev1.
 			if err != nil {
 				return nil, err
 			}
+		case v1alpha1.EnvVarTypeSecret, v1alpha1.EnvVarTypeConfigMap:
+			valueFrom, err = r.getValueFromOfReferencedEnv(env)
+			if err != nil {
+				return nil, err
+			}
 		case v1alpha1.EnvVarTypeFieldRef:
 			valueFrom = &corev1.EnvVarSource{
 				FieldRef: &corev1.ObjectFieldSelector{
@@ -1273,33 +1389,45 @@ func (r *ComponentReconcilerTask) GetPodTemplateWithoutVols() (template *corev1.
 			ValueFrom: valueFrom,
 		})
 	}
-	mainContainer.Env = envs
 
-	envFromCommonCM := corev1.EnvFromSource{
-		ConfigMapRef: &corev1.ConfigMapEnvSource{
-			LocalObjectReference: corev1.LocalObjectReference{
-				Name: NSScopeSharedConfigMapName,
-			},
-		},
+	return envs, nil
+}
+
+func (r *ComponentReconcilerTask) buildExtraContainer(c v1alpha1.Container, envFrom ...corev1.EnvFromSource) (corev1.Container, error) {
+	container := corev1.Container{
+		Name:    c.Name,
+		Image:   c.Image,
+		EnvFrom: envFrom,
 	}
 
-	// envFromCommonSec := corev1.EnvFromSource{
-	// 	SecretRef: &corev1.SecretEnvSource{
-	// 		LocalObjectReference: corev1.LocalObjectReference{
-	// 			Name: NSScopeSharedConfigMapName,
-	// 		},
-	// 	},
-	// }
+	container.Command, container.Args = parseContainerCommand(c.Command)
 
-	mainContainer.EnvFrom = append(mainContainer.EnvFrom, envFromCommonCM)
-
-	err = r.runPlugins(ComponentPluginMethodAfterPodTemplateGeneration, component, template, template)
+	envs, err := r.buildContainerEnvs(c.Env)
 	if err != nil {
-		r.WarningEvent(err, "run "+ComponentPluginMethodAfterPodTemplateGeneration+" save plugin error")
-		return nil, err
+		return container, err
 	}
 
-	return template, nil
+	container.Env = envs
+	container.EnvFrom = append(container.EnvFrom, buildContainerEnvFrom(c.EnvFrom)...)
+
+	for _, port := range c.Ports {
+		cp := corev1.ContainerPort{
+			ContainerPort: int32(port.ContainerPort),
+			Protocol:      corev1.ProtocolTCP,
+		}
+
+		if port.Protocol == v1alpha1.PortProtocolUDP {
+			cp.Protocol = corev1.ProtocolUDP
+		}
+
+		container.Ports = append(container.Ports, cp)
+	}
+
+	if c.ResourceRequirements != nil {
+		container.Resources = *c.ResourceRequirements
+	}
+
+	return container, nil
 }
 
 func getVolName(componentName, diskPath string) string {
@@ -1344,6 +1472,55 @@ func (r *ComponentReconcilerTask) getValueOfLinkedEnv(env v1alpha1.EnvVar) (stri
 	return fmt.Sprintf("%s%s%s", env.Prefix, value, env.Suffix), nil
 }
 
+// getValueFromOfReferencedEnv returns the source of a secret or configMap env
+func (r *ComponentReconcilerTask) getValueFromOfReferencedEnv(env v1alpha1.EnvVar) (*corev1.EnvVarSource, error) {
+	name, key, err := env.GetObjectKeyReference()
+	if err != nil {
+		return nil, err
+	}
+
+	if env.Type == v1alpha1.EnvVarTypeSecret {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  key,
+			},
+		}, nil
+	}
+
+	return &corev1.EnvVarSource{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+		},
+	}, nil
+}
+
+func buildContainerEnvFrom(sources []v1alpha1.EnvFromSource) []corev1.EnvFromSource {
+	var envFrom []corev1.EnvFromSource
+
+	for _, source := range sources {
+		item := corev1.EnvFromSource{Prefix: source.Prefix}
+
+		switch source.Type {
+		case v1alpha1.EnvVarTypeSecret:
+			item.SecretRef = &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: source.Name},
+			}
+		case v1alpha1.EnvVarTypeConfigMap:
+			item.ConfigMapRef = &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: source.Name},
+			}
+		default:
+			continue
+		}
+
+		envFrom = append(envFrom, item)
+	}
+
+	return envFrom
+}
+
 func (r *ComponentReconcilerTask) initPluginRuntime(component *v1alpha1.Component) *js.Runtime {
 	rt := vm.InitRuntime()
 
@@ -1378,7 +1555,7 @@ func (r *ComponentReconcilerTask) runPlugins(methodName string, component *v1alp
 			continue
 		}
 
-		pluginProgram, config, err := findPluginAndValidateConfigNew(&binding, methodName, component)
+		pluginProgram, config, err := findPluginAndValidateConfigNew(r.getPluginsCache(), &binding, methodName, component)
 
 		if err != nil {
 			return err
@@ -1432,8 +1609,16 @@ func (r *ComponentReconcilerTask) runPlugins(methodName string, component *v1alp
 	return nil
 }
 
-func findPluginAndValidateConfigNew(pluginBinding *v1alpha1.ComponentPluginBinding, methodName string, component *v1alpha1.Component) (*ComponentPluginProgram, []byte, error) {
-	pluginProgram := componentPluginsCache.Get(pluginBinding.Spec.PluginName)
+func (r *ComponentReconcilerTask) getPluginsCache() *ComponentPluginsCache {
+	if r.pluginsCache != nil {
+		return r.pluginsCache
+	}
+
+	return componentPluginsCache
+}
+
+func findPluginAndValidateConfigNew(pluginsCache *ComponentPluginsCache, pluginBinding *v1alpha1.ComponentPluginBinding, methodName string, component *v1alpha1.Component) (*ComponentPluginProgram, []byte, error) {
+	pluginProgram := pluginsCache.Get(pluginBinding.Spec.PluginName)
 
 	if pluginProgram == nil {
 		return nil, nil, fmt.Errorf("Can't find plugin %s in cache.", pluginBinding.Spec.PluginName)
@@ -1648,9 +1833,17 @@ func (r *ComponentReconcilerTask) LoadResources() (err error) {
 		return err
 	}
 
+	if err := r.LoadHorizontalPodAutoscaler(); err != nil {
+		return err
+	}
+
 	switch r.component.Spec.WorkloadType {
 	case v1alpha1.WorkloadTypeServer, "":
-		return r.LoadDeployment()
+		if err := r.LoadDeployment(); err != nil {
+			return err
+		}
+
+		return r.LoadDeliveryResources()
 	case v1alpha1.WorkloadTypeCronjob:
 		return r.LoadCronJob()
 	case v1alpha1.WorkloadTypeDaemonSet:
@@ -1834,20 +2027,98 @@ func (r *ComponentReconcilerTask) preparePreInjectedFiles(
 
 		injectCommands = append(injectCommands, cmd)
 
-		*volumeMounts = append(*volumeMounts, corev1.VolumeMount{
+		volumeMount := corev1.VolumeMount{
 			Name:      "pre-injected-files-volume",
 			MountPath: file.MountPath,
 			SubPath:   baseName,
 			ReadOnly:  file.Readonly,
-		})
+		}
+
+		if len(file.Containers) == 0 {
+			*volumeMounts = append(*volumeMounts, volumeMount)
+			continue
+		}
+
+		for _, containerName := range file.Containers {
+			if containerName == component.Name {
+				*volumeMounts = append(*volumeMounts, volumeMount)
+				continue
+			}
+
+			container := findPodContainer(template, containerName)
+			if container == nil {
+				return fmt.Errorf("container %s of pre-injected file %s not exist", containerName, file.MountPath)
+			}
+
+			container.VolumeMounts = append(container.VolumeMounts, volumeMount)
+		}
 	}
 
-	template.Spec.InitContainers = append(template.Spec.InitContainers, corev1.Container{
-		Name:         "inject-files",
-		Image:        "busybox",
-		Command:      []string{"sh", "-c", fmt.Sprintf("%s", strings.Join(injectCommands, " && "))},
-		VolumeMounts: []corev1.VolumeMount{{MountPath: "/files", Name: "pre-injected-files-volume"}},
-	})
+	// files are injected before any other init containers, so init containers can use them too.
+	template.Spec.InitContainers = append([]corev1.Container{
+		{
+			Name:         "inject-files",
+			Image:        "busybox",
+			Command:      []string{"sh", "-c", fmt.Sprintf("%s", strings.Join(injectCommands, " && "))},
+			VolumeMounts: []corev1.VolumeMount{{MountPath: "/files", Name: "pre-injected-files-volume"}},
+		},
+	}, template.Spec.InitContainers...)
+
+	return nil
+}
+
+// findPodContainer returns the container or init container with the name in the pod template
+func findPodContainer(template *corev1.PodTemplateSpec, name string) *corev1.Container {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == name {
+			return &template.Spec.Containers[i]
+		}
+	}
+
+	for i := range template.Spec.InitContainers {
+		if template.Spec.InitContainers[i].Name == name {
+			return &template.Spec.InitContainers[i]
+		}
+	}
+
+	return nil
+}
+
+// mountVolumesIntoExtraContainers mounts component volumes into sidecars and init containers.
+// volNames is the volume name of each component volume path.
+func (r *ComponentReconcilerTask) mountVolumesIntoExtraContainers(template *corev1.PodTemplateSpec, volNames map[string]string) error {
+	var extraContainers []v1alpha1.Container
+	extraContainers = append(extraContainers, r.component.Spec.Sidecars...)
+	extraContainers = append(extraContainers, r.component.Spec.InitContainers...)
+
+	for _, c := range extraContainers {
+		if len(c.VolumeMounts) == 0 {
+			continue
+		}
+
+		container := findPodContainer(template, c.Name)
+		if container == nil {
+			return fmt.Errorf("container %s not exist in pod template", c.Name)
+		}
+
+		for _, mount := range c.VolumeMounts {
+			volName, exist := volNames[mount.Path]
+			if !exist {
+				return fmt.Errorf("volume %s mounted by container %s not exist", mount.Path, c.Name)
+			}
+
+			mountPath := mount.MountPath
+			if mountPath == "" {
+				mountPath = mount.Path
+			}
+
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      volName,
+				MountPath: mountPath,
+				ReadOnly:  mount.ReadOnly,
+			})
+		}
+	}
 
 	return nil
 }
@@ -1862,6 +2133,7 @@ func (r *ComponentReconcilerTask) prepareVolsForSTS(podTemplate *corev1.PodTempl
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
 	var volClaimTemplates []corev1.PersistentVolumeClaim
+	volNames := make(map[string]string)
 
 	if err := r.preparePreInjectedFiles(podTemplate, &volumes, &volumeMounts); err != nil {
 		return nil, err
@@ -1925,6 +2197,8 @@ func (r *ComponentReconcilerTask) prepareVolsForSTS(podTemplate *corev1.PodTempl
 			return nil, fmt.Errorf("unknown disk type: %s", disk.Type)
 		}
 
+		volNames[disk.Path] = volName
+
 		// all mounted into container
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      volName,
@@ -1939,6 +2213,10 @@ func (r *ComponentReconcilerTask) prepareVolsForSTS(podTemplate *corev1.PodTempl
 	mainContainer := &podTemplate.Spec.Containers[0]
 	mainContainer.VolumeMounts = volumeMounts
 
+	if err := r.mountVolumesIntoExtraContainers(podTemplate, volNames); err != nil {
+		return nil, err
+	}
+
 	// for STS, pvc is not in podTemplate but in volumeClaimTemplate
 	return volClaimTemplates, nil
 }
@@ -1950,6 +2228,7 @@ func (r *ComponentReconcilerTask) prepareVolsForSimpleWorkload(template *corev1.
 
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
+	volNames := make(map[string]string)
 
 	if err := r.preparePreInjectedFiles(template, &volumes, &volumeMounts); err != nil {
 		return err
@@ -2065,6 +2344,8 @@ func (r *ComponentReconcilerTask) prepareVolsForSimpleWorkload(template *corev1.
 			return fmt.Errorf("unknown disk type: %s", disk.Type)
 		}
 
+		volNames[disk.Path] = volName
+
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      volName,
 			MountPath: disk.Path,
@@ -2076,6 +2357,10 @@ func (r *ComponentReconcilerTask) prepareVolsForSimpleWorkload(template *corev1.
 	mainContainer := &template.Spec.Containers[0]
 	mainContainer.VolumeMounts = volumeMounts
 
+	if err := r.mountVolumesIntoExtraContainers(template, volNames); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/stretchr/testify/suite"
 	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV2beta2 "k8s.io/api/autoscaling/v2beta2"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -236,6 +237,50 @@ func (suite *ComponentControllerSuite) TestOnlyStaticEnvs() {
 	}, "the second value should be updated")
 }
 
+func (suite *ComponentControllerSuite) TestSecretAndConfigMapEnvs() {
+	component := generateEmptyComponent(suite.ns.Name)
+	component.Spec.Env = []v1alpha1.EnvVar{
+		{
+			Name:  "DB_PASSWORD",
+			Value: "db/password",
+			Type:  v1alpha1.EnvVarTypeSecret,
+		},
+		{
+			Name:  "MODE",
+			Value: "settings/mode",
+			Type:  v1alpha1.EnvVarTypeConfigMap,
+		},
+	}
+	component.Spec.EnvFrom = []v1alpha1.EnvFromSource{
+		{
+			Type:   v1alpha1.EnvVarTypeSecret,
+			Name:   "credentials",
+			Prefix: "CRED_",
+		},
+	}
+	suite.createComponent(component)
+
+	key := types.NamespacedName{
+		Namespace: component.Namespace,
+		Name:      component.Name,
+	}
+
+	var deployment appsV1.Deployment
+	suite.Eventually(func() bool { return suite.K8sClient.Get(context.Background(), key, &deployment) == nil }, "can't get deployment")
+
+	container := deployment.Spec.Template.Spec.Containers[0]
+	suite.Len(container.Env, 2)
+	suite.Equal("db", container.Env[0].ValueFrom.SecretKeyRef.Name)
+	suite.Equal("password", container.Env[0].ValueFrom.SecretKeyRef.Key)
+	suite.Equal("settings", container.Env[1].ValueFrom.ConfigMapKeyRef.Name)
+	suite.Equal("mode", container.Env[1].ValueFrom.ConfigMapKeyRef.Key)
+
+	// the first one is the shared config map of the namespace
+	suite.Len(container.EnvFrom, 2)
+	suite.Equal("credentials", container.EnvFrom[1].SecretRef.Name)
+	suite.Equal("CRED_", container.EnvFrom[1].Prefix)
+}
+
 func (suite *ComponentControllerSuite) TestVolumeTemporaryDisk() {
 	component := generateEmptyComponent(suite.ns.Name)
 	component.Spec.Volumes = []v1alpha1.Volume{
@@ -266,6 +311,74 @@ func (suite *ComponentControllerSuite) TestVolumeTemporaryDisk() {
 	}, "temporary disk should not create pvc")
 }
 
+func (suite *ComponentControllerSuite) TestSidecarsAndInitContainers() {
+	component := generateEmptyComponent(suite.ns.Name)
+	component.Spec.Volumes = []v1alpha1.Volume{
+		{
+			Type: v1alpha1.VolumeTypeTemporaryDisk,
+			Path: "/var/log/app",
+			Size: resource.MustParse("10m"),
+		},
+	}
+	component.Spec.Sidecars = []v1alpha1.Container{
+		{
+			Name:    "log-shipper",
+			Image:   "busybox",
+			Command: "tail -f /logs/app.log",
+			Env: []v1alpha1.EnvVar{
+				{
+					Name:  "POD_NAME",
+					Value: v1alpha1.EnvVarBuiltinPodName,
+					Type:  v1alpha1.EnvVarTypeBuiltin,
+				},
+			},
+			VolumeMounts: []v1alpha1.ContainerVolumeMount{
+				{Path: "/var/log/app", MountPath: "/logs", ReadOnly: true},
+			},
+		},
+	}
+	component.Spec.InitContainers = []v1alpha1.Container{
+		{
+			Name:    "migrate",
+			Image:   "nginx:latest",
+			Command: "/migrate.sh",
+		},
+	}
+	component.Spec.PreInjectedFiles = []v1alpha1.PreInjectFile{
+		{
+			Content:    "echo migrate",
+			MountPath:  "/migrate.sh",
+			Runnable:   true,
+			Containers: []string{"migrate"},
+		},
+	}
+	key := types.NamespacedName{
+		Namespace: component.Namespace,
+		Name:      component.Name,
+	}
+	suite.createComponent(component)
+
+	var deployment appsV1.Deployment
+	suite.Eventually(func() bool {
+		return suite.K8sClient.Get(context.Background(), key, &deployment) == nil
+	}, "can't get deployment")
+
+	podSpec := deployment.Spec.Template.Spec
+	suite.Equal(2, len(podSpec.Containers))
+	suite.Equal("log-shipper", podSpec.Containers[1].Name)
+	suite.Equal([]string{"sh"}, podSpec.Containers[1].Command)
+	suite.Equal("POD_NAME", podSpec.Containers[1].Env[0].Name)
+	suite.Equal("metadata.name", podSpec.Containers[1].Env[0].ValueFrom.FieldRef.FieldPath)
+	suite.Equal("/logs", podSpec.Containers[1].VolumeMounts[0].MountPath)
+	suite.Equal(podSpec.Containers[0].VolumeMounts[0].Name, podSpec.Containers[1].VolumeMounts[0].Name)
+
+	// files are injected before the init containers of the component
+	suite.Equal(2, len(podSpec.InitContainers))
+	suite.Equal("inject-files", podSpec.InitContainers[0].Name)
+	suite.Equal("migrate", podSpec.InitContainers[1].Name)
+	suite.Equal("/migrate.sh", podSpec.InitContainers[1].VolumeMounts[0].MountPath)
+}
+
 func (suite *ComponentControllerSuite) TestVolumeTemporaryMemoryDisk() {
 	component := generateEmptyComponent(suite.ns.Name)
 	component.Spec.Volumes = []v1alpha1.Volume{
@@ -352,6 +465,173 @@ func (suite *ComponentControllerSuite) TestPorts() {
 	}, "service should be deleted")
 }
 
+func (suite *ComponentControllerSuite) TestComponentStatus() {
+	component := generateEmptyComponent(suite.ns.Name)
+	suite.createComponent(component)
+
+	key := types.NamespacedName{
+		Namespace: component.Namespace,
+		Name:      component.Name,
+	}
+
+	// deployment is created but no pods are running yet
+	suite.Eventually(func() bool {
+		suite.reloadComponent(component)
+
+		return component.Status.ObservedGeneration == component.Generation &&
+			component.Status.Replicas == 1 &&
+			component.Status.ReadyReplicas == 0 &&
+			v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionProgressing) &&
+			!v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionAvailable)
+	}, "component status should be progressing")
+
+	// there is no deployment controller in test env, fake a finished rollout
+	var deployment appsV1.Deployment
+	suite.Nil(suite.K8sClient.Get(context.Background(), key, &deployment))
+	deployment.Status.ObservedGeneration = deployment.Generation
+	deployment.Status.Replicas = 1
+	deployment.Status.UpdatedReplicas = 1
+	deployment.Status.ReadyReplicas = 1
+	deployment.Status.AvailableReplicas = 1
+	suite.Nil(suite.K8sClient.Status().Update(context.Background(), &deployment))
+
+	suite.Eventually(func() bool {
+		suite.reloadComponent(component)
+
+		return component.Status.ReadyReplicas == 1 &&
+			component.Status.Image == "nginx:latest" &&
+			!v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionProgressing) &&
+			v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionAvailable) &&
+			!v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionDegraded)
+	}, "component status should be available")
+
+	// a new image starts a new rollout, image in status is kept until it is finished
+	component.Spec.Image = "nginx:1.19"
+	suite.updateComponent(component)
+
+	suite.Eventually(func() bool {
+		suite.reloadComponent(component)
+
+		return component.Status.ObservedGeneration == component.Generation &&
+			component.Status.Image == "nginx:latest" &&
+			v1alpha1.IsComponentConditionTrue(component.Status, v1alpha1.ComponentConditionProgressing)
+	}, "component status should be progressing after image changed")
+}
+
+func (suite *ComponentControllerSuite) TestComponentRevision() {
+	component := generateEmptyComponent(suite.ns.Name)
+	component.Annotations = map[string]string{
+		v1alpha1.KalmAnnoChangedBy:  "foo@bar.com",
+		v1alpha1.KalmAnnoChangedVia: string(v1alpha1.ComponentChangeViaAPI),
+	}
+	suite.createComponent(component)
+
+	getRevisions := func() []v1alpha1.ComponentRevision {
+		var revisionList v1alpha1.ComponentRevisionList
+		_ = suite.K8sClient.List(context.Background(), &revisionList,
+			client.InNamespace(component.Namespace),
+			client.MatchingLabels{v1alpha1.KalmLabelComponentKey: component.Name},
+		)
+		return revisionList.Items
+	}
+
+	suite.Eventually(func() bool {
+		suite.reloadComponent(component)
+		revisions := getRevisions()
+
+		return len(revisions) == 1 &&
+			revisions[0].Spec.Revision == 1 &&
+			revisions[0].Spec.ChangedBy == "foo@bar.com" &&
+			revisions[0].Spec.ChangedVia == v1alpha1.ComponentChangeViaAPI &&
+			component.Status.Revision == 1 &&
+			component.Annotations[v1alpha1.KalmAnnoChangedBy] == ""
+	}, "first revision should be recorded")
+
+	// a change without change annotations is recorded as unknown
+	component.Spec.Image = "nginx:1.19"
+	suite.updateComponent(component)
+
+	suite.Eventually(func() bool {
+		suite.reloadComponent(component)
+		revisions := getRevisions()
+
+		for _, revision := range revisions {
+			if revision.Spec.Revision == 2 {
+				return revision.Spec.ChangedVia == v1alpha1.ComponentChangeViaUnknown &&
+					revision.Spec.ComponentSpec.Image == "nginx:1.19" &&
+					component.Status.Revision == 2
+			}
+		}
+
+		return false
+	}, "second revision should be recorded")
+}
+
+func (suite *ComponentControllerSuite) TestComponentAutoscaling() {
+	component := generateEmptyComponent(suite.ns.Name)
+	minReplicas := int32(2)
+	cpuUtilization := int32(80)
+	component.Spec.ResourceRequirements = &coreV1.ResourceRequirements{
+		Limits: coreV1.ResourceList{
+			coreV1.ResourceCPU: resource.MustParse("100m"),
+		},
+	}
+	component.Spec.Autoscaling = &v1alpha1.AutoscalingSpec{
+		MinReplicas:                    &minReplicas,
+		MaxReplicas:                    5,
+		TargetCPUUtilizationPercentage: &cpuUtilization,
+	}
+	suite.createComponent(component)
+
+	key := types.NamespacedName{
+		Namespace: component.Namespace,
+		Name:      component.Name,
+	}
+
+	var hpa autoscalingV2beta2.HorizontalPodAutoscaler
+	suite.Eventually(func() bool {
+		if err := suite.K8sClient.Get(context.Background(), key, &hpa); err != nil {
+			return false
+		}
+
+		return *hpa.Spec.MinReplicas == 2 &&
+			hpa.Spec.MaxReplicas == 5 &&
+			hpa.Spec.ScaleTargetRef.Kind == "Deployment" &&
+			len(hpa.Spec.Metrics) == 1
+	}, "hpa should be created")
+
+	var deployment appsV1.Deployment
+	suite.Nil(suite.K8sClient.Get(context.Background(), key, &deployment))
+	suite.Equal(int32(2), *deployment.Spec.Replicas)
+
+	// replicas set by the autoscaler is kept
+	replicas := int32(4)
+	deployment.Spec.Replicas = &replicas
+	suite.Nil(suite.K8sClient.Update(context.Background(), &deployment))
+
+	suite.reloadComponent(component)
+	component.Spec.Image = "nginx:1.19"
+	suite.updateComponent(component)
+
+	suite.Eventually(func() bool {
+		if err := suite.K8sClient.Get(context.Background(), key, &deployment); err != nil {
+			return false
+		}
+
+		return deployment.Spec.Template.Spec.Containers[0].Image == "nginx:1.19" &&
+			*deployment.Spec.Replicas == 4
+	}, "replicas of deployment should not be reset")
+
+	// the hpa is removed with autoscaling
+	suite.reloadComponent(component)
+	component.Spec.Autoscaling = nil
+	suite.updateComponent(component)
+
+	suite.Eventually(func() bool {
+		return errors.IsNotFound(suite.K8sClient.Get(context.Background(), key, &hpa))
+	}, "hpa should be deleted")
+}
+
 func (suite *ComponentControllerSuite) getComponentPVCs(component *v1alpha1.Component) []coreV1.PersistentVolumeClaim {
 	var pvcList coreV1.PersistentVolumeClaimList
 	_ = suite.K8sClient.List(context.Background(), &pvcList, client.MatchingLabels{"kalm-component": component.Name})
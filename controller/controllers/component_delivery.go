@@ -0,0 +1,660 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	appsV1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Progressive delivery of a server component.
+//
+// While a new image is being delivered, the deployment of the component keeps running the stable image,
+// and a parallel deployment named "<component>-canary" runs the new image behind its own service.
+// Pods of the two deployments are told apart by the "version" label.
+// Traffic is moved by rewriting the destinations of the HttpRoutes that point at the component service.
+// Once the last step is done, the component deployment is updated to the new image and the canary is removed.
+
+const (
+	componentPodVersionStable = "v1"
+	componentPodVersionCanary = "canary"
+
+	// how often the readiness of the pods is checked while a delivery is going on
+	deliveryCheckInterval = 10 * time.Second
+)
+
+func getNameForCanary(componentName string) string {
+	return componentName + "-canary"
+}
+
+func getServiceHost(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace)
+}
+
+// with a delivery strategy, canary pods share the component labels, so the stable service has to
+// select pods by version as well.
+func (r *ComponentReconcilerTask) getServiceSelector(labels map[string]string) map[string]string {
+	if r.getComponentDeliveryStrategy() == nil || isStatefulSet(r.component) {
+		return labels
+	}
+
+	selector := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		selector[k] = v
+	}
+
+	selector["version"] = componentPodVersionStable
+
+	return selector
+}
+
+func (r *ComponentReconcilerTask) LoadDeliveryResources() error {
+	name := types.NamespacedName{
+		Namespace: r.component.Namespace,
+		Name:      getNameForCanary(r.component.Name),
+	}
+
+	var deployment appsV1.Deployment
+	if err := r.Reader.Get(r.ctx, name, &deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if metaV1.IsControlledBy(&deployment, r.component) {
+		r.canaryDeployment = &deployment
+	}
+
+	var service corev1.Service
+	if err := r.Reader.Get(r.ctx, name, &service); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if metaV1.IsControlledBy(&service, r.component) {
+		r.canaryService = &service
+	}
+
+	r.deliveryStatus = r.component.Status.Delivery.DeepCopy()
+
+	return nil
+}
+
+func (r *ComponentReconcilerTask) ReconcileDelivery(template *corev1.PodTemplateSpec) error {
+	if r.deliveryStatus == nil {
+		r.deliveryStatus = &v1alpha1.DeliveryStatus{}
+	}
+
+	status := r.deliveryStatus
+
+	newImage := getMainContainerImage(template.Spec, r.component.Name)
+
+	if status.StableImage == "" {
+		// the strategy is just enabled, what is running now is the stable image
+		if r.deployment != nil {
+			status.StableImage = getMainContainerImage(r.deployment.Spec.Template.Spec, r.component.Name)
+		} else {
+			status.StableImage = newImage
+		}
+	}
+
+	if newImage == status.StableImage {
+		switch status.Phase {
+		case v1alpha1.DeliveryPhasePromoting:
+			return r.promoteDelivery(template)
+		case v1alpha1.DeliveryPhaseProgressing:
+			status.Phase = v1alpha1.DeliveryPhaseRolledBack
+			status.Message = "delivery is canceled, the image is changed back to the stable image"
+			r.NormalEvent("DeliveryCanceled", status.Message)
+		}
+
+		if err := r.cleanupCanary(); err != nil {
+			return err
+		}
+
+		return r.ReconcileDeployment(template)
+	}
+
+	if newImage != status.NewImage || status.StartedAt == nil {
+		now := metaV1.Now()
+		status.NewImage = newImage
+		status.Strategy = r.getNextDeliveryStrategyType()
+		status.Phase = v1alpha1.DeliveryPhaseProgressing
+		status.Step = 0
+		status.Weight = 0
+		status.StartedAt = &now
+		status.StepStartedAt = &now
+		status.Message = ""
+
+		r.NormalEvent("DeliveryStarted", fmt.Sprintf("start %s delivery of image %s", status.Strategy, newImage))
+
+		if err := r.removeDeliveryStrategyAnnotation(); err != nil {
+			return err
+		}
+	}
+
+	stableTemplate := withMainContainerImage(template, r.component.Name, status.StableImage)
+
+	if status.Phase == v1alpha1.DeliveryPhaseRolledBack {
+		if err := r.cleanupCanary(); err != nil {
+			return err
+		}
+
+		return r.ReconcileDeployment(stableTemplate)
+	}
+
+	if err := r.ReconcileDeployment(stableTemplate); err != nil {
+		return err
+	}
+
+	return r.progressDelivery(template)
+}
+
+// getComponentDeliveryStrategy returns the delivery strategy of the component. Components without one in the spec
+// get a one-off strategy with default steps when the deploy webhook requests a strategy along with a new image,
+// the one-off strategy is kept until the delivery of the image is finished, or the image is changed again.
+func (r *ComponentReconcilerTask) getComponentDeliveryStrategy() *v1alpha1.DeliveryStrategy {
+	if r.component.Spec.DeliveryStrategy != nil {
+		return r.component.Spec.DeliveryStrategy
+	}
+
+	if r.component.Spec.WorkloadType != v1alpha1.WorkloadTypeServer && r.component.Spec.WorkloadType != "" {
+		return nil
+	}
+
+	if t := getDeliveryStrategyAnnotation(r.component); t != "" {
+		return &v1alpha1.DeliveryStrategy{Type: t}
+	}
+
+	status := r.deliveryStatus
+	if status == nil || status.Strategy == "" {
+		return nil
+	}
+
+	image := r.component.Spec.Image
+
+	switch status.Phase {
+	case v1alpha1.DeliveryPhaseProgressing, v1alpha1.DeliveryPhasePromoting:
+		if image == status.NewImage || image == status.StableImage {
+			return &v1alpha1.DeliveryStrategy{Type: status.Strategy}
+		}
+	case v1alpha1.DeliveryPhaseRolledBack:
+		// keep the stable image running until the image is changed
+		if image == status.NewImage {
+			return &v1alpha1.DeliveryStrategy{Type: status.Strategy}
+		}
+	}
+
+	return nil
+}
+
+// getDeliveryStrategyAnnotation returns the strategy type requested by the deploy webhook for the new image.
+func getDeliveryStrategyAnnotation(component *v1alpha1.Component) v1alpha1.DeliveryStrategyType {
+	switch t := v1alpha1.DeliveryStrategyType(component.Annotations[v1alpha1.KalmAnnoDeliveryStrategy]); t {
+	case v1alpha1.DeliveryStrategyCanary, v1alpha1.DeliveryStrategyBlueGreen:
+		return t
+	}
+
+	return ""
+}
+
+// getNextDeliveryStrategyType returns the strategy type requested by the deploy webhook for the new image,
+// or the type of the component delivery strategy.
+func (r *ComponentReconcilerTask) getNextDeliveryStrategyType() v1alpha1.DeliveryStrategyType {
+	if t := getDeliveryStrategyAnnotation(r.component); t != "" {
+		return t
+	}
+
+	return r.getComponentDeliveryStrategy().Type
+}
+
+func (r *ComponentReconcilerTask) removeDeliveryStrategyAnnotation() error {
+	if _, exist := r.component.Annotations[v1alpha1.KalmAnnoDeliveryStrategy]; !exist {
+		return nil
+	}
+
+	copied := r.component.DeepCopy()
+	delete(copied.Annotations, v1alpha1.KalmAnnoDeliveryStrategy)
+
+	if err := r.Patch(r.ctx, copied, client.MergeFrom(r.component)); err != nil {
+		r.WarningEvent(err, "unable to remove the delivery strategy annotation of Component")
+		return err
+	}
+
+	r.component.Annotations = copied.Annotations
+	r.component.ResourceVersion = copied.ResourceVersion
+
+	return nil
+}
+
+// getDeliveryStrategy returns the component delivery strategy with the type of the current delivery.
+func (r *ComponentReconcilerTask) getDeliveryStrategy() *v1alpha1.DeliveryStrategy {
+	strategy := r.getComponentDeliveryStrategy().DeepCopy()
+
+	if r.deliveryStatus.Strategy != "" {
+		strategy.Type = r.deliveryStatus.Strategy
+	}
+
+	return strategy
+}
+
+func (r *ComponentReconcilerTask) progressDelivery(template *corev1.PodTemplateSpec) error {
+	strategy := r.getDeliveryStrategy()
+	status := r.deliveryStatus
+
+	if err := r.reconcileCanaryService(); err != nil {
+		return err
+	}
+
+	if err := r.reconcileCanaryDeployment(template); err != nil {
+		return err
+	}
+
+	state := getDeploymentRolloutState(r.canaryDeployment)
+
+	if !state.observedLatest || state.rollingOut || state.ready < state.desired {
+		deadline := time.Duration(strategy.GetProgressDeadlineSeconds()) * time.Second
+
+		if state.stuck || time.Since(status.StartedAt.Time) > deadline {
+			msg := fmt.Sprintf("%d/%d pods of image %s are ready after %s", state.ready, state.desired, status.NewImage, deadline)
+			if state.stuck {
+				msg = state.stuckMessage
+			}
+
+			return r.rollbackDelivery(msg)
+		}
+
+		status.Message = fmt.Sprintf("waiting for pods of the new image to be ready, %d/%d ready", state.ready, state.desired)
+		r.requeueAfter = deliveryCheckInterval
+
+		return nil
+	}
+
+	steps := strategy.GetSteps()
+
+	// steps may be changed during the delivery
+	if status.Step >= len(steps) {
+		status.Step = len(steps) - 1
+	}
+
+	step := steps[status.Step]
+
+	if status.Weight != step.Weight {
+		now := metaV1.Now()
+		status.Weight = step.Weight
+		status.StepStartedAt = &now
+
+		r.NormalEvent("DeliveryStepStarted", fmt.Sprintf("send %d%% of the traffic to image %s", step.Weight, status.NewImage))
+	}
+
+	if err := r.reconcileDeliveryRouteWeights(status.Weight); err != nil {
+		return err
+	}
+
+	remaining := time.Duration(step.PauseSeconds)*time.Second - time.Since(status.StepStartedAt.Time)
+	if remaining > 0 {
+		status.Message = fmt.Sprintf("step %d/%d, %d%% of the traffic is sent to the new image", status.Step+1, len(steps), status.Weight)
+		r.requeueAfter = remaining
+
+		return nil
+	}
+
+	if status.Step < len(steps)-1 {
+		status.Step++
+		r.requeueAfter = time.Second
+
+		return nil
+	}
+
+	// all steps are done, roll the new image out to the stable deployment
+	status.Phase = v1alpha1.DeliveryPhasePromoting
+	status.StableImage = status.NewImage
+	r.NormalEvent("DeliveryPromoting", fmt.Sprintf("promote image %s", status.NewImage))
+
+	return r.promoteDelivery(template)
+}
+
+// The canary keeps serving until the stable deployment is running the new image.
+func (r *ComponentReconcilerTask) promoteDelivery(template *corev1.PodTemplateSpec) error {
+	status := r.deliveryStatus
+
+	if err := r.ReconcileDeployment(template); err != nil {
+		return err
+	}
+
+	state := getDeploymentRolloutState(r.deployment)
+	if !state.observedLatest || state.rollingOut || state.ready < state.desired {
+		status.Message = fmt.Sprintf("rolling out image %s, %d/%d ready", status.StableImage, state.ready, state.desired)
+		r.requeueAfter = deliveryCheckInterval
+
+		return nil
+	}
+
+	status.Phase = v1alpha1.DeliveryPhaseSucceeded
+	status.Message = ""
+	r.NormalEvent("DeliverySucceeded", fmt.Sprintf("image %s is delivered", status.StableImage))
+
+	return r.cleanupCanary()
+}
+
+func (r *ComponentReconcilerTask) rollbackDelivery(reason string) error {
+	status := r.deliveryStatus
+	status.Phase = v1alpha1.DeliveryPhaseRolledBack
+	status.Message = reason
+
+	r.WarningEvent(fmt.Errorf("%s", reason), fmt.Sprintf("delivery of image %s is rolled back", status.NewImage))
+
+	return r.cleanupCanary()
+}
+
+// cleanupDelivery removes everything left by a delivery, it's called when the component has no delivery strategy,
+// or the one-off strategy requested by the deploy webhook is finished.
+func (r *ComponentReconcilerTask) cleanupDelivery() error {
+	r.deliveryStatus = nil
+
+	if r.component.Status.Delivery == nil && r.canaryDeployment == nil && r.canaryService == nil {
+		return nil
+	}
+
+	return r.cleanupCanary()
+}
+
+func (r *ComponentReconcilerTask) cleanupCanary() error {
+	if r.deliveryStatus != nil {
+		r.deliveryStatus.Weight = 0
+	}
+
+	// move the traffic back before removing the canary pods
+	if err := r.reconcileDeliveryRouteWeights(0); err != nil {
+		return err
+	}
+
+	if r.canaryDeployment != nil {
+		if err := r.DeleteItem(r.canaryDeployment); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		r.canaryDeployment = nil
+	}
+
+	if r.canaryService != nil {
+		if err := r.DeleteItem(r.canaryService); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		r.canaryService = nil
+	}
+
+	return nil
+}
+
+func (r *ComponentReconcilerTask) reconcileCanaryService() error {
+	if r.service == nil {
+		return nil
+	}
+
+	labels := r.GetLabels()
+	selector := r.GetLabels()
+	selector["version"] = componentPodVersionCanary
+
+	service := r.canaryService
+	isNew := service == nil
+
+	if isNew {
+		service = &corev1.Service{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      getNameForCanary(r.component.Name),
+				Namespace: r.component.Namespace,
+				Labels:    labels,
+			},
+		}
+	}
+
+	service.Spec.Selector = selector
+	service.Spec.Ports = r.service.Spec.Ports
+
+	if isNew {
+		if err := ctrl.SetControllerReference(r.component, service, r.Scheme); err != nil {
+			r.WarningEvent(err, "unable to set owner for canary Service")
+			return err
+		}
+
+		if err := r.Create(r.ctx, service); err != nil {
+			r.WarningEvent(err, "unable to create canary Service for Component")
+			return err
+		}
+	} else {
+		if err := r.Update(r.ctx, service); err != nil {
+			r.WarningEvent(err, "unable to update canary Service for Component")
+			return err
+		}
+	}
+
+	r.canaryService = service
+
+	return nil
+}
+
+func (r *ComponentReconcilerTask) reconcileCanaryDeployment(template *corev1.PodTemplateSpec) error {
+	component := r.component
+
+	podTemplateSpec := template.DeepCopy()
+	podTemplateSpec.Labels["version"] = componentPodVersionCanary
+
+	deployment := r.canaryDeployment
+	isNew := deployment == nil
+
+	if isNew {
+		deployment = &appsV1.Deployment{
+			ObjectMeta: metaV1.ObjectMeta{
+				Labels:      podTemplateSpec.Labels,
+				Annotations: r.GetAnnotations(),
+				Name:        getNameForCanary(component.Name),
+				Namespace:   component.Namespace,
+			},
+			Spec: appsV1.DeploymentSpec{
+				Selector: &metaV1.LabelSelector{
+					MatchLabels: podTemplateSpec.Labels,
+				},
+			},
+		}
+	}
+
+	deployment.Spec.Template = *podTemplateSpec
+	// same size as the stable deployment, whose replicas may be managed by the autoscaler
+	deployment.Spec.Replicas = component.Spec.Replicas
+	if r.deployment != nil {
+		deployment.Spec.Replicas = r.deployment.Spec.Replicas
+	}
+	deployment.Spec.Strategy = appsV1.DeploymentStrategy{
+		Type: appsV1.RollingUpdateDeploymentStrategyType,
+	}
+
+	if err := ctrl.SetControllerReference(component, deployment, r.Scheme); err != nil {
+		r.WarningEvent(err, "unable to set owner for canary deployment")
+		return err
+	}
+
+	if err := r.runPlugins(ComponentPluginMethodBeforeDeploymentSave, component, deployment, deployment); err != nil {
+		r.WarningEvent(err, "run before deployment save error.")
+		return err
+	}
+
+	if isNew {
+		if err := r.Create(r.ctx, deployment); err != nil {
+			r.WarningEvent(err, "unable to create canary Deployment for Component")
+			return err
+		}
+
+		r.NormalEvent("DeploymentCreated", deployment.Name+" is created.")
+	} else {
+		if err := r.Update(r.ctx, deployment); err != nil {
+			r.WarningEvent(err, "unable to update canary Deployment for Component")
+			return err
+		}
+	}
+
+	r.canaryDeployment = deployment
+
+	return nil
+}
+
+// reconcileDeliveryRouteWeights sends weight% of the traffic of every HttpRoute pointing at the component to the canary.
+func (r *ComponentReconcilerTask) reconcileDeliveryRouteWeights(weight int) error {
+	var routes v1alpha1.HttpRouteList
+	if err := r.Reader.List(r.ctx, &routes); err != nil {
+		r.WarningEvent(err, "get http route list error.")
+		return err
+	}
+
+	stableHost := getServiceHost(r.component.Name, r.component.Namespace)
+	canaryHost := getServiceHost(getNameForCanary(r.component.Name), r.component.Namespace)
+
+	for i := range routes.Items {
+		route := &routes.Items[i]
+
+		destinations := splitDestinationsForDelivery(route.Spec.Destinations, stableHost, canaryHost, weight)
+		if reflect.DeepEqual(destinations, route.Spec.Destinations) {
+			continue
+		}
+
+		copied := route.DeepCopy()
+		copied.Spec.Destinations = destinations
+
+		if err := r.Patch(r.ctx, copied, client.MergeFrom(route)); err != nil {
+			r.WarningEvent(err, "unable to update destinations of HttpRoute "+route.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitDestinationsForDelivery folds the canary destinations back into the stable ones, then splits the
+// stable destination of each port into a stable and a canary destination.
+//
+// While weight > 0, all weights of the route are scaled by 100, so the canary gets exactly weight% of
+// the traffic of the stable destination however small the stable weight is, and the traffic share of
+// other destinations is kept. e.g. a stable destination with weight 1 becomes stable 90 + canary 10 at 10%.
+// The canary destination always exists while weight > 0, and never exists when weight == 0, which is how
+// scaled weights are told apart when they are folded back.
+func splitDestinationsForDelivery(destinations []v1alpha1.HttpRouteDestination, stableHost, canaryHost string, weight int) []v1alpha1.HttpRouteDestination {
+	type pair struct {
+		stable, canary int
+	}
+
+	pairs := make(map[string]*pair)
+	scaled := false
+
+	for _, dest := range destinations {
+		host, port := splitDestinationHost(dest.Host)
+		if host != stableHost && host != canaryHost {
+			continue
+		}
+
+		p, ok := pairs[port]
+		if !ok {
+			p = &pair{}
+			pairs[port] = p
+		}
+
+		if host == stableHost {
+			p.stable += dest.Weight
+		} else {
+			p.canary += dest.Weight
+			scaled = true
+		}
+	}
+
+	if len(pairs) == 0 {
+		return destinations
+	}
+
+	// weights before the split
+	originWeight := func(w int) int {
+		if scaled {
+			return (w + 50) / 100
+		}
+
+		return w
+	}
+
+	res := make([]v1alpha1.HttpRouteDestination, 0, len(destinations)+len(pairs))
+
+	for _, dest := range destinations {
+		host, port := splitDestinationHost(dest.Host)
+		if host != stableHost && host != canaryHost {
+			dest.Weight = originWeight(dest.Weight)
+
+			if weight > 0 {
+				dest.Weight *= 100
+			}
+
+			res = append(res, dest)
+			continue
+		}
+
+		p := pairs[port]
+		if p == nil {
+			// already emitted at the first destination of this port
+			continue
+		}
+
+		delete(pairs, port)
+
+		total := originWeight(p.stable + p.canary)
+
+		if weight <= 0 {
+			res = append(res, v1alpha1.HttpRouteDestination{Host: joinDestinationHost(stableHost, port), Weight: total})
+			continue
+		}
+
+		res = append(res,
+			v1alpha1.HttpRouteDestination{Host: joinDestinationHost(stableHost, port), Weight: total * (100 - weight)},
+			v1alpha1.HttpRouteDestination{Host: joinDestinationHost(canaryHost, port), Weight: total * weight},
+		)
+	}
+
+	return res
+}
+
+func splitDestinationHost(destinationHost string) (host, port string) {
+	colon := strings.LastIndexByte(destinationHost, ':')
+	if colon == -1 {
+		return destinationHost, ""
+	}
+
+	return destinationHost[:colon], destinationHost[colon+1:]
+}
+
+func joinDestinationHost(host, port string) string {
+	if port == "" {
+		return host
+	}
+
+	return host + ":" + port
+}
+
+// withMainContainerImage returns a copy of the template with the image of the main container replaced.
+func withMainContainerImage(template *corev1.PodTemplateSpec, componentName, image string) *corev1.PodTemplateSpec {
+	copied := template.DeepCopy()
+
+	for i := range copied.Spec.Containers {
+		if copied.Spec.Containers[i].Name == componentName {
+			copied.Spec.Containers[i].Image = image
+			return copied
+		}
+	}
+
+	if len(copied.Spec.Containers) > 0 {
+		copied.Spec.Containers[0].Image = image
+	}
+
+	return copied
+}
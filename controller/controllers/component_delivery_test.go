@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitDestinationsForDelivery(t *testing.T) {
+	stable := "web.app.svc.cluster.local"
+	canary := "web-canary.app.svc.cluster.local"
+
+	origin := []v1alpha1.HttpRouteDestination{
+		{Host: "other.app.svc.cluster.local:80", Weight: 1},
+		{Host: stable + ":80", Weight: 20},
+	}
+
+	// weights are scaled by 100 so that the traffic share of other destinations is kept
+	split := splitDestinationsForDelivery(origin, stable, canary, 5)
+	assert.Equal(t, []v1alpha1.HttpRouteDestination{
+		{Host: "other.app.svc.cluster.local:80", Weight: 100},
+		{Host: stable + ":80", Weight: 1900},
+		{Host: canary + ":80", Weight: 100},
+	}, split)
+
+	// reconciling the same weight again doesn't change the destinations
+	assert.Equal(t, split, splitDestinationsForDelivery(split, stable, canary, 5))
+
+	split = splitDestinationsForDelivery(split, stable, canary, 100)
+	assert.Equal(t, []v1alpha1.HttpRouteDestination{
+		{Host: "other.app.svc.cluster.local:80", Weight: 100},
+		{Host: stable + ":80", Weight: 0},
+		{Host: canary + ":80", Weight: 2000},
+	}, split)
+
+	assert.Equal(t, origin, splitDestinationsForDelivery(split, stable, canary, 0))
+
+	// the default weight 1 of the dashboard is split precisely
+	small := []v1alpha1.HttpRouteDestination{{Host: stable + ":80", Weight: 1}}
+
+	for _, weight := range []int{10, 25, 50} {
+		split = splitDestinationsForDelivery(small, stable, canary, weight)
+		assert.Equal(t, []v1alpha1.HttpRouteDestination{
+			{Host: stable + ":80", Weight: 100 - weight},
+			{Host: canary + ":80", Weight: weight},
+		}, split)
+		assert.Equal(t, []int32{int32(100 - weight), int32(weight)}, adjustDestinationWeightToSumTo100(split))
+		assert.Equal(t, small, splitDestinationsForDelivery(split, stable, canary, 0))
+	}
+
+	unrelated := []v1alpha1.HttpRouteDestination{{Host: "other.app.svc.cluster.local", Weight: 1}}
+	assert.Equal(t, unrelated, splitDestinationsForDelivery(unrelated, stable, canary, 50))
+}
+
+func TestDeliveryStrategyOfWebhook(t *testing.T) {
+	component := &v1alpha1.Component{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "app",
+			Annotations: map[string]string{v1alpha1.KalmAnnoDeliveryStrategy: string(v1alpha1.DeliveryStrategyBlueGreen)},
+		},
+		Spec: v1alpha1.ComponentSpec{
+			DeliveryStrategy: &v1alpha1.DeliveryStrategy{Type: v1alpha1.DeliveryStrategyCanary},
+		},
+	}
+
+	task := &ComponentReconcilerTask{component: component, deliveryStatus: &v1alpha1.DeliveryStatus{}}
+	assert.Equal(t, v1alpha1.DeliveryStrategyBlueGreen, task.getNextDeliveryStrategyType())
+
+	task.deliveryStatus.Strategy = v1alpha1.DeliveryStrategyBlueGreen
+	assert.Equal(t, []v1alpha1.DeliveryStep{{Weight: 100}}, task.getDeliveryStrategy().GetSteps())
+
+	// the stored strategy is not changed
+	assert.Equal(t, v1alpha1.DeliveryStrategyCanary, component.Spec.DeliveryStrategy.Type)
+
+	component.Annotations = nil
+	assert.Equal(t, v1alpha1.DeliveryStrategyCanary, task.getNextDeliveryStrategyType())
+}
+
+func TestOneOffDeliveryStrategyOfWebhook(t *testing.T) {
+	component := &v1alpha1.Component{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "app",
+			Annotations: map[string]string{v1alpha1.KalmAnnoDeliveryStrategy: string(v1alpha1.DeliveryStrategyCanary)},
+		},
+		Spec: v1alpha1.ComponentSpec{
+			Image: "web:v2",
+		},
+	}
+
+	// a component without a delivery strategy gets a one-off strategy with default steps
+	task := &ComponentReconcilerTask{component: component}
+	assert.Equal(t, v1alpha1.DeliveryStrategyCanary, task.getNextDeliveryStrategyType())
+	assert.Equal(t, v1alpha1.DefaultCanarySteps, task.getComponentDeliveryStrategy().GetSteps())
+	assert.Equal(t, componentPodVersionStable, task.getServiceSelector(map[string]string{"app": "web"})["version"])
+
+	// the strategy is kept during the delivery after the annotation is removed
+	component.Annotations = nil
+	task.deliveryStatus = &v1alpha1.DeliveryStatus{
+		Strategy:    v1alpha1.DeliveryStrategyCanary,
+		Phase:       v1alpha1.DeliveryPhaseProgressing,
+		StableImage: "web:v1",
+		NewImage:    "web:v2",
+	}
+	assert.Equal(t, v1alpha1.DeliveryStrategyCanary, task.getComponentDeliveryStrategy().Type)
+
+	task.deliveryStatus.Phase = v1alpha1.DeliveryPhaseRolledBack
+	assert.NotNil(t, task.getComponentDeliveryStrategy())
+
+	// other images are deployed in place
+	component.Spec.Image = "web:v3"
+	assert.Nil(t, task.getComponentDeliveryStrategy())
+	assert.Equal(t, map[string]string{"app": "web"}, task.getServiceSelector(map[string]string{"app": "web"}))
+
+	task.deliveryStatus.Phase = v1alpha1.DeliveryPhaseSucceeded
+	component.Spec.Image = "web:v2"
+	assert.Nil(t, task.getComponentDeliveryStrategy())
+}
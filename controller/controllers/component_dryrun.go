@@ -0,0 +1,513 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/kalmhq/kalm/controller/vm"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type DryRunAction string
+
+const (
+	DryRunActionCreate DryRunAction = "create"
+	DryRunActionUpdate DryRunAction = "update"
+	DryRunActionDelete DryRunAction = "delete"
+)
+
+// DryRunChange is an object written in a dry run.
+// Current is the live object before the dry run, nil if the object would be created.
+// Desired is the object after the dry run, nil if the object would be deleted.
+type DryRunChange struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Current          runtime.Object
+	Desired          runtime.Object
+}
+
+func (c *DryRunChange) Action() DryRunAction {
+	if c.Current == nil {
+		return DryRunActionCreate
+	}
+
+	if c.Desired == nil {
+		return DryRunActionDelete
+	}
+
+	return DryRunActionUpdate
+}
+
+type dryRunKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// DryRunClient records writes instead of persisting them, reads see the recorded writes.
+// Writes of existing objects are sent to the api server in dry run mode, so admission webhooks and defaulting still apply.
+type DryRunClient struct {
+	client.Client
+
+	scheme  *runtime.Scheme
+	changes map[dryRunKey]*DryRunChange
+	keys    []dryRunKey
+}
+
+func NewDryRunClient(c client.Client, scheme *runtime.Scheme) *DryRunClient {
+	return &DryRunClient{
+		Client:  c,
+		scheme:  scheme,
+		changes: make(map[dryRunKey]*DryRunChange),
+	}
+}
+
+// Changes returns the recorded changes in the order they are first written
+func (c *DryRunClient) Changes() []DryRunChange {
+	res := make([]DryRunChange, 0, len(c.keys))
+
+	for _, key := range c.keys {
+		res = append(res, *c.changes[key])
+	}
+
+	return res
+}
+
+func (c *DryRunClient) keyOf(obj runtime.Object) (dryRunKey, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+
+	if err != nil {
+		return dryRunKey{}, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+
+	if err != nil {
+		return dryRunKey{}, err
+	}
+
+	return dryRunKey{gvk: gvk, namespace: accessor.GetNamespace(), name: accessor.GetName()}, nil
+}
+
+func (c *DryRunClient) newObject(gvk schema.GroupVersionKind, like runtime.Object) (runtime.Object, error) {
+	if _, ok := like.(*unstructured.Unstructured); ok {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		return obj, nil
+	}
+
+	return c.scheme.New(gvk)
+}
+
+func (c *DryRunClient) notFound(key dryRunKey) error {
+	return errors.NewNotFound(schema.GroupResource{Group: key.gvk.Group, Resource: strings.ToLower(key.gvk.Kind)}, key.name)
+}
+
+// copyObject copies src into dst, they can be either typed or unstructured objects of the same kind
+func copyObject(src, dst runtime.Object) error {
+	bts, err := json.Marshal(src)
+
+	if err != nil {
+		return err
+	}
+
+	if u, ok := dst.(*unstructured.Unstructured); ok {
+		u.Object = nil
+		return u.UnmarshalJSON(bts)
+	}
+
+	return json.Unmarshal(bts, dst)
+}
+
+func (c *DryRunClient) record(key dryRunKey, current, desired runtime.Object) {
+	if desired != nil {
+		desired = desired.DeepCopyObject()
+	}
+
+	if change, exist := c.changes[key]; exist {
+		change.Desired = desired
+		return
+	}
+
+	c.changes[key] = &DryRunChange{
+		GroupVersionKind: key.gvk,
+		Namespace:        key.namespace,
+		Name:             key.name,
+		Current:          current,
+		Desired:          desired,
+	}
+
+	c.keys = append(c.keys, key)
+}
+
+func (c *DryRunClient) forget(key dryRunKey) {
+	delete(c.changes, key)
+
+	for i := range c.keys {
+		if c.keys[i] == key {
+			c.keys = append(c.keys[:i], c.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// getCurrent returns the live object before the dry run
+func (c *DryRunClient) getCurrent(ctx context.Context, key dryRunKey, like runtime.Object) (runtime.Object, error) {
+	if change, exist := c.changes[key]; exist {
+		return change.Current, nil
+	}
+
+	current, err := c.newObject(key.gvk, like)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Client.Get(ctx, types.NamespacedName{Namespace: key.namespace, Name: key.name}, current); err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
+
+func (c *DryRunClient) Get(ctx context.Context, objKey client.ObjectKey, obj runtime.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+
+	if err != nil {
+		return err
+	}
+
+	key := dryRunKey{gvk: gvk, namespace: objKey.Namespace, name: objKey.Name}
+
+	if change, exist := c.changes[key]; exist {
+		if change.Desired == nil {
+			return c.notFound(key)
+		}
+
+		return copyObject(change.Desired, obj)
+	}
+
+	return c.Client.Get(ctx, objKey, obj)
+}
+
+func (c *DryRunClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+
+	gvk, err := apiutil.GVKForObject(list, c.scheme)
+
+	if err != nil {
+		return err
+	}
+
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+
+	items, err := meta.ExtractList(list)
+
+	if err != nil {
+		return err
+	}
+
+	var res []runtime.Object
+	listed := make(map[dryRunKey]bool, len(items))
+
+	for _, item := range items {
+		key, err := c.keyOf(item)
+
+		if err != nil {
+			return err
+		}
+
+		key.gvk = gvk
+		listed[key] = true
+
+		if change, exist := c.changes[key]; exist {
+			if change.Desired == nil || !matchesDryRunListOptions(change.Desired, listOpts) {
+				continue
+			}
+
+			if err := copyObject(change.Desired, item); err != nil {
+				return err
+			}
+		}
+
+		res = append(res, item)
+	}
+
+	// objects created in the dry run
+	for _, key := range c.keys {
+		change := c.changes[key]
+
+		if key.gvk != gvk || listed[key] || change.Desired == nil || !matchesDryRunListOptions(change.Desired, listOpts) {
+			continue
+		}
+
+		var item runtime.Object
+
+		if _, ok := list.(*unstructured.UnstructuredList); ok {
+			item = &unstructured.Unstructured{}
+		} else if item, err = c.scheme.New(gvk); err != nil {
+			return err
+		}
+
+		if err := copyObject(change.Desired, item); err != nil {
+			return err
+		}
+
+		res = append(res, item)
+	}
+
+	return meta.SetList(list, res)
+}
+
+func matchesDryRunListOptions(obj runtime.Object, opts *client.ListOptions) bool {
+	accessor, err := meta.Accessor(obj)
+
+	if err != nil {
+		return false
+	}
+
+	if opts.Namespace != "" && opts.Namespace != accessor.GetNamespace() {
+		return false
+	}
+
+	if opts.LabelSelector != nil && !opts.LabelSelector.Matches(labels.Set(accessor.GetLabels())) {
+		return false
+	}
+
+	return true
+}
+
+func (c *DryRunClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	key, err := c.keyOf(obj)
+
+	if err != nil {
+		return err
+	}
+
+	if change, exist := c.changes[key]; exist && key.name != "" {
+		if change.Desired != nil {
+			return errors.NewAlreadyExists(schema.GroupResource{Group: key.gvk.Group, Resource: strings.ToLower(key.gvk.Kind)}, key.name)
+		}
+
+		// deleted in the dry run, the api server still has it
+		c.record(key, nil, obj)
+		return nil
+	}
+
+	if err := c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+
+	// the name may be generated by the api server
+	if key, err = c.keyOf(obj); err != nil {
+		return err
+	}
+
+	c.record(key, nil, obj)
+
+	return nil
+}
+
+func (c *DryRunClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	key, err := c.keyOf(obj)
+
+	if err != nil {
+		return err
+	}
+
+	if change, exist := c.changes[key]; exist && (change.Current == nil || change.Desired == nil) {
+		if change.Desired == nil {
+			return c.notFound(key)
+		}
+
+		// only exists in the dry run
+		c.record(key, nil, obj)
+		return nil
+	}
+
+	current, err := c.getCurrent(ctx, key, obj)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+
+	c.record(key, current, obj)
+
+	return nil
+}
+
+// Patch expects obj to be the patched object already, e.g. patches made by client.MergeFrom
+func (c *DryRunClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	key, err := c.keyOf(obj)
+
+	if err != nil {
+		return err
+	}
+
+	if change, exist := c.changes[key]; exist && (change.Current == nil || change.Desired == nil) {
+		if change.Desired == nil {
+			return c.notFound(key)
+		}
+
+		// only exists in the dry run
+		c.record(key, nil, obj)
+		return nil
+	}
+
+	current, err := c.getCurrent(ctx, key, obj)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+
+	c.record(key, current, obj)
+
+	return nil
+}
+
+func (c *DryRunClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	key, err := c.keyOf(obj)
+
+	if err != nil {
+		return err
+	}
+
+	if change, exist := c.changes[key]; exist {
+		if change.Desired == nil {
+			return c.notFound(key)
+		}
+
+		if change.Current == nil {
+			// created in the dry run
+			c.forget(key)
+			return nil
+		}
+	}
+
+	current, err := c.getCurrent(ctx, key, obj)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+
+	c.record(key, current, nil)
+
+	return nil
+}
+
+func (c *DryRunClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return fmt.Errorf("delete all of is not supported in dry run")
+}
+
+// Status changes are not recorded, they are not part of the desired state
+func (c *DryRunClient) Status() client.StatusWriter {
+	return dryRunStatusWriter{}
+}
+
+type dryRunStatusWriter struct{}
+
+func (dryRunStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return nil
+}
+
+func (dryRunStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return nil
+}
+
+// loadComponentPluginsCache compiles all component plugins, instead of using the cache of the running controller
+func loadComponentPluginsCache(ctx context.Context, reader client.Reader) (*ComponentPluginsCache, error) {
+	var pluginList v1alpha1.ComponentPluginList
+
+	if err := reader.List(ctx, &pluginList); err != nil {
+		return nil, err
+	}
+
+	cache := &ComponentPluginsCache{Programs: make(map[string]*ComponentPluginProgram)}
+
+	for i := range pluginList.Items {
+		plugin := &pluginList.Items[i]
+
+		if plugin.DeletionTimestamp != nil || plugin.Spec.Src == "" {
+			continue
+		}
+
+		program, err := vm.CompileProgram(plugin.Spec.Src)
+
+		if err != nil {
+			continue
+		}
+
+		pluginProgram, err := newComponentPluginProgram(plugin, program)
+
+		if err != nil {
+			continue
+		}
+
+		cache.Set(plugin.Name, pluginProgram)
+	}
+
+	return cache, nil
+}
+
+// DryRunComponentReconcile runs the reconciliation of the component against the dry run client.
+// Objects written by the controller, including the ones changed by component plugins, are recorded in the client.
+func DryRunComponentReconcile(c *DryRunClient, namespace, name string) error {
+	ctx := context.Background()
+	pluginsCache, err := loadComponentPluginsCache(ctx, c)
+
+	if err != nil {
+		return err
+	}
+
+	task := &ComponentReconcilerTask{
+		ComponentReconciler: &ComponentReconciler{
+			BaseReconciler: &BaseReconciler{
+				Client: c,
+				Reader: c,
+				Log:    ctrl.Log.WithName("controllers").WithName("ComponentDryRun"),
+				Scheme: c.scheme,
+				// events are dropped
+				Recorder: &record.FakeRecorder{},
+			},
+		},
+		ctx:          ctx,
+		pluginsCache: pluginsCache,
+	}
+
+	var component v1alpha1.Component
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &component); err != nil {
+		return err
+	}
+
+	return task.Run(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	istioScheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+	appsV1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDryRunTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.Nil(t, clientgoscheme.AddToScheme(scheme))
+	require.Nil(t, istioScheme.AddToScheme(scheme))
+	require.Nil(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestDryRunClient(t *testing.T) {
+	scheme := newDryRunTestScheme(t)
+	ctx := context.Background()
+
+	web := &corev1.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{Name: "web", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	underlying := fake.NewFakeClientWithScheme(scheme, web)
+	c := NewDryRunClient(underlying, scheme)
+
+	// update
+	var configMap corev1.ConfigMap
+	require.Nil(t, c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "web"}, &configMap))
+	configMap.Data["key"] = "changed"
+	require.Nil(t, c.Update(ctx, &configMap))
+
+	// create
+	api := &corev1.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{Name: "api", Namespace: "shop", Labels: map[string]string{"app": "api"}},
+	}
+	require.Nil(t, c.Create(ctx, api))
+	assert.True(t, errors.IsAlreadyExists(c.Create(ctx, api.DeepCopy())))
+
+	// writes are visible to reads of the dry run only
+	require.Nil(t, c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "web"}, &configMap))
+	assert.Equal(t, "changed", configMap.Data["key"])
+
+	require.Nil(t, underlying.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "web"}, &configMap))
+	assert.Equal(t, "value", configMap.Data["key"])
+	assert.True(t, errors.IsNotFound(underlying.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "api"}, &configMap)))
+
+	var list corev1.ConfigMapList
+	require.Nil(t, c.List(ctx, &list, client.InNamespace("shop")))
+	assert.Len(t, list.Items, 2)
+
+	require.Nil(t, c.List(ctx, &list, client.MatchingLabels{"app": "api"}))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "api", list.Items[0].Name)
+
+	changes := c.Changes()
+	require.Len(t, changes, 2)
+	assert.Equal(t, DryRunActionUpdate, changes[0].Action())
+	assert.Equal(t, "value", changes[0].Current.(*corev1.ConfigMap).Data["key"])
+	assert.Equal(t, "changed", changes[0].Desired.(*corev1.ConfigMap).Data["key"])
+	assert.Equal(t, DryRunActionCreate, changes[1].Action())
+	assert.Equal(t, "ConfigMap", changes[1].GroupVersionKind.Kind)
+
+	// deleting an object created in the dry run leaves nothing
+	require.Nil(t, c.Delete(ctx, api))
+	assert.Len(t, c.Changes(), 1)
+
+	require.Nil(t, c.Delete(ctx, &configMap))
+	assert.True(t, errors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "web"}, &configMap)))
+
+	changes = c.Changes()
+	require.Len(t, changes, 1)
+	assert.Equal(t, DryRunActionDelete, changes[0].Action())
+	assert.Equal(t, "value", changes[0].Current.(*corev1.ConfigMap).Data["key"])
+}
+
+func TestDryRunComponentReconcile(t *testing.T) {
+	scheme := newDryRunTestScheme(t)
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:   "shop",
+			Labels: map[string]string{KalmEnableLabelName: KalmEnableLabelValue},
+		},
+	}
+
+	component := &v1alpha1.Component{
+		ObjectMeta: metaV1.ObjectMeta{Name: "web", Namespace: "shop", UID: "web-uid"},
+		Spec: v1alpha1.ComponentSpec{
+			Image: "nginx:1.19",
+			Ports: []v1alpha1.Port{{ContainerPort: 80, Protocol: v1alpha1.PortProtocolHTTP}},
+		},
+	}
+
+	underlying := fake.NewFakeClientWithScheme(scheme, ns, component)
+	c := NewDryRunClient(underlying, scheme)
+
+	require.Nil(t, DryRunComponentReconcile(c, "shop", "web"))
+
+	actions := make(map[string]DryRunAction)
+	for _, change := range c.Changes() {
+		actions[change.GroupVersionKind.Kind+"/"+change.Name] = change.Action()
+	}
+
+	assert.Equal(t, DryRunActionCreate, actions["Service/web"])
+	assert.Equal(t, DryRunActionCreate, actions["DestinationRule/web"])
+	assert.Equal(t, DryRunActionCreate, actions["Deployment/web"])
+
+	var deployment appsV1.Deployment
+	require.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "shop", Name: "web"}, &deployment))
+	assert.Equal(t, "nginx:1.19", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	// nothing is persisted
+	assert.True(t, errors.IsNotFound(underlying.Get(context.Background(), types.NamespacedName{Namespace: "shop", Name: "web"}, &deployment)))
+
+	// plugins are run
+	plugin := &v1alpha1.ComponentPlugin{
+		ObjectMeta: metaV1.ObjectMeta{Name: "replicas"},
+		Spec: v1alpha1.ComponentPluginSpec{
+			Src: `
+function BeforeDeploymentSave(deployment) {
+	deployment.spec.replicas = 5;
+	return deployment;
+}`,
+		},
+	}
+
+	binding := &v1alpha1.ComponentPluginBinding{
+		ObjectMeta: metaV1.ObjectMeta{Name: "web-replicas", Namespace: "shop"},
+		Spec:       v1alpha1.ComponentPluginBindingSpec{PluginName: "replicas", ComponentName: "web"},
+	}
+
+	c = NewDryRunClient(fake.NewFakeClientWithScheme(scheme, ns, component, plugin, binding), scheme)
+	require.Nil(t, DryRunComponentReconcile(c, "shop", "web"))
+	require.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "shop", Name: "web"}, &deployment))
+	assert.Equal(t, int32(5), *deployment.Spec.Replicas)
+
+	// plugins that can't be found fail the dry run as they fail the reconciliation
+	binding = &v1alpha1.ComponentPluginBinding{
+		ObjectMeta: metaV1.ObjectMeta{Name: "web-missing", Namespace: "shop"},
+		Spec:       v1alpha1.ComponentPluginBindingSpec{PluginName: "missing", ComponentName: "web"},
+	}
+
+	c = NewDryRunClient(fake.NewFakeClientWithScheme(scheme, ns, component, binding), scheme)
+	assert.NotNil(t, DryRunComponentReconcile(c, "shop", "web"))
+}
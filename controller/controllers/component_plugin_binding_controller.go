@@ -159,5 +159,5 @@ func NewComponentPluginBindingReconciler(mgr ctrl.Manager) *ComponentPluginBindi
 func (r *ComponentPluginBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ComponentPluginBinding{}).
-		Complete(r)
+		Complete(instrumentReconciler("ComponentPluginBindingReconciler", r))
 }
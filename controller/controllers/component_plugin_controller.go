@@ -178,50 +178,62 @@ func (r *ComponentPluginReconcilerTask) Run(req ctrl.Request) error {
 		}
 	}
 
+	// The plugin must be compilable before move on
+	if !r.plugin.Status.CompiledSuccessfully {
+		return nil
+	}
+
+	pluginProgram, err := newComponentPluginProgram(r.plugin, program)
+
+	if err != nil {
+		r.WarningEvent(err, "build plugin program error.")
+		return nil
+	}
+
+	componentPluginsCache.Set(r.plugin.Name, pluginProgram)
+
+	return nil
+}
+
+// newComponentPluginProgram collects the config schema, the defined hooks and the available workload types of the compiled plugin
+func newComponentPluginProgram(plugin *corev1alpha1.ComponentPlugin, program *js.Program) (*ComponentPluginProgram, error) {
 	var configSchema *gojsonschema.Schema
-	if r.plugin.Spec.ConfigSchema != nil {
-		schemaLoader := gojsonschema.NewStringLoader(string(r.plugin.Spec.ConfigSchema.Raw))
-		configSchema, err = gojsonschema.NewSchema(schemaLoader)
+	if plugin.Spec.ConfigSchema != nil {
+		schemaLoader := gojsonschema.NewStringLoader(string(plugin.Spec.ConfigSchema.Raw))
+		schema, err := gojsonschema.NewSchema(schemaLoader)
 
 		if err != nil {
-			r.WarningEvent(err, "compile plugin config schema error")
-			return nil
+			return nil, fmt.Errorf("compile plugin config schema error: %s", err)
 		}
-	}
 
-	// The plugin must be compilable before move on
-	if !r.plugin.Status.CompiledSuccessfully {
-		return nil
+		configSchema = schema
 	}
 
-	methods, err := vm.GetDefinedMethods(r.plugin.Spec.Src, ValidPluginMethods)
+	methods, err := vm.GetDefinedMethods(plugin.Spec.Src, ValidPluginMethods)
 
 	if err != nil {
-		r.WarningEvent(err, "Get Defined Methods error.")
-		return nil
+		return nil, fmt.Errorf("get defined methods error: %s", err)
 	}
 
 	availableWorkloadTypes := make(map[corev1alpha1.WorkloadType]bool)
 	var availableForAllWorkloadTypes bool
 
-	if len(r.plugin.Spec.AvailableWorkloadType) == 0 {
+	if len(plugin.Spec.AvailableWorkloadType) == 0 {
 		availableForAllWorkloadTypes = true
 	} else {
-		for _, workloadType := range r.plugin.Spec.AvailableWorkloadType {
+		for _, workloadType := range plugin.Spec.AvailableWorkloadType {
 			availableWorkloadTypes[workloadType] = true
 		}
 	}
 
-	componentPluginsCache.Set(r.plugin.Name, &ComponentPluginProgram{
-		Name:                         r.plugin.Name,
+	return &ComponentPluginProgram{
+		Name:                         plugin.Name,
 		Program:                      program,
 		Methods:                      methods,
 		AvailableForAllWorkloadTypes: availableForAllWorkloadTypes,
 		AvailableWorkloadTypes:       availableWorkloadTypes,
 		ConfigSchema:                 configSchema,
-	})
-
-	return nil
+	}, nil
 }
 
 func (r *ComponentPluginReconcilerTask) deletePluginBindings() error {
@@ -268,5 +280,5 @@ func NewComponentPluginReconciler(mgr ctrl.Manager) *ComponentPluginReconciler {
 func (r *ComponentPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.ComponentPlugin{}).
-		Complete(r)
+		Complete(instrumentReconciler("ComponentPluginReconciler", r))
 }
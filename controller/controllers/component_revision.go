@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcileRevision records the component spec as a new ComponentRevision if it differs from the latest one,
+// and removes the revisions exceeding the history limit of the namespace.
+// Changes made through the api are recorded when they are written, this records changes made by others, e.g. kubectl.
+func (r *ComponentReconcilerTask) ReconcileRevision() error {
+	revisions, created, err := RecordComponentRevision(r.ctx, r.Reader, r.Client, r.component)
+	if err != nil {
+		r.WarningEvent(err, "unable to record ComponentRevision")
+		return err
+	}
+
+	latest := revisions[len(revisions)-1]
+	r.revision = latest.Spec.Revision
+
+	if created {
+		r.NormalEvent("RevisionCreated", fmt.Sprintf("revision %d is recorded", latest.Spec.Revision))
+	}
+
+	if err := r.removeComponentChangeAnnotations(); err != nil {
+		return err
+	}
+
+	limit := v1alpha1.GetComponentRevisionHistoryLimit(r.namespace.Labels)
+
+	for i := 0; i < len(revisions)-limit; i++ {
+		if err := r.Delete(r.ctx, &revisions[i]); client.IgnoreNotFound(err) != nil {
+			r.WarningEvent(err, "unable to delete ComponentRevision "+revisions[i].Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the revision number may be taken by a concurrent change, it's retried with the next number
+const recordComponentRevisionAttempts = 3
+
+// RecordComponentRevision records the spec of the component as a new ComponentRevision if it differs from the latest one.
+// It's called by the api right after writing a spec, so each change gets its own revision, even if the controller
+// only reconciles the last one of quick successive changes. Revisions of the component are returned, oldest first.
+func RecordComponentRevision(
+	ctx context.Context,
+	reader client.Reader,
+	writer client.Writer,
+	component *v1alpha1.Component,
+) (revisions []v1alpha1.ComponentRevision, created bool, err error) {
+
+	for attempt := 0; attempt < recordComponentRevisionAttempts; attempt++ {
+		var revisionList v1alpha1.ComponentRevisionList
+
+		if err := reader.List(ctx, &revisionList,
+			client.InNamespace(component.Namespace),
+			client.MatchingLabels{v1alpha1.KalmLabelComponentKey: component.Name},
+		); err != nil {
+			return nil, false, err
+		}
+
+		revisions = revisionList.Items
+		sort.Slice(revisions, func(i, j int) bool {
+			return revisions[i].Spec.Revision < revisions[j].Spec.Revision
+		})
+
+		spec := getRevisionComponentSpec(component.Spec)
+
+		var latest *v1alpha1.ComponentRevision
+		if len(revisions) > 0 {
+			latest = &revisions[len(revisions)-1]
+		}
+
+		if latest != nil && equality.Semantic.DeepEqual(latest.Spec.ComponentSpec, spec) {
+			return revisions, false, nil
+		}
+
+		revision := newComponentRevision(component, latest, spec)
+
+		if err := writer.Create(ctx, revision); err != nil {
+			if errors.IsAlreadyExists(err) {
+				continue
+			}
+
+			return nil, false, err
+		}
+
+		return append(revisions, *revision), true, nil
+	}
+
+	return nil, false, fmt.Errorf("fail to record revision of component %s after %d attempts", component.Name, recordComponentRevisionAttempts)
+}
+
+func newComponentRevision(component *v1alpha1.Component, latest *v1alpha1.ComponentRevision, spec v1alpha1.ComponentSpec) *v1alpha1.ComponentRevision {
+	var revisionNumber int64 = 1
+	if latest != nil {
+		revisionNumber = latest.Spec.Revision + 1
+	}
+
+	annotations := component.Annotations
+
+	changedVia := v1alpha1.ComponentChangeVia(annotations[v1alpha1.KalmAnnoChangedVia])
+	if changedVia == "" {
+		changedVia = v1alpha1.ComponentChangeViaUnknown
+	}
+
+	rollbackToRevision, _ := strconv.ParseInt(annotations[v1alpha1.KalmAnnoChangedByRollbackRevision], 10, 64)
+
+	return &v1alpha1.ComponentRevision{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      v1alpha1.GetComponentRevisionName(component.Name, revisionNumber),
+			Namespace: component.Namespace,
+			Labels: map[string]string{
+				v1alpha1.KalmLabelComponentKey: component.Name,
+			},
+			// revisions are garbage collected with the component
+			OwnerReferences: []metaV1.OwnerReference{
+				*metaV1.NewControllerRef(component, v1alpha1.GroupVersion.WithKind("Component")),
+			},
+		},
+		Spec: v1alpha1.ComponentRevisionSpec{
+			ComponentName:       component.Name,
+			Revision:            revisionNumber,
+			ComponentGeneration: component.Generation,
+			ComponentSpec:       spec,
+			ChangedBy:           annotations[v1alpha1.KalmAnnoChangedBy],
+			ChangedVia:          changedVia,
+			AccessToken:         annotations[v1alpha1.KalmAnnoChangedWithAccessToken],
+			RollbackToRevision:  rollbackToRevision,
+			ChangedAt:           metaV1.Now(),
+		},
+	}
+}
+
+// The change annotations describe a single change, once it's recorded they are removed so that
+// a later change made without them, e.g. by kubectl, is not attributed to the same user.
+func (r *ComponentReconcilerTask) removeComponentChangeAnnotations() error {
+	copied := r.component.DeepCopy()
+	changed := false
+
+	for _, key := range v1alpha1.KalmComponentChangeAnnotations {
+		if _, exist := copied.Annotations[key]; exist {
+			delete(copied.Annotations, key)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := r.Patch(r.ctx, copied, client.MergeFrom(r.component)); err != nil {
+		r.WarningEvent(err, "unable to remove change annotations of Component")
+		return err
+	}
+
+	r.component = copied
+
+	return nil
+}
+
+// fields changed by the controller itself are not part of a revision
+func getRevisionComponentSpec(spec v1alpha1.ComponentSpec) v1alpha1.ComponentSpec {
+	copied := spec.DeepCopy()
+	copied.ImmediateTrigger = false
+
+	return *copied
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	appsV1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workloadRolloutState is the common view of a Deployment/StatefulSet/DaemonSet/CronJob
+// that is needed to compute the status of a component.
+type workloadRolloutState struct {
+	exists bool
+
+	desired int32
+	current int32
+	ready   int32
+	updated int32
+
+	// the workload controller has seen the latest spec
+	observedLatest bool
+	// new pods are still being rolled out or old pods are still being terminated
+	rollingOut bool
+	// the workload controller gave up on the rollout
+	stuck        bool
+	stuckMessage string
+
+	image string
+}
+
+func (r *ComponentReconcilerTask) UpdateStatus() error {
+	state := r.getWorkloadRolloutState()
+
+	copied := r.component.DeepCopy()
+	status := &copied.Status
+
+	status.ObservedGeneration = r.component.Generation
+	status.Replicas = state.desired
+	status.ReadyReplicas = state.ready
+	status.UpdatedReplicas = state.updated
+
+	setComponentConditions(status, r.component, state)
+
+	status.Delivery = r.deliveryStatus
+	status.Revision = r.revision
+
+	if equality.Semantic.DeepEqual(r.component.Status, copied.Status) {
+		return nil
+	}
+
+	if err := r.Status().Patch(r.ctx, copied, client.MergeFrom(r.component)); err != nil {
+		r.WarningEvent(err, "unable to patch Component status")
+		return err
+	}
+
+	r.component = copied
+
+	return nil
+}
+
+func setComponentConditions(status *v1alpha1.ComponentStatus, component *v1alpha1.Component, state workloadRolloutState) {
+	if !state.exists {
+		msg := fmt.Sprintf("workload of type %s is not created yet", component.Spec.WorkloadType)
+
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  v1alpha1.ComponentReasonWorkloadNotFound,
+			Message: msg,
+		})
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionAvailable,
+			Status:  corev1.ConditionFalse,
+			Reason:  v1alpha1.ComponentReasonWorkloadNotFound,
+			Message: msg,
+		})
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:   v1alpha1.ComponentConditionDegraded,
+			Status: corev1.ConditionFalse,
+			Reason: v1alpha1.ComponentReasonAsExpected,
+		})
+
+		return
+	}
+
+	// Progressing
+	if component.Spec.WorkloadType == v1alpha1.WorkloadTypeCronjob {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  v1alpha1.ComponentReasonCronJobScheduled,
+			Message: fmt.Sprintf("%d job(s) active", state.current),
+		})
+	} else if !state.observedLatest || state.rollingOut {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionProgressing,
+			Status:  corev1.ConditionTrue,
+			Reason:  v1alpha1.ComponentReasonRolloutInProgress,
+			Message: fmt.Sprintf("%d of %d updated replicas are ready", minInt32(state.ready, state.updated), state.desired),
+		})
+	} else {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  v1alpha1.ComponentReasonRolloutComplete,
+			Message: fmt.Sprintf("%d replicas are running the latest spec", state.updated),
+		})
+
+		// only report the image once every replica is running it
+		status.Image = state.image
+	}
+
+	// Available
+	if component.Spec.WorkloadType == v1alpha1.WorkloadTypeCronjob {
+		if state.stuck {
+			v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+				Type:    v1alpha1.ComponentConditionAvailable,
+				Status:  corev1.ConditionFalse,
+				Reason:  v1alpha1.ComponentReasonCronJobSuspended,
+				Message: state.stuckMessage,
+			})
+		} else {
+			v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+				Type:   v1alpha1.ComponentConditionAvailable,
+				Status: corev1.ConditionTrue,
+				Reason: v1alpha1.ComponentReasonCronJobScheduled,
+			})
+		}
+
+		// a cronjob doesn't roll out, the template image is the running image
+		status.Image = state.image
+	} else if state.ready >= state.desired {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionAvailable,
+			Status:  corev1.ConditionTrue,
+			Reason:  v1alpha1.ComponentReasonMinimumReplicasAvailable,
+			Message: fmt.Sprintf("%d/%d replicas are ready", state.ready, state.desired),
+		})
+	} else {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionAvailable,
+			Status:  corev1.ConditionFalse,
+			Reason:  v1alpha1.ComponentReasonReplicasUnavailable,
+			Message: fmt.Sprintf("%d/%d replicas are ready", state.ready, state.desired),
+		})
+	}
+
+	// Degraded
+	if isComponentLabeledAsExceedingQuota(component) {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  v1alpha1.ReasonExceedingQuota,
+			Message: "component is scaled down because the application is exceeding quota",
+		})
+	} else if state.stuck && component.Spec.WorkloadType != v1alpha1.WorkloadTypeCronjob {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:    v1alpha1.ComponentConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  v1alpha1.ComponentReasonProgressDeadlineExceeded,
+			Message: state.stuckMessage,
+		})
+	} else {
+		v1alpha1.SetComponentCondition(status, v1alpha1.ComponentCondition{
+			Type:   v1alpha1.ComponentConditionDegraded,
+			Status: corev1.ConditionFalse,
+			Reason: v1alpha1.ComponentReasonAsExpected,
+		})
+	}
+}
+
+func (r *ComponentReconcilerTask) getWorkloadRolloutState() workloadRolloutState {
+	switch r.component.Spec.WorkloadType {
+	case v1alpha1.WorkloadTypeServer, "":
+		if r.deployment != nil {
+			return getDeploymentRolloutState(r.deployment)
+		}
+	case v1alpha1.WorkloadTypeStatefulSet:
+		if r.statefulSet != nil {
+			return getStatefulSetRolloutState(r.statefulSet)
+		}
+	case v1alpha1.WorkloadTypeDaemonSet:
+		if r.daemonSet != nil {
+			return getDaemonSetRolloutState(r.daemonSet)
+		}
+	case v1alpha1.WorkloadTypeCronjob:
+		if r.cronJob != nil {
+			state := workloadRolloutState{
+				exists:         true,
+				current:        int32(len(r.cronJob.Status.Active)),
+				observedLatest: true,
+				image:          getMainContainerImage(r.cronJob.Spec.JobTemplate.Spec.Template.Spec, r.component.Name),
+			}
+
+			if r.cronJob.Spec.Suspend != nil && *r.cronJob.Spec.Suspend {
+				state.stuck = true
+				state.stuckMessage = "cronjob is suspended"
+			}
+
+			return state
+		}
+	}
+
+	return workloadRolloutState{}
+}
+
+func getDeploymentRolloutState(deployment *appsV1.Deployment) workloadRolloutState {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+
+	state := workloadRolloutState{
+		exists:         true,
+		desired:        desired,
+		current:        status.Replicas,
+		ready:          status.ReadyReplicas,
+		updated:        status.UpdatedReplicas,
+		observedLatest: status.ObservedGeneration >= deployment.Generation,
+		rollingOut:     status.UpdatedReplicas < desired || status.Replicas > status.UpdatedReplicas || status.AvailableReplicas < status.UpdatedReplicas,
+		image:          getMainContainerImage(deployment.Spec.Template.Spec, deployment.Name),
+	}
+
+	for _, cond := range status.Conditions {
+		if cond.Type == appsV1.DeploymentProgressing &&
+			cond.Status == corev1.ConditionFalse &&
+			cond.Reason == "ProgressDeadlineExceeded" {
+			state.stuck = true
+			state.stuckMessage = cond.Message
+		}
+	}
+
+	return state
+}
+
+func getStatefulSetRolloutState(sts *appsV1.StatefulSet) workloadRolloutState {
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+
+	status := sts.Status
+
+	return workloadRolloutState{
+		exists:         true,
+		desired:        desired,
+		current:        status.Replicas,
+		ready:          status.ReadyReplicas,
+		updated:        status.UpdatedReplicas,
+		observedLatest: status.ObservedGeneration >= sts.Generation,
+		rollingOut: status.UpdatedReplicas < desired ||
+			status.Replicas > desired ||
+			(status.UpdateRevision != "" && status.CurrentRevision != status.UpdateRevision),
+		image: getMainContainerImage(sts.Spec.Template.Spec, sts.Name),
+	}
+}
+
+func getDaemonSetRolloutState(ds *appsV1.DaemonSet) workloadRolloutState {
+	status := ds.Status
+
+	return workloadRolloutState{
+		exists:         true,
+		desired:        status.DesiredNumberScheduled,
+		current:        status.CurrentNumberScheduled,
+		ready:          status.NumberReady,
+		updated:        status.UpdatedNumberScheduled,
+		observedLatest: status.ObservedGeneration >= ds.Generation,
+		rollingOut:     status.UpdatedNumberScheduled < status.DesiredNumberScheduled || status.NumberUnavailable > 0,
+		image:          getMainContainerImage(ds.Spec.Template.Spec, ds.Name),
+	}
+}
+
+// the main container is named after the component, see GetPodTemplateWithoutVols
+func getMainContainerImage(podSpec corev1.PodSpec, componentName string) string {
+	for _, container := range podSpec.Containers {
+		if container.Name == componentName {
+			return container.Image
+		}
+	}
+
+	if len(podSpec.Containers) > 0 {
+		return podSpec.Containers[0].Image
+	}
+
+	return ""
+}
+
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
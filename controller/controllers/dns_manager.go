@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/cloudflare/cloudflare-go"
@@ -18,6 +19,8 @@ type DNSRecord struct {
 	Content string
 }
 
+// DNSManager manages records of the dns provider.
+// Every provider should pass the conformance suite in dns_manager_conformance_test.go
 type DNSManager interface {
 	CreateDNSRecord(dnsType v1alpha1.DNSType, name, content string) error
 	DeleteDNSRecord(dnsType v1alpha1.DNSType, name string) error
@@ -26,6 +29,66 @@ type DNSManager interface {
 	GetDNSRecords(domain string) ([]DNSRecord, error)
 }
 
+// NoZoneForDomainError is returned when the domain is not managed by the DNSManager
+var NoZoneForDomainError = fmt.Errorf("no zone for domain error")
+
+// DNSManagerFactory creates a DNSManager from configuration
+type DNSManagerFactory func() (DNSManager, error)
+
+const DefaultDNSProvider = "cloudflare"
+
+var dnsManagerFactories = make(map[string]DNSManagerFactory)
+
+// RegisterDNSManagerProvider makes a dns provider available by the name, it's called in init()
+func RegisterDNSManagerProvider(provider string, factory DNSManagerFactory) {
+	if _, exist := dnsManagerFactories[provider]; exist {
+		panic("dns provider registered twice: " + provider)
+	}
+
+	dnsManagerFactories[provider] = factory
+}
+
+func GetDNSManagerProviders() []string {
+	var providers []string
+	for provider := range dnsManagerFactories {
+		providers = append(providers, provider)
+	}
+
+	sort.Strings(providers)
+
+	return providers
+}
+
+func NewDNSManager(provider string) (DNSManager, error) {
+	factory, exist := dnsManagerFactories[provider]
+	if !exist {
+		return nil, fmt.Errorf("unknown dns provider: %s, available: %s", provider, strings.Join(GetDNSManagerProviders(), ","))
+	}
+
+	return factory()
+}
+
+// initDNSManagerFromEnv creates the DNSManager of the provider set in ENV: DNS_PROVIDER
+func initDNSManagerFromEnv() (DNSManager, error) {
+	provider := v1alpha1.GetEnvDNSProvider()
+	if provider == "" {
+		provider = DefaultDNSProvider
+	}
+
+	return NewDNSManager(provider)
+}
+
+func init() {
+	RegisterDNSManagerProvider("cloudflare", func() (DNSManager, error) {
+		mgr, err := initCloudflareDNSManagerFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return mgr, nil
+	})
+}
+
 var _ DNSManager = CloudflareDNSManager{}
 
 type CloudflareDNSManager struct {
@@ -47,7 +110,7 @@ func NewCloudflareDNSManager(token string, domain2ZoneIDMap map[string]string) (
 	}, nil
 }
 
-var NoCloudflareZoneIDForDomainError = fmt.Errorf("no cloudflare zone id for domain error")
+var NoCloudflareZoneIDForDomainError = NoZoneForDomainError
 
 func (m CloudflareDNSManager) CreateDNSRecord(dnsType v1alpha1.DNSType, name, content string) error {
 	rootDomain := getRootDomain(name)
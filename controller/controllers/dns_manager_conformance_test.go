@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDNSManagerConformance is the behavior every DNSManager provider must have.
+// zone is a domain managed by the provider, records are created under it.
+func testDNSManagerConformance(t *testing.T, mgr DNSManager, zone string) {
+	name := "kalm-conformance." + zone
+	challengeName := "_acme-challenge.kalm-conformance." + zone
+	aliasName := "kalm-conformance-alias." + zone
+
+	hasRecord := func(records []DNSRecord, dnsType v1alpha1.DNSType, name, content string) bool {
+		for _, r := range records {
+			if r.DNSType == dnsType && r.Name == name && r.Content == content {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// clean up records left by former runs
+	for _, r := range []struct {
+		dnsType v1alpha1.DNSType
+		name    string
+	}{
+		{v1alpha1.DNSTypeA, name},
+		{v1alpha1.DNSTypeTXT, challengeName},
+		{v1alpha1.DNSTypeCNAME, aliasName},
+	} {
+		assert.Nil(t, mgr.DeleteDNSRecord(r.dnsType, r.name))
+	}
+
+	// create
+	assert.Nil(t, mgr.CreateDNSRecord(v1alpha1.DNSTypeA, name, "10.0.0.1"))
+
+	exist, err := mgr.Exist(v1alpha1.DNSTypeA, name, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeA, name, "10.0.0.2")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	records, err := mgr.GetDNSRecords(name)
+	assert.Nil(t, err)
+	assert.True(t, hasRecord(records, v1alpha1.DNSTypeA, name, "10.0.0.1"))
+
+	// upsert replaces the record
+	assert.Nil(t, mgr.UpsertDNSRecord(v1alpha1.DNSTypeA, name, "10.0.0.2"))
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeA, name, "10.0.0.2")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeA, name, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	// upsert is idempotent
+	assert.Nil(t, mgr.UpsertDNSRecord(v1alpha1.DNSTypeA, name, "10.0.0.2"))
+
+	// TXT records are used by dns-01 challenges
+	assert.Nil(t, mgr.UpsertDNSRecord(v1alpha1.DNSTypeTXT, challengeName, "challenge-token"))
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeTXT, challengeName, "challenge-token")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	assert.Nil(t, mgr.UpsertDNSRecord(v1alpha1.DNSTypeCNAME, aliasName, name))
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeCNAME, aliasName, name)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	// delete
+	assert.Nil(t, mgr.DeleteDNSRecord(v1alpha1.DNSTypeA, name))
+	assert.Nil(t, mgr.DeleteDNSRecord(v1alpha1.DNSTypeTXT, challengeName))
+	assert.Nil(t, mgr.DeleteDNSRecord(v1alpha1.DNSTypeCNAME, aliasName))
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeA, name, "10.0.0.2")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	exist, err = mgr.Exist(v1alpha1.DNSTypeTXT, challengeName, "challenge-token")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	// delete not exist record
+	assert.Nil(t, mgr.DeleteDNSRecord(v1alpha1.DNSTypeA, name))
+
+	// domains not managed by the provider
+	unknownName := "kalm-conformance.not-managed-zone.invalid"
+	assert.Equal(t, NoZoneForDomainError, mgr.CreateDNSRecord(v1alpha1.DNSTypeA, unknownName, "10.0.0.1"))
+	assert.Equal(t, NoZoneForDomainError, mgr.UpsertDNSRecord(v1alpha1.DNSTypeA, unknownName, "10.0.0.1"))
+
+	_, err = mgr.GetDNSRecords(unknownName)
+	assert.Equal(t, NoZoneForDomainError, err)
+}
+
+func TestRFC2136DNSManagerConformance(t *testing.T) {
+	server := startTestDNSServer(t, "kalm.test", nil)
+
+	mgr := NewRFC2136DNSManager(server.Addr, []string{"kalm.test"})
+	testDNSManagerConformance(t, mgr, "kalm.test")
+}
+
+func TestRFC2136DNSManagerWithTSIGConformance(t *testing.T) {
+	secret := "c2VjcmV0LXRzaWcta2V5LWZvci10ZXN0cw=="
+	server := startTestDNSServer(t, "kalm.test", map[string]string{"kalm-key.": secret})
+
+	mgr := NewRFC2136DNSManager(server.Addr, []string{"kalm.test"})
+	mgr.TSIGKeyName = "kalm-key"
+	mgr.TSIGSecret = secret
+	testDNSManagerConformance(t, mgr, "kalm.test")
+
+	// updates signed with wrong secret are refused
+	mgr.TSIGSecret = "d3Jvbmctc2VjcmV0"
+	assert.NotNil(t, mgr.CreateDNSRecord(v1alpha1.DNSTypeA, "wrong-key.kalm.test", "10.0.0.1"))
+}
+
+// Run with CLOUDFLARE_TOKEN, CLOUDFLARE_DOMAIN_TO_ZONEID_CONFIG and KALM_TEST_CLOUDFLARE_ZONE set
+func TestCloudflareDNSManagerConformance(t *testing.T) {
+	zone := os.Getenv("KALM_TEST_CLOUDFLARE_ZONE")
+	if zone == "" {
+		t.Skip("KALM_TEST_CLOUDFLARE_ZONE not set")
+	}
+
+	mgr, err := NewDNSManager("cloudflare")
+	assert.Nil(t, err)
+
+	testDNSManagerConformance(t, mgr, zone)
+}
+
+func TestDNSManagerProviders(t *testing.T) {
+	assert.Equal(t, []string{"cloudflare", "rfc2136"}, GetDNSManagerProviders())
+
+	_, err := NewDNSManager("unknown")
+	assert.NotNil(t, err)
+
+	_ = os.Setenv(v1alpha1.ENV_DNS_PROVIDER, "rfc2136")
+	_ = os.Setenv(v1alpha1.ENV_RFC2136_NAMESERVER, "127.0.0.1")
+	_ = os.Setenv(v1alpha1.ENV_RFC2136_ZONES, "example.com, kalm.test")
+	_ = os.Setenv(v1alpha1.ENV_RFC2136_TSIG_ALGORITHM, dns.HmacSHA512)
+	defer func() {
+		_ = os.Unsetenv(v1alpha1.ENV_DNS_PROVIDER)
+		_ = os.Unsetenv(v1alpha1.ENV_RFC2136_NAMESERVER)
+		_ = os.Unsetenv(v1alpha1.ENV_RFC2136_ZONES)
+		_ = os.Unsetenv(v1alpha1.ENV_RFC2136_TSIG_ALGORITHM)
+	}()
+
+	mgr, err := initDNSManagerFromEnv()
+	assert.Nil(t, err)
+
+	rfc2136Mgr, ok := mgr.(*RFC2136DNSManager)
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1:53", rfc2136Mgr.Nameserver)
+	assert.Equal(t, []string{"example.com", "kalm.test"}, rfc2136Mgr.Zones)
+	assert.Equal(t, dns.HmacSHA512, rfc2136Mgr.TSIGAlgorithm)
+
+	zone, err := rfc2136Mgr.findZone("foo.bar.kalm.test")
+	assert.Nil(t, err)
+	assert.Equal(t, "kalm.test.", zone)
+}
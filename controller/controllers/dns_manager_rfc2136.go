@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/miekg/dns"
+)
+
+const DefaultRFC2136TTL = 300
+
+var _ DNSManager = &RFC2136DNSManager{}
+
+// RFC2136DNSManager manages records with dynamic updates (RFC 2136), which is supported by BIND, PowerDNS and others.
+type RFC2136DNSManager struct {
+	// address of the dns server, host:port
+	Nameserver string
+
+	// zones allowed to update, e.g. example.com
+	Zones []string
+
+	// optional TSIG key to sign the updates
+	TSIGKeyName   string
+	TSIGSecret    string
+	TSIGAlgorithm string
+
+	TTL uint32
+
+	// udp or tcp, default to udp
+	Net string
+}
+
+func NewRFC2136DNSManager(nameserver string, zones []string) *RFC2136DNSManager {
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(nameserver, "53")
+	}
+
+	return &RFC2136DNSManager{
+		Nameserver:    nameserver,
+		Zones:         zones,
+		TSIGAlgorithm: dns.HmacSHA256,
+		TTL:           DefaultRFC2136TTL,
+	}
+}
+
+func (m *RFC2136DNSManager) CreateDNSRecord(dnsType v1alpha1.DNSType, name, content string) error {
+	zone, err := m.findZone(name)
+	if err != nil {
+		return err
+	}
+
+	rr, err := m.newRR(dnsType, name, content)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	msg.Insert([]dns.RR{rr})
+
+	return m.update(msg)
+}
+
+func (m *RFC2136DNSManager) DeleteDNSRecord(dnsType v1alpha1.DNSType, name string) error {
+	zone, err := m.findZone(name)
+	if err != nil {
+		return err
+	}
+
+	rrType, err := getRRType(dnsType)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	msg.RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: rrType, Class: dns.ClassINET}}})
+
+	// for not exist record, the update succeeds too
+	return m.update(msg)
+}
+
+func (m *RFC2136DNSManager) UpsertDNSRecord(dnsType v1alpha1.DNSType, name, content string) error {
+	// skip if DNSRecord already exist
+	if exist, _ := m.Exist(dnsType, name, content); exist {
+		return nil
+	}
+
+	zone, err := m.findZone(name)
+	if err != nil {
+		return err
+	}
+
+	rr, err := m.newRR(dnsType, name, content)
+	if err != nil {
+		return err
+	}
+
+	// replace the rrset in one update
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	msg.RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: rr.Header().Name, Rrtype: rr.Header().Rrtype, Class: dns.ClassINET}}})
+	msg.Insert([]dns.RR{rr})
+
+	return m.update(msg)
+}
+
+func (m *RFC2136DNSManager) Exist(dnsType v1alpha1.DNSType, name, content string) (bool, error) {
+	records, err := m.GetDNSRecords(name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range records {
+		if r.DNSType == dnsType && r.Name == name && r.Content == content {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetDNSRecords returns the records of the domain, zone transfer is not used as it's usually not allowed.
+func (m *RFC2136DNSManager) GetDNSRecords(domain string) ([]DNSRecord, error) {
+	if _, err := m.findZone(domain); err != nil {
+		return nil, err
+	}
+
+	var rst []DNSRecord
+	seen := make(map[string]bool)
+
+	for _, dnsType := range []v1alpha1.DNSType{v1alpha1.DNSTypeA, v1alpha1.DNSTypeCNAME, v1alpha1.DNSTypeNS, v1alpha1.DNSTypeTXT} {
+		rrType, _ := getRRType(dnsType)
+
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(domain), rrType)
+		msg.RecursionDesired = false
+
+		resp, err := m.exchange(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+			return nil, fmt.Errorf("query %s %s failed, rcode: %s", dnsType, domain, dns.RcodeToString[resp.Rcode])
+		}
+
+		for _, rr := range resp.Answer {
+			record, ok := rrToDNSRecord(rr)
+			if !ok {
+				continue
+			}
+
+			key := fmt.Sprintf("%s/%s/%s", record.DNSType, record.Name, record.Content)
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			rst = append(rst, record)
+		}
+	}
+
+	return rst, nil
+}
+
+// findZone returns the longest managed zone of the name
+func (m *RFC2136DNSManager) findZone(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	var zone string
+	for _, z := range m.Zones {
+		z = strings.TrimSuffix(strings.ToLower(z), ".")
+
+		if (name == z || strings.HasSuffix(name, "."+z)) && len(z) > len(zone) {
+			zone = z
+		}
+	}
+
+	if zone == "" {
+		return "", NoZoneForDomainError
+	}
+
+	return dns.Fqdn(zone), nil
+}
+
+func (m *RFC2136DNSManager) newRR(dnsType v1alpha1.DNSType, name, content string) (dns.RR, error) {
+	hdr := dns.RR_Header{Name: dns.Fqdn(name), Class: dns.ClassINET, Ttl: m.TTL}
+
+	switch dnsType {
+	case v1alpha1.DNSTypeA:
+		ip := net.ParseIP(content).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ipv4 address: %s", content)
+		}
+
+		hdr.Rrtype = dns.TypeA
+		return &dns.A{Hdr: hdr, A: ip}, nil
+	case v1alpha1.DNSTypeCNAME:
+		hdr.Rrtype = dns.TypeCNAME
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(content)}, nil
+	case v1alpha1.DNSTypeNS:
+		hdr.Rrtype = dns.TypeNS
+		return &dns.NS{Hdr: hdr, Ns: dns.Fqdn(content)}, nil
+	case v1alpha1.DNSTypeTXT:
+		hdr.Rrtype = dns.TypeTXT
+		return &dns.TXT{Hdr: hdr, Txt: []string{content}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported dns type: %s", dnsType)
+}
+
+func getRRType(dnsType v1alpha1.DNSType) (uint16, error) {
+	switch dnsType {
+	case v1alpha1.DNSTypeA:
+		return dns.TypeA, nil
+	case v1alpha1.DNSTypeCNAME:
+		return dns.TypeCNAME, nil
+	case v1alpha1.DNSTypeNS:
+		return dns.TypeNS, nil
+	case v1alpha1.DNSTypeTXT:
+		return dns.TypeTXT, nil
+	}
+
+	return 0, fmt.Errorf("unsupported dns type: %s", dnsType)
+}
+
+func rrToDNSRecord(rr dns.RR) (DNSRecord, bool) {
+	record := DNSRecord{
+		Name: strings.TrimSuffix(rr.Header().Name, "."),
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		record.DNSType = v1alpha1.DNSTypeA
+		record.Content = v.A.String()
+	case *dns.CNAME:
+		record.DNSType = v1alpha1.DNSTypeCNAME
+		record.Content = strings.TrimSuffix(v.Target, ".")
+	case *dns.NS:
+		record.DNSType = v1alpha1.DNSTypeNS
+		record.Content = strings.TrimSuffix(v.Ns, ".")
+	case *dns.TXT:
+		record.DNSType = v1alpha1.DNSTypeTXT
+		record.Content = strings.Join(v.Txt, "")
+	default:
+		return record, false
+	}
+
+	return record, true
+}
+
+func (m *RFC2136DNSManager) update(msg *dns.Msg) error {
+	resp, err := m.exchange(msg)
+	if err != nil {
+		return err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("dns update failed, rcode: %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	return nil
+}
+
+func (m *RFC2136DNSManager) exchange(msg *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{
+		Net:     m.Net,
+		Timeout: 10 * time.Second,
+	}
+
+	if m.TSIGKeyName != "" && m.TSIGSecret != "" {
+		keyName := dns.Fqdn(m.TSIGKeyName)
+		c.TsigSecret = map[string]string{keyName: m.TSIGSecret}
+		msg.SetTsig(keyName, dns.Fqdn(m.TSIGAlgorithm), 300, time.Now().Unix())
+	}
+
+	resp, _, err := c.Exchange(msg, m.Nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func initRFC2136DNSManagerFromEnv() (*RFC2136DNSManager, error) {
+	nameserver := v1alpha1.GetEnvRFC2136Nameserver()
+	if nameserver == "" {
+		return nil, fmt.Errorf("ENV: %s not exist", v1alpha1.ENV_RFC2136_NAMESERVER)
+	}
+
+	// zone1,zone2
+	var zones []string
+	for _, zone := range strings.Split(v1alpha1.GetEnvRFC2136Zones(), ",") {
+		if zone = strings.TrimSpace(zone); zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("ENV: %s not exist", v1alpha1.ENV_RFC2136_ZONES)
+	}
+
+	mgr := NewRFC2136DNSManager(nameserver, zones)
+	mgr.TSIGKeyName = v1alpha1.GetEnvRFC2136TSIGKeyName()
+	mgr.TSIGSecret = v1alpha1.GetEnvRFC2136TSIGSecret()
+
+	if algorithm := v1alpha1.GetEnvRFC2136TSIGAlgorithm(); algorithm != "" {
+		mgr.TSIGAlgorithm = algorithm
+	}
+
+	if ttl := v1alpha1.GetEnvRFC2136TTL(); ttl != "" {
+		v, err := strconv.ParseUint(ttl, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("ENV: %s is not valid, %s", v1alpha1.ENV_RFC2136_TTL, err)
+		}
+
+		mgr.TTL = uint32(v)
+	}
+
+	return mgr, nil
+}
+
+func init() {
+	RegisterDNSManagerProvider("rfc2136", func() (DNSManager, error) {
+		mgr, err := initRFC2136DNSManagerFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return mgr, nil
+	})
+}
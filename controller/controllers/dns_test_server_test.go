@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// testDNSServer is a local authoritative dns server accepting dynamic updates (RFC 2136).
+// It's used to test dns providers and dns-01 challenges without a real dns service.
+type testDNSServer struct {
+	Addr string
+
+	mu      sync.Mutex
+	zone    string
+	records []dns.RR
+	server  *dns.Server
+}
+
+// startTestDNSServer starts a server on a random udp port of localhost, tsigSecrets is optional.
+func startTestDNSServer(t *testing.T, zone string, tsigSecrets map[string]string) *testDNSServer {
+	return startTestDNSServerOn(t, "127.0.0.1:0", zone, tsigSecrets)
+}
+
+// startTestDNSServerOn starts a server on the udp address, for tests where other services query it.
+func startTestDNSServerOn(t *testing.T, addr, zone string, tsigSecrets map[string]string) *testDNSServer {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testDNSServer{
+		Addr: conn.LocalAddr().String(),
+		zone: dns.Fqdn(zone),
+	}
+
+	started := make(chan struct{})
+	s.server = &dns.Server{
+		PacketConn:        conn,
+		Handler:           s,
+		TsigSecret:        tsigSecrets,
+		NotifyStartedFunc: func() { close(started) },
+		// the default one rejects updates
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+	}
+
+	go func() {
+		_ = s.server.ActivateAndServe()
+	}()
+
+	<-started
+
+	t.Cleanup(func() {
+		_ = s.server.Shutdown()
+	})
+
+	return s
+}
+
+func (s *testDNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(r)
+	resp.Authoritative = true
+
+	if r.IsTsig() != nil && w.TsigStatus() != nil {
+		resp.SetRcode(r, dns.RcodeNotAuth)
+		_ = w.WriteMsg(resp)
+		return
+	}
+
+	if r.Opcode == dns.OpcodeUpdate {
+		resp.SetRcode(r, s.update(r))
+	} else if len(r.Question) > 0 {
+		resp.Answer = s.query(r.Question[0])
+	}
+
+	if tsig := r.IsTsig(); tsig != nil {
+		resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, int64(tsig.TimeSigned))
+	}
+
+	_ = w.WriteMsg(resp)
+}
+
+func (s *testDNSServer) update(r *dns.Msg) int {
+	if len(r.Question) != 1 || !strings.EqualFold(r.Question[0].Name, s.zone) {
+		return dns.RcodeNotZone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rr := range r.Ns {
+		hdr := rr.Header()
+
+		if !dns.IsSubDomain(s.zone, hdr.Name) {
+			return dns.RcodeNotZone
+		}
+
+		switch hdr.Class {
+		case dns.ClassANY:
+			// delete the rrset
+			s.remove(func(record dns.RR) bool {
+				return strings.EqualFold(record.Header().Name, hdr.Name) &&
+					(hdr.Rrtype == dns.TypeANY || record.Header().Rrtype == hdr.Rrtype)
+			})
+		case dns.ClassNONE:
+			// delete the rr
+			s.remove(func(record dns.RR) bool {
+				copied := dns.Copy(rr)
+				copied.Header().Class = dns.ClassINET
+				copied.Header().Ttl = record.Header().Ttl
+				return dns.IsDuplicate(record, copied)
+			})
+		default:
+			s.remove(func(record dns.RR) bool { return dns.IsDuplicate(record, rr) })
+			s.records = append(s.records, dns.Copy(rr))
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+func (s *testDNSServer) remove(match func(rr dns.RR) bool) {
+	var records []dns.RR
+
+	for _, record := range s.records {
+		if !match(record) {
+			records = append(records, record)
+		}
+	}
+
+	s.records = records
+}
+
+func (s *testDNSServer) query(q dns.Question) []dns.RR {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var answer []dns.RR
+
+	for _, record := range s.records {
+		hdr := record.Header()
+
+		if !strings.EqualFold(hdr.Name, q.Name) {
+			continue
+		}
+
+		if hdr.Rrtype == q.Qtype || q.Qtype == dns.TypeANY || hdr.Rrtype == dns.TypeCNAME {
+			answer = append(answer, dns.Copy(record))
+		}
+	}
+
+	return answer
+}
@@ -34,11 +34,9 @@ type DNSRecordReconciler struct {
 }
 
 func NewDNSRecordReconciler(mgr ctrl.Manager) *DNSRecordReconciler {
-	var dnsMgr DNSManager
-	if cloudflareDNSMgr, err := initCloudflareDNSManagerFromEnv(); err != nil {
-		ctrl.Log.Info("failed when initCloudflareDNSManagerFromEnv", "err", err)
-	} else {
-		dnsMgr = cloudflareDNSMgr
+	dnsMgr, err := initDNSManagerFromEnv()
+	if err != nil {
+		ctrl.Log.Info("failed when initDNSManagerFromEnv", "err", err)
 	}
 
 	return &DNSRecordReconciler{
@@ -71,7 +69,7 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	err := r.dnsMgr.UpsertDNSRecord(record.Spec.DNSType, record.Spec.Domain, record.Spec.DNSTarget)
 	if err != nil {
-		if err == NoCloudflareZoneIDForDomainError {
+		if err == NoZoneForDomainError {
 			log.Error(err, "unknown domain for this dnsManager, ignored", "domain", record.Spec.Domain)
 
 			return ctrl.Result{}, nil
@@ -95,5 +93,5 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.DNSRecord{}).
-		Complete(r)
+		Complete(instrumentReconciler("DNSRecordReconciler", r))
 }
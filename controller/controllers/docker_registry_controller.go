@@ -61,6 +61,10 @@ func (r *DockerRegistryReconcileTask) NormalEvent(reason, msg string, args ...in
 
 func (r *DockerRegistryReconcileTask) Run(req ctrl.Request) error {
 	if err := r.LoadRegistry(req); err != nil {
+		if errors.IsNotFound(err) {
+			dockerRegistryAuthenticationVerified.DeleteLabelValues(req.Name)
+		}
+
 		return client.IgnoreNotFound(err)
 	}
 
@@ -110,6 +114,8 @@ func (r *DockerRegistryReconcileTask) UpdateStatus() (err error) {
 			r.WarningEvent(err, "Patch docker registry status error.")
 			return err
 		}
+
+		setDockerRegistryMetric(registryCopy)
 		message := ""
 
 		if r.secret == nil {
@@ -132,6 +138,8 @@ func (r *DockerRegistryReconcileTask) UpdateStatus() (err error) {
 			r.WarningEvent(err, "Patch docker registry status error.")
 			return err
 		}
+
+		setDockerRegistryMetric(registryCopy)
 	}
 
 	r.Recorder.Eventf(r.registry, v1.EventTypeNormal, "AuthSucceed", "Authenticate docker registry successfully.")
@@ -391,5 +399,5 @@ func (r *DockerRegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			},
 		).
 		Owns(&v1.Secret{}).
-		Complete(r)
+		Complete(instrumentReconciler("DockerRegistryReconciler", r))
 }
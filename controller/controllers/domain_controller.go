@@ -31,11 +31,9 @@ type DomainReconciler struct {
 }
 
 func NewDomainReconciler(mgr ctrl.Manager) *DomainReconciler {
-	// var dnsMgr DNSManager
-	// if cloudflareDNSMgr, err := initCloudflareDNSManagerFromEnv(); err != nil {
-	// 	ctrl.Log.Info("failed when initCloudflareDNSManagerFromEnv", "err", err)
-	// } else {
-	// 	dnsMgr = cloudflareDNSMgr
+	// dnsMgr, err := initDNSManagerFromEnv()
+	// if err != nil {
+	// 	ctrl.Log.Info("failed when initDNSManagerFromEnv", "err", err)
 	// }
 
 	return &DomainReconciler{
@@ -56,5 +54,5 @@ func (r *DomainReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 func (r *DomainReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.Domain{}).
-		Complete(r)
+		Complete(instrumentReconciler("DomainReconciler", r))
 }
@@ -102,8 +102,8 @@ func (r *GatewayReconcilerTask) HttpsGateway() error {
 	gw.Spec.Servers = []*istioNetworkingV1Beta1.Server{}
 
 	for _, cert := range certs.Items {
-		// skip not ready cert
-		if !v1alpha1.IsHttpsCertReady(cert) {
+		// skip not ready cert, expiring ones are still served until they expire
+		if !v1alpha1.IsHttpsCertUsable(cert) {
 			continue
 		}
 
@@ -252,5 +252,5 @@ func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				ToRequests: &KalmGatewayRequestMapper{r.BaseReconciler},
 			},
 		).
-		Complete(r)
+		Complete(instrumentReconciler("GatewayReconciler", r))
 }
@@ -0,0 +1,263 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// a sync includes fetching the repo and applying all objects
+const gitSyncTimeout = 5 * time.Minute
+
+// GitSyncReconciler reconciles a GitSync object
+type GitSyncReconciler struct {
+	*BaseReconciler
+	ctx context.Context
+}
+
+func NewGitSyncReconciler(mgr ctrl.Manager) *GitSyncReconciler {
+	return &GitSyncReconciler{
+		BaseReconciler: NewBaseReconciler(mgr, "GitSync"),
+		ctx:            context.Background(),
+	}
+}
+
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=gitsyncs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=gitsyncs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=components;componentpluginbindings;httproutes;domains,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kalm.dev,resources=httpscerts;protectedendpoints;dockerregistries;alertrules,verbs=get;list;watch;create;update;patch;delete
+
+func (r *GitSyncReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("gitsync", req.Name)
+
+	var gitSync v1alpha1.GitSync
+	if err := r.Get(r.ctx, client.ObjectKey{Name: req.Name}, &gitSync); err != nil {
+		if errors.IsNotFound(err) {
+			_ = os.RemoveAll(gitSyncRepoDir(req.Name))
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	if gitSync.Spec.Suspend || gitSync.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	copied := gitSync.DeepCopy()
+
+	if err := r.sync(copied); err != nil {
+		log.Error(err, "fail to sync")
+		r.EmitWarningEvent(&gitSync, err, "fail to sync: %s", err.Error())
+
+		copied.Status.Ready = false
+		copied.Status.Message = err.Error()
+	}
+
+	if err := r.Status().Update(r.ctx, copied); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: gitSync.GetInterval()}, nil
+}
+
+func gitSyncRepoDir(name string) string {
+	return filepath.Join(gitSyncWorkDir, name)
+}
+
+func gitSyncFieldOwner(name string) string {
+	return "kalm-gitsync-" + name
+}
+
+type gitSyncObject struct {
+	desired *unstructured.Unstructured
+	live    *unstructured.Unstructured
+}
+
+// sync applies objects in the repo, and updates the status of the gitSync
+func (r *GitSyncReconciler) sync(gitSync *v1alpha1.GitSync) error {
+	ctx, cancel := context.WithTimeout(r.ctx, gitSyncTimeout)
+	defer cancel()
+
+	repoDir := gitSyncRepoDir(gitSync.Name)
+	commit, err := checkoutGitRepo(ctx, repoDir, gitSync.Spec.Repo, gitSync.Spec.Ref)
+
+	if err != nil {
+		return err
+	}
+
+	manifestsDir := filepath.Join(repoDir, filepath.FromSlash(gitSync.Spec.Path))
+
+	if manifestsDir != repoDir && !strings.HasPrefix(manifestsDir, repoDir+string(filepath.Separator)) {
+		return fmt.Errorf("path %s is out of the repo", gitSync.Spec.Path)
+	}
+
+	desiredObjs, err := renderGitSyncManifests(manifestsDir)
+
+	if err != nil {
+		return err
+	}
+
+	// check all objects before applying any of them
+	objs := make([]gitSyncObject, 0, len(desiredObjs))
+
+	for _, desired := range desiredObjs {
+		live, err := r.getLiveObject(ctx, gitSyncResourceOf(desired))
+
+		if err != nil {
+			return err
+		}
+
+		if live != nil {
+			if owner := live.GetLabels()[v1alpha1.GitSyncLabelName]; owner != "" && owner != gitSync.Name {
+				return fmt.Errorf("%s %s is synced by gitsync %s", desired.GetKind(), desired.GetName(), owner)
+			}
+		}
+
+		objs = append(objs, gitSyncObject{desired: desired, live: live})
+	}
+
+	previousResources := gitSync.Status.Resources
+	previous := make(map[v1alpha1.GitSyncResource]bool, len(previousResources))
+
+	for _, resource := range previousResources {
+		previous[resource] = true
+	}
+
+	// objects can only be told drifted if the commit is not changed, otherwise they are changed by the commits
+	sameCommit := gitSync.Status.LastSyncedCommit == commit
+
+	var resources, drifted []v1alpha1.GitSyncResource
+	current := make(map[v1alpha1.GitSyncResource]bool, len(objs))
+
+	for _, obj := range objs {
+		resource := gitSyncResourceOf(obj.desired)
+
+		if sameCommit && previous[resource] && (obj.live == nil || isGitSyncDrifted(obj.desired, obj.live)) {
+			drifted = append(drifted, resource)
+		}
+
+		labels := obj.desired.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		labels[v1alpha1.GitSyncLabelName] = gitSync.Name
+		obj.desired.SetLabels(labels)
+
+		if err := r.Patch(ctx, obj.desired, client.Apply, client.FieldOwner(gitSyncFieldOwner(gitSync.Name)), client.ForceOwnership); err != nil {
+			return fmt.Errorf("fail to apply %s %s: %s", resource.Kind, resource.Name, err)
+		}
+
+		resources = append(resources, resource)
+		current[resource] = true
+
+		// recorded at once, so that objects created by a sync failed later can still be pruned
+		if !previous[resource] {
+			gitSync.Status.Resources = append(gitSync.Status.Resources, resource)
+		}
+	}
+
+	for _, resource := range previousResources {
+		if current[resource] || !gitSync.Spec.Prune {
+			continue
+		}
+
+		if err := r.prune(ctx, gitSync, resource); err != nil {
+			return err
+		}
+	}
+
+	sortGitSyncResources(resources)
+	sortGitSyncResources(drifted)
+
+	if len(drifted) > 0 {
+		r.Recorder.Eventf(gitSync, corev1.EventTypeWarning, "Drifted", "%d objects are changed outside of the repo, applied again", len(drifted))
+	}
+
+	if gitSync.Status.LastSyncedCommit != commit {
+		r.EmitNormalEvent(gitSync, "Synced", "synced commit %s", commit)
+	}
+
+	gitSync.Status.Ready = true
+	gitSync.Status.Message = ""
+	gitSync.Status.LastSyncedCommit = commit
+	gitSync.Status.LastSyncTimestamp = time.Now().Unix()
+	gitSync.Status.Resources = resources
+	gitSync.Status.Drifted = drifted
+
+	return nil
+}
+
+// getLiveObject returns nil if the object doesn't exist
+func (r *GitSyncReconciler) getLiveObject(ctx context.Context, resource v1alpha1.GitSyncResource) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind))
+
+	// read from the api server directly, to avoid informers for all kinds
+	if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: resource.Namespace, Name: resource.Name}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// prune deletes the object removed from the repo, if it is still synced by the gitSync
+func (r *GitSyncReconciler) prune(ctx context.Context, gitSync *v1alpha1.GitSync, resource v1alpha1.GitSyncResource) error {
+	live, err := r.getLiveObject(ctx, resource)
+
+	if err != nil || live == nil {
+		return err
+	}
+
+	if live.GetLabels()[v1alpha1.GitSyncLabelName] != gitSync.Name {
+		return nil
+	}
+
+	if err := r.Delete(ctx, live); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("fail to prune %s %s: %s", resource.Kind, resource.Name, err)
+	}
+
+	r.EmitNormalEvent(gitSync, "Pruned", "%s %s is removed from the repo, deleted", resource.Kind, resource.Name)
+
+	return nil
+}
+
+func (r *GitSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// status is updated in each sync, only spec changes trigger an extra sync
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.GitSync{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(instrumentReconciler("GitSyncReconciler", r))
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type GitSyncControllerSuite struct {
+	BasicSuite
+	ctx context.Context
+}
+
+func TestGitSyncControllerSuite(t *testing.T) {
+	suite.Run(t, new(GitSyncControllerSuite))
+}
+
+func (suite *GitSyncControllerSuite) SetupSuite() {
+	suite.BasicSuite.SetupSuite(true)
+	suite.ctx = context.Background()
+}
+
+func (suite *GitSyncControllerSuite) TearDownSuite() {
+	suite.BasicSuite.TearDownSuite()
+}
+
+func (suite *GitSyncControllerSuite) getGitSync(name string) v1alpha1.GitSync {
+	var gitSync v1alpha1.GitSync
+	suite.Nil(suite.K8sClient.Get(suite.ctx, client.ObjectKey{Name: name}, &gitSync))
+	return gitSync
+}
+
+// spec changes trigger a sync immediately
+func (suite *GitSyncControllerSuite) resync(name string) {
+	gitSync := suite.getGitSync(name)
+	gitSync.Spec.IntervalSeconds++
+	suite.Nil(suite.K8sClient.Update(suite.ctx, &gitSync))
+}
+
+func (suite *GitSyncControllerSuite) TestSyncAndPrune() {
+	repo := newTestGitRepo(suite.T())
+	commit := repo.commit(map[string]*string{"domains.yaml": strPtr(testGitSyncDomains)})
+
+	gitSync := v1alpha1.GitSync{
+		ObjectMeta: metaV1.ObjectMeta{Name: "domains"},
+		Spec: v1alpha1.GitSyncSpec{
+			Repo:            "file://" + repo.bare,
+			IntervalSeconds: 600,
+			Prune:           true,
+		},
+	}
+
+	suite.createObject(&gitSync)
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("domains")
+		return gitSync.Status.Ready && gitSync.Status.LastSyncedCommit == commit
+	})
+
+	var domain v1alpha1.Domain
+	suite.Nil(suite.K8sClient.Get(suite.ctx, client.ObjectKey{Name: "example-www"}, &domain))
+	suite.Equal("www.example.com", domain.Spec.Domain)
+	suite.Equal("domains", domain.Labels[v1alpha1.GitSyncLabelName])
+	suite.Len(suite.getGitSync("domains").Status.Resources, 2)
+
+	// changes outside of the repo are reported and reverted
+	domain.Spec.Domain = "changed.example.com"
+	suite.updateObject(&domain)
+	suite.resync("domains")
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("domains")
+		return len(gitSync.Status.Drifted) == 1 && gitSync.Status.Drifted[0].Name == "example-www"
+	})
+
+	suite.Nil(suite.K8sClient.Get(suite.ctx, client.ObjectKey{Name: "example-www"}, &domain))
+	suite.Equal("www.example.com", domain.Spec.Domain)
+
+	// removed from the repo
+	commit = repo.commit(map[string]*string{"domains.yaml": strPtr(`
+apiVersion: core.kalm.dev/v1alpha1
+kind: Domain
+metadata:
+  name: example
+spec:
+  domain: example.com
+`)})
+	suite.resync("domains")
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("domains")
+		return gitSync.Status.LastSyncedCommit == commit && len(gitSync.Status.Resources) == 1
+	})
+
+	suite.Eventually(func() bool {
+		err := suite.K8sClient.Get(suite.ctx, client.ObjectKey{Name: "example-www"}, &domain)
+		return errors.IsNotFound(err)
+	})
+}
+
+func (suite *GitSyncControllerSuite) TestSyncFailed() {
+	repo := newTestGitRepo(suite.T())
+	repo.commit(map[string]*string{"secret.yaml": strPtr("apiVersion: v1\nkind: Secret\nmetadata:\n  name: foo\n")})
+
+	gitSync := v1alpha1.GitSync{
+		ObjectMeta: metaV1.ObjectMeta{Name: "not-kalm"},
+		Spec: v1alpha1.GitSyncSpec{
+			Repo: "file://" + repo.bare,
+		},
+	}
+
+	suite.createObject(&gitSync)
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("not-kalm")
+		return !gitSync.Status.Ready && gitSync.Status.Message != ""
+	})
+}
+
+// objects applied before the failure are recorded, and can be pruned later
+func (suite *GitSyncControllerSuite) TestPartialSyncRecorded() {
+	repo := newTestGitRepo(suite.T())
+	repo.commit(map[string]*string{
+		"a-domain.yaml":    strPtr("apiVersion: core.kalm.dev/v1alpha1\nkind: Domain\nmetadata:\n  name: partial\nspec:\n  domain: partial.example.com\n"),
+		"b-component.yaml": strPtr("apiVersion: core.kalm.dev/v1alpha1\nkind: Component\nmetadata:\n  name: web\n  namespace: not-exist\nspec:\n  image: nginx\n"),
+	})
+
+	gitSync := v1alpha1.GitSync{
+		ObjectMeta: metaV1.ObjectMeta{Name: "partial"},
+		Spec: v1alpha1.GitSyncSpec{
+			Repo:            "file://" + repo.bare,
+			IntervalSeconds: 600,
+			Prune:           true,
+		},
+	}
+
+	suite.createObject(&gitSync)
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("partial")
+		return !gitSync.Status.Ready && len(gitSync.Status.Resources) == 1 && gitSync.Status.Resources[0].Name == "partial"
+	})
+
+	commit := repo.commit(map[string]*string{"a-domain.yaml": nil, "b-component.yaml": nil})
+	suite.resync("partial")
+
+	suite.Eventually(func() bool {
+		gitSync := suite.getGitSync("partial")
+		return gitSync.Status.Ready && gitSync.Status.LastSyncedCommit == commit && len(gitSync.Status.Resources) == 0
+	})
+
+	suite.Eventually(func() bool {
+		var domain v1alpha1.Domain
+		err := suite.K8sClient.Get(suite.ctx, client.ObjectKey{Name: "partial"}, &domain)
+		return errors.IsNotFound(err)
+	})
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// gitSyncWorkDir is where repos of GitSyncs are checked out, one dir for each GitSync
+var gitSyncWorkDir = filepath.Join(os.TempDir(), "kalm-gitsync")
+
+// gitSyncKinds are kinds of kalm resources which can be synced from repos.
+// Keep them in sync with the rbac markers of the GitSyncReconciler.
+var gitSyncKinds = map[string]bool{
+	"Component":              true,
+	"ComponentPluginBinding": true,
+	"HttpRoute":              true,
+	"Domain":                 true,
+	"HttpsCert":              true,
+	"ProtectedEndpoint":      true,
+	"DockerRegistry":         true,
+	"AlertRule":              true,
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %s, %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+
+	return out.String(), nil
+}
+
+// checkoutGitRepo checks out the ref of the repo into dir, and returns the commit checked out.
+// The dir is reused between syncs, only the ref is fetched each time.
+func checkoutGitRepo(ctx context.Context, dir, repo, ref string) (commit string, err error) {
+	if strings.HasPrefix(repo, "-") || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid repo or ref")
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+
+		if _, err := runGit(ctx, dir, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := runGit(ctx, dir, "fetch", "--quiet", "--depth", "1", "--force", repo, ref); err != nil {
+		return "", err
+	}
+
+	if _, err := runGit(ctx, dir, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+
+	out, err := runGit(ctx, dir, "rev-parse", "HEAD")
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+func isGitSyncManifest(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+
+	return false
+}
+
+// renderGitSyncManifests reads objects from yaml and json files in the dir recursively.
+// Files can hold multiple yaml documents. Only kalm resources of gitSyncKinds are allowed.
+func renderGitSyncManifests(dir string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	seen := make(map[v1alpha1.GitSyncResource]string)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !isGitSyncManifest(path) {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(dir, path)
+
+		fileObjs, err := decodeGitSyncManifest(path)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", relPath, err)
+		}
+
+		for _, obj := range fileObjs {
+			if obj.GroupVersionKind().Group != v1alpha1.GroupVersion.Group {
+				return fmt.Errorf("%s: only kalm resources can be synced, got %s", relPath, obj.GroupVersionKind())
+			}
+
+			if !gitSyncKinds[obj.GetKind()] {
+				return fmt.Errorf("%s: %s can't be synced", relPath, obj.GetKind())
+			}
+
+			if obj.GetName() == "" {
+				return fmt.Errorf("%s: name of %s is required", relPath, obj.GetKind())
+			}
+
+			resource := gitSyncResourceOf(obj)
+
+			if previous, exist := seen[resource]; exist {
+				return fmt.Errorf("%s: %s %s is defined in %s as well", relPath, obj.GetKind(), obj.GetName(), previous)
+			}
+
+			seen[resource] = relPath
+			objs = append(objs, obj)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return objs, nil
+}
+
+func decodeGitSyncManifest(path string) ([]*unstructured.Unstructured, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+
+	for {
+		var raw runtime.RawExtension
+
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, err
+		}
+
+		raw.Raw = bytes.TrimSpace(raw.Raw)
+
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+func gitSyncResourceOf(obj *unstructured.Unstructured) v1alpha1.GitSyncResource {
+	return v1alpha1.GitSyncResource{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+func sortGitSyncResources(resources []v1alpha1.GitSyncResource) {
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+// isGitSyncDrifted tells if the live object is different from the one in the repo.
+// Only fields set in the repo are compared, fields defaulted by the api server or webhooks are ignored.
+func isGitSyncDrifted(desired, live *unstructured.Unstructured) bool {
+	for key, value := range desired.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			if !isSubset(desired.GetLabels(), live.GetLabels()) || !isSubset(desired.GetAnnotations(), live.GetAnnotations()) {
+				return true
+			}
+		default:
+			if !isSubset(value, live.Object[key]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isSubset tells if all fields in a are the same in b
+func isSubset(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+
+		if !ok {
+			return len(av) == 0 && b == nil
+		}
+
+		for key, value := range av {
+			if !isSubset(value, bv[key]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]string:
+		bv, _ := b.(map[string]string)
+
+		for key, value := range av {
+			if bValue, exist := bv[key]; !exist || bValue != value {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+
+		if !ok {
+			return len(av) == 0 && b == nil
+		}
+
+		if len(av) != len(bv) {
+			return false
+		}
+
+		for i := range av {
+			if !isSubset(av[i], bv[i]) {
+				return false
+			}
+		}
+
+		return true
+	case int64:
+		if bv, ok := b.(float64); ok {
+			return float64(av) == bv
+		}
+	case float64:
+		if bv, ok := b.(int64); ok {
+			return av == float64(bv)
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
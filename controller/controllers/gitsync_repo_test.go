@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// testGitRepo is a bare repo with a work tree to push commits from
+type testGitRepo struct {
+	t        *testing.T
+	bare     string
+	workTree string
+}
+
+func newTestGitRepo(t *testing.T) *testGitRepo {
+	dir := t.TempDir()
+	repo := &testGitRepo{
+		t:        t,
+		bare:     filepath.Join(dir, "repo.git"),
+		workTree: filepath.Join(dir, "work"),
+	}
+
+	repo.git(dir, "init", "--quiet", "--bare", repo.bare)
+	repo.git(dir, "clone", "--quiet", repo.bare, repo.workTree)
+
+	return repo
+}
+
+func (repo *testGitRepo) git(dir string, args ...string) string {
+	out, err := runGit(context.Background(), dir, append([]string{"-c", "user.name=kalm", "-c", "user.email=test@kalm.dev"}, args...)...)
+	require.Nil(repo.t, err)
+
+	return out
+}
+
+// commit writes the files, nil content removes the file
+func (repo *testGitRepo) commit(files map[string]*string) string {
+	for name, content := range files {
+		path := filepath.Join(repo.workTree, name)
+
+		if content == nil {
+			require.Nil(repo.t, os.Remove(path))
+			continue
+		}
+
+		require.Nil(repo.t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.Nil(repo.t, ioutil.WriteFile(path, []byte(*content), 0600))
+	}
+
+	repo.git(repo.workTree, "add", "--all")
+	repo.git(repo.workTree, "commit", "--quiet", "-m", "update")
+	repo.git(repo.workTree, "push", "--quiet", "origin", "HEAD")
+
+	return repo.git(repo.workTree, "rev-parse", "HEAD")[:40]
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+const testGitSyncDomains = `
+apiVersion: core.kalm.dev/v1alpha1
+kind: Domain
+metadata:
+  name: example
+spec:
+  domain: example.com
+---
+# comments only
+---
+apiVersion: core.kalm.dev/v1alpha1
+kind: Domain
+metadata:
+  name: example-www
+spec:
+  domain: www.example.com
+`
+
+const testGitSyncComponent = `{
+  "apiVersion": "core.kalm.dev/v1alpha1",
+  "kind": "Component",
+  "metadata": {"name": "web", "namespace": "shop"},
+  "spec": {"image": "nginx", "replicas": 2}
+}`
+
+func TestCheckoutGitRepo(t *testing.T) {
+	repo := newTestGitRepo(t)
+	commit := repo.commit(map[string]*string{"domains.yaml": strPtr(testGitSyncDomains)})
+
+	dir := filepath.Join(t.TempDir(), "checkout")
+
+	for _, url := range []string{"file://" + repo.bare, repo.bare} {
+		checkedOut, err := checkoutGitRepo(context.Background(), dir, url, "")
+		assert.Nil(t, err)
+		assert.Equal(t, commit, checkedOut)
+	}
+
+	newCommit := repo.commit(map[string]*string{
+		"domains.yaml":        nil,
+		"apps/component.json": strPtr(testGitSyncComponent),
+	})
+
+	branch := strings.TrimSpace(repo.git(repo.workTree, "rev-parse", "--abbrev-ref", "HEAD"))
+	checkedOut, err := checkoutGitRepo(context.Background(), dir, "file://"+repo.bare, branch)
+	assert.Nil(t, err)
+	assert.Equal(t, newCommit, checkedOut)
+
+	_, err = os.Stat(filepath.Join(dir, "domains.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = checkoutGitRepo(context.Background(), dir, "file://"+repo.bare, "not-exist")
+	assert.NotNil(t, err)
+
+	_, err = checkoutGitRepo(context.Background(), dir, "--upload-pack=touch", "")
+	assert.NotNil(t, err)
+}
+
+func TestRenderGitSyncManifests(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "apps"), 0700))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, ".github"), 0700))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "domains.yaml"), []byte(testGitSyncDomains), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "apps", "component.json"), []byte(testGitSyncComponent), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# apps"), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, ".github", "ci.yml"), []byte("on: push"), 0600))
+
+	objs, err := renderGitSyncManifests(dir)
+	assert.Nil(t, err)
+	assert.Len(t, objs, 3)
+
+	assert.Equal(t, "Component", objs[0].GetKind())
+	assert.Equal(t, "shop", objs[0].GetNamespace())
+	replicas, _, _ := unstructured.NestedInt64(objs[0].Object, "spec", "replicas")
+	assert.Equal(t, int64(2), replicas)
+
+	assert.Equal(t, "example", objs[1].GetName())
+	assert.Equal(t, "example-www", objs[2].GetName())
+
+	// only kalm resources
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "secret.yaml"), []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: foo\n"), 0600))
+	_, err = renderGitSyncManifests(dir)
+	assert.NotNil(t, err)
+	require.Nil(t, os.Remove(filepath.Join(dir, "secret.yaml")))
+
+	// only kinds which are allowed to be synced
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "gitsync.yaml"), []byte("apiVersion: core.kalm.dev/v1alpha1\nkind: GitSync\nmetadata:\n  name: foo\n"), 0600))
+	_, err = renderGitSyncManifests(dir)
+	assert.NotNil(t, err)
+	require.Nil(t, os.Remove(filepath.Join(dir, "gitsync.yaml")))
+
+	// duplicated
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "apps", "domains.yml"), []byte(testGitSyncDomains), 0600))
+	_, err = renderGitSyncManifests(dir)
+	assert.NotNil(t, err)
+}
+
+func TestIsGitSyncDrifted(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "core.kalm.dev/v1alpha1",
+		"kind":       "Component",
+		"metadata": map[string]interface{}{
+			"name":   "web",
+			"labels": map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"image":    "nginx",
+			"replicas": int64(2),
+			"ports":    []interface{}{map[string]interface{}{"containerPort": int64(80)}},
+		},
+	}}
+
+	live := desired.DeepCopy()
+	live.SetResourceVersion("100")
+	live.SetLabels(map[string]string{"app": "web", "kalm-gitsync": "apps"})
+	assert.Nil(t, unstructured.SetNestedField(live.Object, "Always", "spec", "imagePullPolicy"))
+	assert.Nil(t, unstructured.SetNestedSlice(live.Object, []interface{}{
+		map[string]interface{}{"containerPort": float64(80), "protocol": "http"},
+	}, "spec", "ports"))
+	assert.Nil(t, unstructured.SetNestedField(live.Object, map[string]interface{}{"replicas": int64(2)}, "status"))
+
+	assert.False(t, isGitSyncDrifted(desired, live))
+
+	changed := live.DeepCopy()
+	assert.Nil(t, unstructured.SetNestedField(changed.Object, int64(3), "spec", "replicas"))
+	assert.True(t, isGitSyncDrifted(desired, changed))
+
+	changed = live.DeepCopy()
+	changed.SetLabels(map[string]string{"app": "api"})
+	assert.True(t, isGitSyncDrifted(desired, changed))
+
+	changed = live.DeepCopy()
+	unstructured.RemoveNestedField(changed.Object, "spec", "ports")
+	assert.True(t, isGitSyncDrifted(desired, changed))
+}
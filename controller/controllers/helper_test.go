@@ -241,6 +241,11 @@ func (suite *BasicSuite) SetupTestEnv(testEnv *envtest.Environment, disableWebho
 	suite.Require().Nil(NewGatewayReconciler(mgr).SetupWithManager(mgr))
 	suite.Require().Nil(NewSingleSignOnConfigReconciler(mgr).SetupWithManager(mgr))
 	suite.Require().Nil(NewProtectedEndpointReconciler(mgr).SetupWithManager(mgr))
+	suite.Require().Nil(NewGitSyncReconciler(mgr).SetupWithManager(mgr))
+
+	alertRuleReconciler, err := NewAlertRuleReconciler(mgr)
+	suite.Require().Nil(err)
+	suite.Require().Nil(alertRuleReconciler.SetupWithManager(mgr))
 
 	// v1alpha1.InitializeWebhookClient(mgr)
 	suite.Require().Nil((&v1alpha1.AccessToken{}).SetupWebhookWithManager(mgr))
@@ -255,6 +260,8 @@ func (suite *BasicSuite) SetupTestEnv(testEnv *envtest.Environment, disableWebho
 	suite.Require().Nil((&v1alpha1.SingleSignOnConfig{}).SetupWebhookWithManager(mgr))
 	suite.Require().Nil((&v1alpha1.LogSystem{}).SetupWebhookWithManager(mgr))
 	suite.Require().Nil((&v1alpha1.ACMEServer{}).SetupWebhookWithManager(mgr))
+	suite.Require().Nil((&v1alpha1.GitSync{}).SetupWebhookWithManager(mgr))
+	suite.Require().Nil((&v1alpha1.AlertRule{}).SetupWebhookWithManager(mgr))
 
 	mgrStopChannel := make(chan struct{})
 	suite.StopChannel = mgrStopChannel
@@ -19,11 +19,15 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net"
 	"net/http"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gogo/protobuf/proto"
 	protoTypes "github.com/gogo/protobuf/types"
 	"istio.io/api/networking/v1alpha3"
 	istioNetworkingV1Beta1 "istio.io/api/networking/v1beta1"
@@ -61,11 +65,11 @@ var DANGEROUS_HEADERS = []string{
 
 type HttpRouteReconcilerTask struct {
 	*HttpRouteReconciler
-	ctx                       context.Context
-	routes                    []corev1alpha1.HttpRoute
-	gateways                  []v1beta1.Gateway
-	virtualServices           []v1beta1.VirtualService
-	httpsRedirectEnvoyFilters []v1alpha32.EnvoyFilter
+	ctx             context.Context
+	routes          []corev1alpha1.HttpRoute
+	gateways        []v1beta1.Gateway
+	virtualServices []v1beta1.VirtualService
+	envoyFilters    []v1alpha32.EnvoyFilter
 }
 
 func getIstioHttpRouteName(route *corev1alpha1.HttpRoute) string {
@@ -76,20 +80,116 @@ func getHttpsRedirectEnvoyFilterName(route *corev1alpha1.HttpRoute) string {
 	return fmt.Sprintf("https-redirect-%s", route.Name)
 }
 
+func getRetryBudgetEnvoyFilterName(route *corev1alpha1.HttpRoute) string {
+	return fmt.Sprintf("retry-budget-%s", route.Name)
+}
+
+func isDangerousHeader(name string) bool {
+	for _, header := range DANGEROUS_HEADERS {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyHeaderValues(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	rst := make(map[string]string, len(values))
+
+	for name, value := range values {
+		if !isDangerousHeader(name) {
+			rst[name] = value
+		}
+	}
+
+	return rst
+}
+
+// buildIstioHeaders applies user header operations, dangerous headers are always removed from requests
+// and can't be overwritten by users, so that kalm sso headers can't be forged.
+func buildIstioHeaders(headers *corev1alpha1.HttpRouteHeaders) *istioNetworkingV1Beta1.Headers {
+	request := &istioNetworkingV1Beta1.Headers_HeaderOperations{
+		Remove: DANGEROUS_HEADERS,
+		Set: map[string]string{
+			KALM_ROUTE_HEADER: "true",
+		},
+	}
+
+	rst := &istioNetworkingV1Beta1.Headers{
+		Request: request,
+	}
+
+	if headers == nil {
+		return rst
+	}
+
+	if headers.Request != nil {
+		for name, value := range copyHeaderValues(headers.Request.Set) {
+			request.Set[name] = value
+		}
+
+		request.Add = copyHeaderValues(headers.Request.Add)
+
+		// don't append to DANGEROUS_HEADERS in place
+		remove := append([]string{}, DANGEROUS_HEADERS...)
+
+		for _, name := range headers.Request.Remove {
+			if !isDangerousHeader(name) {
+				remove = append(remove, name)
+			}
+		}
+
+		request.Remove = remove
+	}
+
+	if headers.Response != nil {
+		rst.Response = &istioNetworkingV1Beta1.Headers_HeaderOperations{
+			Set:    copyHeaderValues(headers.Response.Set),
+			Add:    copyHeaderValues(headers.Response.Add),
+			Remove: headers.Response.Remove,
+		}
+	}
+
+	return rst
+}
+
+func millisecondsToDuration(ms int) *protoTypes.Duration {
+	return &protoTypes.Duration{
+		Seconds: int64(ms / 1000),
+		Nanos:   int32(ms%1000) * int32(time.Millisecond),
+	}
+}
+
 // will not care about match
 func (r *HttpRouteReconcilerTask) buildIstioHttpRoute(route *corev1alpha1.HttpRoute) *istioNetworkingV1Beta1.HTTPRoute {
 	spec := &route.Spec
 	httpRoute := &istioNetworkingV1Beta1.HTTPRoute{
-		Name:  getIstioHttpRouteName(route),
-		Route: r.BuildDestinations(route),
-		Headers: &istioNetworkingV1Beta1.Headers{
-			Request: &istioNetworkingV1Beta1.Headers_HeaderOperations{
-				Remove: DANGEROUS_HEADERS,
-				Set: map[string]string{
-					KALM_ROUTE_HEADER: "true",
+		Name:    getIstioHttpRouteName(route),
+		Route:   r.BuildDestinations(route),
+		Headers: buildIstioHeaders(spec.Headers),
+	}
+
+	// Requests are not forwarded, other features of destinations are meaningless
+	if spec.Redirect != nil {
+		httpRoute.Route = nil
+		httpRoute.Redirect = buildIstioHttpRedirect(spec.Redirect)
+		return httpRoute
+	}
+
+	if spec.DirectResponse != nil {
+		httpRoute.Route = []*istioNetworkingV1Beta1.HTTPRouteDestination{
+			{
+				Destination: &istioNetworkingV1Beta1.Destination{
+					Host: directResponsePlaceholderHost,
 				},
 			},
-		},
+		}
+		return httpRoute
 	}
 
 	if spec.StripPath {
@@ -98,22 +198,44 @@ func (r *HttpRouteReconcilerTask) buildIstioHttpRoute(route *corev1alpha1.HttpRo
 		}
 	}
 
-	// Disable 5s timeout bug
-	// if spec.Timeout != nil {
-	// 	httpRoute.Timeout = &protoTypes.Duration{
-	// 		Seconds: int64(*spec.Timeout),
-	// 	}
-	// }
+	if spec.Rewrite != nil && (spec.Rewrite.Prefix != "" || spec.Rewrite.Host != "") {
+		if httpRoute.Rewrite == nil {
+			httpRoute.Rewrite = &istioNetworkingV1Beta1.HTTPRewrite{}
+		}
+
+		if spec.Rewrite.Prefix != "" {
+			httpRoute.Rewrite.Uri = spec.Rewrite.Prefix
+		}
 
-	// if spec.Retries != nil {
-	// 	httpRoute.Retries = &istioNetworkingV1Beta1.HTTPRetry{
-	// 		Attempts: int32(spec.Retries.Attempts),
-	// 		PerTryTimeout: &protoTypes.Duration{
-	// 			Seconds: int64(spec.Retries.PerTtyTimeoutSeconds),
-	// 		},
-	// 		RetryOn: strings.Join(spec.Retries.RetryOn, ","),
-	// 	}
-	// }
+		httpRoute.Rewrite.Authority = spec.Rewrite.Host
+	}
+
+	if spec.TimeoutMilliseconds != nil {
+		httpRoute.Timeout = millisecondsToDuration(*spec.TimeoutMilliseconds)
+	} else if spec.Timeout != nil {
+		httpRoute.Timeout = millisecondsToDuration(*spec.Timeout * 1000)
+	}
+
+	if spec.Retries != nil {
+		httpRoute.Retries = &istioNetworkingV1Beta1.HTTPRetry{
+			Attempts: int32(spec.Retries.Attempts),
+		}
+
+		// A zero per try timeout is rejected by istio, which makes the whole virtual service of the host invalid.
+		// Leave it unset, so each try is only limited by the route timeout.
+		if spec.Retries.PerTryTimeoutMilliseconds != nil {
+			httpRoute.Retries.PerTryTimeout = millisecondsToDuration(*spec.Retries.PerTryTimeoutMilliseconds)
+		} else if spec.Retries.PerTtyTimeoutSeconds > 0 {
+			httpRoute.Retries.PerTryTimeout = millisecondsToDuration(spec.Retries.PerTtyTimeoutSeconds * 1000)
+		}
+
+		// retry conditions are meaningless if retries are disabled
+		if spec.Retries.Attempts > 0 {
+			httpRoute.Retries.RetryOn = strings.Join(spec.Retries.RetryOn, ",")
+		} else {
+			httpRoute.Retries.PerTryTimeout = nil
+		}
+	}
 
 	if spec.Mirror != nil {
 		dest := toHttpRouteDestination(spec.Mirror.Destination, 100)
@@ -253,8 +375,82 @@ func (r *HttpRouteReconcilerTask) buildHttpsRedirectEnvoyFilter(route *corev1alp
 	return filter, nil
 }
 
-func (r *HttpRouteReconcilerTask) buildIstioHttpRoutes(route *corev1alpha1.HttpRoute) []*istioNetworkingV1Beta1.HTTPRoute {
-	matches := r.BuildMatches(route)
+// Retry budget is a circuit breaker threshold of envoy clusters, which is not exposed by istio.
+// It's patched to clusters of the route destinations on the ingress gateway.
+func (r *HttpRouteReconcilerTask) buildRetryBudgetEnvoyFilter(route *corev1alpha1.HttpRoute) *v1alpha32.EnvoyFilter {
+	budget := route.Spec.Retries.Budget
+
+	retryBudget := map[string]interface{}{
+		"budget_percent": map[string]interface{}{
+			"value": float64(budget.BudgetPercent),
+		},
+	}
+
+	if budget.MinRetryConcurrency != nil {
+		retryBudget["min_retry_concurrency"] = *budget.MinRetryConcurrency
+	}
+
+	filter := &v1alpha32.EnvoyFilter{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: istioNamespace,
+			Name:      getRetryBudgetEnvoyFilterName(route),
+			Labels: map[string]string{
+				KALM_ROUTE_LABEL: "true",
+			},
+		},
+		Spec: v1alpha3.EnvoyFilter{
+			WorkloadSelector: &v1alpha3.WorkloadSelector{
+				Labels: map[string]string{
+					"app": "istio-ingressgateway",
+				},
+			},
+		},
+	}
+
+	for _, destination := range route.Spec.Destinations {
+		dest := toHttpRouteDestination(destination, 0).Destination
+
+		clusterMatch := &v1alpha3.EnvoyFilter_ClusterMatch{
+			Service: dest.Host,
+		}
+
+		if dest.Port != nil {
+			clusterMatch.PortNumber = dest.Port.Number
+		}
+
+		filter.Spec.ConfigPatches = append(filter.Spec.ConfigPatches, &v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: v1alpha3.EnvoyFilter_CLUSTER,
+			Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: v1alpha3.EnvoyFilter_GATEWAY,
+				ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_Cluster{
+					Cluster: clusterMatch,
+				},
+			},
+			Patch: &v1alpha3.EnvoyFilter_Patch{
+				Operation: v1alpha3.EnvoyFilter_Patch_MERGE,
+				Value: golangMapToProtoStruct(map[string]interface{}{
+					"circuit_breakers": map[string]interface{}{
+						"thresholds": []interface{}{
+							map[string]interface{}{
+								"retry_budget": retryBudget,
+							},
+						},
+					},
+				}),
+			},
+		})
+	}
+
+	return filter
+}
+
+func (r *HttpRouteReconcilerTask) buildIstioHttpRoutes(route *corev1alpha1.HttpRoute) ([]*istioNetworkingV1Beta1.HTTPRoute, error) {
+	matches, err := r.BuildMatches(route)
+
+	if err != nil {
+		return nil, err
+	}
+
 	res := make([]*istioNetworkingV1Beta1.HTTPRoute, 0)
 
 	for _, match := range matches {
@@ -263,7 +459,7 @@ func (r *HttpRouteReconcilerTask) buildIstioHttpRoutes(route *corev1alpha1.HttpR
 		res = append(res, httpRoute)
 	}
 
-	return res
+	return res, nil
 }
 
 // return should "a" sort before "b"
@@ -310,6 +506,36 @@ func sortRoutes(a, b *istioNetworkingV1Beta1.HTTPRoute) bool {
 	return aPrefix.Prefix > bPrefix.Prefix
 }
 
+// migrateLegacyTimeouts removes the timeout and retries saved as defaults by old dashboards from a route once.
+// They were never applied, applying them now would give all existing routes a 5s timeout and 3 retries suddenly.
+func (r *HttpRouteReconcilerTask) migrateLegacyTimeouts(route *corev1alpha1.HttpRoute) error {
+	if _, migrated := route.Annotations[corev1alpha1.KalmAnnoHttpRouteTimeoutsMigrated]; migrated {
+		return nil
+	}
+
+	copied := route.DeepCopy()
+	removed := copied.Spec.RemoveLegacyDefaultTimeouts()
+
+	if copied.Annotations == nil {
+		copied.Annotations = make(map[string]string)
+	}
+
+	copied.Annotations[corev1alpha1.KalmAnnoHttpRouteTimeoutsMigrated] = "true"
+
+	if err := r.Patch(r.ctx, copied, client.MergeFrom(route)); err != nil {
+		r.EmitWarningEvent(route, err, "Migrate Legacy Timeouts Error")
+		return err
+	}
+
+	if len(removed) > 0 {
+		r.EmitNormalEvent(copied, "LegacyTimeoutsRemoved", "%s saved as defaults by old dashboards were never applied, removed", strings.Join(removed, " and "))
+	}
+
+	*route = *copied
+
+	return nil
+}
+
 func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 	var routes corev1alpha1.HttpRouteList
 	if err := r.Reader.List(r.ctx, &routes); err != nil {
@@ -317,17 +543,23 @@ func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 	}
 	r.routes = routes.Items
 
+	for i := range r.routes {
+		if err := r.migrateLegacyTimeouts(&r.routes[i]); err != nil {
+			return err
+		}
+	}
+
 	var virtualServices v1beta1.VirtualServiceList
 	if err := r.Reader.List(r.ctx, &virtualServices, client.MatchingLabels{KALM_ROUTE_LABEL: "true"}); err != nil {
 		return err
 	}
 	r.virtualServices = virtualServices.Items
 
-	var httpsRedirectEnvoyFilters v1alpha32.EnvoyFilterList
-	if err := r.Reader.List(r.ctx, &httpsRedirectEnvoyFilters, client.MatchingLabels{KALM_ROUTE_LABEL: "true"}); err != nil {
+	var envoyFilters v1alpha32.EnvoyFilterList
+	if err := r.Reader.List(r.ctx, &envoyFilters, client.MatchingLabels{KALM_ROUTE_LABEL: "true"}); err != nil {
 		return err
 	}
-	r.httpsRedirectEnvoyFilters = httpsRedirectEnvoyFilters.Items
+	r.envoyFilters = envoyFilters.Items
 
 	// Each host will has a virtual service
 	// Kalm will order http route rules, and set them in the virtual service http field.
@@ -336,14 +568,17 @@ func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 	for i := range r.routes {
 		route := r.routes[i]
 
+		// an invalid route is left out, instead of being translated without some of its conditions
+		istioHttpRoutes, err := r.buildIstioHttpRoutes(&route)
+
+		if err != nil {
+			r.EmitWarningEvent(&route, err, "Build Http Route Error")
+			continue
+		}
+
 		for j := range route.Spec.Hosts {
 			host := route.Spec.Hosts[j]
-
-			if _, ok := hostVirtualService[host]; ok {
-				hostVirtualService[host] = append(hostVirtualService[host], r.buildIstioHttpRoutes(&route)...)
-			} else {
-				hostVirtualService[host] = r.buildIstioHttpRoutes(&route)
-			}
+			hostVirtualService[host] = append(hostVirtualService[host], istioHttpRoutes...)
 		}
 	}
 
@@ -364,6 +599,86 @@ func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 		}
 	}
 
+	for host, routes := range hostVirtualService {
+		// Less reports whether the element with
+		// index i should sort before the element with index j.
+		sort.Slice(routes, func(i, j int) bool { return sortRoutes(routes[i], routes[j]) })
+
+		if err := r.SaveVirtualService(host, routes); err != nil {
+			return err
+		}
+	}
+
+	envoyFilterMap := make(map[string]*v1alpha32.EnvoyFilter)
+
+	for i := range r.envoyFilters {
+		filter := r.envoyFilters[i]
+		envoyFilterMap[filter.Name] = &filter
+	}
+
+	// Create, update or delete envoy filters on gateway for routes
+	hasRateLimit := false
+
+	// a filter failing to save doesn't block filters of other routes, statuses of filters are saved in routes
+	var saveErr error
+	envoyFilterStatuses := make(map[string][]corev1alpha1.HttpRouteEnvoyFilterStatus)
+
+	for i := range r.routes {
+		route := r.routes[i]
+
+		saveFilter := func(filter *v1alpha32.EnvoyFilter, msg string) {
+			status := corev1alpha1.HttpRouteEnvoyFilterStatus{Name: filter.Name, Status: "normal"}
+
+			if err := r.saveEnvoyFilter(filter, envoyFilterMap); err != nil {
+				r.EmitWarningEvent(&route, err, msg)
+				status.Status = "error"
+				status.Error = err.Error()
+
+				if saveErr == nil {
+					saveErr = err
+				}
+			}
+
+			envoyFilterStatuses[route.Name] = append(envoyFilterStatuses[route.Name], status)
+		}
+
+		if route.Spec.HttpRedirectToHttps {
+			filter, err := r.buildHttpsRedirectEnvoyFilter(&route)
+
+			if err != nil {
+				return err
+			}
+
+			saveFilter(filter, "Save Https Redirect filter Error")
+		}
+
+		if route.Spec.Redirect != nil && route.Spec.Redirect.Scheme != "" {
+			saveFilter(r.buildRedirectSchemeEnvoyFilter(&route), "Save Redirect Scheme filter Error")
+		}
+
+		if route.Spec.DirectResponse != nil {
+			saveFilter(r.buildDirectResponseEnvoyFilter(&route), "Save Direct Response filter Error")
+		}
+
+		if route.Spec.Retries != nil && route.Spec.Retries.Budget != nil && len(route.Spec.Destinations) > 0 {
+			saveFilter(r.buildRetryBudgetEnvoyFilter(&route), "Save Retry Budget filter Error")
+		}
+
+		if route.Spec.RateLimit != nil {
+			if corev1alpha1.IsHttpRouteRateLimitEnabled() {
+				saveFilter(r.buildRateLimitEnvoyFilter(&route), "Save Rate Limit filter Error")
+				hasRateLimit = true
+			} else {
+				// the filter would be rejected by the envoy of ingress gateways, along with all other filters of the listener
+				envoyFilterStatuses[route.Name] = append(envoyFilterStatuses[route.Name], corev1alpha1.HttpRouteEnvoyFilterStatus{
+					Name:   getRateLimitEnvoyFilterName(&route),
+					Status: "error",
+					Error:  "rate limits are not supported by the ingress gateways of the cluster",
+				})
+			}
+		}
+	}
+
 	for i := range r.routes {
 		route := r.routes[i]
 		if len(route.Status.DestinationsStatus) != len(route.Spec.Destinations) {
@@ -387,52 +702,27 @@ func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 
 			}
 		}
-		r.Status().Update(r.ctx, &route)
-	}
 
-	for host, routes := range hostVirtualService {
-		// Less reports whether the element with
-		// index i should sort before the element with index j.
-		sort.Slice(routes, func(i, j int) bool { return sortRoutes(routes[i], routes[j]) })
+		route.Status.EnvoyFilters = envoyFilterStatuses[route.Name]
 
-		if err := r.SaveVirtualService(host, routes); err != nil {
+		if err := r.Status().Update(r.ctx, &route); client.IgnoreNotFound(err) != nil {
 			return err
 		}
 	}
 
-	httpsRedirectFilterMap := make(map[string]*v1alpha32.EnvoyFilter)
-
-	for i := range r.httpsRedirectEnvoyFilters {
-		filter := r.httpsRedirectEnvoyFilters[i]
-		httpsRedirectFilterMap[filter.Name] = &filter
+	if saveErr != nil {
+		return saveErr
 	}
 
-	// Create or delete envoy filter on gateway for routes
-	for i := range r.routes {
-		route := r.routes[i]
-
-		filterName := getHttpsRedirectEnvoyFilterName(&route)
-		if route.Spec.HttpRedirectToHttps {
-			if _, ok := httpsRedirectFilterMap[filterName]; !ok {
-				filter, err := r.buildHttpsRedirectEnvoyFilter(&route)
-
-				if err != nil {
-					return err
-				}
-
-				if err := r.Create(r.ctx, filter); err != nil {
-					r.EmitWarningEvent(&route, err, "Create Https Redirect filter Error")
-					return err
-				}
-			} else {
-				delete(httpsRedirectFilterMap, filterName)
-			}
+	if hasRateLimit {
+		if err := r.saveEnvoyFilter(r.buildLocalRateLimitEnvoyFilter(), envoyFilterMap); err != nil {
+			return err
 		}
 	}
 
 	// clean left unused envoy filters
-	for filterName := range httpsRedirectFilterMap {
-		filter := httpsRedirectFilterMap[filterName]
+	for filterName := range envoyFilterMap {
+		filter := envoyFilterMap[filterName]
 
 		if err := r.Delete(r.ctx, filter); err != nil {
 			return err
@@ -453,6 +743,25 @@ func (r *HttpRouteReconcilerTask) Run(ctrl.Request) error {
 	return nil
 }
 
+// saveEnvoyFilter creates the filter or updates the existing one, the existing one is removed from the map
+func (r *HttpRouteReconcilerTask) saveEnvoyFilter(filter *v1alpha32.EnvoyFilter, existingFilters map[string]*v1alpha32.EnvoyFilter) error {
+	existing, ok := existingFilters[filter.Name]
+
+	if !ok {
+		return r.Create(r.ctx, filter)
+	}
+
+	delete(existingFilters, filter.Name)
+
+	if proto.Equal(&existing.Spec, &filter.Spec) {
+		return nil
+	}
+
+	existing.Spec = filter.Spec
+
+	return r.Update(r.ctx, existing)
+}
+
 func (r *HttpRouteReconcilerTask) SaveVirtualService(host string, routes []*istioNetworkingV1Beta1.HTTPRoute) error {
 	virtualServiceName := fmt.Sprintf("vs-%s", strings.ReplaceAll(strings.ReplaceAll(host, "*", "wildcard"), ".", "-"))
 	virtualServiceNamespace := "kalm-system"
@@ -524,49 +833,280 @@ func getHttpRouteHttpGatewayName(name string) string {
 	return fmt.Sprintf("%s-http-gateway", name)
 }
 
-func conditionToStringMatch(condition corev1alpha1.HttpRouteCondition) *istioNetworkingV1Beta1.StringMatch {
-	switch condition.Operator {
+// The ingress gateway sets the remote address as the external address only if it's not an internal address,
+// the header is missing for requests from private networks. The webhook rejects sourceIP conditions on internal addresses.
+//
+// The remote address is the client only if the ingress gateway sees the source ip of the connection, so the operator
+// sets externalTrafficPolicy of the gateway service to Local, otherwise kube-proxy SNATs requests forwarded by other nodes.
+// Behind a L7 load balancer, the gateway must trust it via numTrustedProxies of the istio gatewayTopology mesh config,
+// then the remote address is read from the x-forwarded-for header.
+const sourceIPHeader = "x-envoy-external-address"
+
+// conditionToStringMatch returns the match of the positive operator, negation is up to the caller
+func conditionToStringMatch(condition corev1alpha1.HttpRouteCondition) (*istioNetworkingV1Beta1.StringMatch, error) {
+	switch condition.Type {
+	case corev1alpha1.HttpRouteConditionTypeCookie:
+		return &istioNetworkingV1Beta1.StringMatch{
+			MatchType: &istioNetworkingV1Beta1.StringMatch_Regex{
+				Regex: cookieConditionsToRegexp([]corev1alpha1.HttpRouteCondition{condition}),
+			},
+		}, nil
+	case corev1alpha1.HttpRouteConditionTypeSourceIP:
+		if _, ipNet, err := net.ParseCIDR(condition.Value); err == nil {
+			regex, err := ipv4CIDRToRegexp(ipNet)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &istioNetworkingV1Beta1.StringMatch{
+				MatchType: &istioNetworkingV1Beta1.StringMatch_Regex{
+					Regex: regex,
+				},
+			}, nil
+		}
+
+		value := condition.Value
+
+		if ip := net.ParseIP(value); ip != nil {
+			value = ip.String()
+		}
+
+		return &istioNetworkingV1Beta1.StringMatch{
+			MatchType: &istioNetworkingV1Beta1.StringMatch_Exact{
+				Exact: value,
+			},
+		}, nil
+	}
+
+	switch condition.Operator.Positive() {
 	case corev1alpha1.HRCOEqual:
 		return &istioNetworkingV1Beta1.StringMatch{
 			MatchType: &istioNetworkingV1Beta1.StringMatch_Exact{
 				Exact: condition.Value,
 			},
-		}
+		}, nil
 	case corev1alpha1.HRCOWithPrefix:
 		return &istioNetworkingV1Beta1.StringMatch{
 			MatchType: &istioNetworkingV1Beta1.StringMatch_Prefix{
 				Prefix: condition.Value,
 			},
-		}
+		}, nil
 	case corev1alpha1.HRCOMatchRegexp:
 		return &istioNetworkingV1Beta1.StringMatch{
 			MatchType: &istioNetworkingV1Beta1.StringMatch_Regex{
 				Regex: condition.Value,
 			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", condition.Operator)
+}
+
+// cookiePairToRegexp matches the name=value pair of the cookie condition
+func cookiePairToRegexp(condition corev1alpha1.HttpRouteCondition) string {
+	var value string
+
+	switch condition.Operator.Positive() {
+	case corev1alpha1.HRCOWithPrefix:
+		value = regexp.QuoteMeta(condition.Value) + "[^;]*"
+	case corev1alpha1.HRCOMatchRegexp:
+		value = "(?:" + condition.Value + ")"
+	default:
+		value = regexp.QuoteMeta(condition.Value)
+	}
+
+	return regexp.QuoteMeta(condition.Name) + "=" + value
+}
+
+// Cookies are matched against the whole cookie header, which is a "; " separated list of name=value pairs.
+// The regexp matches if all of the cookie conditions match, pairs may appear in any order,
+// so each permutation of the conditions is an alternative. The webhook limits the number of conditions.
+func cookieConditionsToRegexp(conditions []corev1alpha1.HttpRouteCondition) string {
+	pairs := make([]string, len(conditions))
+
+	for i := range conditions {
+		pairs[i] = cookiePairToRegexp(conditions[i])
+	}
+
+	var alternatives []string
+
+	permute(pairs, 0, func(pairs []string) {
+		alternatives = append(alternatives, "^(.*?;\\s*)?"+strings.Join(pairs, ";(.*?;)?\\s*")+"(;.*)?$")
+	})
+
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+
+	return "(?:" + strings.Join(alternatives, ")|(?:") + ")"
+}
+
+// anyCookieConditionToRegexp matches if any of the cookie conditions matches
+func anyCookieConditionToRegexp(conditions []corev1alpha1.HttpRouteCondition) string {
+	alternatives := make([]string, len(conditions))
+
+	for i := range conditions {
+		alternatives[i] = cookieConditionsToRegexp(conditions[i : i+1])
+	}
+
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+
+	return "(?:" + strings.Join(alternatives, ")|(?:") + ")"
+}
+
+func permute(values []string, i int, f func([]string)) {
+	if i >= len(values)-1 {
+		f(values)
+		return
+	}
+
+	for j := i; j < len(values); j++ {
+		values[i], values[j] = values[j], values[i]
+		permute(values, i+1, f)
+		values[i], values[j] = values[j], values[i]
+	}
+}
+
+// ipv4CIDRToRegexp matches dotted ipv4 addresses in the network octet by octet
+func ipv4CIDRToRegexp(ipNet *net.IPNet) (string, error) {
+	ip := ipNet.IP.To4()
+	ones, bits := ipNet.Mask.Size()
+
+	if ip == nil || bits != 32 {
+		return "", fmt.Errorf("%s is not an ipv4 cidr", ipNet)
+	}
+
+	octets := make([]string, 4)
+
+	for i := range octets {
+		bits := ones - i*8
+
+		switch {
+		case bits >= 8:
+			octets[i] = strconv.Itoa(int(ip[i]))
+		case bits <= 0:
+			octets[i] = "[0-9]{1,3}"
+		default:
+			low := int(ip[i])
+			high := low | (1<<(8-bits) - 1)
+			values := make([]string, 0, high-low+1)
+
+			for v := low; v <= high; v++ {
+				values = append(values, strconv.Itoa(v))
+			}
+
+			octets[i] = "(?:" + strings.Join(values, "|") + ")"
 		}
 	}
 
-	return nil
+	return "^" + strings.Join(octets, "\\.") + "$", nil
 }
 
-func (r *HttpRouteReconcilerTask) PatchConditionsToHttpMatch(match *istioNetworkingV1Beta1.HTTPMatchRequest, route *corev1alpha1.HttpRouteSpec) {
-	for _, condition := range route.Conditions {
+func (r *HttpRouteReconcilerTask) PatchConditionsToHttpMatch(match *istioNetworkingV1Beta1.HTTPMatchRequest, conditions []corev1alpha1.HttpRouteCondition) error {
+	// cookie conditions are combined into one match on the cookie header
+	var cookieConditions, notCookieConditions []corev1alpha1.HttpRouteCondition
+
+	for _, condition := range conditions {
+		var header string
+
 		switch condition.Type {
 		case corev1alpha1.HttpRouteConditionTypeHeader:
-			if match.Headers == nil {
-				match.Headers = make(map[string]*istioNetworkingV1Beta1.StringMatch)
+			header = http.CanonicalHeaderKey(condition.Name)
+
+		case corev1alpha1.HttpRouteConditionTypeCookie:
+			if condition.Operator.IsNegative() {
+				notCookieConditions = append(notCookieConditions, condition)
+			} else {
+				cookieConditions = append(cookieConditions, condition)
 			}
 
-			match.Headers[http.CanonicalHeaderKey(condition.Name)] = conditionToStringMatch(condition)
+			continue
+
+		case corev1alpha1.HttpRouteConditionTypeSourceIP:
+			header = http.CanonicalHeaderKey(sourceIPHeader)
+
+		case corev1alpha1.HttpRouteConditionTypeAuthority:
+			if !condition.Operator.IsNegative() {
+				stringMatch, err := conditionToStringMatch(condition)
+
+				if err != nil {
+					return err
+				}
+
+				match.Authority = stringMatch
+				continue
+			}
+
+			header = ":authority"
 
 		case corev1alpha1.HttpRouteConditionTypeQuery:
+			stringMatch, err := conditionToStringMatch(condition)
+
+			if err != nil {
+				return err
+			}
+
 			if match.QueryParams == nil {
 				match.QueryParams = make(map[string]*istioNetworkingV1Beta1.StringMatch)
 			}
 
-			match.QueryParams[condition.Name] = conditionToStringMatch(condition)
+			match.QueryParams[condition.Name] = stringMatch
+			continue
+
+		default:
+			continue
+		}
+
+		stringMatch, err := conditionToStringMatch(condition)
+
+		if err != nil {
+			return err
+		}
+
+		if condition.Operator.IsNegative() {
+			if match.WithoutHeaders == nil {
+				match.WithoutHeaders = make(map[string]*istioNetworkingV1Beta1.StringMatch)
+			}
+
+			match.WithoutHeaders[header] = stringMatch
+		} else {
+			if match.Headers == nil {
+				match.Headers = make(map[string]*istioNetworkingV1Beta1.StringMatch)
+			}
+
+			match.Headers[header] = stringMatch
+		}
+	}
+
+	if len(cookieConditions) > 0 {
+		if match.Headers == nil {
+			match.Headers = make(map[string]*istioNetworkingV1Beta1.StringMatch)
+		}
+
+		match.Headers["Cookie"] = &istioNetworkingV1Beta1.StringMatch{
+			MatchType: &istioNetworkingV1Beta1.StringMatch_Regex{
+				Regex: cookieConditionsToRegexp(cookieConditions),
+			},
 		}
 	}
+
+	// none of the negative conditions matches if the header doesn't match any of them
+	if len(notCookieConditions) > 0 {
+		if match.WithoutHeaders == nil {
+			match.WithoutHeaders = make(map[string]*istioNetworkingV1Beta1.StringMatch)
+		}
+
+		match.WithoutHeaders["Cookie"] = &istioNetworkingV1Beta1.StringMatch{
+			MatchType: &istioNetworkingV1Beta1.StringMatch_Regex{
+				Regex: anyCookieConditionToRegexp(notCookieConditions),
+			},
+		}
+	}
+
+	return nil
 }
 
 func isAllowAllMethods(methods []corev1alpha1.HttpRouteMethod) bool {
@@ -583,13 +1123,27 @@ func isAllowAllMethods(methods []corev1alpha1.HttpRouteMethod) bool {
 	return set["GET"] && set["HEAD"] && set["POST"] && set["PUT"] && set["PATCH"] && set["DELETE"] && set["OPTIONS"] && set["TRACE"] && set["CONNECT"]
 }
 
-func (r *HttpRouteReconcilerTask) BuildMatches(route *corev1alpha1.HttpRoute) []*istioNetworkingV1Beta1.HTTPMatchRequest {
+func (r *HttpRouteReconcilerTask) BuildMatches(route *corev1alpha1.HttpRoute) ([]*istioNetworkingV1Beta1.HTTPMatchRequest, error) {
 	spec := &route.Spec
 	res := make(
 		[]*istioNetworkingV1Beta1.HTTPMatchRequest, 0,
 		len(spec.Paths)*len(spec.Methods),
 	)
 
+	// Matches of a route are ORed, each condition group has its own matches
+	conditionSets := [][]corev1alpha1.HttpRouteCondition{spec.Conditions}
+
+	if len(spec.ConditionGroups) > 0 {
+		conditionSets = make([][]corev1alpha1.HttpRouteCondition, 0, len(spec.ConditionGroups))
+
+		for _, group := range spec.ConditionGroups {
+			conditions := make([]corev1alpha1.HttpRouteCondition, 0, len(spec.Conditions)+len(group.Conditions))
+			conditions = append(conditions, spec.Conditions...)
+			conditions = append(conditions, group.Conditions...)
+			conditionSets = append(conditionSets, conditions)
+		}
+	}
+
 	for _, path := range spec.Paths {
 		var methodRegexp strings.Builder
 		methodRegexp.WriteString("^(")
@@ -642,30 +1196,37 @@ func (r *HttpRouteReconcilerTask) BuildMatches(route *corev1alpha1.HttpRoute) []
 			}
 		}
 
-		r.PatchConditionsToHttpMatch(match, spec)
-		res = append(res, match)
-
-		// Prevent double slash after strip path rewrite
-		// Assume we have a route that enabled strip path and has a path prefix with /bbbb
-		//   Request #1 with path /bbbbaaaa will be rewritten to /aaaa, this is CORRECT
-		//   Request #2 with path /bbbb/aaaa will be rewritten to //aaaa, which has double slashes and it is WRONG
-		// To solve this, add another route with path prefix /bbbb/
-		//   Request #1 doesn't match this route. Skip
-		//   Request #2 will be rewritten to /aaaa, which is correct.
-		if route.Spec.StripPath && path != "/" {
-			copyedMatch := match.DeepCopy()
+		for _, conditions := range conditionSets {
+			conditionMatch := match.DeepCopy()
 
-			copyedMatch.Uri = &istioNetworkingV1Beta1.StringMatch{
-				MatchType: &istioNetworkingV1Beta1.StringMatch_Prefix{
-					Prefix: path + "/",
-				},
+			if err := r.PatchConditionsToHttpMatch(conditionMatch, conditions); err != nil {
+				return nil, err
 			}
 
-			res = append(res, copyedMatch)
+			res = append(res, conditionMatch)
+
+			// Prevent double slash after strip path rewrite, the same applies to prefix rewrites ending with a slash
+			// Assume we have a route that enabled strip path and has a path prefix with /bbbb
+			//   Request #1 with path /bbbbaaaa will be rewritten to /aaaa, this is CORRECT
+			//   Request #2 with path /bbbb/aaaa will be rewritten to //aaaa, which has double slashes and it is WRONG
+			// To solve this, add another route with path prefix /bbbb/
+			//   Request #1 doesn't match this route. Skip
+			//   Request #2 will be rewritten to /aaaa, which is correct.
+			if (route.Spec.StripPath || route.Spec.Rewrite != nil && strings.HasSuffix(route.Spec.Rewrite.Prefix, "/")) && path != "/" {
+				copyedMatch := conditionMatch.DeepCopy()
+
+				copyedMatch.Uri = &istioNetworkingV1Beta1.StringMatch{
+					MatchType: &istioNetworkingV1Beta1.StringMatch_Prefix{
+						Prefix: path + "/",
+					},
+				}
+
+				res = append(res, copyedMatch)
+			}
 		}
 	}
 
-	return res
+	return res, nil
 }
 
 func toHttpRouteDestination(destination corev1alpha1.HttpRouteDestination, weight int32) *istioNetworkingV1Beta1.HTTPRouteDestination {
@@ -854,5 +1415,5 @@ func (r *HttpRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				ToRequests: &WatchAllService{},
 			},
 		).
-		Complete(r)
+		Complete(instrumentReconciler("HttpRouteReconciler", r))
 }
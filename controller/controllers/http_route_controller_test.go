@@ -1,15 +1,32 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net"
+	"os"
 	"testing"
 
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	protoTypes "github.com/gogo/protobuf/types"
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	istioNetworking "istio.io/api/networking/v1alpha3"
+	v1alpha32 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1beta1"
+	istioScheme "istio.io/client-go/pkg/clientset/versioned/scheme"
 	coreV1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 type HttpRouteControllerSuite struct {
@@ -154,6 +171,50 @@ func (suite *HttpRouteControllerSuite) TestDestinationsStatus() {
 	}, "route destination status should be error")
 }
 
+func (suite *HttpRouteControllerSuite) TestHttpRouteTimeoutsAndRetries() {
+	timeout := 1500
+	perTryTimeout := 1000
+	route := v1alpha1.HttpRoute{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "test-timeouts",
+		},
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods:             []v1alpha1.HttpRouteMethod{"GET"},
+			Hosts:               []string{"timeouts.example.com"},
+			Paths:               []string{"/"},
+			Schemes:             []v1alpha1.HttpRouteScheme{"http"},
+			TimeoutMilliseconds: &timeout,
+			Retries: &v1alpha1.HttpRouteRetries{
+				Attempts:                  2,
+				PerTryTimeoutMilliseconds: &perTryTimeout,
+				RetryOn:                   []string{"gateway-error", "connect-failure"},
+			},
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{
+					Host:   "test:80",
+					Weight: 100,
+				},
+			},
+		},
+	}
+
+	suite.createObject(&route)
+
+	var vs v1beta1.VirtualService
+	suite.Eventually(func() bool {
+		if err := suite.K8sClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-timeouts-example-com"}, &vs); err != nil {
+			return false
+		}
+
+		return len(vs.Spec.Http) == 1 && vs.Spec.Http[0].Timeout != nil && vs.Spec.Http[0].Retries != nil
+	}, "virtual service should have timeout and retries")
+
+	suite.Equal(&protoTypes.Duration{Seconds: 1, Nanos: 500000000}, vs.Spec.Http[0].Timeout)
+	suite.Equal(int32(2), vs.Spec.Http[0].Retries.Attempts)
+	suite.Equal(&protoTypes.Duration{Seconds: 1}, vs.Spec.Http[0].Retries.PerTryTimeout)
+	suite.Equal("gateway-error,connect-failure", vs.Spec.Http[0].Retries.RetryOn)
+}
+
 func TestHttpRouteControllerSuite(t *testing.T) {
 	suite.Run(t, new(HttpRouteControllerSuite))
 }
@@ -170,3 +231,749 @@ func TestAdjustWeightToSumTo100(t *testing.T) {
 		assert.True(t, 100 == sum(rst))
 	}
 }
+
+func TestBuildIstioHttpRouteTimeoutsAndRetries(t *testing.T) {
+	task := &HttpRouteReconcilerTask{}
+
+	timeout := 5
+	timeoutMilliseconds := 250
+	perTryTimeoutMilliseconds := 100
+
+	route := &v1alpha1.HttpRoute{
+		Spec: v1alpha1.HttpRouteSpec{
+			Timeout: &timeout,
+			Retries: &v1alpha1.HttpRouteRetries{
+				Attempts:             3,
+				PerTtyTimeoutSeconds: 2,
+				RetryOn:              []string{"gateway-error", "connect-failure"},
+			},
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{Host: "web.shop.svc.cluster.local:80", Weight: 1},
+			},
+		},
+	}
+
+	httpRoute := task.buildIstioHttpRoute(route)
+	assert.Equal(t, &protoTypes.Duration{Seconds: 5}, httpRoute.Timeout)
+	assert.Equal(t, int32(3), httpRoute.Retries.Attempts)
+	assert.Equal(t, &protoTypes.Duration{Seconds: 2}, httpRoute.Retries.PerTryTimeout)
+	assert.Equal(t, "gateway-error,connect-failure", httpRoute.Retries.RetryOn)
+
+	// sub-second timeouts
+	route.Spec.Timeout = nil
+	route.Spec.TimeoutMilliseconds = &timeoutMilliseconds
+	route.Spec.Retries = &v1alpha1.HttpRouteRetries{
+		Attempts:                  3,
+		PerTryTimeoutMilliseconds: &perTryTimeoutMilliseconds,
+		RetryOn:                   []string{"5xx", "reset"},
+	}
+
+	httpRoute = task.buildIstioHttpRoute(route)
+	assert.Equal(t, &protoTypes.Duration{Nanos: 250000000}, httpRoute.Timeout)
+	assert.Equal(t, int32(3), httpRoute.Retries.Attempts)
+	assert.Equal(t, &protoTypes.Duration{Nanos: 100000000}, httpRoute.Retries.PerTryTimeout)
+	assert.Equal(t, "5xx,reset", httpRoute.Retries.RetryOn)
+
+	// no per try timeout
+	route.Spec.Retries.PerTryTimeoutMilliseconds = nil
+	httpRoute = task.buildIstioHttpRoute(route)
+	assert.Equal(t, int32(3), httpRoute.Retries.Attempts)
+	assert.Nil(t, httpRoute.Retries.PerTryTimeout)
+
+	// retries are disabled explicitly
+	route.Spec.Retries = &v1alpha1.HttpRouteRetries{Attempts: 0, PerTtyTimeoutSeconds: 1, RetryOn: []string{"5xx"}}
+	httpRoute = task.buildIstioHttpRoute(route)
+	assert.Equal(t, int32(0), httpRoute.Retries.Attempts)
+	assert.Nil(t, httpRoute.Retries.PerTryTimeout)
+	assert.Equal(t, "", httpRoute.Retries.RetryOn)
+}
+
+// Routes saved by old dashboards carry a 5s timeout and 3 retries, which were never applied
+func TestHttpRouteLegacyTimeoutsMigration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = istioScheme.AddToScheme(scheme)
+
+	newRoute := func(name string, timeout int) *v1alpha1.HttpRoute {
+		return &v1alpha1.HttpRoute{
+			ObjectMeta: v1.ObjectMeta{Name: name},
+			Spec: v1alpha1.HttpRouteSpec{
+				Methods: []v1alpha1.HttpRouteMethod{"GET"},
+				Hosts:   []string{name + ".example.com"},
+				Paths:   []string{"/"},
+				Schemes: []v1alpha1.HttpRouteScheme{"http"},
+				Timeout: &timeout,
+				Retries: &v1alpha1.HttpRouteRetries{
+					Attempts:             3,
+					PerTtyTimeoutSeconds: 2,
+					RetryOn:              []string{"gateway-error", "connect-failure", "refused-stream"},
+				},
+				Destinations: []v1alpha1.HttpRouteDestination{
+					{Host: "web.shop.svc.cluster.local:80", Weight: 1},
+				},
+			},
+		}
+	}
+
+	legacy := newRoute("legacy", 5)
+	custom := newRoute("custom", 10)
+
+	// created since timeouts are applied
+	created := newRoute("created", 5)
+	created.Annotations = map[string]string{v1alpha1.KalmAnnoHttpRouteTimeoutsMigrated: "true"}
+
+	fakeClient := fake.NewFakeClientWithScheme(scheme, legacy, custom, created)
+	recorder := record.NewFakeRecorder(100)
+	reconciler := &HttpRouteReconciler{&BaseReconciler{
+		Client:   fakeClient,
+		Reader:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		Scheme:   scheme,
+		Recorder: recorder,
+	}}
+
+	task := &HttpRouteReconcilerTask{HttpRouteReconciler: reconciler, ctx: context.Background()}
+	assert.Nil(t, task.Run(ctrl.Request{}))
+
+	var route v1alpha1.HttpRoute
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "legacy"}, &route))
+	assert.Nil(t, route.Spec.Timeout)
+	assert.Nil(t, route.Spec.Retries)
+	assert.Equal(t, "true", route.Annotations[v1alpha1.KalmAnnoHttpRouteTimeoutsMigrated])
+	assert.Contains(t, <-recorder.Events, "LegacyTimeoutsRemoved")
+
+	var vs v1beta1.VirtualService
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-legacy-example-com"}, &vs))
+	assert.Nil(t, vs.Spec.Http[0].Timeout)
+	assert.Nil(t, vs.Spec.Http[0].Retries)
+
+	// only the retries are the defaults of old dashboards
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "custom"}, &route))
+	assert.Equal(t, 10, *route.Spec.Timeout)
+	assert.Nil(t, route.Spec.Retries)
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-custom-example-com"}, &vs))
+	assert.Equal(t, &protoTypes.Duration{Seconds: 10}, vs.Spec.Http[0].Timeout)
+
+	// migrated once, the same values set later are applied
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "legacy"}, &route))
+	route.Spec = newRoute("legacy", 5).Spec
+	assert.Nil(t, fakeClient.Update(context.Background(), &route))
+
+	for _, name := range []string{"legacy", "created"} {
+		task = &HttpRouteReconcilerTask{HttpRouteReconciler: reconciler, ctx: context.Background()}
+		assert.Nil(t, task.Run(ctrl.Request{}))
+
+		assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-" + name + "-example-com"}, &vs))
+		assert.Equal(t, &protoTypes.Duration{Seconds: 5}, vs.Spec.Http[0].Timeout)
+		assert.Equal(t, int32(3), vs.Spec.Http[0].Retries.Attempts)
+		assert.Equal(t, &protoTypes.Duration{Seconds: 2}, vs.Spec.Http[0].Retries.PerTryTimeout)
+	}
+}
+
+func TestHttpRouteRetryBudgetEnvoyFilter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = istioScheme.AddToScheme(scheme)
+
+	minRetryConcurrency := 5
+	route := &v1alpha1.HttpRoute{
+		ObjectMeta: v1.ObjectMeta{Name: "shop"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Hosts:   []string{"shop.example.com"},
+			Paths:   []string{"/"},
+			Schemes: []v1alpha1.HttpRouteScheme{"http"},
+			Retries: &v1alpha1.HttpRouteRetries{
+				Attempts: 3,
+				RetryOn:  []string{"5xx"},
+				Budget:   &v1alpha1.HttpRouteRetryBudget{BudgetPercent: 20, MinRetryConcurrency: &minRetryConcurrency},
+			},
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{Host: "web.shop.svc.cluster.local:8080", Weight: 1},
+			},
+		},
+	}
+
+	fakeClient := fake.NewFakeClientWithScheme(scheme, route)
+	reconciler := &HttpRouteReconciler{&BaseReconciler{
+		Client:   fakeClient,
+		Reader:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(100),
+	}}
+
+	run := func() {
+		task := &HttpRouteReconcilerTask{HttpRouteReconciler: reconciler, ctx: context.Background()}
+		assert.Nil(t, task.Run(ctrl.Request{}))
+	}
+
+	run()
+
+	var vs v1beta1.VirtualService
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-shop-example-com"}, &vs))
+	assert.Equal(t, int32(3), vs.Spec.Http[0].Retries.Attempts)
+
+	var filter v1alpha32.EnvoyFilter
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "retry-budget-shop"}, &filter))
+
+	if assert.Len(t, filter.Spec.ConfigPatches, 1) {
+		patch := filter.Spec.ConfigPatches[0]
+		cluster := patch.Match.GetCluster()
+		assert.Equal(t, "web.shop.svc.cluster.local", cluster.Service)
+		assert.Equal(t, uint32(8080), cluster.PortNumber)
+
+		retryBudget := patch.Patch.Value.Fields["circuit_breakers"].GetStructValue().
+			Fields["thresholds"].GetListValue().Values[0].GetStructValue().
+			Fields["retry_budget"].GetStructValue()
+		assert.Equal(t, 20.0, retryBudget.Fields["budget_percent"].GetStructValue().Fields["value"].GetNumberValue())
+		assert.Equal(t, 5.0, retryBudget.Fields["min_retry_concurrency"].GetNumberValue())
+	}
+
+	// the budget is updated
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	route.Spec.Retries.Budget.BudgetPercent = 50
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "retry-budget-shop"}, &filter))
+	retryBudget := filter.Spec.ConfigPatches[0].Patch.Value.Fields["circuit_breakers"].GetStructValue().
+		Fields["thresholds"].GetListValue().Values[0].GetStructValue().
+		Fields["retry_budget"].GetStructValue()
+	assert.Equal(t, 50.0, retryBudget.Fields["budget_percent"].GetStructValue().Fields["value"].GetNumberValue())
+
+	// the filter is removed with the budget
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	route.Spec.Retries.Budget = nil
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	var filters v1alpha32.EnvoyFilterList
+	assert.Nil(t, fakeClient.List(context.Background(), &filters))
+	assert.Len(t, filters.Items, 0)
+}
+
+func TestHttpRouteRateLimitEnvoyFilter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = istioScheme.AddToScheme(scheme)
+
+	assert.Nil(t, os.Setenv(v1alpha1.ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED, "true"))
+	defer os.Unsetenv(v1alpha1.ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED)
+
+	route := &v1alpha1.HttpRoute{
+		ObjectMeta: v1.ObjectMeta{Name: "shop"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Hosts:   []string{"shop.example.com", "*.shop.example.com"},
+			Paths:   []string{"/"},
+			Schemes: []v1alpha1.HttpRouteScheme{"http"},
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{Host: "web.shop.svc.cluster.local:8080", Weight: 1},
+			},
+			RateLimit: &v1alpha1.HttpRouteRateLimit{
+				Requests: 10,
+				Unit:     v1alpha1.HttpRouteRateLimitUnitMinute,
+				Burst:    5,
+				Response: &v1alpha1.HttpRouteRateLimitResponse{
+					Body:    "too many requests",
+					Headers: map[string]string{"Retry-After": "60"},
+				},
+			},
+		},
+	}
+
+	fakeClient := fake.NewFakeClientWithScheme(scheme, route)
+	reconciler := &HttpRouteReconciler{&BaseReconciler{
+		Client:   fakeClient,
+		Reader:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(100),
+	}}
+
+	run := func() {
+		task := &HttpRouteReconcilerTask{HttpRouteReconciler: reconciler, ctx: context.Background()}
+		assert.Nil(t, task.Run(ctrl.Request{}))
+	}
+
+	getPerRouteConfig := func(filter *v1alpha32.EnvoyFilter) *protoTypes.Struct {
+		return filter.Spec.ConfigPatches[0].Patch.Value.Fields["typed_per_filter_config"].GetStructValue().
+			Fields[localRateLimitFilterName].GetStructValue().
+			Fields["value"].GetStructValue()
+	}
+
+	run()
+
+	var sharedFilter v1alpha32.EnvoyFilter
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: localRateLimitEnvoyFilterName}, &sharedFilter))
+
+	var filter v1alpha32.EnvoyFilter
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "rate-limit-shop"}, &filter))
+
+	if assert.Len(t, filter.Spec.ConfigPatches, 2) {
+		assert.Equal(t, "kalm-route-shop", filter.Spec.ConfigPatches[0].Match.GetRouteConfiguration().Vhost.Route.Name)
+
+		config := getPerRouteConfig(&filter)
+		tokenBucket := config.Fields["token_bucket"].GetStructValue()
+		assert.Equal(t, 15.0, tokenBucket.Fields["max_tokens"].GetNumberValue())
+		assert.Equal(t, 10.0, tokenBucket.Fields["tokens_per_fill"].GetNumberValue())
+		assert.Equal(t, "60s", tokenBucket.Fields["fill_interval"].GetStringValue())
+
+		header := config.Fields["response_headers_to_add"].GetListValue().Values[0].GetStructValue().Fields["header"].GetStructValue()
+		assert.Equal(t, "Retry-After", header.Fields["key"].GetStringValue())
+
+		mapper := filter.Spec.ConfigPatches[1].Patch.Value.Fields["typed_config"].GetStructValue().
+			Fields["local_reply_config"].GetStructValue().
+			Fields["mappers"].GetListValue().Values[0].GetStructValue()
+		assert.Equal(t, "too many requests", mapper.Fields["body"].GetStructValue().Fields["inline_string"].GetStringValue())
+
+		hostFilters := mapper.Fields["filter"].GetStructValue().Fields["and_filter"].GetStructValue().
+			Fields["filters"].GetListValue().Values[1].GetStructValue().
+			Fields["or_filter"].GetStructValue().Fields["filters"].GetListValue().Values
+		assert.Len(t, hostFilters, 2)
+	}
+
+	// the 429 body mapper is scoped by the paths and methods of the route
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	route.Spec.Paths = []string{"/api", "/v2"}
+	route.Spec.Methods = []v1alpha1.HttpRouteMethod{"GET", "POST"}
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "rate-limit-shop"}, &filter))
+
+	if assert.Len(t, filter.Spec.ConfigPatches, 2) {
+		assert.Nil(t, getPerRouteConfig(&filter).Fields["descriptors"])
+		assert.Nil(t, filter.Spec.ConfigPatches[0].Patch.Value.Fields["route"])
+		assert.Equal(t, httpConnectionManagerFilterName, filter.Spec.ConfigPatches[1].Match.GetListener().FilterChain.Filter.Name)
+
+		andFilters := filter.Spec.ConfigPatches[1].Patch.Value.Fields["typed_config"].GetStructValue().
+			Fields["local_reply_config"].GetStructValue().
+			Fields["mappers"].GetListValue().Values[0].GetStructValue().
+			Fields["filter"].GetStructValue().Fields["and_filter"].GetStructValue().
+			Fields["filters"].GetListValue().Values
+
+		if assert.Len(t, andFilters, 4) {
+			pathFilters := andFilters[2].GetStructValue().Fields["or_filter"].GetStructValue().Fields["filters"].GetListValue().Values
+			assert.Len(t, pathFilters, 2)
+			assert.Equal(t, "/api", pathFilters[0].GetStructValue().Fields["header_filter"].GetStructValue().
+				Fields["header"].GetStructValue().Fields["prefix_match"].GetStringValue())
+
+			methodRegex := andFilters[3].GetStructValue().Fields["header_filter"].GetStructValue().
+				Fields["header"].GetStructValue().Fields["safe_regex_match"].GetStructValue().Fields["regex"].GetStringValue()
+			assert.Equal(t, "^(GET|POST)$", methodRegex)
+		}
+	}
+
+	assert.Equal(t, httpConnectionManagerFilterName, sharedFilter.Spec.ConfigPatches[0].Match.GetListener().FilterChain.Filter.Name)
+	assert.Equal(t, routerFilterName, sharedFilter.Spec.ConfigPatches[0].Match.GetListener().FilterChain.Filter.SubFilter.Name)
+
+	// the generated filters are valid istio EnvoyFilter protos
+	for _, f := range []*v1alpha32.EnvoyFilter{&sharedFilter, &filter} {
+		var marshaler jsonpb.Marshaler
+		data, err := marshaler.MarshalToString(&f.Spec)
+		assert.Nil(t, err)
+
+		var decoded istioNetworking.EnvoyFilter
+		assert.Nil(t, jsonpb.UnmarshalString(data, &decoded))
+		assert.True(t, proto.Equal(&f.Spec, &decoded))
+	}
+
+	typedConfig := sharedFilter.Spec.ConfigPatches[0].Patch.Value.Fields["typed_config"].GetStructValue()
+	assert.Equal(t, localRateLimitTypeUrl, typedConfig.Fields["type_url"].GetStringValue())
+
+	// keyed by client ip, each client gets its own token bucket
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	route.Spec.RateLimit.Key = &v1alpha1.HttpRouteRateLimitKey{Type: v1alpha1.HttpRouteRateLimitKeyTypeClientIP}
+	route.Spec.RateLimit.Response = nil
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "rate-limit-shop"}, &filter))
+
+	if assert.Len(t, filter.Spec.ConfigPatches, 1) {
+		config := getPerRouteConfig(&filter)
+		assert.Equal(t, float64(math.MaxUint32), config.Fields["token_bucket"].GetStructValue().Fields["max_tokens"].GetNumberValue())
+		assert.Equal(t, float64(rateLimitMaxDynamicDescriptors), config.Fields["max_dynamic_descriptors"].GetNumberValue())
+
+		descriptor := config.Fields["descriptors"].GetListValue().Values[0].GetStructValue()
+		assert.Equal(t, 15.0, descriptor.Fields["token_bucket"].GetStructValue().Fields["max_tokens"].GetNumberValue())
+
+		// no value, a wildcard of all clients
+		entry := descriptor.Fields["entries"].GetListValue().Values[0].GetStructValue()
+		assert.Equal(t, "remote_address", entry.Fields["key"].GetStringValue())
+		assert.Nil(t, entry.Fields["value"])
+
+		action := filter.Spec.ConfigPatches[0].Patch.Value.Fields["route"].GetStructValue().
+			Fields["rate_limits"].GetListValue().Values[0].GetStructValue().
+			Fields["actions"].GetListValue().Values[0].GetStructValue()
+		assert.NotNil(t, action.Fields["remote_address"])
+	}
+
+	// keyed by a jwt claim
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	route.Spec.RateLimit.Key = &v1alpha1.HttpRouteRateLimitKey{Type: v1alpha1.HttpRouteRateLimitKeyTypeJWTClaim, Name: "sub", Issuer: "https://auth.example.com"}
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "rate-limit-shop"}, &filter))
+	action := filter.Spec.ConfigPatches[0].Patch.Value.Fields["route"].GetStructValue().
+		Fields["rate_limits"].GetListValue().Values[0].GetStructValue().
+		Fields["actions"].GetListValue().Values[0].GetStructValue().
+		Fields["dynamic_metadata"].GetStructValue()
+	assert.Equal(t, "jwt_claim_sub", action.Fields["descriptor_key"].GetStringValue())
+
+	metadataPath := action.Fields["metadata_key"].GetStructValue().Fields["path"].GetListValue().Values
+	if assert.Len(t, metadataPath, 2) {
+		assert.Equal(t, "https://auth.example.com", metadataPath[0].GetStructValue().Fields["key"].GetStringValue())
+		assert.Equal(t, "sub", metadataPath[1].GetStructValue().Fields["key"].GetStringValue())
+	}
+
+	var filters v1alpha32.EnvoyFilterList
+
+	// not supported by the ingress gateways, the filters are not saved and the route reports the error
+	assert.Nil(t, os.Unsetenv(v1alpha1.ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED))
+	run()
+
+	assert.Nil(t, fakeClient.List(context.Background(), &filters))
+	assert.Len(t, filters.Items, 0)
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "shop"}, route))
+	if assert.Len(t, route.Status.EnvoyFilters, 1) {
+		assert.Equal(t, "rate-limit-shop", route.Status.EnvoyFilters[0].Name)
+		assert.Equal(t, "error", route.Status.EnvoyFilters[0].Status)
+	}
+
+	assert.Nil(t, os.Setenv(v1alpha1.ENV_HTTP_ROUTE_RATE_LIMIT_ENABLED, "true"))
+
+	// filters are removed with the rate limit
+	route.Spec.RateLimit = nil
+	assert.Nil(t, fakeClient.Update(context.Background(), route))
+	run()
+
+	assert.Nil(t, fakeClient.List(context.Background(), &filters))
+	assert.Len(t, filters.Items, 0)
+}
+
+func TestBuildMatchesConditions(t *testing.T) {
+	task := &HttpRouteReconcilerTask{}
+
+	route := &v1alpha1.HttpRoute{
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Paths:   []string{"/api"},
+			Schemes: []v1alpha1.HttpRouteScheme{"https"},
+			Conditions: []v1alpha1.HttpRouteCondition{
+				{Type: v1alpha1.HttpRouteConditionTypeHeader, Name: "x-canary", Operator: v1alpha1.HRCONotEqual, Value: "false"},
+				{Type: v1alpha1.HttpRouteConditionTypeAuthority, Operator: v1alpha1.HRCOWithPrefix, Value: "api."},
+			},
+			ConditionGroups: []v1alpha1.HttpRouteConditionGroup{
+				{Conditions: []v1alpha1.HttpRouteCondition{
+					{Type: v1alpha1.HttpRouteConditionTypeCookie, Name: "beta", Operator: v1alpha1.HRCOEqual, Value: "1"},
+					{Type: v1alpha1.HttpRouteConditionTypeCookie, Name: "region", Operator: v1alpha1.HRCOWithPrefix, Value: "eu-"},
+					{Type: v1alpha1.HttpRouteConditionTypeCookie, Name: "banned", Operator: v1alpha1.HRCONotEqual, Value: "1"},
+					{Type: v1alpha1.HttpRouteConditionTypeCookie, Name: "internal", Operator: v1alpha1.HRCONotEqual, Value: "1"},
+				}},
+				{Conditions: []v1alpha1.HttpRouteCondition{
+					{Type: v1alpha1.HttpRouteConditionTypeSourceIP, Operator: v1alpha1.HRCOEqual, Value: "10.1.0.0/15"},
+					{Type: v1alpha1.HttpRouteConditionTypeQuery, Name: "debug", Operator: v1alpha1.HRCOEqual, Value: "1"},
+				}},
+			},
+		},
+	}
+
+	matches, err := task.BuildMatches(route)
+	assert.Nil(t, err)
+
+	if assert.Len(t, matches, 2) {
+		for _, match := range matches {
+			assert.Equal(t, "false", match.WithoutHeaders["X-Canary"].GetExact())
+			assert.Equal(t, "api.", match.Authority.GetPrefix())
+			assert.Equal(t, "/api", match.Uri.GetPrefix())
+		}
+
+		// cookie conditions are combined
+		cookie := matches[0].Headers["Cookie"].GetRegex()
+		assert.Regexp(t, cookie, "beta=1; region=eu-west")
+		assert.Regexp(t, cookie, "region=eu-west; theme=dark; beta=1")
+		assert.NotRegexp(t, cookie, "beta=1; region=us-east")
+		assert.NotRegexp(t, cookie, "region=eu-west")
+
+		notCookie := matches[0].WithoutHeaders["Cookie"].GetRegex()
+		assert.Regexp(t, notCookie, "beta=1; internal=1")
+		assert.Regexp(t, notCookie, "banned=1")
+		assert.NotRegexp(t, notCookie, "beta=1; banned=0")
+		assert.Nil(t, matches[0].QueryParams)
+
+		assert.Equal(t, `^10\.(?:0|1)\.[0-9]{1,3}\.[0-9]{1,3}$`, matches[1].Headers["X-Envoy-External-Address"].GetRegex())
+		assert.Equal(t, "1", matches[1].QueryParams["debug"].GetExact())
+	}
+}
+
+func TestConditionToStringMatch(t *testing.T) {
+	_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
+	regex, err := ipv4CIDRToRegexp(ipNet)
+	assert.Nil(t, err)
+	assert.Equal(t, `^192\.168\.1\.[0-9]{1,3}$`, regex)
+
+	_, ipNet, _ = net.ParseCIDR("172.16.0.0/12")
+	regex, _ = ipv4CIDRToRegexp(ipNet)
+	assert.Regexp(t, regex, "172.31.255.1")
+	assert.NotRegexp(t, regex, "172.32.0.1")
+
+	match, err := conditionToStringMatch(v1alpha1.HttpRouteCondition{Type: v1alpha1.HttpRouteConditionTypeSourceIP, Operator: v1alpha1.HRCONotEqual, Value: "1.2.3.4"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.3.4", match.GetExact())
+
+	// ipv6 cidrs are not supported
+	_, err = conditionToStringMatch(v1alpha1.HttpRouteCondition{Type: v1alpha1.HttpRouteConditionTypeSourceIP, Operator: v1alpha1.HRCOEqual, Value: "2001:db8::/32"})
+	assert.NotNil(t, err)
+
+	cookie := cookieConditionsToRegexp([]v1alpha1.HttpRouteCondition{{Name: "session", Operator: v1alpha1.HRCOWithoutPrefix, Value: "guest."}})
+	assert.Regexp(t, cookie, "theme=dark; session=guest.42; lang=en")
+	assert.NotRegexp(t, cookie, "theme=dark; session=user.42")
+	assert.NotRegexp(t, cookie, "mysession=guest.42")
+}
+
+func TestBuildIstioHttpRouteRewriteAndHeaders(t *testing.T) {
+	task := &HttpRouteReconcilerTask{}
+
+	route := &v1alpha1.HttpRoute{
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Paths:   []string{"/api"},
+			Schemes: []v1alpha1.HttpRouteScheme{"http"},
+			Rewrite: &v1alpha1.HttpRouteRewrite{Prefix: "/v2/", Host: "api.internal"},
+			Headers: &v1alpha1.HttpRouteHeaders{
+				Request: &v1alpha1.HttpRouteHeaderOperations{
+					Set:    map[string]string{"X-Forwarded-Prefix": "/api", KALM_SSO_USERINFO_HEADER: "forged"},
+					Add:    map[string]string{"X-Trace": "kalm"},
+					Remove: []string{"Cookie", KALM_ROUTE_HEADER},
+				},
+				Response: &v1alpha1.HttpRouteHeaderOperations{
+					Set:    map[string]string{"Cache-Control": "no-store"},
+					Remove: []string{"Server"},
+				},
+			},
+			Destinations: []v1alpha1.HttpRouteDestination{
+				{Host: "web.shop.svc.cluster.local:80", Weight: 1},
+			},
+		},
+	}
+
+	httpRoute := task.buildIstioHttpRoute(route)
+	assert.Equal(t, "/v2/", httpRoute.Rewrite.Uri)
+	assert.Equal(t, "api.internal", httpRoute.Rewrite.Authority)
+
+	request := httpRoute.Headers.Request
+	assert.Equal(t, map[string]string{KALM_ROUTE_HEADER: "true", "X-Forwarded-Prefix": "/api"}, request.Set)
+	assert.Equal(t, map[string]string{"X-Trace": "kalm"}, request.Add)
+	assert.Equal(t, append(append([]string{}, DANGEROUS_HEADERS...), "Cookie"), request.Remove)
+	assert.Len(t, DANGEROUS_HEADERS, 4)
+
+	assert.Equal(t, map[string]string{"Cache-Control": "no-store"}, httpRoute.Headers.Response.Set)
+	assert.Equal(t, []string{"Server"}, httpRoute.Headers.Response.Remove)
+
+	// the prefix ends with a slash, requests of /api/ are matched separately to avoid double slashes
+	matches, err := task.BuildMatches(route)
+	assert.Nil(t, err)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, "/api", matches[0].Uri.GetPrefix())
+		assert.Equal(t, "/api/", matches[1].Uri.GetPrefix())
+	}
+
+	// headers are untouched without user operations
+	route.Spec.Headers = nil
+	route.Spec.Rewrite = nil
+	httpRoute = task.buildIstioHttpRoute(route)
+	assert.Nil(t, httpRoute.Rewrite)
+	assert.Equal(t, DANGEROUS_HEADERS, httpRoute.Headers.Request.Remove)
+	assert.Nil(t, httpRoute.Headers.Response)
+}
+
+func TestHttpRouteRedirectAndDirectResponse(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = istioScheme.AddToScheme(scheme)
+
+	redirectRoute := &v1alpha1.HttpRoute{
+		ObjectMeta: v1.ObjectMeta{Name: "moved"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Hosts:   []string{"old.example.com"},
+			Paths:   []string{"/"},
+			Schemes: []v1alpha1.HttpRouteScheme{"http", "https"},
+			Redirect: &v1alpha1.HttpRouteRedirect{
+				Scheme: "https",
+				Host:   "new.example.com",
+			},
+		},
+	}
+
+	maintenanceRoute := &v1alpha1.HttpRoute{
+		ObjectMeta: v1.ObjectMeta{Name: "maintenance"},
+		Spec: v1alpha1.HttpRouteSpec{
+			Methods: []v1alpha1.HttpRouteMethod{"GET"},
+			Hosts:   []string{"shop.example.com"},
+			Paths:   []string{"/"},
+			Schemes: []v1alpha1.HttpRouteScheme{"https"},
+			DirectResponse: &v1alpha1.HttpRouteDirectResponse{
+				StatusCode:  503,
+				Body:        "<h1>Under maintenance</h1>",
+				ContentType: "text/html",
+			},
+		},
+	}
+
+	fakeClient := fake.NewFakeClientWithScheme(scheme, redirectRoute, maintenanceRoute)
+	reconciler := &HttpRouteReconciler{&BaseReconciler{
+		Client:   fakeClient,
+		Reader:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(100),
+	}}
+
+	task := &HttpRouteReconcilerTask{HttpRouteReconciler: reconciler, ctx: context.Background()}
+	assert.Nil(t, task.Run(ctrl.Request{}))
+
+	var vs v1beta1.VirtualService
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-old-example-com"}, &vs))
+	assert.Nil(t, vs.Spec.Http[0].Route)
+	assert.Equal(t, "new.example.com", vs.Spec.Http[0].Redirect.Authority)
+	assert.Equal(t, "", vs.Spec.Http[0].Redirect.Uri)
+	assert.Equal(t, uint32(301), vs.Spec.Http[0].Redirect.RedirectCode)
+
+	var filter v1alpha32.EnvoyFilter
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "redirect-scheme-moved"}, &filter))
+	assert.True(t, filter.Spec.ConfigPatches[0].Patch.Value.Fields["redirect"].GetStructValue().Fields["https_redirect"].GetBoolValue())
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: "kalm-system", Name: "vs-shop-example-com"}, &vs))
+	assert.Equal(t, directResponsePlaceholderHost, vs.Spec.Http[0].Route[0].Destination.Host)
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Namespace: istioNamespace, Name: "direct-response-maintenance"}, &filter))
+	patch := filter.Spec.ConfigPatches[0]
+	assert.Equal(t, "kalm-route-maintenance", patch.Match.GetRouteConfiguration().Vhost.Route.Name)
+
+	directResponse := patch.Patch.Value.Fields["direct_response"].GetStructValue()
+	assert.Equal(t, 503.0, directResponse.Fields["status"].GetNumberValue())
+	assert.Equal(t, "<h1>Under maintenance</h1>", directResponse.Fields["body"].GetStructValue().Fields["inline_string"].GetStringValue())
+
+	header := patch.Patch.Value.Fields["response_headers_to_add"].GetListValue().Values[0].GetStructValue().Fields["header"].GetStructValue()
+	assert.Equal(t, "text/html", header.Fields["value"].GetStringValue())
+
+	// the route generated by istio for the placeholder destination
+	istioRoute := map[string]interface{}{
+		"name":  "kalm-route-maintenance",
+		"match": map[string]interface{}{"prefix": "/"},
+		"route": map[string]interface{}{"cluster": "outbound|80||" + directResponsePlaceholderHost},
+	}
+
+	merged := mergeEnvoyRoute(istioRoute, protoStructToMap(t, patch.Patch.Value))
+	assertValidEnvoyRouteAction(t, merged)
+	assert.Nil(t, merged["route"])
+	assert.Equal(t, "kalm-route-maintenance", merged["name"])
+
+	// statuses of saved filters are in routes
+	var saved v1alpha1.HttpRoute
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "maintenance"}, &saved))
+	assert.Equal(t, []v1alpha1.HttpRouteEnvoyFilterStatus{{Name: "direct-response-maintenance", Status: "normal"}}, saved.Status.EnvoyFilters)
+
+	assert.Nil(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "moved"}, &saved))
+	assert.Equal(t, []v1alpha1.HttpRouteEnvoyFilterStatus{{Name: "redirect-scheme-moved", Status: "normal"}}, saved.Status.EnvoyFilters)
+}
+
+// fields of the envoy.config.route.v3.Route oneof action
+var envoyRouteActionFields = []string{"route", "redirect", "direct_response", "filter_action"}
+
+var envoyRouteFields = map[string]bool{
+	"name": true, "match": true, "route": true, "redirect": true, "direct_response": true, "filter_action": true,
+	"metadata": true, "decorator": true, "typed_per_filter_config": true, "tracing": true, "per_request_buffer_limit_bytes": true,
+	"request_headers_to_add": true, "request_headers_to_remove": true, "response_headers_to_add": true, "response_headers_to_remove": true,
+}
+
+func protoStructToMap(t *testing.T, value *protoTypes.Struct) map[string]interface{} {
+	var marshaler jsonpb.Marshaler
+	data, err := marshaler.MarshalToString(value)
+	assert.Nil(t, err)
+
+	var res map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(data), &res))
+
+	return res
+}
+
+// mergeEnvoyRoute merges the patch the way proto MergeFrom does for the top level fields of a route,
+// setting a field of the oneof action clears the other ones.
+func mergeEnvoyRoute(route, patch map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(route))
+
+	for key, value := range route {
+		res[key] = value
+	}
+
+	for key, value := range patch {
+		for _, action := range envoyRouteActionFields {
+			if key == action {
+				for _, other := range envoyRouteActionFields {
+					delete(res, other)
+				}
+			}
+		}
+
+		if list, ok := value.([]interface{}); ok {
+			existing, _ := res[key].([]interface{})
+			res[key] = append(existing, list...)
+		} else {
+			res[key] = value
+		}
+	}
+
+	return res
+}
+
+func assertValidEnvoyRouteAction(t *testing.T, route map[string]interface{}) {
+	for key := range route {
+		assert.True(t, envoyRouteFields[key], "unknown route field %s", key)
+	}
+
+	actions := 0
+
+	for _, action := range envoyRouteActionFields {
+		if route[action] != nil {
+			actions++
+		}
+	}
+
+	assert.Equal(t, 1, actions)
+
+	if directResponse, ok := route["direct_response"].(map[string]interface{}); ok {
+		status, _ := directResponse["status"].(float64)
+		assert.True(t, status >= 200 && status <= 599)
+
+		for key := range directResponse {
+			assert.Contains(t, []string{"status", "body"}, key)
+		}
+
+		if body, ok := directResponse["body"].(map[string]interface{}); ok {
+			assert.Len(t, body, 1)
+			assert.Contains(t, []string{"filename", "inline_bytes", "inline_string"}, keysOf(body)[0])
+		}
+	}
+}
+
+func keysOf(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+	v1alpha32 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+)
+
+// The local rate limit filter is inserted into the http connection manager of ingress gateways once,
+// it's disabled by default and only enabled on routes with a rate limit by the per route config.
+const localRateLimitEnvoyFilterName = "kalm-local-rate-limit"
+
+const localRateLimitFilterName = "envoy.filters.http.local_ratelimit"
+const localRateLimitTypeUrl = "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit"
+
+// use the canonical filter names, the deprecated names are removed in later envoy versions
+const httpConnectionManagerFilterName = "envoy.filters.network.http_connection_manager"
+const routerFilterName = "envoy.filters.http.router"
+
+func getRateLimitEnvoyFilterName(route *corev1alpha1.HttpRoute) string {
+	return fmt.Sprintf("rate-limit-%s", route.Name)
+}
+
+func buildLocalRateLimitConfig(value map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"@type":    "type.googleapis.com/udpa.type.v1.TypedStruct",
+		"type_url": localRateLimitTypeUrl,
+		"value":    value,
+	}
+}
+
+func buildRateLimitTokenBucket(maxTokens, tokensPerFill int, unit corev1alpha1.HttpRouteRateLimitUnit) map[string]interface{} {
+	fillInterval := "1s"
+
+	if unit == corev1alpha1.HttpRouteRateLimitUnitMinute {
+		fillInterval = "60s"
+	}
+
+	return map[string]interface{}{
+		"max_tokens":      maxTokens,
+		"tokens_per_fill": tokensPerFill,
+		"fill_interval":   fillInterval,
+	}
+}
+
+func buildFullFractionalPercent(runtimeKey string) map[string]interface{} {
+	return map[string]interface{}{
+		"runtime_key": runtimeKey,
+		"default_value": map[string]interface{}{
+			"numerator":   100,
+			"denominator": "HUNDRED",
+		},
+	}
+}
+
+func (r *HttpRouteReconcilerTask) buildLocalRateLimitEnvoyFilter() *v1alpha32.EnvoyFilter {
+	return &v1alpha32.EnvoyFilter{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: istioNamespace,
+			Name:      localRateLimitEnvoyFilterName,
+			Labels: map[string]string{
+				KALM_ROUTE_LABEL: "true",
+			},
+		},
+		Spec: v1alpha3.EnvoyFilter{
+			WorkloadSelector: &v1alpha3.WorkloadSelector{
+				Labels: map[string]string{
+					"app": "istio-ingressgateway",
+				},
+			},
+			ConfigPatches: []*v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: v1alpha3.EnvoyFilter_HTTP_FILTER,
+					Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+						Context: v1alpha3.EnvoyFilter_GATEWAY,
+						ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+							Listener: &v1alpha3.EnvoyFilter_ListenerMatch{
+								FilterChain: &v1alpha3.EnvoyFilter_ListenerMatch_FilterChainMatch{
+									Filter: &v1alpha3.EnvoyFilter_ListenerMatch_FilterMatch{
+										Name: httpConnectionManagerFilterName,
+										SubFilter: &v1alpha3.EnvoyFilter_ListenerMatch_SubFilterMatch{
+											Name: routerFilterName,
+										},
+									},
+								},
+							},
+						},
+					},
+					Patch: &v1alpha3.EnvoyFilter_Patch{
+						Operation: v1alpha3.EnvoyFilter_Patch_INSERT_BEFORE,
+						Value: golangMapToProtoStruct(map[string]interface{}{
+							"name": localRateLimitFilterName,
+							"typed_config": buildLocalRateLimitConfig(map[string]interface{}{
+								"stat_prefix": "http_local_rate_limiter",
+								// token bucket is required, the filter is not enabled without filter_enabled
+								"token_bucket": buildRateLimitTokenBucket(math.MaxUint32, math.MaxUint32, corev1alpha1.HttpRouteRateLimitUnitSecond),
+							}),
+						}),
+					},
+				},
+			},
+		},
+	}
+}
+
+// max keys of a keyed rate limit tracked by each ingress gateway replica, the least recently used ones are evicted
+const rateLimitMaxDynamicDescriptors = 10000
+
+// Kalm route level rate limit is achieved by the per route config of the envoy local rate limit filter.
+// Limits are counted by each ingress gateway replica separately.
+//
+// For a keyed rate limit, the key is extracted into a descriptor by the route rate limit actions.
+// The descriptor of the filter has no value, as a wildcard each value of the key gets its own token bucket,
+// while the route as a whole is not limited.
+func (r *HttpRouteReconcilerTask) buildRateLimitEnvoyFilter(route *corev1alpha1.HttpRoute) *v1alpha32.EnvoyFilter {
+	rateLimit := route.Spec.RateLimit
+
+	tokenBucket := buildRateLimitTokenBucket(rateLimit.Requests+rateLimit.Burst, rateLimit.Requests, rateLimit.Unit)
+
+	config := map[string]interface{}{
+		"stat_prefix":     "http_local_rate_limiter",
+		"token_bucket":    tokenBucket,
+		"filter_enabled":  buildFullFractionalPercent("local_rate_limit_enabled"),
+		"filter_enforced": buildFullFractionalPercent("local_rate_limit_enforced"),
+	}
+
+	if rateLimit.Response != nil && len(rateLimit.Response.Headers) > 0 {
+		names := make([]string, 0, len(rateLimit.Response.Headers))
+
+		for name := range rateLimit.Response.Headers {
+			names = append(names, name)
+		}
+
+		// keep the order stable, or the filter is updated in each reconcile
+		sort.Strings(names)
+
+		headers := make([]interface{}, 0, len(names))
+
+		for _, name := range names {
+			headers = append(headers, map[string]interface{}{
+				"append": false,
+				"header": map[string]interface{}{
+					"key":   name,
+					"value": rateLimit.Response.Headers[name],
+				},
+			})
+		}
+
+		config["response_headers_to_add"] = headers
+	}
+
+	routePatch := map[string]interface{}{}
+
+	if rateLimit.Key != nil {
+		descriptorKey, action := buildRateLimitKeyAction(rateLimit.Key)
+
+		config["descriptors"] = []interface{}{
+			map[string]interface{}{
+				"entries": []interface{}{
+					map[string]interface{}{"key": descriptorKey},
+				},
+				"token_bucket": tokenBucket,
+			},
+		}
+
+		config["max_dynamic_descriptors"] = rateLimitMaxDynamicDescriptors
+		config["token_bucket"] = buildRateLimitTokenBucket(math.MaxUint32, math.MaxUint32, rateLimit.Unit)
+
+		routePatch["route"] = map[string]interface{}{
+			"rate_limits": []interface{}{
+				map[string]interface{}{
+					"actions": []interface{}{action},
+				},
+			},
+		}
+	}
+
+	routePatch["typed_per_filter_config"] = map[string]interface{}{
+		localRateLimitFilterName: buildLocalRateLimitConfig(config),
+	}
+
+	filter := &v1alpha32.EnvoyFilter{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: istioNamespace,
+			Name:      getRateLimitEnvoyFilterName(route),
+			Labels: map[string]string{
+				KALM_ROUTE_LABEL: "true",
+			},
+		},
+		Spec: v1alpha3.EnvoyFilter{
+			WorkloadSelector: &v1alpha3.WorkloadSelector{
+				Labels: map[string]string{
+					"app": "istio-ingressgateway",
+				},
+			},
+			ConfigPatches: []*v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: v1alpha3.EnvoyFilter_HTTP_ROUTE,
+					Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+						Context: v1alpha3.EnvoyFilter_GATEWAY,
+						ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_RouteConfiguration{
+							RouteConfiguration: &v1alpha3.EnvoyFilter_RouteConfigurationMatch{
+								Vhost: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_VirtualHostMatch{
+									Route: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_RouteMatch{
+										Name: getIstioHttpRouteName(route),
+									},
+								},
+							},
+						},
+					},
+					Patch: &v1alpha3.EnvoyFilter_Patch{
+						Operation: v1alpha3.EnvoyFilter_Patch_MERGE,
+						Value:     golangMapToProtoStruct(routePatch),
+					},
+				},
+			},
+		},
+	}
+
+	if rateLimit.Response != nil && rateLimit.Response.Body != "" {
+		filter.Spec.ConfigPatches = append(filter.Spec.ConfigPatches, buildRateLimitResponseBodyPatch(route))
+	}
+
+	return filter
+}
+
+// buildRateLimitKeyAction returns the descriptor key and the route rate limit action extracting the value of the key
+func buildRateLimitKeyAction(key *corev1alpha1.HttpRouteRateLimitKey) (string, map[string]interface{}) {
+	switch key.Type {
+	case corev1alpha1.HttpRouteRateLimitKeyTypeHeader:
+		descriptorKey := "header_" + strings.ToLower(key.Name)
+
+		return descriptorKey, map[string]interface{}{
+			"request_headers": map[string]interface{}{
+				"header_name":    key.Name,
+				"descriptor_key": descriptorKey,
+			},
+		}
+	case corev1alpha1.HttpRouteRateLimitKeyTypeJWTClaim:
+		descriptorKey := "jwt_claim_" + key.Name
+
+		// istio saves the verified jwt payload in the dynamic metadata of jwt_authn filter under the issuer
+		return descriptorKey, map[string]interface{}{
+			"dynamic_metadata": map[string]interface{}{
+				"descriptor_key": descriptorKey,
+				"metadata_key": map[string]interface{}{
+					"key": "envoy.filters.http.jwt_authn",
+					"path": []interface{}{
+						map[string]interface{}{"key": key.Issuer},
+						map[string]interface{}{"key": key.Name},
+					},
+				},
+			},
+		}
+	default:
+		// the address of the client seen by the ingress gateway, the same as the one matched by sourceIP conditions
+		return "remote_address", map[string]interface{}{
+			"remote_address": map[string]interface{}{},
+		}
+	}
+}
+
+func headerFilter(header map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"header_filter": map[string]interface{}{
+			"header": header,
+		},
+	}
+}
+
+// anyOfFilters returns an access log filter matches if any of the filters matches
+func anyOfFilters(filters []interface{}) interface{} {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+
+	return map[string]interface{}{
+		"or_filter": map[string]interface{}{
+			"filters": filters,
+		},
+	}
+}
+
+// There is no per route local reply config, the 429 body is mapped by the http connection manager.
+// The mapper is scoped to the route by its hosts, paths and methods, as the access log filters of
+// local reply mappers can only inspect the request headers. Conditions of the route are not considered,
+// if rate limited routes with bodies only differ by conditions, any of the bodies may be used.
+func buildRateLimitResponseBodyPatch(route *corev1alpha1.HttpRoute) *v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch {
+	hostFilters := make([]interface{}, 0, len(route.Spec.Hosts))
+
+	for _, host := range route.Spec.Hosts {
+		header := map[string]interface{}{
+			"name": ":authority",
+		}
+
+		if strings.HasPrefix(host, "*") {
+			header["suffix_match"] = strings.TrimPrefix(host, "*")
+		} else {
+			header["exact_match"] = host
+		}
+
+		hostFilters = append(hostFilters, headerFilter(header))
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{
+			"status_code_filter": map[string]interface{}{
+				"comparison": map[string]interface{}{
+					"op": "EQ",
+					"value": map[string]interface{}{
+						"default_value": 429,
+						"runtime_key":   "local_rate_limit_status_code",
+					},
+				},
+			},
+		},
+		anyOfFilters(hostFilters),
+	}
+
+	pathFilters := make([]interface{}, 0, len(route.Spec.Paths))
+
+	for _, path := range route.Spec.Paths {
+		if path == "/" {
+			pathFilters = nil
+			break
+		}
+
+		pathFilters = append(pathFilters, headerFilter(map[string]interface{}{
+			"name":         ":path",
+			"prefix_match": path,
+		}))
+	}
+
+	if len(pathFilters) > 0 {
+		filters = append(filters, anyOfFilters(pathFilters))
+	}
+
+	if !isAllowAllMethods(route.Spec.Methods) && len(route.Spec.Methods) > 0 {
+		methods := make([]string, 0, len(route.Spec.Methods))
+
+		for _, method := range route.Spec.Methods {
+			methods = append(methods, string(method))
+		}
+
+		filters = append(filters, headerFilter(map[string]interface{}{
+			"name": ":method",
+			"safe_regex_match": map[string]interface{}{
+				"google_re2": map[string]interface{}{},
+				"regex":      "^(" + strings.Join(methods, "|") + ")$",
+			},
+		}))
+	}
+
+	return &v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+		ApplyTo: v1alpha3.EnvoyFilter_NETWORK_FILTER,
+		Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+			Context: v1alpha3.EnvoyFilter_GATEWAY,
+			ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+				Listener: &v1alpha3.EnvoyFilter_ListenerMatch{
+					FilterChain: &v1alpha3.EnvoyFilter_ListenerMatch_FilterChainMatch{
+						Filter: &v1alpha3.EnvoyFilter_ListenerMatch_FilterMatch{
+							Name: httpConnectionManagerFilterName,
+						},
+					},
+				},
+			},
+		},
+		Patch: &v1alpha3.EnvoyFilter_Patch{
+			Operation: v1alpha3.EnvoyFilter_Patch_MERGE,
+			Value: golangMapToProtoStruct(map[string]interface{}{
+				"typed_config": map[string]interface{}{
+					"@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+					"local_reply_config": map[string]interface{}{
+						"mappers": []interface{}{
+							map[string]interface{}{
+								"filter": map[string]interface{}{
+									"and_filter": map[string]interface{}{
+										"filters": filters,
+									},
+								},
+								"body": map[string]interface{}{
+									"inline_string": route.Spec.RateLimit.Response.Body,
+								},
+							},
+						},
+					},
+				},
+			}),
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"fmt"
+
+	"istio.io/api/networking/v1alpha3"
+	istioNetworkingV1Beta1 "istio.io/api/networking/v1beta1"
+	v1alpha32 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+)
+
+// Istio 1.7 has no direct response, a virtual service route requires destinations or a redirect.
+// The route of a direct response points to this non-existent service, and its action is replaced
+// by the direct response envoy filter. Requests get 503 before the filter is applied.
+const directResponsePlaceholderHost = "kalm-direct-response.kalm-system.svc.cluster.local"
+
+func getRedirectSchemeEnvoyFilterName(route *corev1alpha1.HttpRoute) string {
+	return fmt.Sprintf("redirect-scheme-%s", route.Name)
+}
+
+func getDirectResponseEnvoyFilterName(route *corev1alpha1.HttpRoute) string {
+	return fmt.Sprintf("direct-response-%s", route.Name)
+}
+
+func buildIstioHttpRedirect(redirect *corev1alpha1.HttpRouteRedirect) *istioNetworkingV1Beta1.HTTPRedirect {
+	code := redirect.StatusCode
+
+	if code == 0 {
+		code = 301
+	}
+
+	return &istioNetworkingV1Beta1.HTTPRedirect{
+		Uri:          redirect.Path,
+		Authority:    redirect.Host,
+		RedirectCode: uint32(code),
+	}
+}
+
+func buildHttpRouteActionEnvoyFilter(route *corev1alpha1.HttpRoute, name string, value map[string]interface{}) *v1alpha32.EnvoyFilter {
+	return &v1alpha32.EnvoyFilter{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: istioNamespace,
+			Name:      name,
+			Labels: map[string]string{
+				KALM_ROUTE_LABEL: "true",
+			},
+		},
+		Spec: v1alpha3.EnvoyFilter{
+			WorkloadSelector: &v1alpha3.WorkloadSelector{
+				Labels: map[string]string{
+					"app": "istio-ingressgateway",
+				},
+			},
+			ConfigPatches: []*v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: v1alpha3.EnvoyFilter_HTTP_ROUTE,
+					Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+						Context: v1alpha3.EnvoyFilter_GATEWAY,
+						ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_RouteConfiguration{
+							RouteConfiguration: &v1alpha3.EnvoyFilter_RouteConfigurationMatch{
+								Vhost: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_VirtualHostMatch{
+									Route: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_RouteMatch{
+										Name: getIstioHttpRouteName(route),
+									},
+								},
+							},
+						},
+					},
+					Patch: &v1alpha3.EnvoyFilter_Patch{
+						Operation: v1alpha3.EnvoyFilter_Patch_MERGE,
+						Value:     golangMapToProtoStruct(value),
+					},
+				},
+			},
+		},
+	}
+}
+
+// The scheme of istio redirects can't be changed, it's merged into the redirect action on ingress gateways.
+func (r *HttpRouteReconcilerTask) buildRedirectSchemeEnvoyFilter(route *corev1alpha1.HttpRoute) *v1alpha32.EnvoyFilter {
+	redirect := map[string]interface{}{
+		"scheme_redirect": route.Spec.Redirect.Scheme,
+	}
+
+	// the port is also changed to 443 by https_redirect
+	if route.Spec.Redirect.Scheme == "https" {
+		redirect = map[string]interface{}{
+			"https_redirect": true,
+		}
+	}
+
+	return buildHttpRouteActionEnvoyFilter(route, getRedirectSchemeEnvoyFilterName(route), map[string]interface{}{
+		"redirect": redirect,
+	})
+}
+
+// The route action of envoy is a oneof, merging direct_response drops the placeholder destination.
+func (r *HttpRouteReconcilerTask) buildDirectResponseEnvoyFilter(route *corev1alpha1.HttpRoute) *v1alpha32.EnvoyFilter {
+	directResponse := route.Spec.DirectResponse
+
+	response := map[string]interface{}{
+		"status": directResponse.StatusCode,
+	}
+
+	if directResponse.Body != "" {
+		response["body"] = map[string]interface{}{
+			"inline_string": directResponse.Body,
+		}
+	}
+
+	value := map[string]interface{}{
+		"direct_response": response,
+	}
+
+	if directResponse.ContentType != "" {
+		value["response_headers_to_add"] = []interface{}{
+			map[string]interface{}{
+				"append": false,
+				"header": map[string]interface{}{
+					"key":   "content-type",
+					"value": directResponse.ContentType,
+				},
+			},
+		}
+	}
+
+	return buildHttpRouteActionEnvoyFilter(route, getDirectResponseEnvoyFilterName(route), value)
+}
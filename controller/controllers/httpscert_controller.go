@@ -63,6 +63,11 @@ const istioNamespace = "istio-system"
 func (r *HttpsCertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	var httpsCert corev1alpha1.HttpsCert
 	if err := r.Get(r.ctx, req.NamespacedName, &httpsCert); err != nil {
+		if errors.IsNotFound(err) {
+			httpsCertExpiryCollector.Delete(req.Name)
+			httpsCertReady.DeleteLabelValues(req.Name)
+		}
+
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -105,8 +110,22 @@ func (r *HttpsCertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 		}
 
-		r.Status().Update(r.ctx, &httpsCert)
+		requeueAfter := r.checkHttpsCertExpiry(&httpsCert)
+
+		// the expiry warning is kept in status, it's emitted again if the status is not saved
+		if updateErr := r.Status().Update(r.ctx, &httpsCert); updateErr != nil {
+			return ctrl.Result{}, updateErr
+		}
+
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
 	} else {
+		// certs of ACMEDNSProvider issuers are issued by kalm, not cert-manager
+		var issuer corev1alpha1.HttpsCertIssuer
+		if err := r.Get(r.ctx, client.ObjectKey{Name: httpsCert.Spec.HttpsCertIssuer}, &issuer); err == nil &&
+			issuer.Spec.ACMEDNSProvider != nil {
+			return r.reconcileForACMEDNSProviderHttpsCert(r.ctx, httpsCert, issuer)
+		}
+
 		// if is wildcard cert, check if acme-dns is ready
 		if httpsCert.Spec.HttpsCertIssuer == corev1alpha1.DefaultDNS01IssuerName {
 			ready, err := r.isACMEServerReadyForWildcardCert()
@@ -121,10 +140,9 @@ func (r *HttpsCertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 		}
 
-		err = r.reconcileForAutoManagedHttpsCert(r.ctx, httpsCert)
+		requeueAfter, err := r.reconcileForAutoManagedHttpsCert(r.ctx, httpsCert)
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
 	}
-
-	return ctrl.Result{}, err
 }
 
 func NewHttpsCertReconciler(mgr ctrl.Manager) *HttpsCertReconciler {
@@ -173,10 +191,10 @@ func (r *HttpsCertReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&corev1alpha1.ACMEServer{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: ACMEServerMapper{*r},
 		}).
-		Complete(r)
+		Complete(instrumentReconciler("HttpsCertReconciler", r))
 }
 
-func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Context, httpsCert corev1alpha1.HttpsCert) error {
+func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Context, httpsCert corev1alpha1.HttpsCert) (time.Duration, error) {
 	certName, certSecretName := getCertAndCertSecretName(httpsCert)
 
 	dnsNames := getDNSNames(httpsCert)
@@ -198,6 +216,12 @@ func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Conte
 		},
 	}
 
+	// validity and key of certs are configurable for CA issuers
+	var issuer corev1alpha1.HttpsCertIssuer
+	if err := r.Get(ctx, client.ObjectKey{Name: httpsCert.Spec.HttpsCertIssuer}, &issuer); err == nil && issuer.Spec.CA != nil {
+		applyCAIssuerToCertSpec(&desiredCert.Spec, issuer.Spec.CA)
+	}
+
 	// reconcile cert
 	var cert cmv1alpha2.Certificate
 	var isNew bool
@@ -208,7 +232,7 @@ func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Conte
 
 	if err != nil {
 		if !errors.IsNotFound(err) {
-			return err
+			return 0, err
 		}
 
 		isNew = true
@@ -219,7 +243,7 @@ func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Conte
 
 	if isNew {
 		if err := ctrl.SetControllerReference(&httpsCert, &cert, r.Scheme); err != nil {
-			return err
+			return 0, err
 		}
 
 		err = r.Create(ctx, &cert)
@@ -254,16 +278,22 @@ func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Conte
 						&certSec,
 					)
 					if err != nil {
-						return err
+						return 0, err
 					}
 
 					cert, interCert, err := ParseCert(string(certSec.Data[SecretKeyOfTLSCert]))
+					if err != nil {
+						return 0, err
+					}
 
 					expireAt := cert.NotAfter
 					isTrusted := checkIfCertIssuedByTrustedCA(cert, interCert)
 
 					httpsCert.Status.ExpireTimestamp = expireAt.Unix()
 					httpsCert.Status.IsSignedByPublicTrustedCA = isTrusted
+				} else if issuedCert := r.getIssuedCert(ctx, cert.Spec.SecretName); issuedCert != nil {
+					// cert was issued before, renewal failed
+					r.markHttpsCertRenewalFailed(&httpsCert, issuedCert.NotAfter, fmt.Errorf("%s", cond.Message))
 				} else {
 					// cert is not ready yet, reset fields
 					httpsCert.Status.ExpireTimestamp = 0
@@ -275,9 +305,43 @@ func (r *HttpsCertReconciler) reconcileForAutoManagedHttpsCert(ctx context.Conte
 		}
 	}
 
-	r.Status().Update(ctx, &httpsCert)
+	requeueAfter := r.checkHttpsCertExpiry(&httpsCert)
+
+	if updateErr := r.Status().Update(ctx, &httpsCert); updateErr != nil {
+		return 0, updateErr
+	}
+
+	return requeueAfter, err
+}
+
+// getIssuedCert returns the not expired cert in the secret, nil if there isn't one
+func (r *HttpsCertReconciler) getIssuedCert(ctx context.Context, secretName string) *x509.Certificate {
+	var certSec corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: istioNamespace}, &certSec); err != nil {
+		return nil
+	}
+
+	cert, _, err := ParseCert(string(certSec.Data[SecretKeyOfTLSCert]))
+	if err != nil || time.Now().After(cert.NotAfter) {
+		return nil
+	}
+
+	return cert
+}
+
+func applyCAIssuerToCertSpec(spec *cmv1alpha2.CertificateSpec, ca *corev1alpha1.CAIssuer) {
+	if ca.Validity != nil {
+		spec.Duration = ca.Validity.DeepCopy()
+
+		// the default renewBefore of cert-manager is 30 days, which may be longer than the validity
+		spec.RenewBefore = &metav1.Duration{Duration: ca.Validity.Duration / 3}
+	}
+
+	if ca.KeyAlgorithm != "" {
+		spec.KeyAlgorithm = cmv1alpha2.KeyAlgorithm(ca.KeyAlgorithm)
+	}
 
-	return err
+	spec.KeySize = ca.KeySize
 }
 
 func (r *HttpsCertReconciler) isACMEServerReadyForWildcardCert() (bool, error) {
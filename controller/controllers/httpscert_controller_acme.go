@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// certs issued by ACMEDNSProvider issuers are renewed 30 days before expiration
+	acmeCertRenewBefore = 30 * 24 * time.Hour
+
+	// how long an ACME order can take, from creating the order to the cert being issued
+	acmeCertObtainTimeout = 10 * time.Minute
+
+	// timeout of the ACME requests in one reconcile
+	acmeRequestTimeout = 30 * time.Second
+)
+
+func (r *HttpsCertReconciler) reconcileForACMEDNSProviderHttpsCert(
+	ctx context.Context,
+	httpsCert corev1alpha1.HttpsCert,
+	issuer corev1alpha1.HttpsCertIssuer,
+) (ctrl.Result, error) {
+
+	_, certSecretName := getCertAndCertSecretName(httpsCert)
+
+	var certSec corev1.Secret
+	isNew := false
+	if err := r.Get(ctx, client.ObjectKey{Namespace: istioNamespace, Name: certSecretName}, &certSec); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		isNew = true
+	}
+
+	// the cert issued before, still used until it expires if renewal fails
+	var issuedCert *x509.Certificate
+
+	if !isNew {
+		cert, interCert, err := ParseCert(string(certSec.Data[SecretKeyOfTLSCert]))
+		if err == nil && isCertForDomains(cert, httpsCert.Spec.Domains) {
+			if time.Until(cert.NotAfter) > acmeCertRenewBefore {
+				return r.updateACMEHttpsCertStatus(ctx, httpsCert, cert, interCert)
+			}
+
+			if time.Now().Before(cert.NotAfter) {
+				issuedCert = cert
+			}
+		}
+	}
+
+	certPEM, keyPEM, err := r.obtainCertForACMEDNSProviderIssuer(ctx, &httpsCert, issuer)
+	if err != nil {
+		r.EmitWarningEvent(&httpsCert, err, "fail to obtain cert from ACME server")
+
+		if issuedCert != nil {
+			r.markHttpsCertRenewalFailed(&httpsCert, issuedCert.NotAfter, err)
+		} else {
+			httpsCert.Status.Conditions = []corev1alpha1.HttpsCertCondition{genConditionWithErr(err)}
+		}
+
+		r.checkHttpsCertExpiry(&httpsCert)
+
+		if updateErr := r.Status().Update(ctx, &httpsCert); updateErr != nil {
+			return ctrl.Result{}, updateErr
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// the order is in progress, its state is kept in status for the next reconcile
+	if certPEM == nil {
+		if err := r.Status().Update(ctx, &httpsCert); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: acmeOrderPollInterval}, nil
+	}
+
+	if isNew {
+		certSec = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: istioNamespace,
+				Name:      certSecretName,
+			},
+		}
+
+		if err := ctrl.SetControllerReference(&httpsCert, &certSec, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	certSec.Type = corev1.SecretTypeTLS
+	certSec.Data = map[string][]byte{
+		SecretKeyOfTLSCert: certPEM,
+		SecretKeyOfTLSKey:  keyPEM,
+	}
+
+	if isNew {
+		err = r.Create(ctx, &certSec)
+	} else {
+		err = r.Update(ctx, &certSec)
+	}
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.EmitNormalEvent(&httpsCert, "CertIssued", "cert is issued by the ACME server.")
+
+	cert, interCert, err := ParseCert(string(certPEM))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return r.updateACMEHttpsCertStatus(ctx, httpsCert, cert, interCert)
+}
+
+// obtainCertForACMEDNSProviderIssuer starts or moves forward the ACME order of the cert, the order is kept
+// in the status of the cert. The cert and key are returned once issued, nothing while the order is in progress.
+func (r *HttpsCertReconciler) obtainCertForACMEDNSProviderIssuer(
+	ctx context.Context,
+	httpsCert *corev1alpha1.HttpsCert,
+	issuer corev1alpha1.HttpsCertIssuer,
+) (certPEM, keyPEM []byte, err error) {
+
+	if !issuer.Status.OK {
+		return nil, nil, fmt.Errorf("issuer %s is not ready", issuer.Name)
+	}
+
+	var keySec corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{
+		Namespace: corev1alpha1.KalmSystemNamespace,
+		Name:      getPrvKeyNameForIssuer(issuer),
+	}, &keySec); err != nil {
+		return nil, nil, err
+	}
+
+	acmeClient, err := newACMEClientForIssuer(issuer, keySec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dnsMgr, err := newDNSManagerForIssuer(issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, acmeRequestTimeout)
+	defer cancel()
+
+	domains := getDNSNames(*httpsCert)
+	order := httpsCert.Status.ACMEOrder
+
+	if order != nil && !isSameDomains(order.Domains, domains) {
+		cleanupDNS01Order(dnsMgr, order)
+		order = nil
+	}
+
+	if order != nil && time.Since(order.StartedAt.Time) > acmeCertObtainTimeout {
+		cleanupDNS01Order(dnsMgr, order)
+		httpsCert.Status.ACMEOrder = nil
+
+		return nil, nil, fmt.Errorf("ACME order is not finished in %s", acmeCertObtainTimeout)
+	}
+
+	if order == nil {
+		httpsCert.Status.ACMEOrder, err = startDNS01Order(ctx, acmeClient, dnsMgr, domains)
+		return nil, nil, err
+	}
+
+	certPEM, keyPEM, err = progressDNS01Order(ctx, acmeClient, dnsMgr, order)
+
+	// the order is done, a new one is created if it failed
+	if err != nil || certPEM != nil {
+		cleanupDNS01Order(dnsMgr, order)
+		httpsCert.Status.ACMEOrder = nil
+	}
+
+	return certPEM, keyPEM, err
+}
+
+// updateACMEHttpsCertStatus marks the cert ready, and requeues it for renewal
+func (r *HttpsCertReconciler) updateACMEHttpsCertStatus(
+	ctx context.Context,
+	httpsCert corev1alpha1.HttpsCert,
+	cert, interCert *x509.Certificate,
+) (ctrl.Result, error) {
+
+	httpsCert.Status.Conditions = []corev1alpha1.HttpsCertCondition{
+		{
+			Type:   corev1alpha1.HttpsCertConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}
+	httpsCert.Status.ExpireTimestamp = cert.NotAfter.Unix()
+	httpsCert.Status.IsSignedByPublicTrustedCA = checkIfCertIssuedByTrustedCA(cert, interCert)
+
+	requeueAfter := r.checkHttpsCertExpiry(&httpsCert)
+
+	if err := r.Status().Update(ctx, &httpsCert); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	renewAfter := time.Until(cert.NotAfter.Add(-acmeCertRenewBefore))
+	return ctrl.Result{RequeueAfter: minRequeueAfter(renewAfter, requeueAfter)}, nil
+}
+
+func isCertForDomains(cert *x509.Certificate, domains []string) bool {
+	return isSameDomains(cert.DNSNames, domains)
+}
+
+// isSameDomains compares domains case-insensitively, regardless of the order
+func isSameDomains(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	lowerA := make([]string, len(a))
+	for i, name := range a {
+		lowerA[i] = strings.ToLower(name)
+	}
+
+	lowerB := make([]string, len(b))
+	for i, name := range b {
+		lowerB[i] = strings.ToLower(name)
+	}
+
+	sort.Strings(lowerA)
+	sort.Strings(lowerB)
+
+	for i := range lowerA {
+		if lowerA[i] != lowerB[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// certs within the expiry window are checked daily
+const certExpiryCheckInterval = 24 * time.Hour
+
+var certExpiryDaysDesc = prometheus.NewDesc(
+	"kalm_https_cert_expiry_days",
+	"Days until the https cert expires, negative if already expired.",
+	[]string{"name", "issuer"},
+	nil,
+)
+
+// certExpiryCollector computes days-to-expiry of certs when scraped, so the values don't go stale between reconciles.
+type certExpiryCollector struct {
+	mu sync.Mutex
+
+	// cert name -> issuer & expiration
+	certs map[string]certExpiry
+}
+
+type certExpiry struct {
+	issuer   string
+	expireAt time.Time
+}
+
+var httpsCertExpiryCollector = &certExpiryCollector{certs: make(map[string]certExpiry)}
+
+func init() {
+	metrics.Registry.MustRegister(httpsCertExpiryCollector)
+}
+
+func (c *certExpiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- certExpiryDaysDesc
+}
+
+func (c *certExpiryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, cert := range c.certs {
+		ch <- prometheus.MustNewConstMetric(
+			certExpiryDaysDesc,
+			prometheus.GaugeValue,
+			time.Until(cert.expireAt).Hours()/24,
+			name,
+			cert.issuer,
+		)
+	}
+}
+
+func (c *certExpiryCollector) Set(httpsCert corev1alpha1.HttpsCert) {
+	if httpsCert.Status.ExpireTimestamp <= 0 {
+		c.Delete(httpsCert.Name)
+		return
+	}
+
+	issuer := httpsCert.Spec.HttpsCertIssuer
+	if httpsCert.Spec.IsSelfManaged {
+		issuer = "self-managed"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.certs[httpsCert.Name] = certExpiry{
+		issuer:   issuer,
+		expireAt: time.Unix(httpsCert.Status.ExpireTimestamp, 0),
+	}
+}
+
+func (c *certExpiryCollector) Delete(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.certs, name)
+}
+
+// checkHttpsCertExpiry marks issued certs within the expiry window not ready, and emits events for them.
+// The returned duration is when the cert needs to be checked again, 0 means no need.
+func (r *HttpsCertReconciler) checkHttpsCertExpiry(httpsCert *corev1alpha1.HttpsCert) time.Duration {
+	httpsCertExpiryCollector.Set(*httpsCert)
+
+	// the status is settled after the expiry check
+	defer setHttpsCertReadyMetric(*httpsCert)
+
+	if httpsCert.Status.ExpireTimestamp <= 0 {
+		httpsCert.Status.ExpiryWarning = ""
+		return 0
+	}
+
+	expireAt := time.Unix(httpsCert.Status.ExpireTimestamp, 0)
+	left := time.Until(expireAt)
+	window := time.Duration(httpsCert.GetExpiryWarningDays()) * 24 * time.Hour
+
+	if left <= 0 {
+		msg := fmt.Sprintf("cert expired at %s", expireAt.UTC().Format(time.RFC3339))
+		setHttpsCertNotReady(httpsCert, corev1alpha1.HttpsCertReasonExpired, msg)
+		r.warnHttpsCertExpiry(httpsCert, corev1alpha1.HttpsCertReasonExpired, "CertExpired", msg)
+
+		return 0
+	}
+
+	if left > window {
+		httpsCert.Status.ExpiryWarning = ""
+		return left - window
+	}
+
+	msg := fmt.Sprintf("cert expires in %d days, at %s", int(left.Hours()/24), expireAt.UTC().Format(time.RFC3339))
+
+	// failed renewal is the more useful reason, keep it
+	if corev1alpha1.IsHttpsCertReady(*httpsCert) {
+		setHttpsCertNotReady(httpsCert, corev1alpha1.HttpsCertReasonExpiringSoon, msg)
+	}
+
+	r.warnHttpsCertExpiry(httpsCert, corev1alpha1.HttpsCertReasonExpiringSoon, "CertExpiringSoon", msg)
+
+	if left < certExpiryCheckInterval {
+		return left
+	}
+
+	return certExpiryCheckInterval
+}
+
+// warnHttpsCertExpiry emits the event only if the cert is not warned with the same reason yet,
+// the warning is kept in status, so that an event is not emitted on every reconcile.
+func (r *HttpsCertReconciler) warnHttpsCertExpiry(httpsCert *corev1alpha1.HttpsCert, warning, reason, msg string) {
+	if httpsCert.Status.ExpiryWarning == warning {
+		return
+	}
+
+	httpsCert.Status.ExpiryWarning = warning
+	r.Recorder.Event(httpsCert, corev1.EventTypeWarning, reason, msg)
+}
+
+// markHttpsCertRenewalFailed is used when the cert was issued before, but the new one can't be issued
+func (r *HttpsCertReconciler) markHttpsCertRenewalFailed(httpsCert *corev1alpha1.HttpsCert, expireAt time.Time, err error) {
+	httpsCert.Status.ExpireTimestamp = expireAt.Unix()
+	setHttpsCertNotReady(httpsCert, corev1alpha1.HttpsCertReasonRenewalFailed, err.Error())
+
+	r.Recorder.Event(httpsCert, corev1.EventTypeWarning, "CertRenewalFailed", err.Error())
+}
+
+func setHttpsCertNotReady(httpsCert *corev1alpha1.HttpsCert, reason, msg string) {
+	httpsCert.Status.Conditions = []corev1alpha1.HttpsCertCondition{
+		{
+			Type:    corev1alpha1.HttpsCertConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  reason,
+			Message: msg,
+		},
+	}
+}
+
+// minRequeueAfter returns the earlier one of non-zero durations
+func minRequeueAfter(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+
+	if b <= 0 || a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func genReadyHttpsCert(name string, expireAt time.Time) corev1alpha1.HttpsCert {
+	return corev1alpha1.HttpsCert{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1alpha1.HttpsCertSpec{
+			HttpsCertIssuer: corev1alpha1.DefaultHTTP01IssuerName,
+			Domains:         []string{"kalm.test"},
+		},
+		Status: corev1alpha1.HttpsCertStatus{
+			Conditions: []corev1alpha1.HttpsCertCondition{
+				{
+					Type:   corev1alpha1.HttpsCertConditionReady,
+					Status: corev1.ConditionTrue,
+				},
+			},
+			ExpireTimestamp: expireAt.Unix(),
+		},
+	}
+}
+
+func TestCheckHttpsCertExpiry(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &HttpsCertReconciler{BaseReconciler: &BaseReconciler{Recorder: recorder}}
+
+	// far from expiry
+	cert := genReadyHttpsCert("not-expiring", time.Now().Add(60*24*time.Hour))
+	requeueAfter := r.checkHttpsCertExpiry(&cert)
+	assert.True(t, corev1alpha1.IsHttpsCertReady(cert))
+	assert.InDelta(t, (46 * 24 * time.Hour).Seconds(), requeueAfter.Seconds(), 60)
+	assert.Len(t, recorder.Events, 0)
+
+	// within the default 14 days window
+	cert = genReadyHttpsCert("expiring", time.Now().Add(10*24*time.Hour+time.Hour))
+	requeueAfter = r.checkHttpsCertExpiry(&cert)
+	assert.False(t, corev1alpha1.IsHttpsCertReady(cert))
+	assert.True(t, corev1alpha1.IsHttpsCertUsable(cert))
+	assert.Equal(t, corev1alpha1.HttpsCertReasonExpiringSoon, cert.Status.Conditions[0].Reason)
+	assert.Contains(t, cert.Status.Conditions[0].Message, "expires in 10 days")
+	assert.Equal(t, certExpiryCheckInterval, requeueAfter)
+	assert.Contains(t, <-recorder.Events, "CertExpiringSoon")
+	assert.Equal(t, corev1alpha1.HttpsCertReasonExpiringSoon, cert.Status.ExpiryWarning)
+
+	// the warning is emitted once
+	r.checkHttpsCertExpiry(&cert)
+	assert.Len(t, recorder.Events, 0)
+
+	// it's cleared once the cert is renewed, the renewed cert is warned again
+	cert.Status.ExpireTimestamp = time.Now().Add(60 * 24 * time.Hour).Unix()
+	r.checkHttpsCertExpiry(&cert)
+	assert.Equal(t, "", cert.Status.ExpiryWarning)
+
+	cert.Status.ExpireTimestamp = time.Now().Add(10 * 24 * time.Hour).Unix()
+	r.checkHttpsCertExpiry(&cert)
+	assert.Contains(t, <-recorder.Events, "CertExpiringSoon")
+
+	// customized window
+	cert = genReadyHttpsCert("customized-window", time.Now().Add(10*24*time.Hour))
+	cert.Spec.ExpiryWarningDays = 7
+	r.checkHttpsCertExpiry(&cert)
+	assert.True(t, corev1alpha1.IsHttpsCertReady(cert))
+
+	// renewal failed is kept as reason
+	cert = genReadyHttpsCert("renewal-failed", time.Now())
+	r.markHttpsCertRenewalFailed(&cert, time.Now().Add(time.Hour), assert.AnError)
+	assert.Contains(t, <-recorder.Events, "CertRenewalFailed")
+
+	requeueAfter = r.checkHttpsCertExpiry(&cert)
+	assert.Equal(t, corev1alpha1.HttpsCertReasonRenewalFailed, cert.Status.Conditions[0].Reason)
+	assert.True(t, corev1alpha1.IsHttpsCertUsable(cert))
+	assert.True(t, requeueAfter <= time.Hour)
+	<-recorder.Events
+
+	// expired
+	cert = genReadyHttpsCert("expired", time.Now().Add(-time.Hour))
+	requeueAfter = r.checkHttpsCertExpiry(&cert)
+	assert.Equal(t, corev1alpha1.HttpsCertReasonExpired, cert.Status.Conditions[0].Reason)
+	assert.False(t, corev1alpha1.IsHttpsCertUsable(cert))
+	assert.Equal(t, time.Duration(0), requeueAfter)
+	assert.Contains(t, <-recorder.Events, "CertExpired")
+
+	// the expiring soon warning is followed by the expired one
+	cert = genReadyHttpsCert("expired", time.Now().Add(-time.Hour))
+	cert.Status.ExpiryWarning = corev1alpha1.HttpsCertReasonExpiringSoon
+	r.checkHttpsCertExpiry(&cert)
+	assert.Contains(t, <-recorder.Events, "CertExpired")
+
+	r.checkHttpsCertExpiry(&cert)
+	assert.Len(t, recorder.Events, 0)
+}
+
+func TestHttpsCertExpiryCollector(t *testing.T) {
+	collector := &certExpiryCollector{certs: make(map[string]certExpiry)}
+
+	cert := genReadyHttpsCert("foo", time.Now().Add(30*24*time.Hour))
+	collector.Set(cert)
+	assert.InDelta(t, 30, testutil.ToFloat64(collector), 0.01)
+
+	// not issued certs are removed
+	cert.Status.ExpireTimestamp = 0
+	collector.Set(cert)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector))
+
+	collector.Set(genReadyHttpsCert("bar", time.Now()))
+	collector.Delete("bar")
+	assert.Equal(t, 0, testutil.CollectAndCount(collector))
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmv1alpha2 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeHttpsCertIssuerReconciler(objs ...runtime.Object) *HttpsCertIssuerReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = cmv1alpha2.AddToScheme(scheme)
+
+	return &HttpsCertIssuerReconciler{
+		BaseReconciler: &BaseReconciler{
+			Client:   fake.NewFakeClientWithScheme(scheme, objs...),
+			Log:      ctrl.Log.WithName("test"),
+			Scheme:   scheme,
+			Recorder: record.NewFakeRecorder(10),
+		},
+	}
+}
+
+func TestReconcileCAIssuerWithSecret(t *testing.T) {
+	r := newFakeHttpsCertIssuerReconciler()
+
+	key, crt, err := r.generateRandomPrvKeyAndCrtForCA()
+	assert.Nil(t, err)
+
+	issuer := corev1alpha1.HttpsCertIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp-ca"},
+		Spec: corev1alpha1.HttpsCertIssuerSpec{
+			CA: &corev1alpha1.CAIssuer{SecretName: "corp-ca-secret"},
+		},
+	}
+
+	ctx := context.Background()
+	assert.Nil(t, r.Create(ctx, &issuer))
+
+	// secret not exist yet
+	_, err = r.ReconcileCA(ctx, issuer)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, r.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: CertManagerNamespace, Name: "corp-ca-secret"},
+		Data: map[string][]byte{
+			SecretKeyOfTLSCert: crt,
+			SecretKeyOfTLSKey:  key,
+		},
+	}))
+
+	_, err = r.ReconcileCA(ctx, issuer)
+	assert.Nil(t, err)
+
+	var clusterIssuer cmv1alpha2.ClusterIssuer
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Name: "corp-ca"}, &clusterIssuer))
+	assert.Equal(t, "corp-ca-secret", clusterIssuer.Spec.CA.SecretName)
+	assert.Nil(t, clusterIssuer.Spec.Vault)
+
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Name: "corp-ca"}, &issuer))
+	assert.True(t, issuer.Status.OK)
+
+	// key not matched
+	otherKey, _, err := r.generateRandomPrvKeyAndCrtForCA()
+	assert.Nil(t, err)
+
+	var sec corev1.Secret
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: CertManagerNamespace, Name: "corp-ca-secret"}, &sec))
+	sec.Data[SecretKeyOfTLSKey] = otherKey
+	assert.Nil(t, r.Update(ctx, &sec))
+
+	_, err = r.ReconcileCA(ctx, issuer)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Name: "corp-ca"}, &issuer))
+	assert.False(t, issuer.Status.OK)
+
+	// the error of saving the status is returned
+	stale := issuer.DeepCopy()
+	stale.Status.OK = true
+	issuer.Spec.CA.SecretName = "corp-ca-secret"
+	assert.Nil(t, r.Update(ctx, &issuer))
+
+	_, err = r.ReconcileCA(ctx, *stale)
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestReconcileCAIssuerWithVault(t *testing.T) {
+	r := newFakeHttpsCertIssuerReconciler(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: CertManagerNamespace, Name: "vault-token"},
+		Data:       map[string][]byte{"token": []byte("s.token")},
+	})
+
+	issuer := corev1alpha1.HttpsCertIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp-vault"},
+		Spec: corev1alpha1.HttpsCertIssuerSpec{
+			CA: &corev1alpha1.CAIssuer{
+				Vault: &corev1alpha1.VaultPKIIssuer{
+					Server:          "https://vault.example.com:8200",
+					Path:            "pki_int/sign/example-dot-com",
+					TokenSecretName: "vault-token",
+				},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	assert.Nil(t, r.Create(ctx, &issuer))
+
+	_, err := r.ReconcileCA(ctx, issuer)
+	assert.Nil(t, err)
+
+	var clusterIssuer cmv1alpha2.ClusterIssuer
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Name: "corp-vault"}, &clusterIssuer))
+	assert.Equal(t, "https://vault.example.com:8200", clusterIssuer.Spec.Vault.Server)
+	assert.Equal(t, "pki_int/sign/example-dot-com", clusterIssuer.Spec.Vault.Path)
+	assert.Equal(t, "vault-token", clusterIssuer.Spec.Vault.Auth.TokenSecretRef.Name)
+	assert.Equal(t, "token", clusterIssuer.Spec.Vault.Auth.TokenSecretRef.Key)
+
+	// key not in secret
+	issuer.Spec.CA.Vault.TokenSecretKey = "not-exist"
+	_, err = r.ReconcileCA(ctx, issuer)
+	assert.NotNil(t, err)
+}
+
+func TestApplyCAIssuerToCertSpec(t *testing.T) {
+	var spec cmv1alpha2.CertificateSpec
+
+	applyCAIssuerToCertSpec(&spec, &corev1alpha1.CAIssuer{})
+	assert.Nil(t, spec.Duration)
+	assert.Equal(t, cmv1alpha2.KeyAlgorithm(""), spec.KeyAlgorithm)
+
+	applyCAIssuerToCertSpec(&spec, &corev1alpha1.CAIssuer{
+		Validity:     &metav1.Duration{Duration: 30 * 24 * time.Hour},
+		KeyAlgorithm: corev1alpha1.CAIssuerKeyAlgorithmECDSA,
+		KeySize:      384,
+	})
+	assert.Equal(t, 30*24*time.Hour, spec.Duration.Duration)
+	assert.Equal(t, 10*24*time.Hour, spec.RenewBefore.Duration)
+	assert.Equal(t, cmv1alpha2.ECDSAKeyAlgorithm, spec.KeyAlgorithm)
+	assert.Equal(t, 384, spec.KeySize)
+}
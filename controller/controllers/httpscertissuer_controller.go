@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/json"
@@ -57,6 +58,16 @@ type HttpsCertIssuerReconciler struct {
 func (r *HttpsCertIssuerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 
+	var httpsCertIssuer corev1alpha1.HttpsCertIssuer
+	if err := r.Get(ctx, req.NamespacedName, &httpsCertIssuer); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// issued by kalm itself, cert-manager is not needed
+	if httpsCertIssuer.Spec.ACMEDNSProvider != nil {
+		return r.ReconcileACMEDNSProvider(ctx, httpsCertIssuer)
+	}
+
 	certMgrNs := corev1.Namespace{}
 	err := r.Get(ctx, client.ObjectKey{Name: CertManagerNamespace}, &certMgrNs)
 
@@ -69,11 +80,6 @@ func (r *HttpsCertIssuerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, er
 		return ctrl.Result{}, err
 	}
 
-	var httpsCertIssuer corev1alpha1.HttpsCertIssuer
-	if err := r.Get(ctx, req.NamespacedName, &httpsCertIssuer); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
 	if httpsCertIssuer.Spec.CAForTest != nil {
 		return r.ReconcileCAForTest(ctx, httpsCertIssuer)
 	}
@@ -90,6 +96,10 @@ func (r *HttpsCertIssuerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, er
 		return r.ReconcileDNS01(ctx, httpsCertIssuer)
 	}
 
+	if httpsCertIssuer.Spec.CA != nil {
+		return r.ReconcileCA(ctx, httpsCertIssuer)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -137,7 +147,7 @@ func (r *HttpsCertIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&corev1.Namespace{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: CertManagerNSWatcher{r},
 		}).
-		Complete(r)
+		Complete(instrumentReconciler("HttpsCertIssuerReconciler", r))
 }
 
 const (
@@ -620,3 +630,219 @@ func (r *HttpsCertIssuerReconciler) reconcileSecForDNS01Issuer(
 
 	return err
 }
+
+// ReconcileCA configs a cert-manager CA or Vault issuer with the CA of the user
+func (r *HttpsCertIssuerReconciler) ReconcileCA(
+	ctx context.Context,
+	issuer corev1alpha1.HttpsCertIssuer,
+) (ctrl.Result, error) {
+
+	ca := issuer.Spec.CA
+
+	var issuerConfig cmv1alpha2.IssuerConfig
+	var err error
+
+	if ca.Vault != nil {
+		issuerConfig.Vault, err = r.getVaultIssuer(ctx, ca.Vault)
+	} else {
+		err = r.checkCASecret(ctx, ca.SecretName)
+		issuerConfig.CA = &cmv1alpha2.CAIssuer{
+			SecretName: ca.SecretName,
+		}
+	}
+
+	if err != nil {
+		r.EmitWarningEvent(&issuer, err, "CA of the issuer is not valid")
+
+		if issuer.Status.OK {
+			issuer.Status.OK = false
+			if updateErr := r.Status().Update(ctx, &issuer); updateErr != nil {
+				return ctrl.Result{}, updateErr
+			}
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	expectedClusterIssuer := cmv1alpha2.ClusterIssuer{
+		ObjectMeta: v1.ObjectMeta{
+			Name: issuer.Name,
+		},
+		Spec: cmv1alpha2.IssuerSpec{
+			IssuerConfig: issuerConfig,
+		},
+	}
+
+	clusterIssuer := cmv1alpha2.ClusterIssuer{}
+	isNew := false
+
+	if err := r.Get(ctx, client.ObjectKey{Name: expectedClusterIssuer.Name}, &clusterIssuer); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		isNew = true
+	}
+
+	if isNew {
+		clusterIssuer = expectedClusterIssuer
+
+		if err := ctrl.SetControllerReference(&issuer, &clusterIssuer, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		err = r.Create(ctx, &clusterIssuer)
+	} else {
+		clusterIssuer.Spec = expectedClusterIssuer.Spec
+		err = r.Update(ctx, &clusterIssuer)
+	}
+
+	if err != nil {
+		r.EmitWarningEvent(&issuer, err, "fail to reconcile issuer")
+		return ctrl.Result{}, err
+	}
+
+	if !issuer.Status.OK {
+		issuer.Status.OK = true
+		if err := r.Status().Update(ctx, &issuer); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// checkCASecret makes sure the secret holds a CA cert and the matched key
+func (r *HttpsCertIssuerReconciler) checkCASecret(ctx context.Context, secretName string) error {
+	var sec corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: CertManagerNamespace, Name: secretName}, &sec); err != nil {
+		return err
+	}
+
+	if _, err := tls.X509KeyPair(sec.Data[SecretKeyOfTLSCert], sec.Data[SecretKeyOfTLSKey]); err != nil {
+		return fmt.Errorf("secret %s has no valid %s and %s, %s", secretName, SecretKeyOfTLSCert, SecretKeyOfTLSKey, err)
+	}
+
+	cert, _, err := ParseCert(string(sec.Data[SecretKeyOfTLSCert]))
+	if err != nil {
+		return err
+	}
+
+	if !cert.IsCA {
+		return fmt.Errorf("cert in secret %s is not a CA", secretName)
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("CA in secret %s expired at %s", secretName, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+const DefaultVaultTokenSecretKey = "token"
+
+func (r *HttpsCertIssuerReconciler) getVaultIssuer(ctx context.Context, vault *corev1alpha1.VaultPKIIssuer) (*cmv1alpha2.VaultIssuer, error) {
+	tokenKey := vault.TokenSecretKey
+	if tokenKey == "" {
+		tokenKey = DefaultVaultTokenSecretKey
+	}
+
+	var sec corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: CertManagerNamespace, Name: vault.TokenSecretName}, &sec); err != nil {
+		return nil, err
+	}
+
+	if len(sec.Data[tokenKey]) == 0 {
+		return nil, fmt.Errorf("secret %s has no key: %s", vault.TokenSecretName, tokenKey)
+	}
+
+	vaultIssuer := cmv1alpha2.VaultIssuer{
+		Server: vault.Server,
+		Path:   vault.Path,
+		Auth: cmv1alpha2.VaultAuth{
+			TokenSecretRef: &cmmetav1.SecretKeySelector{
+				LocalObjectReference: cmmetav1.LocalObjectReference{
+					Name: vault.TokenSecretName,
+				},
+				Key: tokenKey,
+			},
+		},
+	}
+
+	if vault.CABundle != "" {
+		vaultIssuer.CABundle = []byte(vault.CABundle)
+	}
+
+	return &vaultIssuer, nil
+}
+
+// ReconcileACMEDNSProvider ensures the ACME account of the issuer, certs are issued in HttpsCertReconciler
+func (r *HttpsCertIssuerReconciler) ReconcileACMEDNSProvider(
+	ctx context.Context,
+	issuer corev1alpha1.HttpsCertIssuer,
+) (ctrl.Result, error) {
+
+	keySecretName := getPrvKeyNameForIssuer(issuer)
+
+	var keySec corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: corev1alpha1.KalmSystemNamespace, Name: keySecretName}, &keySec); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		keyPEM, err := generateACMEAccountKey()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		keySec = corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: corev1alpha1.KalmSystemNamespace,
+				Name:      keySecretName,
+			},
+			Data: map[string][]byte{
+				SecretKeyOfTLSKey: keyPEM,
+			},
+		}
+
+		if err := ctrl.SetControllerReference(&issuer, &keySec, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.Create(ctx, &keySec); err != nil {
+			r.EmitWarningEvent(&issuer, err, "fail to create secret for ACME account key")
+			return ctrl.Result{}, err
+		}
+
+		r.EmitNormalEvent(&issuer, "SecretCreated", "ACME account key secret is created.")
+	}
+
+	acmeClient, err := newACMEClientForIssuer(issuer, keySec)
+	if err == nil {
+		err = registerACMEAccount(ctx, acmeClient, issuer.Spec.ACMEDNSProvider.Email)
+	}
+
+	if err != nil {
+		r.EmitWarningEvent(&issuer, err, "fail to register ACME account")
+
+		if issuer.Status.OK {
+			issuer.Status.OK = false
+			if updateErr := r.Status().Update(ctx, &issuer); updateErr != nil {
+				return ctrl.Result{}, updateErr
+			}
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	if !issuer.Status.OK {
+		issuer.Status.OK = true
+		if err := r.Status().Update(ctx, &issuer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		r.EmitNormalEvent(&issuer, "AccountRegistered", "ACME account is registered.")
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -73,7 +74,12 @@ func (r *KalmNSReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&v1alpha1.HttpsCertIssuer{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: MapperForDefaultHttpsCertIssuer{},
 		}).
-		Complete(r)
+		Watches(genSourceForObject(&v1alpha1.Component{}), &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: MapperForNamespaceOfObject{},
+		}, builder.WithPredicates(componentQuotaPredicate)).
+		Owns(&v1.ResourceQuota{}).
+		Owns(&v1.LimitRange{}).
+		Complete(instrumentReconciler("KalmNSReconciler", r))
 }
 
 func genSourceForObject(obj runtime.Object) source.Source {
@@ -105,6 +111,10 @@ func (r *KalmNSReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			return ctrl.Result{}, err
 		}
 
+		if err := r.reconcileApplicationQuota(&ns); err != nil {
+			return ctrl.Result{}, err
+		}
+
 		// if err := r.reconcileCommonSecret(ns.Name); err != nil {
 		// 	return ctrl.Result{}, err
 		// }
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/kalmhq/kalm/controller/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Annotation on components scaled down by the application quota, only these ones are recovered by kalm.
+// Components labeled as exceeding quota by others are left untouched.
+const KalmAnnoExceedingApplicationQuota = "kalm-exceeding-application-quota"
+
+// default limits of containers without limits in applications with cpu or memory quota, same as component defaults
+var (
+	applicationQuotaDefaultCPULimit    = resource.MustParse("200m")
+	applicationQuotaDefaultMemoryLimit = resource.MustParse("128Mi")
+)
+
+// resource change needs trigger reconcile
+type MapperForNamespaceOfObject struct{}
+
+func (m MapperForNamespaceOfObject) Map(mapObj handler.MapObject) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: mapObj.Meta.GetNamespace(),
+	}}}
+}
+
+// components are only interesting to the quota when the spec or labels changed, not the status
+var componentQuotaPredicate = predicate.Or(
+	predicate.GenerationChangedPredicate{},
+	predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !equality.Semantic.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+		},
+	},
+)
+
+// +kubebuilder:rbac:groups="",resources=resourcequotas;limitranges,verbs=get;list;watch;create;update;patch;delete
+
+func (r *KalmNSReconciler) reconcileApplicationQuota(ns *v1.Namespace) error {
+	quota, err := v1alpha1.GetApplicationQuota(ns)
+
+	if err != nil {
+		r.EmitWarningEvent(ns, err, "invalid application quota")
+		return nil
+	}
+
+	if err := r.reconcileResourceQuota(ns, quota); err != nil {
+		return err
+	}
+
+	if err := r.reconcileLimitRange(ns, quota); err != nil {
+		return err
+	}
+
+	return r.reconcileExceedingQuotaComponents(ns, quota)
+}
+
+// reconcileResourceQuota limits all pods in the namespace, including the ones not created by components
+func (r *KalmNSReconciler) reconcileResourceQuota(ns *v1.Namespace, quota *v1alpha1.ApplicationQuota) error {
+	hard := v1.ResourceList{}
+
+	if quota != nil {
+		if quota.CPU != nil {
+			hard[v1.ResourceLimitsCPU] = *quota.CPU
+		}
+
+		if quota.Memory != nil {
+			hard[v1.ResourceLimitsMemory] = *quota.Memory
+		}
+
+		if quota.Storage != nil {
+			hard[v1.ResourceRequestsStorage] = *quota.Storage
+		}
+	}
+
+	expected := v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns.Name,
+			Name:      v1alpha1.ApplicationQuotaObjectName,
+		},
+		Spec: v1.ResourceQuotaSpec{
+			Hard: hard,
+		},
+	}
+
+	var current v1.ResourceQuota
+
+	return r.applyQuotaObject(ns, &expected, &current, len(hard) == 0, func() bool {
+		if equality.Semantic.DeepEqual(current.Spec, expected.Spec) {
+			return false
+		}
+
+		current.Spec = expected.Spec
+		return true
+	})
+}
+
+// reconcileLimitRange sets default limits to containers, pods without limits are rejected by the cpu or memory ResourceQuota
+func (r *KalmNSReconciler) reconcileLimitRange(ns *v1.Namespace, quota *v1alpha1.ApplicationQuota) error {
+	defaults := v1.ResourceList{}
+
+	if quota != nil {
+		if quota.CPU != nil {
+			defaults[v1.ResourceCPU] = applicationQuotaDefaultCPULimit
+		}
+
+		if quota.Memory != nil {
+			defaults[v1.ResourceMemory] = applicationQuotaDefaultMemoryLimit
+		}
+	}
+
+	expected := v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns.Name,
+			Name:      v1alpha1.ApplicationQuotaObjectName,
+		},
+		Spec: v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{
+				{
+					Type:    v1.LimitTypeContainer,
+					Default: defaults,
+				},
+			},
+		},
+	}
+
+	var current v1.LimitRange
+
+	return r.applyQuotaObject(ns, &expected, &current, len(defaults) == 0, func() bool {
+		if equality.Semantic.DeepEqual(current.Spec, expected.Spec) {
+			return false
+		}
+
+		current.Spec = expected.Spec
+		return true
+	})
+}
+
+// applyQuotaObject creates, updates or deletes the object rendered from the quota,
+// mergeSpec copies the expected spec into current and returns whether current is changed.
+func (r *KalmNSReconciler) applyQuotaObject(ns *v1.Namespace, expected, current runtime.Object, isEmpty bool, mergeSpec func() bool) error {
+	key := types.NamespacedName{Namespace: ns.Name, Name: v1alpha1.ApplicationQuotaObjectName}
+
+	if err := r.Get(r.ctx, key, current); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		if isEmpty {
+			return nil
+		}
+
+		if err := ctrl.SetControllerReference(ns, expected.(metav1.Object), r.Scheme); err != nil {
+			return err
+		}
+
+		return r.Create(r.ctx, expected)
+	}
+
+	if isEmpty {
+		return client.IgnoreNotFound(r.Delete(r.ctx, current))
+	}
+
+	if !mergeSpec() {
+		return nil
+	}
+
+	return r.Update(r.ctx, current)
+}
+
+// reconcileExceedingQuotaComponents scales down the most recently created components until the others fit in the quota.
+// Components scaled down by the quota are recovered once they fit again, e.g. the quota is raised or other components are deleted.
+func (r *KalmNSReconciler) reconcileExceedingQuotaComponents(ns *v1.Namespace, quota *v1alpha1.ApplicationQuota) error {
+	var compList v1alpha1.ComponentList
+	if err := r.List(r.ctx, &compList, client.InNamespace(ns.Name)); err != nil {
+		return err
+	}
+
+	components := compList.Items
+
+	// older components are kept first
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].CreationTimestamp.Equal(&components[j].CreationTimestamp) {
+			return components[i].Name < components[j].Name
+		}
+
+		return components[i].CreationTimestamp.Before(&components[j].CreationTimestamp)
+	})
+
+	usage := &v1alpha1.ApplicationQuotaUsage{}
+	var scaledDown []*v1alpha1.Component
+
+	for i := range components {
+		component := &components[i]
+
+		if component.DeletionTimestamp != nil {
+			continue
+		}
+
+		if isComponentLabeledAsExceedingQuota(component) {
+			if component.Annotations[KalmAnnoExceedingApplicationQuota] == "true" {
+				scaledDown = append(scaledDown, component)
+			}
+
+			continue
+		}
+
+		newUsage := usage.DeepCopy()
+		newUsage.Add(component.GetQuotaUsage())
+
+		if quota == nil || len(quota.Exceeded(newUsage)) == 0 {
+			usage = newUsage
+			continue
+		}
+
+		// the component controller scales it down
+		copied := component.DeepCopy()
+		if copied.Labels == nil {
+			copied.Labels = make(map[string]string)
+		}
+		if copied.Annotations == nil {
+			copied.Annotations = make(map[string]string)
+		}
+
+		copied.Labels[v1alpha1.KalmLabelKeyExceedingQuota] = "true"
+		copied.Annotations[KalmAnnoExceedingApplicationQuota] = "true"
+
+		if err := r.Update(r.ctx, copied); err != nil {
+			return err
+		}
+
+		r.EmitNormalEvent(ns, v1alpha1.ReasonExceedingQuota, "component %s exceeds the application quota, scaled down", component.Name)
+	}
+
+	for _, component := range scaledDown {
+		originalReplicas, exist := component.Labels[v1alpha1.KalmLabelKeyOriginalReplicas]
+
+		// not scaled down by the component controller yet
+		if !exist {
+			continue
+		}
+
+		replicas, err := strconv.Atoi(originalReplicas)
+		if err != nil {
+			replicas = 1
+		}
+
+		recovered := component.DeepCopy()
+		replicas32 := int32(replicas)
+		recovered.Spec.Replicas = &replicas32
+		delete(recovered.Labels, v1alpha1.KalmLabelKeyExceedingQuota)
+		delete(recovered.Labels, v1alpha1.KalmLabelKeyOriginalReplicas)
+		delete(recovered.Annotations, KalmAnnoExceedingApplicationQuota)
+
+		newUsage := usage.DeepCopy()
+		newUsage.Add(recovered.GetQuotaUsage())
+
+		if quota != nil && len(quota.Exceeded(newUsage)) > 0 {
+			continue
+		}
+
+		if err := r.Update(r.ctx, recovered); err != nil {
+			return err
+		}
+
+		usage = newUsage
+		r.EmitNormalEvent(ns, v1alpha1.ReasonExceedingQuota, "component %s fits in the application quota, recovered", component.Name)
+	}
+
+	return nil
+}
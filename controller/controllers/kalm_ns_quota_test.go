@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeKalmNSReconciler(objs ...runtime.Object) *KalmNSReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	return &KalmNSReconciler{
+		BaseReconciler: &BaseReconciler{
+			Client:   fake.NewFakeClientWithScheme(scheme, objs...),
+			Log:      ctrl.Log.WithName("test"),
+			Scheme:   scheme,
+			Recorder: record.NewFakeRecorder(10),
+		},
+		ctx: context.Background(),
+	}
+}
+
+func newQuotaComponent(name string, replicas int32, createdAt time.Time) *corev1alpha1.Component {
+	return &corev1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "shop",
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(createdAt),
+		},
+		Spec: corev1alpha1.ComponentSpec{
+			Image:    "foo:bar",
+			Replicas: &replicas,
+			ResourceRequirements: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("500m"),
+				},
+			},
+		},
+	}
+}
+
+func TestKalmNSReconcileApplicationQuota(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}}
+	cpu := resource.MustParse("1")
+	replicas := int32(2)
+	assert.Nil(t, corev1alpha1.SetApplicationQuota(ns, &corev1alpha1.ApplicationQuota{CPU: &cpu, Replicas: &replicas}))
+
+	now := time.Now()
+	r := newFakeKalmNSReconciler(
+		ns,
+		newQuotaComponent("web", 1, now.Add(-2*time.Hour)),
+		newQuotaComponent("api", 1, now.Add(-time.Hour)),
+		newQuotaComponent("worker", 1, now),
+	)
+
+	assert.Nil(t, r.reconcileApplicationQuota(ns))
+
+	var resourceQuota corev1.ResourceQuota
+	assert.Nil(t, r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: corev1alpha1.ApplicationQuotaObjectName}, &resourceQuota))
+	cpuHard := resourceQuota.Spec.Hard[corev1.ResourceLimitsCPU]
+	assert.Equal(t, "1", cpuHard.String())
+
+	var limitRange corev1.LimitRange
+	assert.Nil(t, r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: corev1alpha1.ApplicationQuotaObjectName}, &limitRange))
+	assert.Equal(t, "200m", limitRange.Spec.Limits[0].Default.Cpu().String())
+
+	// the newest component is over the quota
+	getComponent := func(name string) *corev1alpha1.Component {
+		var component corev1alpha1.Component
+		assert.Nil(t, r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: name}, &component))
+		return &component
+	}
+
+	assert.False(t, isComponentLabeledAsExceedingQuota(getComponent("web")))
+	assert.False(t, isComponentLabeledAsExceedingQuota(getComponent("api")))
+	worker := getComponent("worker")
+	assert.True(t, isComponentLabeledAsExceedingQuota(worker))
+	assert.Equal(t, "true", worker.Annotations[KalmAnnoExceedingApplicationQuota])
+
+	// scaled down by the component controller
+	zero := int32(0)
+	worker.Spec.Replicas = &zero
+	worker.Labels[corev1alpha1.KalmLabelKeyOriginalReplicas] = "1"
+	assert.Nil(t, r.Update(r.ctx, worker))
+
+	// recovered once the quota is removed
+	assert.Nil(t, corev1alpha1.SetApplicationQuota(ns, nil))
+	assert.Nil(t, r.reconcileApplicationQuota(ns))
+
+	worker = getComponent("worker")
+	assert.False(t, isComponentLabeledAsExceedingQuota(worker))
+	assert.Equal(t, int32(1), *worker.Spec.Replicas)
+	_, exist := worker.Labels[corev1alpha1.KalmLabelKeyOriginalReplicas]
+	assert.False(t, exist)
+
+	err := r.Get(r.ctx, types.NamespacedName{Namespace: "shop", Name: corev1alpha1.ApplicationQuotaObjectName}, &resourceQuota)
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -211,7 +211,7 @@ func (r *KalmPVReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&corev1.PersistentVolumeClaim{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: &PVCMapper{r.BaseReconciler},
 		}).
-		Complete(r)
+		Complete(instrumentReconciler("KalmPVReconciler", r))
 }
 
 func (r *KalmPVReconciler) reconcileOrphanPVs(kalmPVList corev1.PersistentVolumeList) error {
@@ -151,5 +151,5 @@ func (r *KalmPVCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(genSourceForObject(&corev1.Pod{}), &handler.EnqueueRequestsFromMapFunc{
 			ToRequests: PodMapperForPVC{r.BaseReconciler},
 		}).
-		Complete(r)
+		Complete(instrumentReconciler("KalmPVCReconciler", r))
 }
@@ -908,7 +908,7 @@ func (r *LogSystemReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.LogSystem{}).
 		Owns(&corev1alpha1.Component{}).
-		Complete(r)
+		Complete(instrumentReconciler("LogSystemReconciler", r))
 }
 
 func NewLogSystemReconciler(mgr ctrl.Manager) *LogSystemReconciler {
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Kalm metrics are served by the controller-runtime metrics server, along with the controller_runtime_* ones.
+var (
+	reconcileTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kalm_controller_reconcile_total",
+			Help: "Total number of reconciliations per controller and result.",
+		},
+		[]string{"controller", "result"},
+	)
+
+	reconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kalm_controller_reconcile_errors_total",
+			Help: "Total number of reconciliation errors per controller.",
+		},
+		[]string{"controller"},
+	)
+
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kalm_controller_reconcile_duration_seconds",
+			Help:    "Duration of reconciliations per controller.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+		},
+		[]string{"controller"},
+	)
+
+	httpsCertReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kalm_https_cert_ready",
+			Help: "Whether the https cert is ready, 1 for ready and 0 for not.",
+		},
+		[]string{"name"},
+	)
+
+	dockerRegistryAuthenticationVerified = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kalm_docker_registry_authentication_verified",
+			Help: "Whether kalm can authenticate against the docker registry, 1 for verified and 0 for not.",
+		},
+		[]string{"name"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		reconcileTotal,
+		reconcileErrors,
+		reconcileDuration,
+		httpsCertReady,
+		dockerRegistryAuthenticationVerified,
+	)
+}
+
+// instrumentedReconciler records kalm_controller_reconcile_* metrics of the wrapped reconciler
+type instrumentedReconciler struct {
+	name string
+	reconcile.Reconciler
+}
+
+func instrumentReconciler(name string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &instrumentedReconciler{name: name, Reconciler: r}
+}
+
+func (r *instrumentedReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := r.Reconciler.Reconcile(req)
+	reconcileDuration.WithLabelValues(r.name).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err != nil:
+		reconcileErrors.WithLabelValues(r.name).Inc()
+		reconcileTotal.WithLabelValues(r.name, "error").Inc()
+	case result.RequeueAfter > 0:
+		reconcileTotal.WithLabelValues(r.name, "requeue_after").Inc()
+	case result.Requeue:
+		reconcileTotal.WithLabelValues(r.name, "requeue").Inc()
+	default:
+		reconcileTotal.WithLabelValues(r.name, "success").Inc()
+	}
+
+	return result, err
+}
+
+func setHttpsCertReadyMetric(httpsCert corev1alpha1.HttpsCert) {
+	var value float64
+	if corev1alpha1.IsHttpsCertReady(httpsCert) {
+		value = 1
+	}
+
+	httpsCertReady.WithLabelValues(httpsCert.Name).Set(value)
+}
+
+func setDockerRegistryMetric(registry *corev1alpha1.DockerRegistry) {
+	var value float64
+	if registry.Status.AuthenticationVerified {
+		value = 1
+	}
+
+	dockerRegistryAuthenticationVerified.WithLabelValues(registry.Name).Set(value)
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kalmhq/kalm/controller/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestInstrumentedReconciler(t *testing.T) {
+	var result ctrl.Result
+	var err error
+
+	r := instrumentReconciler("TestReconciler", reconcile.Func(func(ctrl.Request) (ctrl.Result, error) {
+		return result, err
+	}))
+
+	_, _ = r.Reconcile(ctrl.Request{})
+
+	result = ctrl.Result{RequeueAfter: time.Minute}
+	_, _ = r.Reconcile(ctrl.Request{})
+
+	result, err = ctrl.Result{}, fmt.Errorf("failed")
+	_, returnedErr := r.Reconcile(ctrl.Request{})
+	assert.Equal(t, err, returnedErr)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(reconcileTotal.WithLabelValues("TestReconciler", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(reconcileTotal.WithLabelValues("TestReconciler", "requeue_after")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(reconcileTotal.WithLabelValues("TestReconciler", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(reconcileErrors.WithLabelValues("TestReconciler")))
+}
+
+func TestHealthMetrics(t *testing.T) {
+	cert := genReadyHttpsCert("metrics-test", time.Now().Add(30*24*time.Hour))
+	setHttpsCertReadyMetric(cert)
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpsCertReady.WithLabelValues("metrics-test")))
+
+	cert.Status.Conditions = nil
+	setHttpsCertReadyMetric(cert)
+	assert.Equal(t, 0.0, testutil.ToFloat64(httpsCertReady.WithLabelValues("metrics-test")))
+
+	registry := &corev1alpha1.DockerRegistry{ObjectMeta: metav1.ObjectMeta{Name: "metrics-test"}}
+	setDockerRegistryMetric(registry)
+	assert.Equal(t, 0.0, testutil.ToFloat64(dockerRegistryAuthenticationVerified.WithLabelValues("metrics-test")))
+
+	registry.Status.AuthenticationVerified = true
+	setDockerRegistryMetric(registry)
+	assert.Equal(t, 1.0, testutil.ToFloat64(dockerRegistryAuthenticationVerified.WithLabelValues("metrics-test")))
+}
@@ -398,6 +398,18 @@ func golangValueToProtoValue(val interface{}) *protoTypes.Value {
 				StringValue: typeVal,
 			},
 		}
+	case int:
+		return &protoTypes.Value{
+			Kind: &protoTypes.Value_NumberValue{
+				NumberValue: float64(typeVal),
+			},
+		}
+	case float64:
+		return &protoTypes.Value{
+			Kind: &protoTypes.Value_NumberValue{
+				NumberValue: typeVal,
+			},
+		}
 	case []interface{}:
 		values := make([]*protoTypes.Value, len(typeVal))
 
@@ -496,7 +508,7 @@ func (r *ProtectedEndpointReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				ToRequests: &WatchAllSSOConfig{r.BaseReconciler},
 			},
 		).
-		Complete(r)
+		Complete(instrumentReconciler("ProtectedEndpointReconciler", r))
 }
 
 func NewProtectedEndpointReconciler(mgr ctrl.Manager) *ProtectedEndpointReconciler {
@@ -919,5 +919,5 @@ func (r *SingleSignOnConfigReconciler) SetupWithManager(mgr ctrl.Manager) error
 		// 		ToRequests: &SSORequestMapper{r.BaseReconciler},
 		// 	},
 		// ).
-		Complete(r)
+		Complete(instrumentReconciler("SingleSignOnConfigReconciler", r))
 }
@@ -26,7 +26,7 @@ func NewStorageClassReconciler(mgr ctrl.Manager) *StorageClassReconciler {
 func (r *StorageClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1.StorageClass{}).
-		Complete(r)
+		Complete(instrumentReconciler("StorageClassReconciler", r))
 }
 
 // +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch;create;update;patch;delete
@@ -188,6 +188,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = controllers.NewGitSyncReconciler(mgr).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller: GitSync")
+		os.Exit(1)
+	}
+
+	alertRuleReconciler, err := controllers.NewAlertRuleReconciler(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller: AlertRule")
+		os.Exit(1)
+	}
+
+	if err = alertRuleReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller: AlertRule")
+		os.Exit(1)
+	}
+
 	// only run webhook if explicitly declared
 	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
 
@@ -206,6 +222,11 @@ func main() {
 			os.Exit(1)
 		}
 
+		if err = (&corev1alpha1.ComponentRevision{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ComponentRevision")
+			os.Exit(1)
+		}
+
 		if err = (&corev1alpha1.ComponentPluginBinding{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "ComponentPluginBinding")
 			os.Exit(1)
@@ -256,6 +277,16 @@ func main() {
 			os.Exit(1)
 		}
 
+		if err = (&corev1alpha1.GitSync{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GitSync")
+			os.Exit(1)
+		}
+
+		if err = (&corev1alpha1.AlertRule{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AlertRule")
+			os.Exit(1)
+		}
+
 		setupLog.Info("WEBHOOK enabled")
 	} else {
 		setupLog.Info("WEBHOOK not enabled")
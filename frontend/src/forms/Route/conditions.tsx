@@ -9,6 +9,42 @@ import { AddIcon, DeleteIcon } from "widgets/Icon";
 import { IconButtonWithTooltip } from "widgets/IconButtonWithTooltip";
 import { ValidatorRequired } from "../validator";
 
+const conditionTypes = [
+  { type: "header", text: "Header" },
+  { type: "query", text: "Query" },
+  { type: "cookie", text: "Cookie" },
+  { type: "sourceIP", text: "Source IP" },
+  { type: "authority", text: "Authority" },
+];
+
+const conditionTypeHasName = (type: string) => type === "header" || type === "query" || type === "cookie";
+
+const conditionOperatorOptions = (type: string) => {
+  if (type === "sourceIP") {
+    return [
+      { value: "equal", text: "In" },
+      { value: "notEqual", text: "Not In" },
+    ];
+  }
+
+  const options = [
+    { value: "equal", text: "Equal" },
+    { value: "withPrefix", text: "With Prefix" },
+    { value: "matchRegexp", text: "Match Regexp" },
+  ];
+
+  // negative query conditions are not supported by istio
+  if (type === "query") {
+    return options;
+  }
+
+  return options.concat([
+    { value: "notEqual", text: "Not Equal" },
+    { value: "withoutPrefix", text: "Without Prefix" },
+    { value: "notMatchRegexp", text: "Not Match Regexp" },
+  ]);
+};
+
 export const RenderHttpRouteConditions: React.FC = () => {
   return (
     <FieldArray<HttpRouteCondition, any>
@@ -16,42 +52,26 @@ export const RenderHttpRouteConditions: React.FC = () => {
       render={({ fields }) => (
         <div>
           <Box display="flex">
-            <Box mt={2} mr={2} mb={2}>
-              <Button
-                variant="outlined"
-                color="primary"
-                startIcon={<AddIcon />}
-                size="small"
-                onClick={() => {
-                  fields.push({
-                    type: "header",
-                    operator: "equal",
-                    name: "",
-                    value: "",
-                  });
-                }}
-              >
-                Add Header Rule
-              </Button>
-            </Box>
-            <Box mt={2} mr={2} mb={2}>
-              <Button
-                variant="outlined"
-                color="primary"
-                startIcon={<AddIcon />}
-                size="small"
-                onClick={() => {
-                  fields.push({
-                    type: "query",
-                    operator: "equal",
-                    name: "",
-                    value: "",
-                  });
-                }}
-              >
-                Add Query Rule
-              </Button>
-            </Box>
+            {conditionTypes.map(({ type, text }) => (
+              <Box mt={2} mr={2} mb={2} key={type}>
+                <Button
+                  variant="outlined"
+                  color="primary"
+                  startIcon={<AddIcon />}
+                  size="small"
+                  onClick={() => {
+                    fields.push({
+                      type,
+                      operator: "equal",
+                      name: "",
+                      value: "",
+                    });
+                  }}
+                >
+                  Add {text} Rule
+                </Button>
+              </Box>
+            ))}
           </Box>
           {fields.value &&
             fields.value.map((condition, index) => (
@@ -60,23 +80,21 @@ export const RenderHttpRouteConditions: React.FC = () => {
                   <div style={{ padding: "12px 0" }}>{condition.type} Rule</div>
                 </Grid>
                 <Grid item md={2}>
-                  <Field
-                    name={`conditions.${index}.name`}
-                    component={FinalTextField}
-                    label="Name"
-                    validate={ValidatorRequired}
-                  />
+                  {conditionTypeHasName(condition.type) ? (
+                    <Field
+                      name={`conditions.${index}.name`}
+                      component={FinalTextField}
+                      label="Name"
+                      validate={ValidatorRequired}
+                    />
+                  ) : null}
                 </Grid>
                 <Grid item md={2}>
                   <Field
                     name={`conditions.${index}.operator`}
                     component={FinalSelectField}
                     label="operator"
-                    options={[
-                      { value: "equal", text: "Equal" },
-                      { value: "withPrefix", text: "With Prefix" },
-                      { value: "matchRegexp", text: "Match Regexp" },
-                    ]}
+                    options={conditionOperatorOptions(condition.type)}
                   ></Field>
                 </Grid>
                 <Grid item md={2}>
@@ -25,6 +25,10 @@ export interface HttpRouteCondition {
   value: string;
 }
 
+export interface HttpRouteConditionGroup {
+  conditions: HttpRouteCondition[];
+}
+
 export interface HttpRouteDestination {
   host: string;
   weight: number;
@@ -35,10 +39,46 @@ export interface HttpRouteDestinationStatus {
   error: string;
 }
 
+export interface HttpRouteRetryBudget {
+  budgetPercent: number;
+  minRetryConcurrency?: number;
+}
+
 export interface HttpRouteRetry {
   attempts: number;
-  perTtyTimeoutSeconds: number;
+  perTtyTimeoutSeconds?: number;
+  perTryTimeoutMilliseconds?: number;
   retryOn: string[];
+  budget?: HttpRouteRetryBudget;
+}
+
+export interface HttpRouteRedirect {
+  scheme?: "http" | "https";
+  host?: string;
+  path?: string;
+  statusCode?: 301 | 302 | 303 | 307 | 308;
+}
+
+export interface HttpRouteDirectResponse {
+  statusCode: number;
+  body?: string;
+  contentType?: string;
+}
+
+export interface HttpRouteRewrite {
+  prefix?: string;
+  host?: string;
+}
+
+export interface HttpRouteHeaderOperations {
+  set?: { [key: string]: string };
+  add?: { [key: string]: string };
+  remove?: string[];
+}
+
+export interface HttpRouteHeaders {
+  request?: HttpRouteHeaderOperations;
+  response?: HttpRouteHeaderOperations;
 }
 
 export interface HttpRouteMirror {
@@ -64,6 +104,25 @@ export interface HttpRouteCORS {
   maxAge: number;
 }
 
+export interface HttpRouteRateLimitResponse {
+  body?: string;
+  headers?: { [key: string]: string };
+}
+
+export interface HttpRouteRateLimitKey {
+  type: "clientIP" | "header" | "jwtClaim";
+  name?: string;
+  issuer?: string;
+}
+
+export interface HttpRouteRateLimit {
+  requests: number;
+  unit: "second" | "minute";
+  burst?: number;
+  key?: HttpRouteRateLimitKey;
+  response?: HttpRouteRateLimitResponse;
+}
+
 export const methodsModeAll = "all";
 export const methodsModeSpecific = "specific";
 export const httpMethods = ["GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD", "TRACE", "CONNECT"];
@@ -75,16 +134,23 @@ export interface HttpRoute {
   methods: string[];
   schemes: string[];
   stripPath?: boolean;
+  rewrite?: HttpRouteRewrite;
+  headers?: HttpRouteHeaders;
   conditions?: HttpRouteCondition[];
+  conditionGroups?: HttpRouteConditionGroup[];
   destinations: HttpRouteDestination[];
+  redirect?: HttpRouteRedirect;
+  directResponse?: HttpRouteDirectResponse;
   destinationsStatus?: HttpRouteDestinationStatus[];
   httpRedirectToHttps?: boolean;
   timeout?: number;
+  timeoutMilliseconds?: number;
   retries?: HttpRouteRetry;
   mirror?: HttpRouteMirror;
   fault?: HttpRouteFault;
   delay?: HttpRouteDelay;
   cors?: HttpRouteCORS;
+  rateLimit?: HttpRouteRateLimit;
   methodsMode?: string;
 }
 
@@ -98,12 +164,6 @@ export const newEmptyRouteForm = (defaultDomain?: string): HttpRoute => {
     httpRedirectToHttps: false,
     schemes: ["http"],
     methods: ["GET"],
-    timeout: 5,
-    retries: {
-      attempts: 3,
-      perTtyTimeoutSeconds: 2,
-      retryOn: ["gateway-error", "connect-failure", "refused-stream"],
-    },
     methodsMode: methodsModeAll,
   };
 };
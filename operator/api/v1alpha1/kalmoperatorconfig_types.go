@@ -41,6 +41,16 @@ type ControllerConfig struct {
 	UseLetsEncryptProductionAPI bool `json:"useLetsencryptProductionAPI"`
 	// +optional
 	ExternalDNSServerIP string `json:"externalDNSServerIP"`
+	// dns provider to manage DNSRecords: cloudflare or rfc2136, default to cloudflare
+	// +kubebuilder:validation:Enum=cloudflare;rfc2136
+	// +optional
+	DNSProvider string `json:"dnsProvider,omitempty"`
+	// +optional
+	RFC2136Config *RFC2136Config `json:"rfc2136Config,omitempty"`
+	// days before expiration when https certs are considered expiring, default to 14
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CertExpiryWarningDays int `json:"certExpiryWarningDays,omitempty"`
 }
 
 // KalmOperatorConfigSpec defines the desired state of KalmOperatorConfig
@@ -113,6 +123,22 @@ type CloudflareConfig struct {
 	DomainToZoneIDConfig map[string]string `json:"domainToZoneIDConfig,omitempty"`
 }
 
+// RFC2136Config is the config of dns servers supporting dynamic updates, like BIND and PowerDNS
+type RFC2136Config struct {
+	// host:port of the dns server
+	Nameserver string   `json:"nameserver"`
+	Zones      []string `json:"zones"`
+	// +optional
+	TSIGKeyName string `json:"tsigKeyName,omitempty"`
+	// +optional
+	TSIGSecret string `json:"tsigSecret,omitempty"`
+	// default to hmac-sha256
+	// +optional
+	TSIGAlgorithm string `json:"tsigAlgorithm,omitempty"`
+	// +optional
+	TTL uint32 `json:"ttl,omitempty"`
+}
+
 type InstallStatusKey string
 
 var (
@@ -89,6 +89,11 @@ func (in *ControllerConfig) DeepCopyInto(out *ControllerConfig) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.RFC2136Config != nil {
+		in, out := &in.RFC2136Config, &out.RFC2136Config
+		*out = new(RFC2136Config)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfig.
@@ -404,6 +409,26 @@ func (in *OIDCIssuerConfig) DeepCopy() *OIDCIssuerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RFC2136Config) DeepCopyInto(out *RFC2136Config) {
+	*out = *in
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RFC2136Config.
+func (in *RFC2136Config) DeepCopy() *RFC2136Config {
+	if in == nil {
+		return nil
+	}
+	out := new(RFC2136Config)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SaaSModeConfig) DeepCopyInto(out *SaaSModeConfig) {
 	*out = *in
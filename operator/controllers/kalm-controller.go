@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/kalmhq/kalm/controller/api/v1alpha1"
@@ -202,6 +203,9 @@ func getEnvVarsForController(configSpec installv1alpha1.KalmOperatorConfigSpec)
 
 	var envUseLetsencryptProductionAPI string
 	var extDNSServerIP string
+	var dnsProvider string
+	var rfc2136Config *installv1alpha1.RFC2136Config
+	var certExpiryWarningDays string
 
 	controllerConfig := configSpec.Controller
 	if controllerConfig != nil {
@@ -212,6 +216,12 @@ func getEnvVarsForController(configSpec installv1alpha1.KalmOperatorConfigSpec)
 		}
 
 		extDNSServerIP = controllerConfig.ExternalDNSServerIP
+		dnsProvider = controllerConfig.DNSProvider
+		rfc2136Config = controllerConfig.RFC2136Config
+
+		if controllerConfig.CertExpiryWarningDays > 0 {
+			certExpiryWarningDays = strconv.Itoa(controllerConfig.CertExpiryWarningDays)
+		}
 	}
 
 	kalmMode := DecideKalmMode(configSpec)
@@ -250,6 +260,24 @@ func getEnvVarsForController(configSpec installv1alpha1.KalmOperatorConfigSpec)
 		{Name: v1alpha1.ENV_CLOUDFLARE_TOKEN, Value: cloudflareToken},
 		{Name: v1alpha1.ENV_CLOUDFLARE_DOMAIN_TO_ZONEID_CONFIG, Value: cloudflareDomainToZoneConfigStr},
 		{Name: v1alpha1.ENV_EXTERNAL_DNS_SERVER_IP, Value: extDNSServerIP},
+		{Name: v1alpha1.ENV_DNS_PROVIDER, Value: dnsProvider},
+		{Name: v1alpha1.ENV_CERT_EXPIRY_WARNING_DAYS, Value: certExpiryWarningDays},
+	}
+
+	if rfc2136Config != nil {
+		var ttl string
+		if rfc2136Config.TTL > 0 {
+			ttl = strconv.FormatUint(uint64(rfc2136Config.TTL), 10)
+		}
+
+		envVars = append(envVars,
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_NAMESERVER, Value: rfc2136Config.Nameserver},
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_ZONES, Value: strings.Join(rfc2136Config.Zones, ",")},
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_TSIG_KEY_NAME, Value: rfc2136Config.TSIGKeyName},
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_TSIG_SECRET, Value: rfc2136Config.TSIGSecret},
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_TSIG_ALGORITHM, Value: rfc2136Config.TSIGAlgorithm},
+			corev1.EnvVar{Name: v1alpha1.ENV_RFC2136_TTL, Value: ttl},
+		)
 	}
 
 	return envVars
@@ -141,7 +141,7 @@ func istioYaml() (*asset, error) {
 	return a, nil
 }
 
-var _istiocontrolplaneYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x64\x8f\xbd\x4e\x73\x31\x0c\x86\xf7\x5c\x85\xd5\xfd\xf4\x6b\x3f\x09\x84\xb2\x21\x26\x06\x7e\x24\x04\xbb\x9b\xb8\x34\xaa\x13\x87\xd8\xa9\xe8\xdd\xa3\x53\x7a\xa0\xa5\x5b\xf2\x24\xef\xeb\xc7\x58\xd3\x1b\x35\x4d\x52\x3c\xec\x96\x6e\x9b\x4a\xf4\xf0\x88\x99\xb4\x62\x20\x97\xc9\x30\xa2\xa1\x77\x00\x05\x33\x79\x48\x6a\x49\x06\xdd\xab\x51\x76\x00\x8c\x2b\x62\x1d\x9f\x01\xb6\xc8\x79\x08\x52\xac\x09\x0f\x95\xb1\x90\x87\x99\xb5\x4e\x33\x37\x0c\x83\x3b\x1d\x95\x8a\x1a\x32\xcf\x0f\x6d\xf3\x24\xff\x76\x4b\xe4\xba\xc1\xc9\xe0\x7e\xe4\x4f\x95\x1a\x9a\xb4\x0b\x8b\x83\xdb\x85\xca\x89\xdf\x51\xe2\xe0\xe0\xb4\x52\x18\x93\x3b\xe4\x4e\x47\xd5\x77\x96\x15\xf2\xf7\x19\xa0\x36\xf9\xdc\x4f\x17\x80\x8d\x70\xbc\xad\x95\x53\x40\x4b\x52\x5e\x8b\x25\x7e\x1e\xbf\xbc\x18\x36\x53\x0f\xe3\x4e\x0e\x00\x63\x94\x72\x27\xb9\x4a\xa1\x62\xc7\xe6\xda\x24\x93\x6d\xa8\xeb\x54\x48\x05\x57\x4c\xf1\x27\x56\x9b\xac\x13\x93\x87\x48\x6b\xec\x6c\x0e\x20\xfc\x2d\x49\x2c\x36\xe5\xb7\x37\xfa\xeb\xd6\x48\xa5\xb7\x40\x27\x68\x84\x1f\x9d\xd4\xce\x18\x40\xa8\xdd\xc3\x72\xb1\xc8\x67\x34\x53\x96\xb6\xf7\xf0\xff\xea\xfa\x21\xb9\xaf\x00\x00\x00\xff\xff\xbf\xda\xcb\x90\xff\x01\x00\x00")
+var _istiocontrolplaneYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x91\x4f\x6f\xd3\x40\x10\xc5\xef\xfe\x14\x4f\xe5\x5a\x97\x16\x09\x84\x7c\xab\x38\xa0\x4a\x50\x22\xb5\x70\x9f\xec\x8e\xe3\x51\xd6\x3b\xcb\xee\x38\xa9\xbf\x3d\x72\x62\x47\x49\x8a\x4f\x9e\x7f\x6f\x7e\x6f\x87\x92\xfc\xe1\x5c\x44\x63\x83\xdd\x43\xb5\x95\xe8\x1b\x3c\x53\xcf\x25\x91\xe3\xaa\x67\x23\x4f\x46\x4d\x05\x44\xea\xb9\x81\x14\x13\xad\xcb\x58\x8c\xfb\x0a\x08\xb4\xe6\x50\xa6\x32\xb0\xa5\xd0\xd7\x4e\xa3\x65\x0d\x75\x0a\x14\xb9\xc1\x8d\xe5\x81\x6f\xaa\xba\xae\xab\xf3\x55\x12\x8b\x51\x08\x77\x07\xb5\x3b\xd1\x8f\xbb\x07\x0a\xa9\xa3\x85\xe0\x69\xca\xff\x4a\x9c\xc9\x34\xbf\xa3\x38\xb0\xbd\x43\x39\xe3\x9b\x21\x0e\x0c\x55\x49\xec\x26\xc0\x1d\x85\x81\x67\xd4\x4d\xd0\x35\x85\xe3\x3f\x90\xb2\xbe\x8d\x4b\x00\x74\x1a\xfc\x63\x4a\x41\x1c\x99\x68\xfc\x1d\x4d\xc2\x6a\x6a\x79\x31\xca\x56\x1a\x4c\x9e\x2a\x80\xbc\xd7\xf8\x4d\xfb\xa4\x91\xa3\xcd\xca\x29\x6b\xcf\xd6\xf1\x30\xc7\x00\x47\x5a\x07\xf6\xa7\xb1\x94\xb5\x95\xc0\x0d\x3c\xb7\x34\x04\xab\x00\x77\x2d\x22\x41\x6d\x99\xdf\x7e\x3d\x49\x01\x99\x8b\x0e\xd9\x2d\x3e\x96\xe4\xdf\x81\xcb\x32\xbc\x7c\x2e\x0d\x0d\x1e\xee\xef\xfb\x8b\x6c\xcf\xbd\xe6\xb1\xc1\xa7\xcf\x5f\x7e\xca\xa1\x22\x71\x93\xb9\x94\xef\x64\xbc\xa7\xf1\x24\x52\x5f\x1c\x7c\x6e\xda\x1c\x9b\x4e\x8a\xd7\xde\xfe\x43\x0c\x14\xce\x3b\x71\x7c\x9e\x02\x3e\x60\xcb\x9c\x70\x74\x03\x49\x05\xda\xc2\x05\x99\x5e\xf2\x16\x6a\x1d\xe7\xbd\x14\x3e\x79\x43\xab\x79\x4f\xd9\xb3\xc7\x7a\x3c\xd6\x11\xd5\x73\x01\x65\xc6\xcb\xf3\xe3\x2b\x7b\x98\x42\xa2\x71\x8e\x14\xa6\xeb\x4c\xb6\xb8\xdc\x5e\xed\xdd\x77\xe2\x3a\xac\x33\xd3\xb6\xcc\xfb\x9f\x56\x70\x1a\xbd\x4c\xe7\x2e\xa0\xe8\x67\x12\x48\x42\x26\x63\x04\xe9\xc5\x0e\x88\x9d\x59\x42\xd6\xc1\xb8\x5c\xe8\xf2\xdb\x71\xef\x6b\xa6\xb6\x15\xb7\xd2\x20\x6e\x6c\xf0\x43\x1d\x85\xea\xdf\x00\xa4\x78\x1a\xd9\x66\x03\x00\x00")
 
 func istiocontrolplaneYamlBytes() ([]byte, error) {
 	return bindataRead(